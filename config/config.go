@@ -23,6 +23,7 @@ type AddConfig struct {
 	Digest                checksum.DigestAlgorithm
 	Fixity                []string
 	Message               string
+	DryRun                bool
 }
 
 type UpdateConfig struct {
@@ -32,6 +33,7 @@ type UpdateConfig struct {
 	Echo        bool
 	Message     string
 	Digest      checksum.DigestAlgorithm
+	DryRun      bool
 }
 
 type AESConfig struct {
@@ -42,13 +44,50 @@ type AESConfig struct {
 	IV           configutil.EnvString
 }
 
+type DisplayJWTConfig struct {
+	JWKS          string   `toml:"jwks"`
+	Issuer        string   `toml:"issuer"`
+	Audience      string   `toml:"audience"`
+	Algorithms    []string `toml:"algorithms"`
+	UserClaim     string   `toml:"userclaim"`
+	GroupsClaim   string   `toml:"groupsclaim"`
+	LeewaySeconds int      `toml:"leeway"`
+}
+
+type DisplayAuthConfig struct {
+	// Methods is a list of "bearer", "htpasswd" and "jwt". empty: no authentication
+	Methods  []string          `toml:"methods"`
+	Realm    string            `toml:"realm"`
+	Tokens   map[string]string `toml:"tokens"`
+	Htpasswd string            `toml:"htpasswd"`
+	JWT      DisplayJWTConfig  `toml:"jwt"`
+}
+
+// DisplayRule grants access to all objects whose id starts with Prefix.
+// the rule with the longest matching prefix is used
+type DisplayRule struct {
+	Prefix    string   `toml:"prefix"`
+	Users     []string `toml:"users"`
+	Groups    []string `toml:"groups"`
+	Anonymous bool     `toml:"anonymous"`
+	Deny      bool     `toml:"deny"`
+}
+
 type DisplayConfig struct {
-	Addr      string `toml:"addr"`
-	AddrExt   string `toml:"addrext"`
-	CertFile  string `toml:"certfile"`
-	KeyFile   string `toml:"keyfile"`
-	Templates string `toml:"templates"`
-	Obfuscate bool   `toml:"obfuscate"`
+	Addr             string              `toml:"addr"`
+	AddrExt          string              `toml:"addrext"`
+	CertFile         string              `toml:"certfile"`
+	KeyFile          string              `toml:"keyfile"`
+	Templates        string              `toml:"templates"`
+	Obfuscate        bool                `toml:"obfuscate"`
+	SearchIndex      string              `toml:"searchindex"`
+	SearchRebuild    bool                `toml:"searchrebuild"`
+	DashboardRefresh configutil.Duration `toml:"dashboardrefresh"`
+	DashboardCheck   bool                `toml:"dashboardcheck"`
+	DAV              bool                `toml:"dav"`
+	DAVArea          string              `toml:"davarea"`
+	Auth             DisplayAuthConfig   `toml:"auth"`
+	Rule             []*DisplayRule      `toml:"rule"`
 }
 type ExtractConfig struct {
 	Manifest   bool
@@ -58,9 +97,38 @@ type ExtractConfig struct {
 	Area       string
 }
 
+type ExportConfig struct {
+	Version    string
+	Format     string
+	ObjectPath string
+	ObjectID   string
+}
+
+type IngestConfig struct {
+	Format  string
+	Message string
+}
+
+type MountConfig struct {
+	Area       string
+	AllowOther bool
+}
+
 type ValidateConfig struct {
 	ObjectPath string
 	ObjectID   string
+	Timestamps bool
+	Signatures bool
+}
+
+type SignatureConfig struct {
+	TrustStore []string
+}
+
+type TimestampConfig struct {
+	ObjectPath string
+	ObjectID   string
+	TrustStore []string
 }
 
 type ExtractMetaConfig struct {
@@ -70,6 +138,7 @@ type ExtractMetaConfig struct {
 	ObjectPath string
 	ObjectID   string
 	Obfuscate  bool
+	XMLLint    string
 }
 
 type StatConfig struct {
@@ -78,6 +147,16 @@ type StatConfig struct {
 	ObjectID   string
 }
 
+type DedupConfig struct {
+	Enabled bool
+	Report  string
+}
+
+type LockConfig struct {
+	Disabled bool
+	Expiry   configutil.Duration
+}
+
 type UserConfig struct {
 	Name    string
 	Address string
@@ -119,6 +198,7 @@ type S3Config struct {
 	AccessKeyID configutil.EnvString
 	AccessKey   configutil.EnvString
 	Region      configutil.EnvString
+	UseSSL      bool
 }
 
 type GOCFLConfig struct {
@@ -134,12 +214,21 @@ type GOCFLConfig struct {
 	Add           AddConfig                    `toml:"add"`
 	Update        UpdateConfig                 `toml:"update"`
 	Display       DisplayConfig                `toml:"display"`
+	Lock          LockConfig                   `toml:"lock"`
+	Dedup         DedupConfig                  `toml:"dedup"`
 	Extract       ExtractConfig                `toml:"extract"`
 	ExtractMeta   ExtractMetaConfig            `toml:"extractmeta"`
+	Export        ExportConfig                 `toml:"export"`
+	Ingest        IngestConfig                 `toml:"ingest"`
+	Mount         MountConfig                  `toml:"mount"`
 	Stat          StatConfig                   `toml:"stat"`
 	Validate      ValidateConfig               `toml:"validate"`
+	Timestamp     TimestampConfig              `toml:"timestamp"`
+	Signature     SignatureConfig              `toml:"signature"`
 	S3            S3Config                     `toml:"s3"`
 	DefaultArea   string                       `toml:"defaultarea"`
+	Progress      string                       `toml:"progress"`
+	LinkMode      string                       `toml:"linkmode"`
 	Log           stashconfig.Config           `toml:"log"`
 }
 
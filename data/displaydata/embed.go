@@ -45,4 +45,8 @@ var WebRoot embed.FS
 //go:embed templates/version.gohtml
 //go:embed templates/detail.gohtml
 //go:embed templates/report.gohtml
+//go:embed templates/history.gohtml
+//go:embed templates/diff.gohtml
+//go:embed templates/search.gohtml
+//go:embed templates/dashboard.gohtml
 var TemplateRoot embed.FS
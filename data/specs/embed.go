@@ -15,3 +15,9 @@ var XLinkXSD []byte
 
 //go:embed premis.xsd
 var PremisXSD []byte
+
+//go:embed DILCISExtensionMETS.xsd
+var CSIPExtensionMETSXSD []byte
+
+//go:embed DILCISExtensionSIPMETS.xsd
+var SIPExtensionMETSXSD []byte
@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"emperror.dev/errors"
@@ -15,6 +16,8 @@ import (
 	"github.com/je4/utils/v2/pkg/checksum"
 	"github.com/je4/utils/v2/pkg/zLogger"
 	"github.com/ocfl-archive/gocfl/v2/internal"
+	"github.com/ocfl-archive/gocfl/v2/pkg/bagit"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
@@ -51,6 +54,9 @@ func initAdd() {
 	addCmd.Flags().StringP("digest", "d", "", "digest to use for ocfl checksum")
 	addCmd.Flags().Bool("deduplicate", false, "force deduplication (slower)")
 	addCmd.Flags().Bool("no-compress", false, "do not compress data in zip file")
+	addCmd.Flags().Bool("dry-run", false, "show what would be done without writing anything")
+	addCmd.Flags().String("dedup-report", "", "check for content stored in other objects and write json report to file")
+	addCmd.Flags().String("link-mode", "", "put content into the object with copy, hardlink or reflink (default: copy)")
 }
 
 func doAddConf(cmd *cobra.Command) {
@@ -93,6 +99,13 @@ func doAddConf(cmd *cobra.Command) {
 			conf.Add.NoCompress = b
 		}
 	}
+	if b, ok := getFlagBool(cmd, "dry-run"); b {
+		if ok {
+			conf.Add.DryRun = b
+		}
+	}
+	doDedupConf(cmd)
+	doLinkConf(cmd)
 
 	if str := getFlagString(cmd, "digest"); str != "" {
 		conf.Add.Digest = checksum.DigestAlgorithm(str)
@@ -211,7 +224,17 @@ func doAdd(cmd *cobra.Command, args []string) {
 	if err != nil {
 		logger.Panic().Stack().Err(err).Msgf("cannot get filesystem for '%s'", srcPath)
 	}
-	destFS, err := fsFactory.Get(ocflPath, false)
+	var bag *bagSource
+	if bagit.IsBag(sourceFS) {
+		if bag, err = openBagSource(sourceFS, srcPath, logger); err != nil {
+			logger.Error().Err(err).Msgf("cannot use bag '%s'", srcPath)
+			return
+		}
+		defer bag.Close()
+		sourceFS = bag.payloadFS
+		srcPath = filepath.Join(srcPath, bagit.PayloadFolder)
+	}
+	destFS, err := fsFactory.Get(ocflPath, conf.Add.DryRun)
 	if err != nil {
 		logger.Panic().Stack().Msgf("cannot get filesystem for '%s'", ocflPath)
 	}
@@ -231,12 +254,14 @@ func doAdd(cmd *cobra.Command, args []string) {
 		area = "content"
 	}
 	var areaPaths = map[string]fs.FS{}
+	var linkSources = map[string]string{area: srcPath}
 	for i := 2; i < len(args); i++ {
 		matches := areaPathRegexp.FindStringSubmatch(args[i])
 		if matches == nil {
 			logger.Error().Msgf("no area given in areapath '%s'", args[i])
 			continue
 		}
+		linkSources[matches[1]] = matches[2]
 		areaPaths[matches[1]], err = fsFactory.Get(matches[2], true)
 		if err != nil {
 			doNotClose = true
@@ -259,6 +284,14 @@ func doAdd(cmd *cobra.Command, args []string) {
 	thumb.SetSourceFS(sourceFS)
 
 	extensionParams := GetExtensionParamValues(cmd, conf)
+	var knownDigests object.KnownDigests
+	if bag != nil {
+		if err := bag.apply(area, areaPaths, extensionParams); err != nil {
+			logger.Error().Err(err).Msgf("cannot use bag '%s'", args[1])
+			return
+		}
+		knownDigests = bag.knownDigests
+	}
 	extensionFactory, err := InitExtensionFactory(extensionParams, addr, localCache, indexerActions, mig, thumb, sourceFS, (logger))
 	if err != nil {
 		doNotClose = true
@@ -295,7 +328,53 @@ func doAdd(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	_, err = addObjectByPath(
+	if conf.Add.DryRun {
+		report, err := dryRunObjectByPath(
+			storageRoot,
+			fixityAlgs,
+			extensionFactory,
+			objectExtensionManager,
+			conf.Add.Deduplicate,
+			flagObjectID,
+			conf.Add.User.Name,
+			conf.Add.User.Address,
+			conf.Add.Message,
+			sourceFS,
+			area,
+			areaPaths,
+			false,
+			logger,
+		)
+		if err != nil {
+			doNotClose = true
+			logger.Panic().Stack().Err(err).Msgf("error simulating content for storageroot filesystem '%s'", destFS)
+		}
+		if err := report.Write(os.Stdout); err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot write dry run report")
+		}
+		_ = showStatus(ctx, logger)
+		return
+	}
+
+	linker, err := newLinker(conf.LinkMode, ocflPath, linkSources, logger)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Err(err).Msg("cannot initialize link mode")
+	}
+
+	locker, err := newLocker(ocflPath, storageRoot.GetFS(), conf.Add.User.Name, logger)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Err(err).Msg("cannot initialize object locks")
+	}
+
+	checker, err := newDedupChecker(storageRoot, ocflPath, flagObjectID, locker, logger)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Err(err).Msg("cannot load content digest index")
+	}
+
+	modified, err := addObjectByPath(
 		storageRoot,
 		fixityAlgs,
 		extensionFactory,
@@ -309,12 +388,17 @@ func doAdd(cmd *cobra.Command, args []string) {
 		area,
 		areaPaths,
 		false,
+		linker,
+		locker,
+		checker,
+		knownDigests,
 		logger,
 	)
 	if err != nil {
 		doNotClose = true
 		logger.Panic().Stack().Err(err).Msgf("error adding content to storageroot filesystem '%s'", destFS)
 	}
+	checkCrossObjectDuplicates(storageRoot, ocflPath, flagObjectID, modified, checker, locker, logger)
 	_ = showStatus(ctx, logger)
 
 }
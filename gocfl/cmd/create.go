@@ -8,12 +8,16 @@ import (
 	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"testing/fstest"
 
 	"github.com/je4/filesystem/v3/pkg/writefs"
 	"github.com/je4/utils/v2/pkg/checksum"
 	"github.com/je4/utils/v2/pkg/zLogger"
 	"github.com/ocfl-archive/gocfl/v2/internal"
+	"github.com/ocfl-archive/gocfl/v2/pkg/bagit"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
@@ -54,6 +58,9 @@ func initCreate() {
 	createCmd.Flags().String("default-area", "", "default area for update or ingest (default: content)")
 	createCmd.Flags().Bool("deduplicate", false, "force deduplication (slower)")
 	createCmd.Flags().Bool("no-compress", false, "do not compress data in zip file")
+	createCmd.Flags().Bool("dry-run", false, "show what would be done without writing anything")
+	createCmd.Flags().String("dedup-report", "", "check for content stored in other objects and write json report to file")
+	createCmd.Flags().String("link-mode", "", "put content into the object with copy, hardlink or reflink (default: copy)")
 	createCmd.Flags().Bool("encrypt-aes", false, "create encrypted container (only for container target)")
 	createCmd.Flags().String("aes-key", "", "key to use for encrypted container in hex format (64 chars, empty: generate random key)")
 	createCmd.Flags().String("aes-iv", "", "initialisation vector to use for encrypted container in hex format (32 char, sempty: generate random vector)")
@@ -195,21 +202,38 @@ func doCreate(cmd *cobra.Command, args []string) {
 	if err != nil {
 		logger.Panic().Stack().Err(err).Msgf("cannot get filesystem for '%s'", srcPath)
 	}
-	destFS, err := fsFactory.Get(ocflPath, false)
-	if err != nil {
-		logger.Panic().Stack().Msgf("cannot get filesystem for '%s'", ocflPath)
+	var bag *bagSource
+	if bagit.IsBag(sourceFS) {
+		if bag, err = openBagSource(sourceFS, srcPath, logger); err != nil {
+			logger.Error().Err(err).Msgf("cannot use bag '%s'", srcPath)
+			return
+		}
+		defer bag.Close()
+		sourceFS = bag.payloadFS
+		srcPath = filepath.Join(srcPath, bagit.PayloadFolder)
 	}
-	defer func() {
-		if err := writefs.Close(destFS); err != nil {
-			logger.Panic().Stack().Err(err).Msgf("error closing filesystem '%s'", destFS)
+	var destFS fs.FS
+	if conf.Add.DryRun {
+		// the storage root does not exist yet and must not be created
+		destFS = fstest.MapFS{}
+	} else {
+		destFS, err = fsFactory.Get(ocflPath, false)
+		if err != nil {
+			logger.Panic().Stack().Msgf("cannot get filesystem for '%s'", ocflPath)
 		}
-	}()
+		defer func() {
+			if err := writefs.Close(destFS); err != nil {
+				logger.Panic().Stack().Err(err).Msgf("error closing filesystem '%s'", destFS)
+			}
+		}()
+	}
 
 	area := conf.DefaultArea
 	if area == "" {
 		area = "content"
 	}
 	var areaPaths = map[string]fs.FS{}
+	var linkSources = map[string]string{area: srcPath}
 	for i := 2; i < len(args); i++ {
 		matches := areaPathRegexp.FindStringSubmatch(args[i])
 		if matches == nil {
@@ -220,6 +244,7 @@ func doCreate(cmd *cobra.Command, args []string) {
 		if err != nil {
 			logger.Panic().Err(err).Msgf("cannot get fullpath for '%s'", matches[2])
 		}
+		linkSources[matches[1]] = path
 		areaPaths[matches[1]], err = fsFactory.Get(path, true)
 		if err != nil {
 			logger.Panic().Stack().Err(err).Msgf("cannot get filesystem for '%s'", args[i])
@@ -241,6 +266,14 @@ func doCreate(cmd *cobra.Command, args []string) {
 	thumb.SetSourceFS(sourceFS)
 
 	extensionParams := GetExtensionParamValues(cmd, conf)
+	var knownDigests object.KnownDigests
+	if bag != nil {
+		if err := bag.apply(area, areaPaths, extensionParams); err != nil {
+			logger.Error().Err(err).Msgf("cannot use bag '%s'", args[1])
+			return
+		}
+		knownDigests = bag.knownDigests
+	}
 	extensionFactory, err := InitExtensionFactory(extensionParams, addr, localCache, indexerActions, mig, thumb, sourceFS, logger)
 	if err != nil {
 		logger.Error().Stack().Err(err).Msg("cannot create extension factory")
@@ -262,6 +295,44 @@ func doCreate(cmd *cobra.Command, args []string) {
 	}()
 
 	ctx := validation.NewContextValidation(context.TODO())
+	if conf.Add.DryRun {
+		storageRoot, err := storageroot.NewDryRunStorageRoot(
+			ctx,
+			destFS,
+			version.OCFLVersion(conf.Init.OCFLVersion),
+			extensionFactory,
+			storageRootExtensionManager,
+			conf.Init.Digest,
+			logger,
+		)
+		if err != nil {
+			logger.Panic().Stack().Err(err).Msg("cannot create storage root for dry run")
+		}
+		report, err := dryRunObjectByPath(
+			storageRoot,
+			fixityAlgs,
+			extensionFactory,
+			objectExtensionManager,
+			conf.Add.Deduplicate,
+			flagObjectID,
+			conf.Add.User.Name,
+			conf.Add.User.Address,
+			conf.Add.Message,
+			sourceFS,
+			area,
+			areaPaths,
+			false,
+			logger,
+		)
+		if err != nil {
+			logger.Panic().Stack().Err(err).Msgf("error simulating content for storageroot '%s'", ocflPath)
+		}
+		if err := report.Write(os.Stdout); err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot write dry run report")
+		}
+		_ = showStatus(ctx, logger)
+		return
+	}
 	storageRoot, err := storageroot.CreateStorageRoot(
 		ctx,
 		destFS,
@@ -278,7 +349,22 @@ func doCreate(cmd *cobra.Command, args []string) {
 		logger.Panic().Stack().Err(err).Msg("cannot create new storage root")
 	}
 
-	_, err = addObjectByPath(
+	linker, err := newLinker(conf.LinkMode, ocflPath, linkSources, logger)
+	if err != nil {
+		logger.Panic().Stack().Err(err).Msg("cannot initialize link mode")
+	}
+
+	locker, err := newLocker(ocflPath, storageRoot.GetFS(), conf.Add.User.Name, logger)
+	if err != nil {
+		logger.Panic().Stack().Err(err).Msg("cannot initialize object locks")
+	}
+
+	checker, err := newDedupChecker(storageRoot, ocflPath, flagObjectID, locker, logger)
+	if err != nil {
+		logger.Panic().Stack().Err(err).Msg("cannot load content digest index")
+	}
+
+	modified, err := addObjectByPath(
 		storageRoot,
 		fixityAlgs,
 		extensionFactory,
@@ -292,11 +378,16 @@ func doCreate(cmd *cobra.Command, args []string) {
 		area,
 		areaPaths,
 		false,
+		linker,
+		locker,
+		checker,
+		knownDigests,
 		logger,
 	)
 	if err != nil {
 		logger.Panic().Stack().Err(err).Msgf("error adding content to storageroot filesystem '%s'", destFS)
 	}
+	checkCrossObjectDuplicates(storageRoot, ocflPath, flagObjectID, modified, checker, locker, logger)
 	_ = showStatus(ctx, logger)
 
 }
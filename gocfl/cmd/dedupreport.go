@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/dedup"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/spf13/cobra"
+	ublogger "gitlab.switch.ch/ub-unibas/go-ublogger/v2"
+	"go.ub.unibas.ch/cloud/certloader/v2/pkg/loader"
+)
+
+var dedupReportCmd = &cobra.Command{
+	Use:     "dedup-report [path to ocfl structure]",
+	Aliases: []string{},
+	Short:   "reports content stored in more than one object",
+	Long: "reads the inventories of all objects and reports content digests which are stored in more than one object.\n" +
+		"OCFL does not allow references between objects, so this is the amount of bytes which could be saved by restructuring the objects",
+	Example: "gocfl dedup-report ./archive.zip --json --output dedup.json",
+	Args:    cobra.ExactArgs(1),
+	Run:     doDedupReport,
+}
+
+func initDedupReport() {
+	dedupReportCmd.Flags().Bool("json", false, "write report as json")
+	dedupReportCmd.Flags().StringP("output", "o", "", "output file (default is console)")
+	dedupReportCmd.Flags().Bool("store-index", false, "store content digest index in storage root")
+}
+
+// doDedupConf sets the cross object duplicate check for add, create and update
+func doDedupConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "dedup-report"); str != "" {
+		conf.Dedup.Enabled = true
+		conf.Dedup.Report = str
+	}
+}
+
+func doDedupReport(cmd *cobra.Command, args []string) {
+	ocflPath, err := util.Fullpath(args[0])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+
+	// create logger instance
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("cannot get hostname: %v", err)
+	}
+
+	var loggerTLSConfig *tls.Config
+	var loggerLoader io.Closer
+	if conf.Log.Stash.TLS != nil {
+		loggerTLSConfig, loggerLoader, err = loader.CreateClientLoader(conf.Log.Stash.TLS, nil)
+		if err != nil {
+			log.Fatalf("cannot create client loader: %v", err)
+		}
+		defer loggerLoader.Close()
+	}
+
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	_logger, _logstash, _logfile, err := ublogger.CreateUbMultiLoggerTLS(conf.Log.Level, conf.Log.File,
+		ublogger.SetDataset(conf.Log.Stash.Dataset),
+		ublogger.SetLogStash(conf.Log.Stash.LogstashHost, conf.Log.Stash.LogstashPort, conf.Log.Stash.Namespace, conf.Log.Stash.LogstashTraceLevel),
+		ublogger.SetTLS(conf.Log.Stash.TLS != nil),
+		ublogger.SetTLSConfig(loggerTLSConfig),
+	)
+	if err != nil {
+		log.Fatalf("cannot create logger: %v", err)
+	}
+	if _logstash != nil {
+		defer _logstash.Close()
+	}
+
+	if _logfile != nil {
+		defer _logfile.Close()
+	}
+
+	l2 := _logger.With().Timestamp().Str("host", hostname).Logger() //.Output(output)
+	var logger zLogger.ZLogger = &l2
+
+	asJSON, _ := getFlagBool(cmd, "json")
+	storeIndex, _ := getFlagBool(cmd, "store-index")
+	output := getFlagString(cmd, "output")
+
+	t := startTimer()
+	defer func() { logger.Info().Msgf("Duration: %s", t.String()) }()
+
+	if storeIndex && zipRootRegexp.MatchString(ocflPath) {
+		logger.Warn().Msgf("'%s' is a container, content digest index not stored", ocflPath)
+		storeIndex = false
+	}
+
+	logger.Info().Msgf("opening '%s'", ocflPath)
+
+	fsFactory, err := initializeFSFactory(nil, nil, nil, true, !storeIndex, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot create filesystem factory")
+		return
+	}
+	ocflFS, err := fsFactory.Get(ocflPath, !storeIndex)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
+		return
+	}
+	defer func() {
+		if err := writefs.Close(ocflFS); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot close filesystem '%s'", ocflFS)
+		}
+	}()
+
+	extensionParams := GetExtensionParamValues(cmd, conf)
+	extensionFactory, err := InitExtensionFactory(extensionParams, "", false, nil, nil, nil, nil, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize extension factory")
+		return
+	}
+
+	ctx := validation.NewContextValidation(context.TODO())
+	sr, err := storageroot.LoadStorageRoot(ctx, ocflFS, extensionFactory, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot load storage root")
+		return
+	}
+	objectFolders, err := sr.GetObjectFolders()
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot get object folders")
+		return
+	}
+	var locker *lock.Locker
+	if storeIndex {
+		if locker, err = newLocker(ocflPath, ocflFS, "dedup-report", logger); err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot initialize index lock")
+			return
+		}
+	}
+	unlock, err := lockObject(locker, dedup.ExtensionName, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot lock content digest index")
+		return
+	}
+	defer unlock()
+	idx, err := dedup.BuildIndex(sr.GetFS(), objectFolders, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot build content digest index")
+		return
+	}
+	if storeIndex {
+		if err := idx.Store(); err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot store content digest index")
+		}
+	}
+	report := idx.Report()
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		fp, err := os.Create(output)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot create '%s'", output)
+			return
+		}
+		defer fp.Close()
+		w = fp
+	}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot write report")
+		}
+	} else {
+		fmt.Fprintf(w, "objects: %d\n", report.Objects)
+		fmt.Fprintf(w, "content digests: %d\n", report.Digests)
+		fmt.Fprintf(w, "content size: %s\n", humanize.Bytes(uint64(report.TotalBytes)))
+		fmt.Fprintf(w, "digests in more than one object: %d\n", len(report.Duplicates))
+		fmt.Fprintf(w, "savable size: %s\n", humanize.Bytes(uint64(report.SavableBytes)))
+		for _, dup := range report.Duplicates {
+			fmt.Fprintf(w, "  %s %s in %v\n", humanize.Bytes(uint64(dup.Size)), dup.Digest, dup.Objects)
+		}
+	}
+	_ = showStatus(ctx, logger)
+}
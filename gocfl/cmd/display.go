@@ -52,6 +52,10 @@ func initDisplay() {
 	displayCmd.Flags().StringP("display-templates", "t", "", "path to templates")
 	displayCmd.Flags().StringP("display-tls-cert", "c", "", "path to tls certificate")
 	displayCmd.Flags().StringP("display-tls-key", "k", "", "path to tls certificate key")
+	displayCmd.Flags().String("display-search-index", "", "file for the persistent search index")
+	displayCmd.Flags().Bool("display-search-rebuild", false, "rebuild the search index even if the index file exists")
+	displayCmd.Flags().Bool("display-dav", false, "enable read-only webdav access at /dav")
+	displayCmd.Flags().String("display-dav-area", "", "data area of the webdav paths (default \"content\")")
 }
 
 func doDisplayConf(cmd *cobra.Command) {
@@ -70,6 +74,18 @@ func doDisplayConf(cmd *cobra.Command) {
 	if str := getFlagString(cmd, "display-tls-key"); str != "" {
 		conf.Display.KeyFile = str
 	}
+	if str := getFlagString(cmd, "display-search-index"); str != "" {
+		conf.Display.SearchIndex = str
+	}
+	if b, ok := getFlagBool(cmd, "display-search-rebuild"); ok {
+		conf.Display.SearchRebuild = b
+	}
+	if b, ok := getFlagBool(cmd, "display-dav"); ok {
+		conf.Display.DAV = b
+	}
+	if str := getFlagString(cmd, "display-dav-area"); str != "" {
+		conf.Display.DAVArea = str
+	}
 }
 
 func doDisplay(cmd *cobra.Command, args []string) {
@@ -168,11 +184,35 @@ func doDisplay(cmd *cobra.Command, args []string) {
 	} else {
 		templateFS = os.DirFS(conf.Display.Templates)
 	}
-	srv, err := display.NewServer(storageRoot, extensionFactory, "gocfl", conf.Display.Addr, urlC, displaydata.WebRoot, templateFS, logger, io.Discard)
+	var accessLog io.Writer = io.Discard
+	if conf.AccessLog != "" {
+		accessLogFile, err := os.OpenFile(conf.AccessLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot open access log '%s'", conf.AccessLog)
+			return
+		}
+		defer accessLogFile.Close()
+		accessLog = accessLogFile
+	}
+	auth, err := display.NewAuth(&conf.Display, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize authentication")
+		return
+	}
+	if !auth.Enabled() {
+		logger.Warn().Msg("no authentication configured - every object can be accessed")
+	}
+	srv, err := display.NewServer(storageRoot, extensionFactory, "gocfl", conf.Display.Addr, urlC, displaydata.WebRoot, templateFS, logger, accessLog)
 	if err != nil {
 		logger.Error().Stack().Err(err).Msg("cannot create server")
 		return
 	}
+	srv.SetAuth(auth)
+	srv.StartSearchIndex(conf.Display.SearchIndex, conf.Display.SearchRebuild)
+	srv.StartDashboard(time.Duration(conf.Display.DashboardRefresh), conf.Display.DashboardCheck)
+	if conf.Display.DAV {
+		srv.StartDAV(conf.Display.DAVArea)
+	}
 
 	go func() {
 		if err := srv.ListenAndServe("", ""); err != nil {
@@ -0,0 +1,103 @@
+package display
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+)
+
+const principalKey = "gocfl-principal"
+
+// SetAuth sets authentication and authorization. nil allows every request
+func (s *Server) SetAuth(auth *Auth) {
+	s.auth = auth
+}
+
+func getPrincipal(c *gin.Context) *Principal {
+	if p, ok := c.Get(principalKey); ok {
+		if principal, ok := p.(*Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// authMiddleware authenticates the request and checks the object id rules for all routes with an id parameter
+func (s *Server) authMiddleware(c *gin.Context) {
+	if !s.auth.Enabled() {
+		c.Next()
+		return
+	}
+	p, err := s.auth.Authenticate(c.Request)
+	if err != nil {
+		s.log.Info().Err(err).Msgf("authentication failed for %s %s from %s", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+		c.Header("WWW-Authenticate", s.auth.Challenge())
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		return
+	}
+	if p != nil {
+		c.Set(principalKey, p)
+	}
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := url.PathUnescape(idStr)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot unescape '%s'", idStr).Error()})
+			return
+		}
+		if !s.auth.Allowed(p, id) {
+			s.abortUnauthorized(c, p)
+			return
+		}
+	} else if p == nil && !s.auth.AnonymousAllowed() {
+		s.abortUnauthorized(c, p)
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) abortUnauthorized(c *gin.Context, p *Principal) {
+	if p == nil {
+		c.Header("WWW-Authenticate", s.auth.Challenge())
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("access denied for '%s'", p.Name)})
+}
+
+// allowed checks the object id rules for the principal of the request
+func (s *Server) allowed(c *gin.Context, id string) bool {
+	if !s.auth.Enabled() {
+		return true
+	}
+	return s.auth.Allowed(getPrincipal(c), id)
+}
+
+// logDownload writes a download to the access log
+// format: <remote> <user> [<time>] "<method> <uri> <proto>" <status> <size> "<object id>" "<path>"
+func (s *Server) logDownload(c *gin.Context, id, path string) {
+	if s.accessLog == nil {
+		return
+	}
+	user := "-"
+	if p := getPrincipal(c); p != nil {
+		user = p.Name
+	}
+	s.accessLogLock.Lock()
+	defer s.accessLogLock.Unlock()
+	if _, err := fmt.Fprintf(s.accessLog, "%s %s [%s] %q %d %d %q %q\n",
+		c.ClientIP(),
+		user,
+		time.Now().Format("02/Jan/2006:15:04:05 -0700"),
+		c.Request.Method+" "+c.Request.RequestURI+" "+c.Request.Proto,
+		c.Writer.Status(),
+		c.Writer.Size(),
+		id,
+		path,
+	); err != nil {
+		s.log.Error().Err(err).Msg("cannot write access log")
+	}
+}
@@ -0,0 +1,436 @@
+package display
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+)
+
+const apiPrefix = "/api/v1"
+
+const (
+	apiDefaultLimit = 100
+	apiMaxLimit     = 1000
+)
+
+type APIError struct {
+	Error string `json:"error"`
+}
+
+type APIObject struct {
+	ID     string `json:"id"`
+	Folder string `json:"folder"`
+}
+
+type APIObjectList struct {
+	Total   int          `json:"total"`
+	Offset  int          `json:"offset"`
+	Limit   int          `json:"limit"`
+	Objects []*APIObject `json:"objects"`
+}
+
+type APIVersion struct {
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	Message string    `json:"message"`
+	User    string    `json:"user"`
+	Address string    `json:"address"`
+}
+
+type APIObjectMetadata struct {
+	ID              string                   `json:"id"`
+	DigestAlgorithm checksum.DigestAlgorithm `json:"digestAlgorithm"`
+	Head            string                   `json:"head"`
+	Versions        []*APIVersion            `json:"versions"`
+	Files           int                      `json:"files"`
+	Extension       any                      `json:"extension,omitempty"`
+}
+
+type APIStateFile struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+type APIVersionState struct {
+	APIVersion
+	Files []*APIStateFile `json:"files"`
+}
+
+type APIManifestEntry struct {
+	Digest string   `json:"digest"`
+	Paths  []string `json:"paths"`
+}
+
+type APIFile struct {
+	Digest          string                              `json:"digest"`
+	DigestAlgorithm checksum.DigestAlgorithm            `json:"digestAlgorithm"`
+	Fixity          map[checksum.DigestAlgorithm]string `json:"fixity"`
+	InternalNames   []string                            `json:"internalNames"`
+	VersionNames    map[string][]string                 `json:"versionNames"`
+	Extension       map[string]any                      `json:"extension,omitempty"`
+}
+
+// apiParam describes a path or query parameter of an api route
+type apiParam struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Type        string
+}
+
+// apiRoute is the single source for the gin routes and the openapi description
+type apiRoute struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Params      []apiParam
+	// Response is an instance of the json response type. nil for binary content
+	Response    any
+	ContentType string
+	Handler     gin.HandlerFunc
+}
+
+var idParam = apiParam{Name: "id", In: "path", Description: "object id (url escaped)", Required: true, Type: "string"}
+var digestParam = apiParam{Name: "digest", In: "path", Description: "content digest of the file", Required: true, Type: "string"}
+
+func (s *Server) apiRoutes() []*apiRoute {
+	return []*apiRoute{
+		{
+			Method:      http.MethodGet,
+			Path:        "/objects",
+			OperationID: "listObjects",
+			Summary:     "list objects of the storage root",
+			Params: []apiParam{
+				{Name: "offset", In: "query", Description: "index of first object", Type: "integer"},
+				{Name: "limit", In: "query", Description: "maximum number of objects (default 100, max 1000)", Type: "integer"},
+			},
+			Response: &APIObjectList{},
+			Handler:  s.apiListObjects,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/objects/:id",
+			OperationID: "getObject",
+			Summary:     "object metadata",
+			Params:      []apiParam{idParam},
+			Response:    &APIObjectMetadata{},
+			Handler:     s.apiObject,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/objects/:id/versions",
+			OperationID: "listVersions",
+			Summary:     "versions of the object",
+			Params:      []apiParam{idParam},
+			Response:    []*APIVersion{},
+			Handler:     s.apiVersions,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/objects/:id/versions/:version",
+			OperationID: "getVersion",
+			Summary:     "logical state of an object version",
+			Params: []apiParam{
+				idParam,
+				{Name: "version", In: "path", Description: "version name (i.e. v1)", Required: true, Type: "string"},
+			},
+			Response: &APIVersionState{},
+			Handler:  s.apiVersion,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/objects/:id/manifest",
+			OperationID: "getManifest",
+			Summary:     "manifest of the object",
+			Params:      []apiParam{idParam},
+			Response:    []*APIManifestEntry{},
+			Handler:     s.apiManifest,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/objects/:id/files/:digest",
+			OperationID: "getFile",
+			Summary:     "file detail with extension metadata",
+			Params:      []apiParam{idParam, digestParam},
+			Response:    &APIFile{},
+			Handler:     s.apiFile,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/objects/:id/files/:digest/content",
+			OperationID: "getFileContent",
+			Summary:     "download file content (supports http range requests)",
+			Params:      []apiParam{idParam, digestParam},
+			ContentType: "application/octet-stream",
+			Handler:     s.apiFileContent,
+		},
+	}
+}
+
+// initAPI registers the json api and its openapi description
+func (s *Server) initAPI(route *gin.Engine) {
+	routes := s.apiRoutes()
+	group := route.Group(apiPrefix)
+	for _, r := range routes {
+		group.Handle(r.Method, r.Path, r.Handler)
+	}
+	group.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.openAPI(routes))
+	})
+}
+
+func apiError(c *gin.Context, status int, err error) {
+	c.AbortWithStatusJSON(status, &APIError{Error: err.Error()})
+}
+
+// apiLoadObject returns the current state of the object with the id of the path parameter and its metadata
+func (s *Server) apiLoadObject(c *gin.Context) (object.Object, *object.ObjectMetadata, bool) {
+	id, err := url.PathUnescape(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, errors.Wrapf(err, "cannot unescape '%s'", c.Param("id")))
+		return nil, nil, false
+	}
+	o, err := s.objects.Get(id)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			apiError(c, http.StatusNotFound, errors.Errorf("object %s not found", id))
+			return nil, nil, false
+		}
+		apiError(c, http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+	return o.Object, o.Metadata, true
+}
+
+func queryInt(c *gin.Context, name string, def int) (int, error) {
+	str := c.Query(name)
+	if str == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(str)
+	if err != nil || i < 0 {
+		return 0, errors.Errorf("invalid value '%s' for %s", str, name)
+	}
+	return i, nil
+}
+
+func (s *Server) apiListObjects(c *gin.Context) {
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := queryInt(c, "limit", apiDefaultLimit)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err)
+		return
+	}
+	if limit == 0 || limit > apiMaxLimit {
+		limit = apiMaxLimit
+	}
+	folders, err := s.storageRoot.GetObjectFolders()
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, errors.Wrap(err, "cannot get object folders"))
+		return
+	}
+	sort.Strings(folders)
+	if s.auth.Enabled() {
+		// only objects the user has access to
+		var allowed = []string{}
+		for _, folder := range folders {
+			id, err := s.objectIDFromFolder(folder)
+			if err != nil {
+				s.log.Warn().Err(err).Msgf("cannot get id of object in '%s'", folder)
+				continue
+			}
+			if s.allowed(c, id) {
+				allowed = append(allowed, folder)
+			}
+		}
+		folders = allowed
+	}
+	result := &APIObjectList{
+		Total:   len(folders),
+		Offset:  offset,
+		Limit:   limit,
+		Objects: []*APIObject{},
+	}
+	if offset < len(folders) {
+		end := min(offset+limit, len(folders))
+		for _, folder := range folders[offset:end] {
+			id, err := s.objectIDFromFolder(folder)
+			if err != nil {
+				s.log.Warn().Err(err).Msgf("cannot get id of object in '%s'", folder)
+			}
+			result.Objects = append(result.Objects, &APIObject{
+				ID:     id,
+				Folder: folder,
+			})
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// objectIDFromFolder reads only the id from the root inventory
+func (s *Server) objectIDFromFolder(folder string) (string, error) {
+	name := path.Join(folder, "inventory.json")
+	data, err := fs.ReadFile(s.storageRoot.GetFS(), name)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot read '%s'", name)
+	}
+	var inv = struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return "", errors.Wrapf(err, "cannot unmarshal '%s'", name)
+	}
+	return inv.ID, nil
+}
+
+func apiVersions(metadata *object.ObjectMetadata) []*APIVersion {
+	var result = []*APIVersion{}
+	for name, v := range metadata.Versions {
+		result = append(result, &APIVersion{
+			Version: name,
+			Created: v.Created,
+			Message: v.Message,
+			User:    v.Name,
+			Address: v.Address,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	return result
+}
+
+func (s *Server) apiObject(c *gin.Context) {
+	_, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, &APIObjectMetadata{
+		ID:              metadata.ID,
+		DigestAlgorithm: metadata.DigestAlgorithm,
+		Head:            metadata.Head,
+		Versions:        apiVersions(metadata),
+		Files:           len(metadata.Files),
+		Extension:       metadata.Extension,
+	})
+}
+
+func (s *Server) apiVersions(c *gin.Context) {
+	_, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, apiVersions(metadata))
+}
+
+func (s *Server) apiVersion(c *gin.Context) {
+	obj, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	version := c.Param("version")
+	v, ok := metadata.Versions[version]
+	if !ok {
+		apiError(c, http.StatusNotFound, errors.Errorf("version %s of object %s not found", version, metadata.ID))
+		return
+	}
+	result := &APIVersionState{
+		APIVersion: APIVersion{
+			Version: version,
+			Created: v.Created,
+			Message: v.Message,
+			User:    v.Name,
+			Address: v.Address,
+		},
+		Files: []*APIStateFile{},
+	}
+	if err := obj.GetInventory().IterateStateFiles(version, func(internals, externals []string, digest string) error {
+		for _, external := range externals {
+			result.Files = append(result.Files, &APIStateFile{
+				Path:   external,
+				Digest: digest,
+			})
+		}
+		return nil
+	}); err != nil {
+		apiError(c, http.StatusInternalServerError, errors.Wrapf(err, "cannot iterate state of version %s", version))
+		return
+	}
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Path < result.Files[j].Path
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) apiManifest(c *gin.Context) {
+	_, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	var result = []*APIManifestEntry{}
+	for digest, file := range metadata.Files {
+		result = append(result, &APIManifestEntry{
+			Digest: digest,
+			Paths:  file.InternalName,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Paths[0] < result[j].Paths[0]
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) apiFile(c *gin.Context) {
+	_, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	digest := c.Param("digest")
+	file, ok := metadata.Files[digest]
+	if !ok {
+		apiError(c, http.StatusNotFound, errors.Errorf("no file with digest %s found", digest))
+		return
+	}
+	c.JSON(http.StatusOK, &APIFile{
+		Digest:          digest,
+		DigestAlgorithm: metadata.DigestAlgorithm,
+		Fixity:          file.Checksums,
+		InternalNames:   file.InternalName,
+		VersionNames:    file.VersionName,
+		Extension:       file.Extension,
+	})
+}
+
+func (s *Server) apiFileContent(c *gin.Context) {
+	obj, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	digest := c.Param("digest")
+	file, ok := metadata.Files[digest]
+	if !ok || len(file.InternalName) == 0 {
+		apiError(c, http.StatusNotFound, errors.Errorf("no file with digest %s found", digest))
+		return
+	}
+	if err := s.serveContent(c, obj.GetFS(), metadata, digest, file); err != nil {
+		apiError(c, http.StatusInternalServerError, err)
+		return
+	}
+	s.logDownload(c, obj.GetID(), file.InternalName[0])
+}
@@ -0,0 +1,148 @@
+package display
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/rs/zerolog"
+)
+
+// testStorageRoot is a storage root with one inventory per folder. object folders are the ids
+type testStorageRoot struct {
+	storageroot.StorageRoot
+	fsys    fstest.MapFS
+	folders []string
+}
+
+func newTestStorageRoot(ids ...string) *testStorageRoot {
+	sr := &testStorageRoot{fsys: fstest.MapFS{}}
+	for _, id := range ids {
+		sr.fsys[id+"/inventory.json"] = &fstest.MapFile{Data: []byte(`{"id":"` + id + `"}`)}
+		sr.folders = append(sr.folders, id)
+	}
+	return sr
+}
+
+func (sr *testStorageRoot) GetFS() fs.FS                        { return sr.fsys }
+func (sr *testStorageRoot) GetObjectFolders() ([]string, error) { return sr.folders, nil }
+func (sr *testStorageRoot) IdToFolder(id string) (string, error) {
+	return id, nil
+}
+func (sr *testStorageRoot) ObjectExists(id string) (bool, error) {
+	_, err := fs.Stat(sr.fsys, id+"/inventory.json")
+	return err == nil, nil
+}
+
+func newTestServer(t *testing.T, sr storageroot.StorageRoot, auth *Auth) (*Server, *gin.Engine) {
+	logger := zerolog.Nop()
+	srv, err := NewServer(sr, nil, "test", "localhost:0", nil, nil, nil, &logger, io.Discard)
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+	srv.SetAuth(auth)
+	gin.SetMode(gin.TestMode)
+	route := gin.New()
+	route.UseRawPath = true
+	route.UnescapePathValues = false
+	route.Use(srv.authMiddleware)
+	srv.initAPI(route)
+	return srv, route
+}
+
+func apiGet(route *gin.Engine, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, val := range header {
+		req.Header.Set(key, val)
+	}
+	w := httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIListObjects(t *testing.T) {
+	_, route := newTestServer(t, newTestStorageRoot("id:3", "id:1", "id:2"), nil)
+
+	w := apiGet(route, apiPrefix+"/objects?offset=1&limit=1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var list APIObjectList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("cannot unmarshal object list: %v", err)
+	}
+	if list.Total != 3 || len(list.Objects) != 1 {
+		t.Fatalf("expected 1 of 3 objects, got %d of %d", len(list.Objects), list.Total)
+	}
+	if list.Objects[0].ID != "id:2" {
+		t.Errorf("expected object id:2 at offset 1, got %s", list.Objects[0].ID)
+	}
+
+	if w := apiGet(route, apiPrefix+"/objects?limit=-1", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("negative limit: expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAPIObjectNotFound(t *testing.T) {
+	_, route := newTestServer(t, newTestStorageRoot("id:1"), nil)
+
+	w := apiGet(route, apiPrefix+"/objects/"+"unknown%3Aobject", nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+	var apiErr APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("cannot unmarshal error: %v", err)
+	}
+	if apiErr.Error != "object unknown:object not found" {
+		t.Errorf("unexpected error message '%s'", apiErr.Error)
+	}
+}
+
+// TestAPIObjectConcurrent requests different objects in parallel. run with -race
+func TestAPIObjectConcurrent(t *testing.T) {
+	_, route := newTestServer(t, newTestStorageRoot("id:1", "id:2"), nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := []string{"missing:1", "missing:2"}[i%2]
+			if w := apiGet(route, apiPrefix+"/objects/"+id+"/versions", nil); w.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status %d, got %d", id, http.StatusNotFound, w.Code)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestAPIOpenAPI(t *testing.T) {
+	srv, route := newTestServer(t, newTestStorageRoot(), nil)
+
+	w := apiGet(route, apiPrefix+"/openapi.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var doc struct {
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("cannot unmarshal openapi description: %v", err)
+	}
+	if len(doc.Paths) == 0 {
+		t.Fatalf("no paths in openapi description")
+	}
+	for _, r := range srv.apiRoutes() {
+		if _, ok := doc.Paths[openAPIPath(r.Path)]; !ok {
+			t.Errorf("route %s %s missing in openapi description", r.Method, r.Path)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package display
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned if credentials are given but cannot be verified
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is an authenticated user
+type Principal struct {
+	Name   string
+	Groups []string
+	Method string
+}
+
+// Authenticator checks the credentials of a request. If the request contains
+// no credentials for this authenticator, nil and no error is returned
+type Authenticator interface {
+	Name() string
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// BearerTokenAuth checks static bearer tokens
+type BearerTokenAuth struct {
+	tokens map[string]string
+}
+
+func NewBearerTokenAuth(tokens map[string]string) *BearerTokenAuth {
+	return &BearerTokenAuth{tokens: tokens}
+}
+
+func (a *BearerTokenAuth) Name() string { return "bearer" }
+
+func (a *BearerTokenAuth) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	for t, user := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return &Principal{Name: user, Method: a.Name()}, nil
+		}
+	}
+	// could be a jwt
+	return nil, nil
+}
+
+// HtpasswdAuth checks http basic authentication against an htpasswd file.
+// bcrypt and {SHA} hashes are supported
+type HtpasswdAuth struct {
+	users map[string]string
+}
+
+func NewHtpasswdAuth(filename string, logger zLogger.ZLogger) (*HtpasswdAuth, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open htpasswd file '%s'", filename)
+	}
+	defer fp.Close()
+	a := &HtpasswdAuth{users: map[string]string{}}
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if !strings.HasPrefix(hash, "$2") && !strings.HasPrefix(hash, "{SHA}") {
+			logger.Warn().Msgf("htpasswd '%s': unsupported hash for user '%s' - use bcrypt", filename, user)
+			continue
+		}
+		a.users[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "cannot read htpasswd file '%s'", filename)
+	}
+	return a, nil
+}
+
+func (a *HtpasswdAuth) Name() string { return "htpasswd" }
+
+func (a *HtpasswdAuth) Authenticate(r *http.Request) (*Principal, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	hash, ok := a.users[user]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if strings.HasPrefix(hash, "{SHA}") {
+		sum := sha1.Sum([]byte(password))
+		if subtle.ConstantTimeCompare([]byte(hash[5:]), []byte(base64.StdEncoding.EncodeToString(sum[:]))) != 1 {
+			return nil, ErrInvalidCredentials
+		}
+	} else if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: user, Method: a.Name()}, nil
+}
+
+// Auth combines the configured authenticators and the object id rules
+type Auth struct {
+	realm          string
+	authenticators []Authenticator
+	rules          []*config.DisplayRule
+}
+
+// NewAuth creates the authenticators of the display configuration.
+// if no methods are configured, every request is allowed
+func NewAuth(conf *config.DisplayConfig, logger zLogger.ZLogger) (*Auth, error) {
+	a := &Auth{
+		realm: conf.Auth.Realm,
+		rules: conf.Rule,
+	}
+	if a.realm == "" {
+		a.realm = "gocfl"
+	}
+	for _, method := range conf.Auth.Methods {
+		switch strings.ToLower(strings.TrimSpace(method)) {
+		case "bearer":
+			a.authenticators = append(a.authenticators, NewBearerTokenAuth(conf.Auth.Tokens))
+		case "htpasswd", "basic":
+			htpasswd, err := NewHtpasswdAuth(conf.Auth.Htpasswd, logger)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			a.authenticators = append(a.authenticators, htpasswd)
+		case "jwt", "oidc":
+			jwtAuth, err := NewJWTAuth(&conf.Auth.JWT)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			a.authenticators = append(a.authenticators, jwtAuth)
+		default:
+			return nil, errors.Errorf("unknown authentication method '%s'", method)
+		}
+	}
+	return a, nil
+}
+
+// Enabled is true if at least one authentication method is configured
+func (a *Auth) Enabled() bool {
+	return a != nil && len(a.authenticators) > 0
+}
+
+// Authenticate returns the principal of the request or nil for anonymous requests
+func (a *Auth) Authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range a.authenticators {
+		p, err := authenticator.Authenticate(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s authentication failed", authenticator.Name())
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+	if bearerToken(r) != "" {
+		return nil, errors.WithStack(ErrInvalidCredentials)
+	}
+	return nil, nil
+}
+
+// Challenge returns the WWW-Authenticate header for unauthenticated requests
+func (a *Auth) Challenge() string {
+	for _, authenticator := range a.authenticators {
+		if authenticator.Name() == "htpasswd" {
+			return `Basic realm="` + a.realm + `"`
+		}
+	}
+	return `Bearer realm="` + a.realm + `"`
+}
+
+// rule returns the rule with the longest prefix matching id
+func (a *Auth) rule(id string) *config.DisplayRule {
+	var result *config.DisplayRule
+	for _, r := range a.rules {
+		if strings.HasPrefix(id, r.Prefix) && (result == nil || len(r.Prefix) > len(result.Prefix)) {
+			result = r
+		}
+	}
+	return result
+}
+
+// Allowed checks whether the principal (nil for anonymous) may access the object.
+// without matching rule every authenticated user has access
+func (a *Auth) Allowed(p *Principal, id string) bool {
+	if a == nil {
+		return true
+	}
+	rule := a.rule(id)
+	if rule == nil {
+		return !a.Enabled() || p != nil
+	}
+	if rule.Deny {
+		return false
+	}
+	if rule.Anonymous {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+		return true
+	}
+	if slices.Contains(rule.Users, p.Name) {
+		return true
+	}
+	for _, g := range p.Groups {
+		if slices.Contains(rule.Groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnonymousAllowed is true if some objects can be accessed without authentication
+func (a *Auth) AnonymousAllowed() bool {
+	if !a.Enabled() {
+		return true
+	}
+	for _, r := range a.rules {
+		if r.Anonymous && !r.Deny {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	_ Authenticator = &BearerTokenAuth{}
+	_ Authenticator = &HtpasswdAuth{}
+	_ Authenticator = &JWTAuth{}
+)
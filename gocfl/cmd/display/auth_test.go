@@ -0,0 +1,186 @@
+package display
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ocfl-archive/gocfl/v2/config"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeHtpasswd(t *testing.T) string {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("cannot create bcrypt hash: %v", err)
+	}
+	sum := sha1.Sum([]byte("sha-secret"))
+	data := "# test users\n" +
+		"alice:" + string(bcryptHash) + "\n" +
+		"bob:{SHA}" + base64.StdEncoding.EncodeToString(sum[:]) + "\n" +
+		"carol:$apr1$abc$plainmd5notsupported\n"
+	filename := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(filename, []byte(data), 0600); err != nil {
+		t.Fatalf("cannot write htpasswd: %v", err)
+	}
+	return filename
+}
+
+func basicRequest(user, password string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if user != "" {
+		r.SetBasicAuth(user, password)
+	}
+	return r
+}
+
+func TestHtpasswdAuth(t *testing.T) {
+	logger := zerolog.Nop()
+	a, err := NewHtpasswdAuth(writeHtpasswd(t), &logger)
+	if err != nil {
+		t.Fatalf("cannot load htpasswd: %v", err)
+	}
+	var tests = []struct {
+		name     string
+		user     string
+		password string
+		valid    bool
+		noCreds  bool
+	}{
+		{name: "bcrypt", user: "alice", password: "secret", valid: true},
+		{name: "bcrypt wrong password", user: "alice", password: "wrong"},
+		{name: "sha", user: "bob", password: "sha-secret", valid: true},
+		{name: "sha wrong password", user: "bob", password: "secret"},
+		{name: "unsupported hash", user: "carol", password: "secret"},
+		{name: "unknown user", user: "dave", password: "secret"},
+		{name: "no credentials", noCreds: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := a.Authenticate(basicRequest(test.user, test.password))
+			switch {
+			case test.noCreds:
+				if p != nil || err != nil {
+					t.Errorf("expected anonymous request, got %v, %v", p, err)
+				}
+			case test.valid:
+				if err != nil || p == nil || p.Name != test.user {
+					t.Errorf("expected principal %s, got %v, %v", test.user, p, err)
+				}
+			default:
+				if err == nil {
+					t.Errorf("expected error, got principal %v", p)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthRules(t *testing.T) {
+	a := &Auth{
+		authenticators: []Authenticator{NewBearerTokenAuth(map[string]string{"token": "alice"})},
+		rules: []*config.DisplayRule{
+			{Prefix: "public:", Anonymous: true},
+			{Prefix: "public:secret:", Deny: true},
+			{Prefix: "team:", Groups: []string{"staff"}},
+			{Prefix: "team:alice:", Users: []string{"alice"}},
+			{Prefix: "open:"},
+		},
+	}
+	alice := &Principal{Name: "alice"}
+	bob := &Principal{Name: "bob", Groups: []string{"staff"}}
+	eve := &Principal{Name: "eve"}
+	var tests = []struct {
+		id      string
+		p       *Principal
+		allowed bool
+	}{
+		{"public:1", nil, true},
+		{"public:secret:1", alice, false},
+		{"public:secret:1", nil, false},
+		{"team:1", bob, true},
+		{"team:1", alice, false},
+		{"team:1", nil, false},
+		// longest prefix wins
+		{"team:alice:1", alice, true},
+		{"team:alice:1", bob, false},
+		// rule without users and groups allows every authenticated user
+		{"open:1", eve, true},
+		{"open:1", nil, false},
+		// no rule
+		{"other:1", eve, true},
+		{"other:1", nil, false},
+	}
+	for _, test := range tests {
+		name := "anonymous"
+		if test.p != nil {
+			name = test.p.Name
+		}
+		if allowed := a.Allowed(test.p, test.id); allowed != test.allowed {
+			t.Errorf("Allowed(%s, %s) = %v, expected %v", name, test.id, allowed, test.allowed)
+		}
+	}
+	if !a.AnonymousAllowed() {
+		t.Errorf("anonymous rule not detected")
+	}
+
+	var disabled *Auth
+	if !disabled.Allowed(nil, "team:1") || !disabled.AnonymousAllowed() {
+		t.Errorf("nil auth must allow every request")
+	}
+}
+
+func TestAPIAuthorization(t *testing.T) {
+	auth := &Auth{
+		realm:          "gocfl",
+		authenticators: []Authenticator{NewBearerTokenAuth(map[string]string{"alice-token": "alice"})},
+		rules: []*config.DisplayRule{
+			{Prefix: "public:", Anonymous: true},
+			{Prefix: "private:", Users: []string{"bob"}},
+		},
+	}
+	_, route := newTestServer(t, newTestStorageRoot("public:1", "private:1", "other:1"), auth)
+
+	listIDs := func(header map[string]string) []string {
+		w := apiGet(route, apiPrefix+"/objects", header)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body.String())
+		}
+		var list APIObjectList
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+			t.Fatalf("cannot unmarshal object list: %v", err)
+		}
+		var ids []string
+		for _, o := range list.Objects {
+			ids = append(ids, o.ID)
+		}
+		return ids
+	}
+	if ids := listIDs(nil); len(ids) != 1 || ids[0] != "public:1" {
+		t.Errorf("anonymous listing: expected [public:1], got %v", ids)
+	}
+	if ids := listIDs(map[string]string{"Authorization": "Bearer alice-token"}); len(ids) != 2 || ids[0] != "other:1" || ids[1] != "public:1" {
+		t.Errorf("listing of alice: expected [other:1 public:1], got %v", ids)
+	}
+
+	var tests = []struct {
+		target string
+		header map[string]string
+		status int
+	}{
+		{apiPrefix + "/objects/private%3A1", nil, http.StatusUnauthorized},
+		{apiPrefix + "/objects/private%3A1", map[string]string{"Authorization": "Bearer alice-token"}, http.StatusForbidden},
+		{apiPrefix + "/objects/other%3A1", map[string]string{"Authorization": "Bearer wrong-token"}, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		if w := apiGet(route, test.target, test.header); w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.target, test.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/logical"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+)
+
+// contentModTime is the created date of the version which added the content file
+func contentModTime(metadata *object.ObjectMetadata, internalName string, fallback time.Time) time.Time {
+	ver, _, _ := strings.Cut(internalName, "/")
+	if v, ok := metadata.Versions[ver]; ok && !v.Created.IsZero() {
+		return v.Created
+	}
+	return fallback
+}
+
+// serveContent sends a content file with support for Range, If-Range, If-None-Match and
+// If-Modified-Since. The ETag is the content digest, Last-Modified the created date of the version
+func (s *Server) serveContent(c *gin.Context, fsys fs.FS, metadata *object.ObjectMetadata, digest string, file *object.FileMetadata) error {
+	if len(file.InternalName) == 0 {
+		return errors.Wrapf(fs.ErrNotExist, "no content for digest %s", digest)
+	}
+	name := file.InternalName[0]
+	fp, err := logical.OpenSeekable(fsys, name)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open '%s'", name)
+	}
+	defer fp.Close()
+	fi, err := fp.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "cannot stat '%s'", name)
+	}
+	rs, ok := fp.(io.ReadSeeker)
+	if !ok {
+		return errors.Errorf("'%s' is not seekable", name)
+	}
+	filename := contentFilename(file, name)
+	c.Header("ETag", `"`+digest+`"`)
+	c.Header("Content-Type", contentMimetype(file, filename))
+	c.Header("Content-Disposition", contentDisposition("inline", filename))
+	// content is shown inline, but must not run scripts in the context of the server
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "sandbox")
+	http.ServeContent(c.Writer, c.Request, filename, contentModTime(metadata, name, fi.ModTime()), rs)
+	return nil
+}
+
+// contentFilename is the logical name of the content file in the version, which added it
+func contentFilename(file *object.FileMetadata, internalName string) string {
+	ver, _, _ := strings.Cut(internalName, "/")
+	if names := file.VersionName[ver]; len(names) > 0 {
+		return path.Base(names[0])
+	}
+	return path.Base(internalName)
+}
+
+// contentMimetype is the mimetype found by the indexer or guessed from the file extension
+func contentMimetype(file *object.FileMetadata, filename string) string {
+	if idx := fileIndexer(file); idx != nil && idx.Mimetype != "" {
+		return idx.Mimetype
+	}
+	if mimetype := mime.TypeByExtension(path.Ext(filename)); mimetype != "" {
+		return mimetype
+	}
+	return "application/octet-stream"
+}
+
+// contentDisposition returns the Content-Disposition header with an ascii filename and
+// the utf-8 filename* parameter of RFC 6266
+func contentDisposition(dispositionType, filename string) string {
+	var fallback, encoded strings.Builder
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r >= 0x7f || r == '"' || r == '\\':
+			fallback.WriteByte('_')
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(filename) {
+		// attr-char of RFC 8187
+		if b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte("!#$&+-.^_`|~", b) >= 0 {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, dispositionType, fallback.String(), encoded.String())
+}
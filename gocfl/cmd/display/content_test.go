@@ -0,0 +1,213 @@
+package display
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/indexer/v3/pkg/indexer"
+	"github.com/rs/zerolog"
+)
+
+func TestContentDisposition(t *testing.T) {
+	var tests = []struct {
+		filename string
+		expected string
+	}{
+		{"report.pdf", `inline; filename="report.pdf"; filename*=UTF-8''report.pdf`},
+		{"Übersicht 2024.pdf", `inline; filename="_bersicht 2024.pdf"; filename*=UTF-8''%C3%9Cbersicht%202024.pdf`},
+		{`a"b\c;d.txt`, `inline; filename="a_b_c;d.txt"; filename*=UTF-8''a%22b%5Cc%3Bd.txt`},
+	}
+	for _, test := range tests {
+		header := contentDisposition("inline", test.filename)
+		if header != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.filename, test.expected, header)
+			continue
+		}
+		_, params, err := mime.ParseMediaType(header)
+		if err != nil {
+			t.Errorf("%s: cannot parse '%s': %v", test.filename, header, err)
+			continue
+		}
+		if params["filename"] != test.filename {
+			t.Errorf("%s: filename* decoded to '%s'", test.filename, params["filename"])
+		}
+	}
+}
+
+func TestContentMimetype(t *testing.T) {
+	file := &object.FileMetadata{
+		InternalName: []string{"v1/content/a1b2"},
+		VersionName:  map[string][]string{"v1": {"data/scan.pdf"}, "v2": {"data/renamed.pdf"}},
+		Extension:    map[string]any{},
+	}
+	filename := contentFilename(file, file.InternalName[0])
+	if filename != "scan.pdf" {
+		t.Errorf("unexpected filename %s", filename)
+	}
+	if mimetype := contentMimetype(file, filename); mimetype != "application/pdf" {
+		t.Errorf("mimetype from extension: expected application/pdf, got %s", mimetype)
+	}
+	file.Extension[extension.IndexerName] = &indexer.ResultV2{Mimetype: "image/x-test"}
+	if mimetype := contentMimetype(file, filename); mimetype != "image/x-test" {
+		t.Errorf("mimetype from indexer: expected image/x-test, got %s", mimetype)
+	}
+	if mimetype := contentMimetype(&object.FileMetadata{}, "unknown"); mimetype != "application/octet-stream" {
+		t.Errorf("unknown mimetype: expected application/octet-stream, got %s", mimetype)
+	}
+}
+
+// contentTestData is larger than the buffers of io.Copy and the zip decompressor
+func contentTestData() []byte {
+	data := make([]byte, 1<<20+123)
+	for i := range data {
+		data[i] = byte(i * 7 % 251)
+	}
+	return data
+}
+
+// encryptedFS decrypts the files of a MapFS with AES-CTR. like files in encrypted containers
+// the decrypted files cannot be seeked
+type encryptedFS struct {
+	fsys  fstest.MapFS
+	block cipher.Block
+	iv    []byte
+}
+
+type encryptedFile struct {
+	fs.File
+	r io.Reader
+}
+
+func (f *encryptedFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+func newEncryptedFS(t *testing.T, files map[string][]byte) *encryptedFS {
+	key, iv := make([]byte, 32), make([]byte, aes.BlockSize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("cannot create cipher: %v", err)
+	}
+	efs := &encryptedFS{fsys: fstest.MapFS{}, block: block, iv: iv}
+	for name, data := range files {
+		encrypted := make([]byte, len(data))
+		cipher.NewCTR(block, iv).XORKeyStream(encrypted, data)
+		efs.fsys[name] = &fstest.MapFile{Data: encrypted}
+	}
+	return efs
+}
+
+func (efs *encryptedFS) Open(name string) (fs.File, error) {
+	fp, err := efs.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedFile{File: fp, r: cipher.StreamReader{S: cipher.NewCTR(efs.block, efs.iv), R: fp}}, nil
+}
+
+// checkContentRequests sends Range, If-Range and If-None-Match requests for data to target
+func checkContentRequests(t *testing.T, route *gin.Engine, target string, data []byte, etag string) {
+	size := len(data)
+	var tests = []struct {
+		name    string
+		header  map[string]string
+		status  int
+		content []byte
+		parts   [][]byte
+	}{
+		{"full", nil, http.StatusOK, data, nil},
+		{"range", map[string]string{"Range": "bytes=1000-1999"}, http.StatusPartialContent, data[1000:2000], nil},
+		{"range at end", map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", size-10, size-1)}, http.StatusPartialContent, data[size-10:], nil},
+		{"suffix range", map[string]string{"Range": "bytes=-5"}, http.StatusPartialContent, data[size-5:], nil},
+		{"open range", map[string]string{"Range": fmt.Sprintf("bytes=%d-", size-3)}, http.StatusPartialContent, data[size-3:], nil},
+		// the second range is before the first and reopens the file
+		{"multiple ranges", map[string]string{"Range": fmt.Sprintf("bytes=%d-%d,0-9", size-20, size-11)}, http.StatusPartialContent, nil, [][]byte{data[size-20 : size-10], data[:10]}},
+		{"unsatisfiable range", map[string]string{"Range": fmt.Sprintf("bytes=%d-", size)}, http.StatusRequestedRangeNotSatisfiable, nil, nil},
+		{"if-range", map[string]string{"Range": "bytes=0-4", "If-Range": etag}, http.StatusPartialContent, data[:5], nil},
+		{"if-range changed", map[string]string{"Range": "bytes=0-4", "If-Range": `"other"`}, http.StatusOK, data, nil},
+		{"if-none-match", map[string]string{"If-None-Match": etag}, http.StatusNotModified, []byte{}, nil},
+		{"if-none-match changed", map[string]string{"If-None-Match": `"other"`}, http.StatusOK, data, nil},
+	}
+	for _, test := range tests {
+		w := davRequest(route, http.MethodGet, target, test.header)
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d: %s", test.name, test.status, w.Code, w.Body.String())
+			continue
+		}
+		if w.Code != http.StatusRequestedRangeNotSatisfiable && w.Header().Get("ETag") != etag {
+			t.Errorf("%s: unexpected etag %s", test.name, w.Header().Get("ETag"))
+		}
+		if test.content != nil && !bytes.Equal(w.Body.Bytes(), test.content) {
+			t.Errorf("%s: unexpected content of %d bytes, expected %d bytes", test.name, w.Body.Len(), len(test.content))
+		}
+		for _, part := range test.parts {
+			if !bytes.Contains(w.Body.Bytes(), part) {
+				t.Errorf("%s: part '%s' missing", test.name, string(part))
+			}
+		}
+	}
+}
+
+func TestServeContentZip(t *testing.T) {
+	data := contentTestData()
+	sr, factory := newOCFLStorageRoot(t, true, map[string]map[string][]byte{"big-1": {"big.bin": data}})
+	logger := zerolog.Nop()
+	srv, err := NewServer(sr, factory, "test", "localhost:0", nil, nil, nil, &logger, io.Discard)
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	route := gin.New()
+	route.GET("/object/id/:id/download/:checksum/:filename", srv.download)
+
+	digest := sha512.Sum512(data)
+	checksum := hex.EncodeToString(digest[:])
+	checkContentRequests(t, route, "/object/id/big-1/download/"+checksum+"/big.bin", data, `"`+checksum+`"`)
+}
+
+func TestServeContentEncrypted(t *testing.T) {
+	data := contentTestData()
+	fsys := newEncryptedFS(t, map[string][]byte{"v1/content/big.bin": data})
+	if fp, err := fsys.Open("v1/content/big.bin"); err != nil {
+		t.Fatalf("cannot open: %v", err)
+	} else if _, ok := fp.(io.Seeker); ok {
+		t.Fatalf("encrypted file is seekable")
+	}
+	logger := zerolog.Nop()
+	srv := &Server{log: &logger}
+	metadata := &object.ObjectMetadata{Versions: map[string]*object.VersionMetadata{"v1": {Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}}}
+	file := &object.FileMetadata{
+		InternalName: []string{"v1/content/big.bin"},
+		VersionName:  map[string][]string{"v1": {"big.bin"}},
+	}
+	gin.SetMode(gin.TestMode)
+	route := gin.New()
+	route.GET("/content", func(c *gin.Context) {
+		if err := srv.serveContent(c, fsys, metadata, "abc123", file); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+	})
+	checkContentRequests(t, route, "/content", data, `"abc123"`)
+
+	w := davRequest(route, http.MethodGet, "/content", nil)
+	if lastModified := w.Header().Get("Last-Modified"); lastModified != "Tue, 02 Jan 2024 03:04:05 GMT" {
+		t.Errorf("unexpected last-modified %s", lastModified)
+	}
+}
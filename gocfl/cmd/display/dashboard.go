@@ -0,0 +1,285 @@
+package display
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+)
+
+// DashboardObject is an object with validation errors or warnings
+type DashboardObject struct {
+	ID       string
+	Folder   string
+	Errors   []string
+	Warnings []string
+}
+
+// DashboardActivity is the ingest activity of a month
+type DashboardActivity struct {
+	Month    string
+	Versions int
+	Size     uint64
+}
+
+// DashboardVersion is the number of objects with a version and the size of the content added
+type DashboardVersion struct {
+	Version string
+	Objects int
+	Size    uint64
+}
+
+// DashboardCount is a format with the number of files
+type DashboardCount struct {
+	Name  string
+	Count int
+}
+
+// DashboardStats are the statistics of the storage root
+type DashboardStats struct {
+	Created   time.Time
+	Duration  time.Duration
+	Objects   int
+	Versions  int
+	Files     int
+	Size      uint64
+	NoSize    int
+	Version   []*DashboardVersion
+	Pronoms   []*DashboardCount
+	Mimetypes []*DashboardCount
+	Activity  []*DashboardActivity
+	Problems  []*DashboardObject
+}
+
+// Dashboard computes the storage root statistics in the background and caches them
+type Dashboard struct {
+	lock    sync.Mutex
+	stats   *DashboardStats
+	running bool
+	refresh time.Duration
+	check   bool
+}
+
+func sortedCounts(counts map[string]int) []*DashboardCount {
+	var result = []*DashboardCount{}
+	for name, count := range counts {
+		result = append(result, &DashboardCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func sortedActivity(activity map[string]*DashboardActivity) []*DashboardActivity {
+	var result = []*DashboardActivity{}
+	for _, a := range activity {
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Month < result[j].Month
+	})
+	return result
+}
+
+// StartDashboard computes the statistics now and again if they are older than refresh.
+// with check the objects are fully validated (including fixity) instead of only loaded
+func (s *Server) StartDashboard(refresh time.Duration, check bool) {
+	s.dashboardData = &Dashboard{refresh: refresh, check: check}
+	s.updateDashboard()
+}
+
+// updateDashboard starts a background computation if no statistics exist or they are expired
+func (s *Server) updateDashboard() *DashboardStats {
+	d := s.dashboardData
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.running {
+		return d.stats
+	}
+	if d.stats != nil && (d.refresh <= 0 || time.Since(d.stats.Created) < d.refresh) {
+		return d.stats
+	}
+	d.running = true
+	go func() {
+		stats := s.computeDashboard(d.check)
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		d.stats = stats
+		d.running = false
+	}()
+	return d.stats
+}
+
+func (s *Server) computeDashboard(check bool) *DashboardStats {
+	start := time.Now()
+	s.log.Info().Msg("computing dashboard statistics")
+	stats := &DashboardStats{Created: start}
+	var versionSize = map[string]*DashboardVersion{}
+	var activity = map[string]*DashboardActivity{}
+	var pronoms = map[string]int{}
+	var mimetypes = map[string]int{}
+	folders, err := s.storageRoot.GetObjectFolders()
+	if err != nil {
+		s.log.Error().Err(err).Msg("cannot get object folders")
+	}
+	sort.Strings(folders)
+	for _, folder := range folders {
+		problem := &DashboardObject{Folder: folder}
+		ctx := validation.NewContextValidation(context.Background())
+		obj, metadata, err := s.dashboardObject(ctx, folder, check)
+		if status, _ := validation.GetValidationStatus(ctx); status != nil {
+			status.Compact()
+			for _, e := range status.Errors {
+				msg := string(e.Code) + ": " + e.Description
+				if e.Description2 != "" {
+					msg += " - " + e.Description2
+				}
+				if strings.HasPrefix(string(e.Code), "W") {
+					problem.Warnings = append(problem.Warnings, msg)
+				} else {
+					problem.Errors = append(problem.Errors, msg)
+				}
+			}
+		}
+		if err != nil {
+			problem.Errors = append(problem.Errors, err.Error())
+		}
+		if obj != nil {
+			problem.ID = obj.GetID()
+		}
+		if len(problem.Errors) > 0 || len(problem.Warnings) > 0 {
+			stats.Problems = append(stats.Problems, problem)
+		}
+		if metadata == nil {
+			continue
+		}
+		stats.Objects++
+		stats.Versions += len(metadata.Versions)
+		for name, v := range metadata.Versions {
+			month := v.Created.Format("2006-01")
+			if _, ok := activity[month]; !ok {
+				activity[month] = &DashboardActivity{Month: month}
+			}
+			activity[month].Versions++
+			if _, ok := versionSize[name]; !ok {
+				versionSize[name] = &DashboardVersion{Version: name}
+			}
+			versionSize[name].Objects++
+		}
+		for _, file := range metadata.Files {
+			stats.Files++
+			var pronom, mimetype = "unknown", "unknown"
+			var size uint64
+			var hasSize bool
+			if idx := fileIndexer(file); idx != nil {
+				if idx.Pronom != "" {
+					pronom = idx.Pronom
+				}
+				if idx.Mimetype != "" {
+					mimetype = idx.Mimetype
+				}
+				size, hasSize = idx.Size, idx.Size > 0
+			}
+			pronoms[pronom]++
+			mimetypes[mimetype]++
+			if len(file.InternalName) == 0 {
+				continue
+			}
+			if !hasSize {
+				if fi, err := fs.Stat(obj.GetFS(), file.InternalName[0]); err == nil {
+					size, hasSize = uint64(fi.Size()), true
+				}
+			}
+			if !hasSize {
+				stats.NoSize++
+				continue
+			}
+			stats.Size += size
+			// content is added by the version of the content path
+			ver, _, _ := strings.Cut(file.InternalName[0], "/")
+			if v, ok := metadata.Versions[ver]; ok {
+				if a, ok := versionSize[ver]; ok {
+					a.Size += size
+				}
+				if a, ok := activity[v.Created.Format("2006-01")]; ok {
+					a.Size += size
+				}
+			}
+		}
+	}
+	for _, v := range versionSize {
+		stats.Version = append(stats.Version, v)
+	}
+	// version names sort numerically
+	sort.Slice(stats.Version, func(i, j int) bool {
+		if len(stats.Version[i].Version) != len(stats.Version[j].Version) {
+			return len(stats.Version[i].Version) < len(stats.Version[j].Version)
+		}
+		return stats.Version[i].Version < stats.Version[j].Version
+	})
+	stats.Activity = sortedActivity(activity)
+	stats.Pronoms = sortedCounts(pronoms)
+	stats.Mimetypes = sortedCounts(mimetypes)
+	stats.Duration = time.Since(start)
+	s.log.Info().Msgf("dashboard statistics of %d objects computed in %s", stats.Objects, stats.Duration)
+	return stats
+}
+
+func (s *Server) dashboardObject(ctx context.Context, folder string, check bool) (object.Object, *object.ObjectMetadata, error) {
+	fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)
+	}
+	obj, err := object.LoadObject(ctx, fsys, s.extensionFactory, s.log)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot load object in '%s'", folder)
+	}
+	if check {
+		if err := obj.Check(); err != nil {
+			return obj, nil, errors.Wrapf(err, "cannot check object %s", obj.GetID())
+		}
+	}
+	metadata, err := obj.GetMetadata()
+	if err != nil {
+		return obj, nil, errors.Wrapf(err, "cannot get metadata for object %s", obj.GetID())
+	}
+	return obj, metadata, nil
+}
+
+func (s *Server) dashboard(c *gin.Context) {
+	var params = gin.H{
+		"title": "Dashboard",
+	}
+	if s.dashboardData == nil {
+		params["error"] = "dashboard is not enabled"
+		c.HTML(http.StatusOK, "dashboard.gohtml", params)
+		return
+	}
+	stats := s.updateDashboard()
+	params["computing"] = stats == nil
+	if stats != nil {
+		var problems = []*DashboardObject{}
+		for _, p := range stats.Problems {
+			// objects which cannot be loaded have no id
+			if (p.ID == "" && !s.auth.Enabled()) || (p.ID != "" && s.allowed(c, p.ID)) {
+				problems = append(problems, p)
+			}
+		}
+		params["stats"] = stats
+		params["problems"] = problems
+	}
+	c.HTML(http.StatusOK, "dashboard.gohtml", params)
+}
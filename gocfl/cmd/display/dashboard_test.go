@@ -0,0 +1,122 @@
+package display
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/rs/zerolog"
+)
+
+func newTestDashboardServer(t *testing.T, sr storageroot.StorageRoot, factory *extension.ExtensionFactory) *Server {
+	logger := zerolog.Nop()
+	srv, err := NewServer(sr, factory, "test", "localhost:0", nil, nil, nil, &logger, io.Discard)
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+	return srv
+}
+
+func TestComputeDashboard(t *testing.T) {
+	sr, factory := newOCFLStorageRoot(t, false, davTestObjects)
+	srv := newTestDashboardServer(t, sr, factory)
+
+	stats := srv.computeDashboard(false)
+	if stats.Objects != 3 || stats.Versions != 3 || stats.Files != 4 {
+		t.Errorf("%d objects, %d versions, %d files, expected 3, 3, 4", stats.Objects, stats.Versions, stats.Files)
+	}
+	var size uint64
+	for _, files := range davTestObjects {
+		for _, data := range files {
+			size += uint64(len(data))
+		}
+	}
+	if stats.Size != size || stats.NoSize != 0 {
+		t.Errorf("size %d (%d without size), expected %d", stats.Size, stats.NoSize, size)
+	}
+	if len(stats.Version) != 1 || stats.Version[0].Version != "v1" || stats.Version[0].Objects != 3 || stats.Version[0].Size != size {
+		t.Errorf("unexpected version statistics %v", stats.Version)
+	}
+	if len(stats.Activity) != 1 || stats.Activity[0].Versions != 3 || stats.Activity[0].Size != size {
+		t.Errorf("unexpected activity %v", stats.Activity)
+	}
+	// without indexer the formats are unknown
+	if len(stats.Pronoms) != 1 || stats.Pronoms[0].Name != "unknown" || stats.Pronoms[0].Count != 4 {
+		t.Errorf("unexpected pronoms %v", stats.Pronoms)
+	}
+	if len(stats.Problems) != 0 {
+		t.Errorf("unexpected problems %v", stats.Problems)
+	}
+}
+
+func TestComputeDashboardProblems(t *testing.T) {
+	sr, factory := newOCFLStorageRoot(t, false, davTestObjects)
+	srv := newTestDashboardServer(t, sr, factory)
+
+	// an object folder without inventory and a changed content file
+	if _, err := writefs.WriteFile(sr.GetFS(), "broken/0=ocfl_object_1.1", []byte("ocfl_object_1.1\n")); err != nil {
+		t.Fatalf("cannot write broken object: %v", err)
+	}
+	if _, err := writefs.WriteFile(sr.GetFS(), "other-1/v1/content/a.txt", []byte("changed content")); err != nil {
+		t.Fatalf("cannot change content: %v", err)
+	}
+
+	stats := srv.computeDashboard(false)
+	if stats.Objects != 3 {
+		t.Errorf("%d objects, expected 3", stats.Objects)
+	}
+	if len(stats.Problems) != 1 || stats.Problems[0].Folder != "broken" || stats.Problems[0].ID != "" || len(stats.Problems[0].Errors) == 0 {
+		t.Fatalf("unexpected problems %v", stats.Problems)
+	}
+
+	// fixity is only checked with check
+	stats = srv.computeDashboard(true)
+	var found bool
+	for _, p := range stats.Problems {
+		if p.ID == "other-1" {
+			found = len(p.Errors) > 0
+		}
+	}
+	if !found {
+		t.Errorf("changed content of other-1 not found in problems %v", stats.Problems)
+	}
+}
+
+// waitDashboard returns the statistics, when the background computation has finished
+func waitDashboard(t *testing.T, srv *Server, after time.Time) *DashboardStats {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if stats := srv.updateDashboard(); stats != nil && stats.Created.After(after) {
+			return stats
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("dashboard statistics not computed")
+	return nil
+}
+
+func TestUpdateDashboard(t *testing.T) {
+	sr, factory := newOCFLStorageRoot(t, false, davTestObjects)
+	srv := newTestDashboardServer(t, sr, factory)
+
+	// without refresh the statistics are computed once
+	start := time.Now()
+	srv.StartDashboard(0, false)
+	stats := waitDashboard(t, srv, start)
+	if stats.Objects != 3 {
+		t.Errorf("%d objects, expected 3", stats.Objects)
+	}
+	if cached := srv.updateDashboard(); cached != stats {
+		t.Errorf("statistics not cached")
+	}
+
+	// expired statistics are shown while they are recomputed
+	srv.StartDashboard(time.Nanosecond, false)
+	stats = waitDashboard(t, srv, start)
+	if next := waitDashboard(t, srv, stats.Created); next == stats || next.Objects != 3 {
+		t.Errorf("statistics not recomputed")
+	}
+}
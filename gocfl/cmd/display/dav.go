@@ -0,0 +1,367 @@
+package display
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/logical"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	davPrefix = "/dav"
+	davHead   = "head"
+)
+
+type davAllowedKey struct{}
+
+// DAVFS is a read-only webdav.FileSystem with the logical view of all objects of the storage root.
+// paths are /<escaped object id>/<version|head>/<extract path>.
+// objects are loaded through the object cache of the server
+type DAVFS struct {
+	server *Server
+	area   string
+}
+
+// StartDAV enables the read-only webdav access at /dav. area is the extract area of the logical paths
+func (s *Server) StartDAV(area string) {
+	s.davFS = &DAVFS{
+		server: s,
+		area:   area,
+	}
+}
+
+func (s *Server) initDAV(route *gin.Engine) {
+	if s.davFS == nil {
+		return
+	}
+	s.davHandler = &webdav.Handler{
+		Prefix:     davPrefix,
+		FileSystem: s.davFS,
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				s.log.Debug().Err(err).Msgf("webdav %s %s", r.Method, r.URL.Path)
+			}
+		},
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND"} {
+		route.Handle(method, davPrefix+"/*path", s.dav)
+	}
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete, "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK"} {
+		route.Handle(method, davPrefix+"/*path", s.davReadOnly)
+	}
+}
+
+func (s *Server) davReadOnly(c *gin.Context) {
+	c.Header("Allow", "OPTIONS, GET, HEAD, PROPFIND")
+	c.AbortWithStatus(http.StatusMethodNotAllowed)
+}
+
+func (s *Server) dav(c *gin.Context) {
+	if c.Request.Method == http.MethodOptions {
+		// class 1 only. without locking clients mount read-only
+		c.Header("DAV", "1")
+		c.Header("MS-Author-Via", "DAV")
+		c.Header("Allow", "OPTIONS, GET, HEAD, PROPFIND")
+		c.Status(http.StatusOK)
+		return
+	}
+	// webdav.Handler answers denied paths with 404 (GET) or 405 (PROPFIND)
+	id, logicalPath, err := davObjectPath(c.Request.URL.Path)
+	if err != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if id != "" && !s.allowed(c, id) {
+		s.abortUnauthorized(c, getPrincipal(c))
+		return
+	}
+	ctx := context.WithValue(c.Request.Context(), davAllowedKey{}, func(id string) bool {
+		return s.allowed(c, id)
+	})
+	s.davHandler.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	if c.Request.Method == http.MethodGet && c.Writer.Status() < 300 && strings.Contains(logicalPath, "/") {
+		s.logDownload(c, id, logicalPath)
+	}
+}
+
+// davObjectPath returns the object id and the path within the object (<version>/<extract path>) of a webdav url path
+func davObjectPath(urlPath string) (id, logicalPath string, err error) {
+	parts := strings.SplitN(strings.Trim(strings.TrimPrefix(urlPath, davPrefix), "/"), "/", 2)
+	if parts[0] == "" {
+		return "", "", nil
+	}
+	id, err = url.PathUnescape(parts[0])
+	if err != nil {
+		return "", "", errors.Wrapf(err, "invalid object id '%s'", parts[0])
+	}
+	if len(parts) == 2 {
+		logicalPath = parts[1]
+	}
+	return id, logicalPath, nil
+}
+
+func davAllowed(ctx context.Context, id string) bool {
+	if allowed, ok := ctx.Value(davAllowedKey{}).(func(string) bool); ok {
+		return allowed(id)
+	}
+	return false
+}
+
+func (d *DAVFS) Mkdir(_ context.Context, name string, _ os.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (d *DAVFS) RemoveAll(_ context.Context, name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func (d *DAVFS) Rename(_ context.Context, oldName, _ string) error {
+	return &fs.PathError{Op: "rename", Path: oldName, Err: fs.ErrPermission}
+}
+
+func (d *DAVFS) OpenFile(ctx context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	info, entries, o, file, err := d.resolve(ctx, name, true)
+	if err != nil {
+		return nil, davError("open", name, err)
+	}
+	if info.IsDir() {
+		return &davDir{info: info, entries: entries}, nil
+	}
+	fp, err := logical.OpenSeekable(o.Object.GetFS(), file.Internal)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open '%s'", file.Internal)
+	}
+	seeker, ok := fp.(io.ReadSeekCloser)
+	if !ok {
+		fp.Close()
+		return nil, errors.Errorf("'%s' is not seekable", file.Internal)
+	}
+	return &davContent{ReadSeekCloser: seeker, info: info}, nil
+}
+
+func (d *DAVFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, _, _, _, err := d.resolve(ctx, name, false)
+	if err != nil {
+		return nil, davError("stat", name, err)
+	}
+	return info, nil
+}
+
+// davError returns a fs.PathError for not existing or denied paths. webdav.Handler
+// uses os.IsNotExist and os.IsPermission which do not unwrap errors
+func davError(op, name string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	case errors.Is(err, fs.ErrPermission):
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	return err
+}
+
+// resolve returns the fileinfo and with list set the directory entries of name
+func (d *DAVFS) resolve(ctx context.Context, name string, list bool) (*davFileInfo, []os.FileInfo, *logical.CachedObject, *logical.File, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		info := &davFileInfo{name: "/", dir: true}
+		if !list {
+			return info, nil, nil, nil, nil
+		}
+		entries, err := d.listObjects(ctx)
+		return info, entries, nil, nil, err
+	}
+	parts := strings.SplitN(name, "/", 3)
+	id, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return nil, nil, nil, nil, errors.Wrapf(fs.ErrNotExist, "invalid object id '%s'", parts[0])
+	}
+	if !davAllowed(ctx, id) {
+		return nil, nil, nil, nil, errors.Wrapf(fs.ErrPermission, "access to '%s' denied", id)
+	}
+	o, err := d.server.objects.Get(id)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	inv := o.Object.GetInventory()
+	if len(parts) == 1 {
+		var modTime time.Time
+		if v, ok := o.Metadata.Versions[inv.GetHead()]; ok {
+			modTime = v.Created
+		}
+		info := &davFileInfo{name: parts[0], dir: true, modTime: modTime}
+		if !list {
+			return info, nil, o, nil, nil
+		}
+		var entries = []os.FileInfo{}
+		for _, ver := range inv.GetVersionStrings() {
+			var created time.Time
+			if v, ok := o.Metadata.Versions[ver]; ok {
+				created = v.Created
+			}
+			entries = append(entries, &davFileInfo{name: ver, dir: true, modTime: created})
+		}
+		entries = append(entries, &davFileInfo{name: davHead, dir: true, modTime: modTime})
+		return info, entries, o, nil, nil
+	}
+	version := parts[1]
+	if version == davHead {
+		version = inv.GetHead()
+	}
+	tree, err := o.Tree(version, d.area)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	var logical string
+	if len(parts) == 3 {
+		logical = parts[2]
+	}
+	if file, ok := tree.Files[logical]; ok {
+		return d.fileInfo(o, tree, path.Base(logical), file), nil, o, file, nil
+	}
+	names, ok := tree.Children(logical)
+	if !ok {
+		return nil, nil, nil, nil, errors.Wrapf(fs.ErrNotExist, "'%s' not found", name)
+	}
+	info := &davFileInfo{name: path.Base(name), dir: true, modTime: tree.ModTime}
+	if !list {
+		return info, nil, o, nil, nil
+	}
+	var entries = []os.FileInfo{}
+	for _, child := range names {
+		childPath := path.Join(logical, child)
+		if file, ok := tree.Files[childPath]; ok {
+			entries = append(entries, d.fileInfo(o, tree, child, file))
+		} else {
+			entries = append(entries, &davFileInfo{name: child, dir: true, modTime: tree.ModTime})
+		}
+	}
+	return info, entries, o, nil, nil
+}
+
+func (d *DAVFS) fileInfo(o *logical.CachedObject, tree *logical.Tree, name string, file *logical.File) *davFileInfo {
+	info := &davFileInfo{
+		name:    name,
+		digest:  file.Digest,
+		modTime: contentModTime(o.Metadata, file.Internal, tree.ModTime),
+	}
+	if fm, ok := o.Metadata.Files[file.Digest]; ok {
+		if idx := fileIndexer(fm); idx != nil && idx.Size > 0 {
+			info.size = int64(idx.Size)
+			return info
+		}
+	}
+	if fi, err := fs.Stat(o.Object.GetFS(), file.Internal); err == nil {
+		info.size = fi.Size()
+	}
+	return info
+}
+
+// listObjects lists the escaped ids of all objects the user has access to
+func (d *DAVFS) listObjects(ctx context.Context) ([]os.FileInfo, error) {
+	folders, err := d.server.storageRoot.GetObjectFolders()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get object folders")
+	}
+	var entries = []os.FileInfo{}
+	for _, folder := range folders {
+		id, err := d.server.objectIDFromFolder(folder)
+		if err != nil {
+			d.server.log.Warn().Err(err).Msgf("cannot get id of object in '%s'", folder)
+			continue
+		}
+		if !davAllowed(ctx, id) {
+			continue
+		}
+		entries = append(entries, &davFileInfo{name: url.PathEscape(id), dir: true})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+type davFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	digest  string
+}
+
+func (i *davFileInfo) Name() string       { return i.name }
+func (i *davFileInfo) Size() int64        { return i.size }
+func (i *davFileInfo) ModTime() time.Time { return i.modTime }
+func (i *davFileInfo) IsDir() bool        { return i.dir }
+func (i *davFileInfo) Sys() any           { return nil }
+func (i *davFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ETag is the content digest
+func (i *davFileInfo) ETag(context.Context) (string, error) {
+	if i.digest == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + i.digest + `"`, nil
+}
+
+type davDir struct {
+	info    os.FileInfo
+	entries []os.FileInfo
+	pos     int
+}
+
+func (d *davDir) Close() error                   { return nil }
+func (d *davDir) Read([]byte) (int, error)       { return 0, errors.Wrap(fs.ErrInvalid, "is a directory") }
+func (d *davDir) Seek(int64, int) (int64, error) { return 0, nil }
+func (d *davDir) Write([]byte) (int, error)      { return 0, errors.Wrap(fs.ErrPermission, "read only") }
+func (d *davDir) Stat() (os.FileInfo, error)     { return d.info, nil }
+func (d *davDir) Readdir(count int) ([]os.FileInfo, error) {
+	rest := d.entries[d.pos:]
+	if count <= 0 {
+		d.pos = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	count = min(count, len(rest))
+	d.pos += count
+	return rest[:count], nil
+}
+
+type davContent struct {
+	io.ReadSeekCloser
+	info os.FileInfo
+}
+
+func (f *davContent) Write([]byte) (int, error)  { return 0, errors.Wrap(fs.ErrPermission, "read only") }
+func (f *davContent) Stat() (os.FileInfo, error) { return f.info, nil }
+func (f *davContent) Readdir(int) ([]os.FileInfo, error) {
+	return nil, errors.Wrap(fs.ErrInvalid, "not a directory")
+}
+
+var (
+	_ webdav.FileSystem = &DAVFS{}
+	_ webdav.File       = &davDir{}
+	_ webdav.File       = &davContent{}
+	_ webdav.ETager     = &davFileInfo{}
+)
@@ -0,0 +1,176 @@
+package display
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ocfl-archive/gocfl/v2/config"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/rs/zerolog"
+)
+
+var davTestObjects = map[string]map[string][]byte{
+	"public-1":  {"a.txt": []byte("public content"), "dir/b.txt": []byte("0123456789")},
+	"private-1": {"a.txt": []byte("private content")},
+	"other-1":   {"a.txt": []byte("other content")},
+}
+
+func newTestDAVAuth() *Auth {
+	return &Auth{
+		realm:          "gocfl",
+		authenticators: []Authenticator{NewBearerTokenAuth(map[string]string{"alice-token": "alice"})},
+		rules: []*config.DisplayRule{
+			{Prefix: "public-", Anonymous: true},
+			{Prefix: "private-", Users: []string{"bob"}},
+		},
+	}
+}
+
+func newTestDAVServer(t *testing.T, sr storageroot.StorageRoot, factory *extension.ExtensionFactory, auth *Auth) *gin.Engine {
+	logger := zerolog.Nop()
+	srv, err := NewServer(sr, factory, "test", "localhost:0", nil, nil, nil, &logger, io.Discard)
+	if err != nil {
+		t.Fatalf("cannot create server: %v", err)
+	}
+	srv.SetAuth(auth)
+	srv.StartDAV("content")
+	gin.SetMode(gin.TestMode)
+	route := gin.New()
+	route.UseRawPath = true
+	route.UnescapePathValues = false
+	route.Use(srv.authMiddleware)
+	srv.initDAV(route)
+	return route
+}
+
+func davRequest(route *gin.Engine, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for key, val := range header {
+		req.Header.Set(key, val)
+	}
+	w := httptest.NewRecorder()
+	route.ServeHTTP(w, req)
+	return w
+}
+
+var aliceHeader = map[string]string{"Authorization": "Bearer alice-token"}
+
+func TestDAVPropfindListing(t *testing.T) {
+	sr, factory := newOCFLStorageRoot(t, true, davTestObjects)
+	route := newTestDAVServer(t, sr, factory, newTestDAVAuth())
+
+	var tests = []struct {
+		name    string
+		header  map[string]string
+		listed  []string
+		ignored []string
+	}{
+		{"anonymous", nil, []string{"public-1"}, []string{"private-1", "other-1"}},
+		{"alice", aliceHeader, []string{"public-1", "other-1"}, []string{"private-1"}},
+	}
+	for _, test := range tests {
+		header := map[string]string{"Depth": "1"}
+		for key, val := range test.header {
+			header[key] = val
+		}
+		w := davRequest(route, "PROPFIND", davPrefix+"/", header)
+		if w.Code != http.StatusMultiStatus {
+			t.Fatalf("%s: status %d: %s", test.name, w.Code, w.Body.String())
+		}
+		body := w.Body.String()
+		for _, id := range test.listed {
+			if !strings.Contains(body, davPrefix+"/"+id+"/") {
+				t.Errorf("%s: %s not listed", test.name, id)
+			}
+		}
+		for _, id := range test.ignored {
+			if strings.Contains(body, id) {
+				t.Errorf("%s: %s listed", test.name, id)
+			}
+		}
+	}
+
+	// versions and head of an object
+	header := map[string]string{"Depth": "1", "Authorization": "Bearer alice-token"}
+	w := davRequest(route, "PROPFIND", davPrefix+"/other-1/", header)
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	for _, name := range []string{"/other-1/v1/", "/other-1/head/"} {
+		if !strings.Contains(w.Body.String(), davPrefix+name) {
+			t.Errorf("%s not listed", name)
+		}
+	}
+}
+
+func TestDAVDenied(t *testing.T) {
+	sr, factory := newOCFLStorageRoot(t, true, davTestObjects)
+	route := newTestDAVServer(t, sr, factory, newTestDAVAuth())
+
+	var tests = []struct {
+		method string
+		target string
+		header map[string]string
+		status int
+	}{
+		{http.MethodGet, "/private-1/head/a.txt", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/private-1/head/a.txt", aliceHeader, http.StatusForbidden},
+		{"PROPFIND", "/private-1/", aliceHeader, http.StatusForbidden},
+		{"PROPFIND", "/other-1/", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/unknown-1/head/a.txt", aliceHeader, http.StatusNotFound},
+		{"PROPFIND", "/unknown-1/", aliceHeader, http.StatusNotFound},
+		{http.MethodGet, "/public-1/head/missing.txt", nil, http.StatusNotFound},
+		{http.MethodGet, "/public-1/v9/a.txt", nil, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if w := davRequest(route, test.method, davPrefix+test.target, test.header); w.Code != test.status {
+			t.Errorf("%s %s: expected status %d, got %d", test.method, test.target, test.status, w.Code)
+		}
+	}
+}
+
+func TestDAVGetZip(t *testing.T) {
+	sr, factory := newOCFLStorageRoot(t, true, davTestObjects)
+	route := newTestDAVServer(t, sr, factory, nil)
+
+	for _, target := range []string{"/public-1/head/dir/b.txt", "/public-1/v1/dir/b.txt"} {
+		w := davRequest(route, http.MethodGet, davPrefix+target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", target, w.Code, w.Body.String())
+		}
+		if w.Body.String() != "0123456789" {
+			t.Errorf("%s: unexpected content '%s'", target, w.Body.String())
+		}
+		if w.Header().Get("ETag") == "" {
+			t.Errorf("%s: no etag", target)
+		}
+	}
+	w := davRequest(route, http.MethodGet, davPrefix+"/public-1/head/dir/b.txt", map[string]string{"Range": "bytes=3-5"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "345" {
+		t.Errorf("range: status %d, content '%s'", w.Code, w.Body.String())
+	}
+}
+
+func TestDAVReadOnly(t *testing.T) {
+	sr, factory := newOCFLStorageRoot(t, false, davTestObjects)
+	route := newTestDAVServer(t, sr, factory, nil)
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete, "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK"} {
+		w := davRequest(route, method, davPrefix+"/public-1/head/a.txt", nil)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: expected status %d, got %d", method, http.StatusMethodNotAllowed, w.Code)
+		}
+		if allow := w.Header().Get("Allow"); allow != "OPTIONS, GET, HEAD, PROPFIND" {
+			t.Errorf("%s: unexpected allow header '%s'", method, allow)
+		}
+	}
+	w := davRequest(route, http.MethodOptions, davPrefix+"/", nil)
+	if w.Code != http.StatusOK || w.Header().Get("DAV") != "1" {
+		t.Errorf("options: status %d, dav header '%s'", w.Code, w.Header().Get("DAV"))
+	}
+}
@@ -3,12 +3,14 @@ package display
 import (
 	"io/fs"
 	"math"
+	"net/http"
 	"path"
 	"strings"
 	"time"
 
 	"emperror.dev/errors"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/inventory"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/logical"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
 	"golang.org/x/exp/slices"
 )
@@ -41,6 +43,24 @@ func NewObjectFS(obj object.Object) (*ObjectFS, error) {
 	}, nil
 }
 
+// objectFSKey is the key of the object browser filesystem in the object cache
+const objectFSKey = "objectfs"
+
+// cachedObjectFS returns the http filesystem of the object browser, which is created once per loaded object
+func cachedObjectFS(o *logical.CachedObject) (http.FileSystem, error) {
+	value, err := o.Value(objectFSKey, func() (any, error) {
+		objectFS, err := NewObjectFS(o.Object)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get filesystem for object %s", o.Object.GetID())
+		}
+		return http.FS(objectFS), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(http.FileSystem), nil
+}
+
 type ObjectFS struct {
 	object object.Object
 	//	metadata  *ocfl.ObjectMetadata
@@ -111,8 +131,7 @@ func (o *ObjectFS) openState(name string) (fs.File, error) {
 		if len(realpaths) == 0 {
 			return nil, errors.Wrapf(fs.ErrNotExist, "no files found for checksum %s for version %s and path %s", cs, versionStr, path)
 		}
-		fsys := o.object.GetFS()
-		return fsys.Open(realpaths[0])
+		return logical.OpenSeekable(o.object.GetFS(), realpaths[0])
 	}
 	return nil, errors.Wrapf(fs.ErrNotExist, "invalid state path: %s", name)
 }
@@ -247,8 +266,7 @@ func (o *ObjectFS) openManifest(name string) (fs.File, error) {
 	if len(realpaths) == 0 {
 		return nil, errors.Wrapf(fs.ErrNotExist, "no files found for checksum %s", name)
 	}
-	fsys := o.object.GetFS()
-	return fsys.Open(realpaths[0])
+	return logical.OpenSeekable(o.object.GetFS(), realpaths[0])
 }
 func (o *ObjectFS) statManifest(name string) (fs.FileInfo, error) {
 	realpaths, ok := o.manifest[name]
@@ -290,8 +308,7 @@ func (o *ObjectFS) openData(name string) (fs.File, error) {
 		if !slices.Contains(realpaths, name) {
 			continue
 		}
-		fs := o.object.GetFS()
-		return fs.Open(realpaths[0])
+		return logical.OpenSeekable(o.object.GetFS(), realpaths[0])
 	}
 	return nil, errors.Wrapf(fs.ErrNotExist, "unknown file %s", name)
 }
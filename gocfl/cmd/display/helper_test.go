@@ -0,0 +1,180 @@
+package display
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/je4/filesystem/v3/pkg/osfsrw"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/filesystem/v3/pkg/zipfs"
+	"github.com/je4/utils/v2/pkg/checksum"
+	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/version"
+	"github.com/rs/zerolog"
+)
+
+func newTestExtensionFactory(t *testing.T) *extension.ExtensionFactory {
+	logger := zerolog.Nop()
+	factory, err := extension.NewExtensionFactory(map[string]string{}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create extension factory: %v", err)
+	}
+	factory.AddCreator(ocflextension.InitialName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewInitialFS(fsys)
+	})
+	factory.AddCreator(ocflextension.GOCFLExtensionManagerName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewGOCFLExtensionManagerFS(fsys)
+	})
+	factory.AddCreator(ocflextension.StorageLayoutFlatDirectName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewStorageLayoutFlatDirectFS(fsys)
+	})
+	return factory
+}
+
+func newTestExtensionManager(t *testing.T, exts ...extension.Extension) *ocflextension.GOCFLExtensionManager {
+	manager, err := ocflextension.NewGOCFLExtensionManager(&extension.ExtensionManagerConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: ocflextension.GOCFLExtensionManagerName},
+		Sort:            map[string][]string{},
+		Exclusion:       map[string][][]string{},
+	})
+	if err != nil {
+		t.Fatalf("cannot create extension manager: %v", err)
+	}
+	initial, err := ocflextension.NewInitialFS(nil)
+	if err != nil {
+		t.Fatalf("cannot create initial extension: %v", err)
+	}
+	manager.SetInitial(initial)
+	for _, ext := range exts {
+		if err := manager.Add(ext); err != nil {
+			t.Fatalf("cannot add extension '%s': %v", ext.GetName(), err)
+		}
+	}
+	manager.Finalize()
+	return manager
+}
+
+// newOCFLStorageRoot creates a storage root with flat direct layout and one version of every object
+// in a temporary folder. with zipped set, the storage root is read from a zip file of the folder
+func newOCFLStorageRoot(t *testing.T, zipped bool, objects map[string]map[string][]byte) (storageroot.StorageRoot, *extension.ExtensionFactory) {
+	logger := zerolog.Nop()
+	dir := t.TempDir()
+	fsys, err := osfsrw.NewFS(dir, true, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	factory := newTestExtensionFactory(t)
+	layout, err := ocflextension.NewStorageLayoutFlatDirect(&ocflextension.StorageLayoutFlatDirectConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: ocflextension.StorageLayoutFlatDirectName},
+	})
+	if err != nil {
+		t.Fatalf("cannot create storage layout: %v", err)
+	}
+	ctx := validation.NewContextValidation(context.Background())
+	sr, err := storageroot.CreateStorageRoot(ctx, fsys, version.Version1_1, factory, newTestExtensionManager(t, layout), checksum.DigestSHA512, &logger)
+	if err != nil {
+		t.Fatalf("cannot create storage root: %v", err)
+	}
+	for id, files := range objects {
+		folder, err := sr.IdToFolder(id)
+		if err != nil {
+			t.Fatalf("cannot get folder of %s: %v", id, err)
+		}
+		objFS, err := writefs.SubFSCreate(fsys, folder)
+		if err != nil {
+			t.Fatalf("cannot create object filesystem: %v", err)
+		}
+		obj, err := object.CreateObject(ctx, id, version.Version1_1, checksum.DigestSHA512, nil, factory, newTestExtensionManager(t), objFS, &logger)
+		if err != nil {
+			t.Fatalf("cannot create object %s: %v", id, err)
+		}
+		if _, err := obj.StartUpdate(nil, "test", "test", "test", false); err != nil {
+			t.Fatalf("cannot start update: %v", err)
+		}
+		for name, data := range files {
+			if err := obj.AddData(data, name, false, "content", false, false); err != nil {
+				t.Fatalf("cannot add '%s': %v", name, err)
+			}
+		}
+		if err := obj.EndUpdate(); err != nil {
+			t.Fatalf("cannot end update: %v", err)
+		}
+		if err := obj.Close(); err != nil {
+			t.Fatalf("cannot close object %s: %v", id, err)
+		}
+	}
+	if !zipped {
+		return sr, factory
+	}
+
+	zipName := filepath.Join(t.TempDir(), "ocfl.zip")
+	writeTestZip(t, dir, zipName)
+	fsFactory, err := writefs.NewFactory()
+	if err != nil {
+		t.Fatalf("cannot create filesystem factory: %v", err)
+	}
+	if err := fsFactory.Register(zipfs.NewCreateFSFunc(&logger), "\\.zip$", writefs.HighFS); err != nil {
+		t.Fatalf("cannot register zipfs: %v", err)
+	}
+	if err := fsFactory.Register(osfsrw.NewCreateFSFunc(&logger), "", writefs.LowFS); err != nil {
+		t.Fatalf("cannot register osfs: %v", err)
+	}
+	zipFS, err := fsFactory.Get(zipName, true)
+	if err != nil {
+		t.Fatalf("cannot open '%s': %v", zipName, err)
+	}
+	t.Cleanup(func() {
+		if err := writefs.Close(zipFS); err != nil {
+			t.Errorf("cannot close '%s': %v", zipName, err)
+		}
+	})
+	zipRoot, err := storageroot.LoadStorageRoot(validation.NewContextValidation(context.Background()), zipFS, factory, &logger)
+	if err != nil {
+		t.Fatalf("cannot load storage root from '%s': %v", zipName, err)
+	}
+	return zipRoot, factory
+}
+
+// writeTestZip writes the files of dir deflated to zipName. entries of deflated zip files cannot be seeked
+func writeTestZip(t *testing.T, dir, zipName string) {
+	fp, err := os.Create(zipName)
+	if err != nil {
+		t.Fatalf("cannot create '%s': %v", zipName, err)
+	}
+	defer fp.Close()
+	zw := zip.NewWriter(fp)
+	if err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: filepath.ToSlash(rel), Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	}); err != nil {
+		t.Fatalf("cannot write '%s': %v", zipName, err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("cannot close '%s': %v", zipName, err)
+	}
+}
@@ -0,0 +1,387 @@
+package display
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/inventory"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+)
+
+const (
+	// textDiffMaxSize is the maximum size of a file for the text diff
+	textDiffMaxSize = 512 * 1024
+	// textDiffMaxCells limits the lcs table of the text diff
+	textDiffMaxCells = 4_000_000
+)
+
+// TimelineEntry is a version in the history of an object
+type TimelineEntry struct {
+	Version  string
+	Created  time.Time
+	User     string
+	Address  string
+	Message  string
+	Files    int
+	Added    int
+	Removed  int
+	Changed  int
+	Renamed  int
+	Previous string
+}
+
+// DiffEntry is a file which differs between two versions
+type DiffEntry struct {
+	// Status is one of added, removed, changed or renamed
+	Status     string
+	FromPath   string
+	ToPath     string
+	FromDigest string
+	ToDigest   string
+	// TextDiff is true if both files are small enough for a text diff
+	TextDiff bool
+}
+
+// DiffRow is a line of the side-by-side text diff
+type DiffRow struct {
+	// Op is one of equal, delete, insert or replace
+	Op        string
+	LeftLine  int
+	LeftText  string
+	RightLine int
+	RightText string
+}
+
+func versionState(v *inventory.Version) map[string]string {
+	var result = map[string]string{}
+	if v == nil || v.State == nil {
+		return result
+	}
+	for digest, paths := range v.State.State {
+		for _, p := range paths {
+			result[p] = digest
+		}
+	}
+	return result
+}
+
+// DiffVersions compares the state maps of two versions. Files which are removed and added with
+// the same digest are renamed
+func DiffVersions(from, to *inventory.Version) []*DiffEntry {
+	fromState := versionState(from)
+	toState := versionState(to)
+	var result = []*DiffEntry{}
+	var removed = map[string][]string{}
+	for p, digest := range fromState {
+		toDigest, ok := toState[p]
+		if !ok {
+			removed[digest] = append(removed[digest], p)
+			continue
+		}
+		if toDigest != digest {
+			result = append(result, &DiffEntry{Status: "changed", FromPath: p, ToPath: p, FromDigest: digest, ToDigest: toDigest})
+		}
+	}
+	for _, paths := range removed {
+		sort.Strings(paths)
+	}
+	var added = []string{}
+	for p := range toState {
+		if _, ok := fromState[p]; !ok {
+			added = append(added, p)
+		}
+	}
+	sort.Strings(added)
+	for _, p := range added {
+		digest := toState[p]
+		if paths := removed[digest]; len(paths) > 0 {
+			result = append(result, &DiffEntry{Status: "renamed", FromPath: paths[0], ToPath: p, FromDigest: digest, ToDigest: digest})
+			removed[digest] = paths[1:]
+			continue
+		}
+		result = append(result, &DiffEntry{Status: "added", ToPath: p, ToDigest: digest})
+	}
+	for digest, paths := range removed {
+		for _, p := range paths {
+			result = append(result, &DiffEntry{Status: "removed", FromPath: p, FromDigest: digest})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		pi, pj := result[i].ToPath, result[j].ToPath
+		if pi == "" {
+			pi = result[i].FromPath
+		}
+		if pj == "" {
+			pj = result[j].FromPath
+		}
+		return pi < pj
+	})
+	return result
+}
+
+// DiffLines returns the side-by-side line diff of two texts based on the longest common subsequence
+func DiffLines(from, to string) ([]*DiffRow, error) {
+	a := strings.Split(strings.TrimSuffix(from, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(to, "\n"), "\n")
+	// common prefix and suffix keep the lcs table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma := a[prefix : len(a)-suffix]
+	mb := b[prefix : len(b)-suffix]
+	if len(ma)*len(mb) > textDiffMaxCells {
+		return nil, errors.Errorf("too many changed lines for text diff (%d x %d)", len(ma), len(mb))
+	}
+	lcs := make([][]int32, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var rows = []*DiffRow{}
+	for i := 0; i < prefix; i++ {
+		rows = append(rows, &DiffRow{Op: "equal", LeftLine: i + 1, LeftText: a[i], RightLine: i + 1, RightText: b[i]})
+	}
+	var deleted, inserted []*DiffRow
+	flush := func() {
+		// pair deleted and inserted lines of a block
+		for k := 0; k < max(len(deleted), len(inserted)); k++ {
+			row := &DiffRow{}
+			switch {
+			case k < len(deleted) && k < len(inserted):
+				row.Op = "replace"
+				row.LeftLine, row.LeftText = deleted[k].LeftLine, deleted[k].LeftText
+				row.RightLine, row.RightText = inserted[k].RightLine, inserted[k].RightText
+			case k < len(deleted):
+				row = deleted[k]
+			default:
+				row = inserted[k]
+			}
+			rows = append(rows, row)
+		}
+		deleted, inserted = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			flush()
+			rows = append(rows, &DiffRow{Op: "equal", LeftLine: prefix + i + 1, LeftText: ma[i], RightLine: prefix + j + 1, RightText: mb[j]})
+			i++
+			j++
+		case j < len(mb) && (i == len(ma) || lcs[i][j+1] >= lcs[i+1][j]):
+			inserted = append(inserted, &DiffRow{Op: "insert", RightLine: prefix + j + 1, RightText: mb[j]})
+			j++
+		default:
+			deleted = append(deleted, &DiffRow{Op: "delete", LeftLine: prefix + i + 1, LeftText: ma[i]})
+			i++
+		}
+	}
+	flush()
+	for k := 0; k < suffix; k++ {
+		ai := len(a) - suffix + k
+		bi := len(b) - suffix + k
+		rows = append(rows, &DiffRow{Op: "equal", LeftLine: ai + 1, LeftText: a[ai], RightLine: bi + 1, RightText: b[bi]})
+	}
+	return rows, nil
+}
+
+func contentSize(fsys fs.FS, metadata *object.ObjectMetadata, digest string) (int64, bool) {
+	file, ok := metadata.Files[digest]
+	if !ok || len(file.InternalName) == 0 {
+		return 0, false
+	}
+	fi, err := fs.Stat(fsys, file.InternalName[0])
+	if err != nil {
+		return 0, false
+	}
+	return fi.Size(), true
+}
+
+// readText reads a small content file and checks that it is utf-8 text
+func readText(fsys fs.FS, metadata *object.ObjectMetadata, digest string) (string, error) {
+	file, ok := metadata.Files[digest]
+	if !ok || len(file.InternalName) == 0 {
+		return "", errors.Errorf("no file with digest %s found", digest)
+	}
+	fp, err := fsys.Open(file.InternalName[0])
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot open '%s'", file.InternalName[0])
+	}
+	defer fp.Close()
+	data, err := io.ReadAll(io.LimitReader(fp, textDiffMaxSize+1))
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot read '%s'", file.InternalName[0])
+	}
+	if len(data) > textDiffMaxSize {
+		return "", errors.Errorf("'%s' is too large for text diff", file.InternalName[0])
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return "", errors.Errorf("'%s' is not a text file", file.InternalName[0])
+	}
+	return string(data), nil
+}
+
+func ocflString(str *inventory.OCFLString) string {
+	if str == nil {
+		return ""
+	}
+	return str.String()
+}
+
+func timelineEntry(name string, v *inventory.Version) *TimelineEntry {
+	entry := &TimelineEntry{Version: name}
+	if v.Created != nil {
+		entry.Created = v.Created.Time
+	}
+	entry.Message = ocflString(v.Message)
+	if v.User != nil {
+		entry.User = ocflString(v.User.Name)
+		entry.Address = ocflString(v.User.Address)
+	}
+	entry.Files = len(versionState(v))
+	return entry
+}
+
+// history shows the timeline of all versions and a form to compare two of them
+func (s *Server) history(c *gin.Context) {
+	obj, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	inv := obj.GetInventory()
+	versions := inv.GetVersions()
+	var timeline = []*TimelineEntry{}
+	var previous string
+	for _, name := range inv.GetVersionStrings() {
+		v, ok := versions[name]
+		if !ok {
+			continue
+		}
+		entry := timelineEntry(name, v)
+		if previous != "" {
+			entry.Previous = previous
+			for _, d := range DiffVersions(versions[previous], v) {
+				switch d.Status {
+				case "added":
+					entry.Added++
+				case "removed":
+					entry.Removed++
+				case "changed":
+					entry.Changed++
+				case "renamed":
+					entry.Renamed++
+				}
+			}
+		} else {
+			entry.Added = entry.Files
+		}
+		timeline = append(timeline, entry)
+		previous = name
+	}
+	// newest first
+	for i, j := 0, len(timeline)-1; i < j; i, j = i+1, j-1 {
+		timeline[i], timeline[j] = timeline[j], timeline[i]
+	}
+	var params = gin.H{
+		"title":    "History",
+		"id":       metadata.ID,
+		"versions": metadata.Versions,
+		"timeline": timeline,
+		"head":     metadata.Head,
+	}
+	if len(timeline) > 1 {
+		params["previous"] = timeline[1].Version
+	} else if len(timeline) == 1 {
+		params["previous"] = timeline[0].Version
+	}
+	c.HTML(http.StatusOK, "history.gohtml", params)
+}
+
+// diff compares the versions of the query parameters from and to. with the parameter path
+// the text diff of a changed file is shown
+func (s *Server) diff(c *gin.Context) {
+	obj, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	inv := obj.GetInventory()
+	versions := inv.GetVersions()
+	fromName := c.Query("from")
+	toName := c.Query("to")
+	if toName == "" {
+		toName = inv.GetHead()
+	}
+	from, ok := versions[fromName]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": errors.Errorf("version '%s' of object %s not found", fromName, metadata.ID).Error()})
+		return
+	}
+	to, ok := versions[toName]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": errors.Errorf("version '%s' of object %s not found", toName, metadata.ID).Error()})
+		return
+	}
+	entries := DiffVersions(from, to)
+	fsys := obj.GetFS()
+	for _, e := range entries {
+		if e.Status != "changed" {
+			continue
+		}
+		fromSize, ok1 := contentSize(fsys, metadata, e.FromDigest)
+		toSize, ok2 := contentSize(fsys, metadata, e.ToDigest)
+		e.TextDiff = ok1 && ok2 && fromSize <= textDiffMaxSize && toSize <= textDiffMaxSize
+	}
+	var params = gin.H{
+		"title":       "Diff",
+		"id":          metadata.ID,
+		"versions":    metadata.Versions,
+		"from":        timelineEntry(fromName, from),
+		"to":          timelineEntry(toName, to),
+		"entries":     entries,
+		"versionList": inv.GetVersionStrings(),
+	}
+	if p := c.Query("path"); p != "" {
+		params["path"] = p
+		var entry *DiffEntry
+		for _, e := range entries {
+			if e.Status == "changed" && e.ToPath == p {
+				entry = e
+				break
+			}
+		}
+		if entry == nil {
+			params["textError"] = "file not changed between the versions"
+		} else if fromText, err := readText(fsys, metadata, entry.FromDigest); err != nil {
+			params["textError"] = err.Error()
+		} else if toText, err := readText(fsys, metadata, entry.ToDigest); err != nil {
+			params["textError"] = err.Error()
+		} else if rows, err := DiffLines(fromText, toText); err != nil {
+			params["textError"] = err.Error()
+		} else {
+			params["rows"] = rows
+		}
+	}
+	c.HTML(http.StatusOK, "diff.gohtml", params)
+}
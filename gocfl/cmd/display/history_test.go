@@ -0,0 +1,146 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/inventory"
+)
+
+func testVersion(state map[string][]string) *inventory.Version {
+	return &inventory.Version{State: &inventory.OCFLState{State: state}}
+}
+
+func TestDiffVersions(t *testing.T) {
+	tests := []struct {
+		name string
+		from *inventory.Version
+		to   *inventory.Version
+		want []string
+	}{
+		{
+			name: "first version",
+			from: nil,
+			to:   testVersion(map[string][]string{"d1": {"b.txt", "a.txt"}}),
+			want: []string{"added  -> a.txt", "added  -> b.txt"},
+		},
+		{
+			name: "unchanged",
+			from: testVersion(map[string][]string{"d1": {"a.txt"}}),
+			to:   testVersion(map[string][]string{"d1": {"a.txt"}}),
+			want: nil,
+		},
+		{
+			name: "changed, renamed, added and removed",
+			from: testVersion(map[string][]string{"d1": {"a.txt"}, "d2": {"b.txt"}, "d3": {"c.txt"}, "d5": {"old2", "old1"}}),
+			to:   testVersion(map[string][]string{"d1": {"a.txt"}, "d4": {"b.txt"}, "d3": {"moved/c.txt"}, "d6": {"new.txt"}, "d5": {"renamed"}}),
+			want: []string{
+				"changed b.txt -> b.txt",
+				"renamed c.txt -> moved/c.txt",
+				"added  -> new.txt",
+				"removed old2 -> ",
+				"renamed old1 -> renamed",
+			},
+		},
+		{
+			name: "copy is added",
+			from: testVersion(map[string][]string{"d1": {"a.txt"}}),
+			to:   testVersion(map[string][]string{"d1": {"a.txt", "copy.txt"}}),
+			want: []string{"added  -> copy.txt"},
+		},
+		{
+			name: "all removed",
+			from: testVersion(map[string][]string{"d1": {"a.txt"}}),
+			to:   testVersion(map[string][]string{}),
+			want: []string{"removed a.txt -> "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range DiffVersions(tt.from, tt.to) {
+				got = append(got, fmt.Sprintf("%s %s -> %s", entry.Status, entry.FromPath, entry.ToPath))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []string
+	}{
+		{
+			name: "equal",
+			from: "a\nb\n",
+			to:   "a\nb",
+			want: []string{"equal 1:a 1:a", "equal 2:b 2:b"},
+		},
+		{
+			name: "replace",
+			from: "a\nb\nc\n",
+			to:   "a\nx\nc\n",
+			want: []string{"equal 1:a 1:a", "replace 2:b 2:x", "equal 3:c 3:c"},
+		},
+		{
+			name: "insert",
+			from: "a\nc\n",
+			to:   "a\nb\nc\n",
+			want: []string{"equal 1:a 1:a", "insert 0: 2:b", "equal 2:c 3:c"},
+		},
+		{
+			name: "delete",
+			from: "a\nb\nc\n",
+			to:   "a\nc\n",
+			want: []string{"equal 1:a 1:a", "delete 2:b 0:", "equal 3:c 2:c"},
+		},
+		{
+			name: "moved line",
+			from: "a\nb\nc\nd\n",
+			to:   "b\nc\na\nd\n",
+			want: []string{"delete 1:a 0:", "equal 2:b 1:b", "equal 3:c 2:c", "insert 0: 3:a", "equal 4:d 4:d"},
+		},
+		{
+			name: "uneven block",
+			from: "a\nb\nc\nz\n",
+			to:   "x\nz\n",
+			want: []string{"replace 1:a 1:x", "delete 2:b 0:", "delete 3:c 0:", "equal 4:z 2:z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := DiffLines(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("cannot diff: %v", err)
+			}
+			var got []string
+			for _, row := range rows {
+				got = append(got, fmt.Sprintf("%s %d:%s %d:%s", row.Op, row.LeftLine, row.LeftText, row.RightLine, row.RightText))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	var from, to strings.Builder
+	for i := 0; i < 2001; i++ {
+		fmt.Fprintf(&from, "a%d\n", i)
+		fmt.Fprintf(&to, "b%d\n", i)
+	}
+	if _, err := DiffLines(from.String(), to.String()); err == nil {
+		t.Errorf("lcs table limit not enforced")
+	}
+	// common prefix and suffix do not count
+	if _, err := DiffLines("x\n"+from.String(), "x\n"+from.String()+"y\n"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
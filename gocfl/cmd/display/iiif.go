@@ -0,0 +1,543 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/indexer/v3/pkg/indexer"
+)
+
+const iiifPrefix = "/iiif/3"
+
+const (
+	iiifImageContext        = "http://iiif.io/api/image/3/context.json"
+	iiifPresentationContext = "http://iiif.io/api/presentation/3/context.json"
+	iiifProfile             = "level2"
+	iiifTileSize            = 512
+	// iiifMaxArea limits the number of pixels of a response image
+	iiifMaxArea = 100_000_000
+)
+
+var iiifFormats = map[string]string{
+	"jpg": "image/jpeg",
+	"png": "image/png",
+}
+
+type IIIFTile struct {
+	Width        int   `json:"width"`
+	ScaleFactors []int `json:"scaleFactors"`
+}
+
+type IIIFSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// IIIFImageInfo is the info.json of the image api 3
+type IIIFImageInfo struct {
+	Context        string      `json:"@context"`
+	ID             string      `json:"id"`
+	Type           string      `json:"type"`
+	Protocol       string      `json:"protocol"`
+	Profile        string      `json:"profile"`
+	Width          int         `json:"width"`
+	Height         int         `json:"height"`
+	MaxArea        int         `json:"maxArea"`
+	Sizes          []*IIIFSize `json:"sizes,omitempty"`
+	Tiles          []*IIIFTile `json:"tiles"`
+	ExtraQualities []string    `json:"extraQualities"`
+	ExtraFeatures  []string    `json:"extraFeatures"`
+}
+
+// IIIFLabel is a language map
+type IIIFLabel map[string][]string
+
+type IIIFMetadataEntry struct {
+	Label IIIFLabel `json:"label"`
+	Value IIIFLabel `json:"value"`
+}
+
+type IIIFService struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Profile string `json:"profile"`
+}
+
+type IIIFImageResource struct {
+	ID      string         `json:"id"`
+	Type    string         `json:"type"`
+	Format  string         `json:"format"`
+	Width   int            `json:"width"`
+	Height  int            `json:"height"`
+	Service []*IIIFService `json:"service"`
+}
+
+type IIIFAnnotation struct {
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	Motivation string             `json:"motivation"`
+	Body       *IIIFImageResource `json:"body"`
+	Target     string             `json:"target"`
+}
+
+type IIIFAnnotationPage struct {
+	ID    string            `json:"id"`
+	Type  string            `json:"type"`
+	Items []*IIIFAnnotation `json:"items"`
+}
+
+type IIIFCanvas struct {
+	ID     string                `json:"id"`
+	Type   string                `json:"type"`
+	Label  IIIFLabel             `json:"label"`
+	Width  int                   `json:"width"`
+	Height int                   `json:"height"`
+	Items  []*IIIFAnnotationPage `json:"items"`
+}
+
+// IIIFManifest is a presentation api 3 manifest of an object version
+type IIIFManifest struct {
+	Context  string               `json:"@context"`
+	ID       string               `json:"id"`
+	Type     string               `json:"type"`
+	Label    IIIFLabel            `json:"label"`
+	Summary  IIIFLabel            `json:"summary,omitempty"`
+	Metadata []*IIIFMetadataEntry `json:"metadata"`
+	Items    []*IIIFCanvas        `json:"items"`
+}
+
+func iiifLabel(str string) IIIFLabel {
+	return IIIFLabel{"none": []string{str}}
+}
+
+func (s *Server) initIIIF(route *gin.Engine) {
+	route.GET(iiifPrefix+"/:id/manifest", s.iiifManifest)
+	route.GET(iiifPrefix+"/:id/manifest/:version", s.iiifManifest)
+	route.GET(iiifPrefix+"/:id/image/:digest/*params", s.iiifImage)
+}
+
+// iiifBase returns the external url of the iiif endpoints
+func (s *Server) iiifBase(c *gin.Context) string {
+	if s.urlExt != nil {
+		return strings.TrimRight(s.urlExt.String(), "/") + iiifPrefix
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + iiifPrefix
+}
+
+func (s *Server) iiifImageID(c *gin.Context, id, digest string) string {
+	return fmt.Sprintf("%s/%s/image/%s", s.iiifBase(c), url.PathEscape(id), digest)
+}
+
+func fileIndexer(file *object.FileMetadata) *indexer.ResultV2 {
+	if file.Extension == nil {
+		return nil
+	}
+	idx, _ := file.Extension[extension.IndexerName].(*indexer.ResultV2)
+	return idx
+}
+
+// iiifImageSize returns the dimensions from the indexer metadata or decodes the image
+func iiifImageSize(fsys fs.FS, file *object.FileMetadata) (int, int, error) {
+	if idx := fileIndexer(file); idx != nil {
+		if idx.Mimetype != "" && !strings.HasPrefix(idx.Mimetype, "image/") {
+			return 0, 0, errors.Errorf("'%s' is not an image but %s", file.InternalName[0], idx.Mimetype)
+		}
+		if idx.Width > 0 && idx.Height > 0 {
+			return int(idx.Width), int(idx.Height), nil
+		}
+	}
+	fp, err := fsys.Open(file.InternalName[0])
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "cannot open '%s'", file.InternalName[0])
+	}
+	defer fp.Close()
+	width, height, err := extension.ImageSize(fp)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "cannot get size of '%s'", file.InternalName[0])
+	}
+	return width, height, nil
+}
+
+// iiifScaleFactors returns the tile scale factors down to a single tile
+func iiifScaleFactors(width, height int) []int {
+	var result = []int{}
+	longest := max(width, height)
+	for sf := 1; ; sf *= 2 {
+		result = append(result, sf)
+		if (longest+sf-1)/sf <= iiifTileSize {
+			return result
+		}
+	}
+}
+
+func (s *Server) iiifImage(c *gin.Context) {
+	obj, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	c.Header("Access-Control-Allow-Origin", "*")
+	digest := c.Param("digest")
+	file, ok := metadata.Files[digest]
+	if !ok || len(file.InternalName) == 0 {
+		apiError(c, http.StatusNotFound, errors.Errorf("no file with digest %s found", digest))
+		return
+	}
+	imageID := s.iiifImageID(c, metadata.ID, digest)
+	params := strings.Trim(c.Param("params"), "/")
+	if params == "" {
+		c.Redirect(http.StatusSeeOther, imageID+"/info.json")
+		return
+	}
+	width, height, err := iiifImageSize(obj.GetFS(), file)
+	if err != nil {
+		apiError(c, http.StatusUnsupportedMediaType, err)
+		return
+	}
+	if params == "info.json" {
+		info := &IIIFImageInfo{
+			Context:        iiifImageContext,
+			ID:             imageID,
+			Type:           "ImageService3",
+			Protocol:       "http://iiif.io/api/image",
+			Profile:        iiifProfile,
+			Width:          width,
+			Height:         height,
+			MaxArea:        iiifMaxArea,
+			Tiles:          []*IIIFTile{{Width: iiifTileSize, ScaleFactors: iiifScaleFactors(width, height)}},
+			ExtraQualities: []string{"color", "gray"},
+			ExtraFeatures:  []string{"mirroring", "sizeUpscaling"},
+		}
+		sfs := info.Tiles[0].ScaleFactors
+		for i := len(sfs) - 1; i > 0; i-- {
+			info.Sizes = append(info.Sizes, &IIIFSize{
+				Width:  (width + sfs[i] - 1) / sfs[i],
+				Height: (height + sfs[i] - 1) / sfs[i],
+			})
+		}
+		data, err := json.Marshal(info)
+		if err != nil {
+			apiError(c, http.StatusInternalServerError, errors.Wrap(err, "cannot marshal info.json"))
+			return
+		}
+		c.Data(http.StatusOK, `application/ld+json;profile="`+iiifImageContext+`"`, data)
+		return
+	}
+	req, err := ParseIIIFRequest(params, width, height)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, err)
+		return
+	}
+	img, release, err := s.images.Get(digest, func() (extension.DecodedImage, error) {
+		fp, err := obj.GetFS().Open(file.InternalName[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot open '%s'", file.InternalName[0])
+		}
+		defer fp.Close()
+		return extension.DecodeImage(fp)
+	})
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, errors.Wrapf(err, "cannot decode '%s'", file.InternalName[0]))
+		return
+	}
+	defer release()
+	data, err := img.Transform(req)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, errors.Wrapf(err, "cannot process '%s'", file.InternalName[0]))
+		return
+	}
+	c.Header("Link", `<http://iiif.io/api/image/3/`+iiifProfile+`.json>;rel="profile"`)
+	c.Header("ETag", `"`+digest+"/"+params+`"`)
+	c.Data(http.StatusOK, iiifFormats[req.Format], data)
+}
+
+func parseIIIFInts(str string, num int) ([]int, error) {
+	parts := strings.Split(str, ",")
+	if len(parts) != num {
+		return nil, errors.Errorf("invalid value '%s'", str)
+	}
+	var result = make([]int, num)
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return nil, errors.Errorf("invalid value '%s'", str)
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+func parseIIIFFloats(str string, num int) ([]float64, error) {
+	parts := strings.Split(str, ",")
+	if len(parts) != num {
+		return nil, errors.Errorf("invalid value '%s'", str)
+	}
+	var result = make([]float64, num)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, errors.Errorf("invalid value '%s'", str)
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// ParseIIIFRequest converts the {region}/{size}/{rotation}/{quality}.{format} parameters
+// of an image api 3 request for an image of width x height pixels
+func ParseIIIFRequest(params string, width, height int) (*extension.ImageRequest, error) {
+	parts := strings.Split(params, "/")
+	if len(parts) != 4 {
+		return nil, errors.Errorf("invalid request '%s'", params)
+	}
+	region, size, rotation, qualityFormat := parts[0], parts[1], parts[2], parts[3]
+	req := &extension.ImageRequest{}
+
+	switch {
+	case region == "full":
+		req.W, req.H = width, height
+	case region == "square":
+		if width > height {
+			req.X, req.W, req.H = (width-height)/2, height, height
+		} else {
+			req.Y, req.W, req.H = (height-width)/2, width, width
+		}
+	case strings.HasPrefix(region, "pct:"):
+		v, err := parseIIIFFloats(region[4:], 4)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid region")
+		}
+		// clamp before the conversion, percentages above 100 must not overflow
+		req.X = int(math.Round(math.Min(v[0], 100) * float64(width) / 100))
+		req.Y = int(math.Round(math.Min(v[1], 100) * float64(height) / 100))
+		req.W = int(math.Round(math.Min(v[2], 100) * float64(width) / 100))
+		req.H = int(math.Round(math.Min(v[3], 100) * float64(height) / 100))
+	default:
+		v, err := parseIIIFInts(region, 4)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid region")
+		}
+		req.X, req.Y, req.W, req.H = v[0], v[1], v[2], v[3]
+	}
+	if req.X >= width || req.Y >= height || req.W <= 0 || req.H <= 0 {
+		return nil, errors.Errorf("region '%s' outside of image %d x %d", region, width, height)
+	}
+	req.W = min(req.W, width-req.X)
+	req.H = min(req.H, height-req.Y)
+
+	upscale := strings.HasPrefix(size, "^")
+	size = strings.TrimPrefix(size, "^")
+	// the target size is computed as float, so huge values cannot overflow
+	var w, h float64
+	switch {
+	case size == "max":
+		w, h = float64(req.W), float64(req.H)
+		if w*h > iiifMaxArea {
+			scale := math.Sqrt(iiifMaxArea / (w * h))
+			w, h = math.Floor(w*scale), math.Floor(h*scale)
+		}
+	case strings.HasPrefix(size, "pct:"):
+		v, err := parseIIIFFloats(size[4:], 1)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid size")
+		}
+		w = math.Round(float64(req.W) * v[0] / 100)
+		h = math.Round(float64(req.H) * v[0] / 100)
+	case strings.HasPrefix(size, "!"):
+		v, err := parseIIIFInts(size[1:], 2)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid size")
+		}
+		scale := math.Min(float64(v[0])/float64(req.W), float64(v[1])/float64(req.H))
+		w = math.Round(float64(req.W) * scale)
+		h = math.Round(float64(req.H) * scale)
+	case strings.HasSuffix(size, ","):
+		v, err := strconv.Atoi(strings.TrimSuffix(size, ","))
+		if err != nil {
+			return nil, errors.Errorf("invalid size '%s'", size)
+		}
+		w = float64(v)
+		h = math.Round(float64(req.H) * w / float64(req.W))
+	case strings.HasPrefix(size, ","):
+		v, err := strconv.Atoi(strings.TrimPrefix(size, ","))
+		if err != nil {
+			return nil, errors.Errorf("invalid size '%s'", size)
+		}
+		h = float64(v)
+		w = math.Round(float64(req.W) * h / float64(req.H))
+	default:
+		v, err := parseIIIFInts(size, 2)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid size")
+		}
+		w, h = float64(v[0]), float64(v[1])
+	}
+	if w <= 0 || h <= 0 {
+		return nil, errors.Errorf("size '%s' results in empty image", size)
+	}
+	if !upscale && (w > float64(req.W) || h > float64(req.H)) {
+		return nil, errors.Errorf("size '%s' larger than region without '^'", size)
+	}
+	if w*h > iiifMaxArea {
+		return nil, errors.Errorf("size '%s' exceeds maximum area of %d pixels", size, iiifMaxArea)
+	}
+	req.Width, req.Height = int(w), int(h)
+
+	if strings.HasPrefix(rotation, "!") {
+		req.Mirror = true
+		rotation = rotation[1:]
+	}
+	switch rotation {
+	case "0", "90", "180", "270":
+		req.Rotation, _ = strconv.Atoi(rotation)
+	default:
+		return nil, errors.Errorf("unsupported rotation '%s'", rotation)
+	}
+
+	quality, format, ok := strings.Cut(qualityFormat, ".")
+	if !ok {
+		return nil, errors.Errorf("missing format in '%s'", qualityFormat)
+	}
+	switch quality {
+	case "default", "color":
+	case "gray":
+		req.Gray = true
+	default:
+		return nil, errors.Errorf("unsupported quality '%s'", quality)
+	}
+	if _, ok := iiifFormats[format]; !ok {
+		return nil, errors.Errorf("unsupported format '%s'", format)
+	}
+	req.Format = format
+	return req, nil
+}
+
+// iiifManifest builds a presentation api 3 manifest with a canvas for every image in the
+// state of the version (head if not given)
+func (s *Server) iiifManifest(c *gin.Context) {
+	obj, metadata, ok := s.apiLoadObject(c)
+	if !ok {
+		return
+	}
+	c.Header("Access-Control-Allow-Origin", "*")
+	version := c.Param("version")
+	manifestID := fmt.Sprintf("%s/%s/manifest", s.iiifBase(c), url.PathEscape(metadata.ID))
+	if version == "" {
+		version = metadata.Head
+	} else {
+		manifestID += "/" + version
+	}
+	v, ok := metadata.Versions[version]
+	if !ok {
+		apiError(c, http.StatusNotFound, errors.Errorf("version %s of object %s not found", version, metadata.ID))
+		return
+	}
+	manifest := &IIIFManifest{
+		Context: iiifPresentationContext,
+		ID:      manifestID,
+		Type:    "Manifest",
+		Label:   iiifLabel(fmt.Sprintf("%s (%s)", metadata.ID, version)),
+		Metadata: []*IIIFMetadataEntry{
+			{Label: iiifLabel("Object"), Value: iiifLabel(metadata.ID)},
+			{Label: iiifLabel("Version"), Value: iiifLabel(version)},
+			{Label: iiifLabel("Created"), Value: iiifLabel(v.Created.Format("2006-01-02 15:04:05"))},
+			{Label: iiifLabel("User"), Value: iiifLabel(v.Name)},
+		},
+		Items: []*IIIFCanvas{},
+	}
+	if v.Message != "" {
+		manifest.Summary = iiifLabel(v.Message)
+	}
+
+	type image struct {
+		path, digest  string
+		width, height int
+	}
+	var images = []*image{}
+	if err := obj.GetInventory().IterateStateFiles(version, func(internals, externals []string, digest string) error {
+		file, ok := metadata.Files[digest]
+		if !ok || len(file.InternalName) == 0 {
+			return nil
+		}
+		for _, external := range externals {
+			var mimetype string
+			if idx := fileIndexer(file); idx != nil {
+				mimetype = idx.Mimetype
+			}
+			if mimetype == "" {
+				mimetype, _, _ = strings.Cut(mime.TypeByExtension(path.Ext(external)), ";")
+			}
+			if !strings.HasPrefix(mimetype, "image/") {
+				continue
+			}
+			width, height, err := iiifImageSize(obj.GetFS(), file)
+			if err != nil {
+				s.log.Info().Err(err).Msgf("no iiif canvas for '%s'", external)
+				continue
+			}
+			images = append(images, &image{path: external, digest: digest, width: width, height: height})
+		}
+		return nil
+	}); err != nil {
+		apiError(c, http.StatusInternalServerError, errors.Wrapf(err, "cannot iterate state of version %s", version))
+		return
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].path < images[j].path
+	})
+	for i, img := range images {
+		canvasID := fmt.Sprintf("%s/canvas/%d", manifestID, i+1)
+		imageID := s.iiifImageID(c, metadata.ID, img.digest)
+		manifest.Items = append(manifest.Items, &IIIFCanvas{
+			ID:     canvasID,
+			Type:   "Canvas",
+			Label:  iiifLabel(img.path),
+			Width:  img.width,
+			Height: img.height,
+			Items: []*IIIFAnnotationPage{{
+				ID:   canvasID + "/page",
+				Type: "AnnotationPage",
+				Items: []*IIIFAnnotation{{
+					ID:         canvasID + "/page/image",
+					Type:       "Annotation",
+					Motivation: "painting",
+					Body: &IIIFImageResource{
+						ID:     imageID + "/full/max/0/default.jpg",
+						Type:   "Image",
+						Format: "image/jpeg",
+						Width:  img.width,
+						Height: img.height,
+						Service: []*IIIFService{{
+							ID:      imageID,
+							Type:    "ImageService3",
+							Profile: iiifProfile,
+						}},
+					},
+					Target: canvasID,
+				}},
+			}},
+		})
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, errors.Wrap(err, "cannot marshal manifest"))
+		return
+	}
+	c.Data(http.StatusOK, `application/ld+json;profile="`+iiifPresentationContext+`"`, data)
+}
@@ -0,0 +1,141 @@
+package display
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/ocfl-archive/gocfl/v2/pkg/extension"
+)
+
+func TestParseIIIFRequest(t *testing.T) {
+	maxInt := strconv.Itoa(math.MaxInt)
+	var tests = []struct {
+		params string
+		width  int
+		height int
+		// nil if the request is invalid
+		expected *extension.ImageRequest
+	}{
+		{"full/max/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 1000, Height: 500, Format: "jpg"}},
+		{"square/max/0/default.png", 1000, 500, &extension.ImageRequest{X: 250, W: 500, H: 500, Width: 500, Height: 500, Format: "png"}},
+		{"square/max/0/default.png", 500, 1000, &extension.ImageRequest{Y: 250, W: 500, H: 500, Width: 500, Height: 500, Format: "png"}},
+		{"10,20,100,50/max/0/default.jpg", 1000, 500, &extension.ImageRequest{X: 10, Y: 20, W: 100, H: 50, Width: 100, Height: 50, Format: "jpg"}},
+		// region is clipped at the image border
+		{"900,400,200,200/max/0/default.jpg", 1000, 500, &extension.ImageRequest{X: 900, Y: 400, W: 100, H: 100, Width: 100, Height: 100, Format: "jpg"}},
+		{"pct:10,10,50,50/max/0/default.jpg", 1000, 500, &extension.ImageRequest{X: 100, Y: 50, W: 500, H: 250, Width: 500, Height: 250, Format: "jpg"}},
+		{"full/500,/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 500, Height: 250, Format: "jpg"}},
+		{"full/,100/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 200, Height: 100, Format: "jpg"}},
+		{"full/pct:50/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 500, Height: 250, Format: "jpg"}},
+		{"full/!200,200/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 200, Height: 100, Format: "jpg"}},
+		{"full/300,100/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 300, Height: 100, Format: "jpg"}},
+		{"full/^2000,/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 2000, Height: 1000, Format: "jpg"}},
+		{"full/max/!90/gray.png", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 1000, Height: 500, Mirror: true, Rotation: 90, Gray: true, Format: "png"}},
+		// max is limited to the maximum area
+		{"full/max/0/default.jpg", 20000, 20000, &extension.ImageRequest{W: 20000, H: 20000, Width: 10000, Height: 10000, Format: "jpg"}},
+		{"full/2000,/0/default.jpg", 1000, 500, nil},
+		{"full/^20000,20000/0/default.jpg", 1000, 500, nil},
+		{"full/0,/0/default.jpg", 1000, 500, nil},
+		{"1000,0,10,10/max/0/default.jpg", 1000, 500, nil},
+		{"0,0,0,10/max/0/default.jpg", 1000, 500, nil},
+		{"-1,0,10,10/max/0/default.jpg", 1000, 500, nil},
+		{"full/max/45/default.jpg", 1000, 500, nil},
+		{"full/max/0/bitonal.jpg", 1000, 500, nil},
+		{"full/max/0/default.gif", 1000, 500, nil},
+		{"full/max/0/default", 1000, 500, nil},
+		{"full/max/0", 1000, 500, nil},
+		// width x height overflows int
+		{"full/^" + maxInt + "," + maxInt + "/0/default.jpg", 1000, 500, nil},
+		{"full/^4294967296,4294967296/0/default.jpg", 1000, 500, nil},
+		{"full/^" + maxInt + ",/0/default.jpg", 1000, 500, nil},
+		{"full/^," + maxInt + "/0/default.jpg", 1000, 500, nil},
+		{"full/^!" + maxInt + "," + maxInt + "/0/default.jpg", 1000, 500, nil},
+		{"full/^pct:1e300/0/default.jpg", 1000, 500, nil},
+		{"pct:0,0,1e300,1e300/max/0/default.jpg", 1000, 500, &extension.ImageRequest{W: 1000, H: 500, Width: 1000, Height: 500, Format: "jpg"}},
+		{"pct:1e300,0,10,10/max/0/default.jpg", 1000, 500, nil},
+	}
+	for _, test := range tests {
+		req, err := ParseIIIFRequest(test.params, test.width, test.height)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("%s (%d x %d): expected error, got %+v", test.params, test.width, test.height, req)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s (%d x %d): %v", test.params, test.width, test.height, err)
+			continue
+		}
+		if *req != *test.expected {
+			t.Errorf("%s (%d x %d): expected %+v, got %+v", test.params, test.width, test.height, test.expected, req)
+		}
+	}
+}
+
+type testDecodedImage struct {
+	closed bool
+}
+
+func (ti *testDecodedImage) Width() int  { return 1 }
+func (ti *testDecodedImage) Height() int { return 1 }
+func (ti *testDecodedImage) Close()      { ti.closed = true }
+func (ti *testDecodedImage) Transform(req *extension.ImageRequest) ([]byte, error) {
+	if ti.closed {
+		return nil, errors.New("image closed")
+	}
+	return []byte{}, nil
+}
+
+func TestImageCache(t *testing.T) {
+	ic := NewImageCache(2)
+	var decoded = map[string]*testDecodedImage{}
+	get := func(digest string) (extension.DecodedImage, func()) {
+		img, release, err := ic.Get(digest, func() (extension.DecodedImage, error) {
+			ti := &testDecodedImage{}
+			decoded[digest] = ti
+			return ti, nil
+		})
+		if err != nil {
+			t.Fatalf("cannot get image %s: %v", digest, err)
+		}
+		return img, release
+	}
+
+	a, releaseA := get("a")
+	if again, release := get("a"); again != a {
+		t.Errorf("image a decoded twice")
+	} else {
+		release()
+	}
+	_, releaseB := get("b")
+	releaseB()
+	// c evicts a, which is still in use
+	_, releaseC := get("c")
+	releaseC()
+	if decoded["a"].closed {
+		t.Errorf("image a closed while in use")
+	}
+	if _, err := a.Transform(&extension.ImageRequest{}); err != nil {
+		t.Errorf("cannot transform evicted image in use: %v", err)
+	}
+	releaseA()
+	if !decoded["a"].closed {
+		t.Errorf("evicted image a not closed after release")
+	}
+	// d evicts b, which is not in use
+	_, releaseD := get("d")
+	releaseD()
+	if !decoded["b"].closed {
+		t.Errorf("evicted image b not closed")
+	}
+	if decoded["c"].closed || decoded["d"].closed {
+		t.Errorf("cached images closed")
+	}
+
+	if _, _, err := ic.Get("e", func() (extension.DecodedImage, error) {
+		return nil, errors.New("cannot decode")
+	}); err == nil {
+		t.Errorf("decode error not returned")
+	}
+}
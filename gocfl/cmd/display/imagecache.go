@@ -0,0 +1,89 @@
+package display
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ocfl-archive/gocfl/v2/pkg/extension"
+)
+
+// imageCacheSize is small, because decoded images need width x height x 4 bytes
+const imageCacheSize = 4
+
+type cachedImage struct {
+	digest  string
+	img     extension.DecodedImage
+	refs    int
+	evicted bool
+}
+
+// ImageCache keeps the recently used decoded images of the iiif image api, so tile requests
+// do not decode the whole image again. Images are identified by their content digest and never change
+type ImageCache struct {
+	size    int
+	lock    sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+func NewImageCache(size int) *ImageCache {
+	if size <= 0 {
+		size = imageCacheSize
+	}
+	return &ImageCache{
+		size:    size,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Get returns the decoded image with the digest. decode is called if the image is not cached.
+// release must be called after the image is not used anymore
+func (ic *ImageCache) Get(digest string, decode func() (extension.DecodedImage, error)) (img extension.DecodedImage, release func(), err error) {
+	ic.lock.Lock()
+	if elem, ok := ic.entries[digest]; ok {
+		ic.lru.MoveToFront(elem)
+		ci := elem.Value.(*cachedImage)
+		ci.refs++
+		ic.lock.Unlock()
+		return ci.img, func() { ic.release(ci) }, nil
+	}
+	ic.lock.Unlock()
+
+	// decode without lock. concurrent requests for the same image may decode it twice
+	decoded, err := decode()
+	if err != nil {
+		return nil, nil, err
+	}
+	ci := &cachedImage{digest: digest, img: decoded, refs: 1}
+	ic.lock.Lock()
+	if elem, ok := ic.entries[digest]; ok {
+		ic.evict(elem)
+	}
+	ic.entries[digest] = ic.lru.PushFront(ci)
+	for ic.lru.Len() > ic.size {
+		ic.evict(ic.lru.Back())
+	}
+	ic.lock.Unlock()
+	return decoded, func() { ic.release(ci) }, nil
+}
+
+// evict removes the image from the cache. it is closed after the last release
+func (ic *ImageCache) evict(elem *list.Element) {
+	ci := elem.Value.(*cachedImage)
+	ic.lru.Remove(elem)
+	delete(ic.entries, ci.digest)
+	ci.evicted = true
+	if ci.refs == 0 {
+		ci.img.Close()
+	}
+}
+
+func (ic *ImageCache) release(ci *cachedImage) {
+	ic.lock.Lock()
+	defer ic.lock.Unlock()
+	ci.refs--
+	if ci.evicted && ci.refs == 0 {
+		ci.img.Close()
+	}
+}
@@ -0,0 +1,354 @@
+package display
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/ocfl-archive/gocfl/v2/config"
+)
+
+// JSONWebKey is the subset of RFC 7517 needed for signature verification
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+func b64Int(str string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// PublicKey returns the rsa, ecdsa or ed25519 public key
+func (k *JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64Int(k.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid modulus of key '%s'", k.Kid)
+		}
+		e, err := b64Int(k.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid exponent of key '%s'", k.Kid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve '%s' of key '%s'", k.Crv, k.Kid)
+		}
+		x, err := b64Int(k.X)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid x of key '%s'", k.Kid)
+		}
+		y, err := b64Int(k.Y)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid y of key '%s'", k.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.Errorf("unsupported curve '%s' of key '%s'", k.Crv, k.Kid)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("invalid ed25519 key '%s'", k.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, errors.Errorf("unsupported key type '%s' of key '%s'", k.Kty, k.Kid)
+	}
+}
+
+type jwtKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// JWTAuth verifies json web tokens (i.e. oidc access tokens) against a locally configured key set
+type JWTAuth struct {
+	keys        []*jwtKey
+	issuer      string
+	audience    string
+	algorithms  []string
+	userClaim   string
+	groupsClaim string
+	leeway      time.Duration
+	now         func() time.Time
+}
+
+func NewJWTAuth(conf *config.DisplayJWTConfig) (*JWTAuth, error) {
+	data, err := os.ReadFile(conf.JWKS)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read jwks '%s'", conf.JWKS)
+	}
+	var jwks = &JSONWebKeySet{}
+	if err := json.Unmarshal(data, jwks); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal jwks '%s'", conf.JWKS)
+	}
+	a := &JWTAuth{
+		keys:        []*jwtKey{},
+		issuer:      conf.Issuer,
+		audience:    conf.Audience,
+		algorithms:  conf.Algorithms,
+		userClaim:   conf.UserClaim,
+		groupsClaim: conf.GroupsClaim,
+		leeway:      time.Duration(conf.LeewaySeconds) * time.Second,
+		now:         time.Now,
+	}
+	if a.userClaim == "" {
+		a.userClaim = "sub"
+	}
+	if len(a.algorithms) == 0 {
+		a.algorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}
+	}
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid key in jwks '%s'", conf.JWKS)
+		}
+		a.keys = append(a.keys, &jwtKey{kid: k.Kid, alg: k.Alg, key: pub})
+	}
+	if len(a.keys) == 0 {
+		return nil, errors.Errorf("no signature keys in jwks '%s'", conf.JWKS)
+	}
+	return a, nil
+}
+
+func (a *JWTAuth) Name() string { return "jwt" }
+
+func (a *JWTAuth) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" || strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+	claims, err := a.Verify(token)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	p := &Principal{Method: a.Name()}
+	p.Name, _ = claims[a.userClaim].(string)
+	if p.Name == "" {
+		return nil, errors.Errorf("no user claim '%s' in token", a.userClaim)
+	}
+	if a.groupsClaim != "" {
+		switch groups := claims[a.groupsClaim].(type) {
+		case []any:
+			for _, g := range groups {
+				if str, ok := g.(string); ok {
+					p.Groups = append(p.Groups, str)
+				}
+			}
+		case string:
+			p.Groups = strings.Fields(groups)
+		}
+	}
+	return p, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func jwtHash(alg string) (crypto.Hash, func() hash.Hash) {
+	switch alg[2:] {
+	case "384":
+		return crypto.SHA384, sha512.New384
+	case "512":
+		return crypto.SHA512, sha512.New
+	default:
+		return crypto.SHA256, sha256.New
+	}
+}
+
+// jwtCurve returns the curve of an ecdsa algorithm
+func jwtCurve(alg string) elliptic.Curve {
+	switch alg {
+	case "ES256":
+		return elliptic.P256()
+	case "ES384":
+		return elliptic.P384()
+	case "ES512":
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, signed, sig) {
+			return errors.WithStack(ErrInvalidCredentials)
+		}
+		return nil
+	}
+	if len(alg) != 5 {
+		return errors.Errorf("unsupported algorithm '%s'", alg)
+	}
+	h, newHash := jwtHash(alg)
+	hasher := newHash()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+	switch alg[:2] {
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.WithStack(ErrInvalidCredentials)
+		}
+		return errors.WithStack(rsa.VerifyPKCS1v15(pub, h, digest, sig))
+	case "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.WithStack(ErrInvalidCredentials)
+		}
+		return errors.WithStack(rsa.VerifyPSS(pub, h, digest, sig, nil))
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != jwtCurve(alg) {
+			return errors.WithStack(ErrInvalidCredentials)
+		}
+		// r and s are padded to the size of the curve (RFC 7518, 3.4)
+		if size := (pub.Curve.Params().BitSize + 7) / 8; len(sig) != 2*size {
+			return errors.Wrapf(ErrInvalidCredentials, "invalid signature length %d for %s", len(sig), alg)
+		}
+		rInt := new(big.Int).SetBytes(sig[:len(sig)/2])
+		sInt := new(big.Int).SetBytes(sig[len(sig)/2:])
+		if !ecdsa.Verify(pub, digest, rInt, sInt) {
+			return errors.WithStack(ErrInvalidCredentials)
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported algorithm '%s'", alg)
+	}
+}
+
+func numericDate(v any) (time.Time, bool) {
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// Verify checks signature, expiry, issuer and audience of a compact serialized jwt and returns its claims.
+// tokens without exp claim are rejected
+func (a *JWTAuth) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid token format")
+	}
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token header")
+	}
+	var header = &jwtHeader{}
+	if err := json.Unmarshal(headerData, header); err != nil {
+		return nil, errors.Wrap(err, "invalid token header")
+	}
+	if !slices.Contains(a.algorithms, header.Alg) {
+		return nil, errors.Errorf("token algorithm '%s' not allowed", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	var verified bool
+	for _, k := range a.keys {
+		if header.Kid != "" && k.kid != "" && k.kid != header.Kid {
+			continue
+		}
+		if k.alg != "" && k.alg != header.Alg {
+			continue
+		}
+		if err := verifySignature(header.Alg, k.key, signed, sig); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.Wrap(ErrInvalidCredentials, "token signature not valid")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token payload")
+	}
+	var claims = map[string]any{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.Wrap(err, "invalid token payload")
+	}
+	now := a.now()
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return nil, errors.Wrap(ErrInvalidCredentials, "token without expiration time")
+	}
+	if now.After(exp.Add(a.leeway)) {
+		return nil, errors.Wrap(ErrInvalidCredentials, "token expired")
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(a.leeway).Before(nbf) {
+		return nil, errors.Wrap(ErrInvalidCredentials, "token not yet valid")
+	}
+	if a.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.issuer {
+			return nil, errors.Wrapf(ErrInvalidCredentials, "invalid issuer '%s'", iss)
+		}
+	}
+	if a.audience != "" {
+		var found bool
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == a.audience
+		case []any:
+			for _, v := range aud {
+				if str, ok := v.(string); ok && str == a.audience {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, errors.Wrap(ErrInvalidCredentials, "invalid audience")
+		}
+	}
+	return claims, nil
+}
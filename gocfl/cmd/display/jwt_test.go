@@ -0,0 +1,193 @@
+package display
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ocfl-archive/gocfl/v2/config"
+)
+
+type testJWTKeys struct {
+	rsa     *rsa.PrivateKey
+	ec256   *ecdsa.PrivateKey
+	ec384   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func padded(i *big.Int, size int) []byte {
+	return i.FillBytes(make([]byte, size))
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) *JSONWebKey {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return &JSONWebKey{Kty: "EC", Kid: kid, Crv: key.Curve.Params().Name, X: b64(padded(key.X, size)), Y: b64(padded(key.Y, size))}
+}
+
+func newTestJWTAuth(t *testing.T, conf config.DisplayJWTConfig) (*JWTAuth, *testJWTKeys) {
+	var keys = &testJWTKeys{}
+	var err error
+	if keys.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatalf("cannot generate rsa key: %v", err)
+	}
+	if keys.ec256, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatalf("cannot generate ecdsa key: %v", err)
+	}
+	if keys.ec384, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader); err != nil {
+		t.Fatalf("cannot generate ecdsa key: %v", err)
+	}
+	if _, keys.ed25519, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		t.Fatalf("cannot generate ed25519 key: %v", err)
+	}
+	jwks := &JSONWebKeySet{Keys: []*JSONWebKey{
+		{Kty: "RSA", Kid: "rsa", N: b64(keys.rsa.N.Bytes()), E: b64(big.NewInt(int64(keys.rsa.E)).Bytes())},
+		ecJWK("ec256", keys.ec256),
+		ecJWK("ec384", keys.ec384),
+		{Kty: "OKP", Kid: "ed25519", Crv: "Ed25519", X: b64(keys.ed25519.Public().(ed25519.PublicKey))},
+	}}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("cannot marshal jwks: %v", err)
+	}
+	conf.JWKS = filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(conf.JWKS, data, 0644); err != nil {
+		t.Fatalf("cannot write jwks: %v", err)
+	}
+	a, err := NewJWTAuth(&conf)
+	if err != nil {
+		t.Fatalf("cannot create jwt auth: %v", err)
+	}
+	a.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return a, keys
+}
+
+// signJWT creates a compact jwt. ecdsa signatures are shortened by trim bytes
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any, trim int) string {
+	header, err := json.Marshal(&jwtHeader{Alg: alg, Kid: kid})
+	if err != nil {
+		t.Fatalf("cannot marshal header: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("cannot marshal claims: %v", err)
+	}
+	signed := b64(header) + "." + b64(payload)
+	var sig []byte
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	case *rsa.PrivateKey:
+		h, newHash := jwtHash(alg)
+		hasher := newHash()
+		hasher.Write([]byte(signed))
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, h, hasher.Sum(nil)); err != nil {
+			t.Fatalf("cannot sign: %v", err)
+		}
+	case *ecdsa.PrivateKey:
+		_, newHash := jwtHash(alg)
+		hasher := newHash()
+		hasher.Write([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, k, hasher.Sum(nil))
+		if err != nil {
+			t.Fatalf("cannot sign: %v", err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = append(padded(r, size), padded(s, size)...)
+		sig = sig[trim:]
+	}
+	return signed + "." + b64(sig)
+}
+
+func TestJWTVerify(t *testing.T) {
+	a, keys := newTestJWTAuth(t, config.DisplayJWTConfig{Issuer: "https://idp.example.org", Audience: "gocfl", LeewaySeconds: 60})
+	now := a.now().Unix()
+	valid := func() map[string]any {
+		return map[string]any{"sub": "alice", "iss": "https://idp.example.org", "aud": []string{"other", "gocfl"}, "exp": now + 300}
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate ed25519 key: %v", err)
+	}
+	with := func(key string, val any) map[string]any {
+		claims := valid()
+		if val == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = val
+		}
+		return claims
+	}
+	var tests = []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"RS256", signJWT(t, "RS256", "rsa", keys.rsa, valid(), 0), true},
+		{"ES256", signJWT(t, "ES256", "ec256", keys.ec256, valid(), 0), true},
+		{"ES384", signJWT(t, "ES384", "ec384", keys.ec384, valid(), 0), true},
+		{"EdDSA", signJWT(t, "EdDSA", "ed25519", keys.ed25519, valid(), 0), true},
+		{"EdDSA without kid", signJWT(t, "EdDSA", "", keys.ed25519, valid(), 0), true},
+		{"expired within leeway", signJWT(t, "EdDSA", "ed25519", keys.ed25519, with("exp", now-30), 0), true},
+		{"expired", signJWT(t, "EdDSA", "ed25519", keys.ed25519, with("exp", now-120), 0), false},
+		{"no exp", signJWT(t, "EdDSA", "ed25519", keys.ed25519, with("exp", nil), 0), false},
+		{"not yet valid", signJWT(t, "EdDSA", "ed25519", keys.ed25519, with("nbf", now+120), 0), false},
+		{"wrong issuer", signJWT(t, "EdDSA", "ed25519", keys.ed25519, with("iss", "https://evil.example.org"), 0), false},
+		{"wrong audience", signJWT(t, "EdDSA", "ed25519", keys.ed25519, with("aud", "other"), 0), false},
+		{"ES256 short signature", signJWT(t, "ES256", "ec256", keys.ec256, valid(), 2), false},
+		{"ES384 with P-256 key", signJWT(t, "ES384", "ec256", keys.ec256, valid(), 0), false},
+		{"ES256 with P-384 key", signJWT(t, "ES256", "ec384", keys.ec384, valid(), 0), false},
+		{"wrong key", signJWT(t, "EdDSA", "ed25519", otherKey, valid(), 0), false},
+		{"alg none", b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{"sub":"alice"}`)) + ".", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := a.Verify(test.token)
+			if test.valid {
+				if err != nil {
+					t.Fatalf("expected valid token: %v", err)
+				}
+				if claims["sub"] != "alice" {
+					t.Errorf("wrong subject %v", claims["sub"])
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("expected invalid token")
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticateGroups(t *testing.T) {
+	a, keys := newTestJWTAuth(t, config.DisplayJWTConfig{UserClaim: "preferred_username", GroupsClaim: "groups", Algorithms: []string{"EdDSA"}})
+	claims := map[string]any{"preferred_username": "alice", "groups": []string{"staff", "admin"}, "exp": a.now().Unix() + 60}
+	r := basicRequest("", "")
+	r.Header.Set("Authorization", "Bearer "+signJWT(t, "EdDSA", "ed25519", keys.ed25519, claims, 0))
+	p, err := a.Authenticate(r)
+	if err != nil {
+		t.Fatalf("cannot authenticate: %v", err)
+	}
+	if p == nil || p.Name != "alice" || len(p.Groups) != 2 || p.Groups[1] != "admin" {
+		t.Errorf("unexpected principal %+v", p)
+	}
+
+	// algorithm not in the configured list
+	r.Header.Set("Authorization", "Bearer "+signJWT(t, "RS256", "rsa", keys.rsa, claims, 0))
+	if _, err := a.Authenticate(r); err == nil {
+		t.Errorf("RS256 must not be accepted")
+	}
+}
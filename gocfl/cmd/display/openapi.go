@@ -0,0 +1,130 @@
+package display
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ginParamRegexp = regexp.MustCompile(`:([^/]+)`)
+
+// openAPIPath converts gin path parameters to openapi syntax (:id -> {id})
+func openAPIPath(p string) string {
+	return ginParamRegexp.ReplaceAllString(p, "{$1}")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonSchema builds the schema of a response type with the names of the json tags
+func jsonSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if field.Anonymous && name == "" {
+				// embedded struct: fields are inlined
+				if embedded, ok := jsonSchema(field.Type)["properties"].(map[string]any); ok {
+					for k, v := range embedded {
+						properties[k] = v
+					}
+				}
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchema(field.Type)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	default:
+		// interface values like extension metadata
+		return map[string]any{}
+	}
+}
+
+// openAPI generates the openapi 3 description of the api routes
+func (s *Server) openAPI(routes []*apiRoute) map[string]any {
+	paths := map[string]any{}
+	errorResponse := map[string]any{
+		"description": "error",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": jsonSchema(reflect.TypeOf(APIError{}))},
+		},
+	}
+	for _, r := range routes {
+		var params = []any{}
+		for _, p := range r.Params {
+			params = append(params, map[string]any{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Description,
+				"required":    p.Required,
+				"schema":      map[string]any{"type": p.Type},
+			})
+		}
+		var content map[string]any
+		if r.Response != nil {
+			content = map[string]any{
+				"application/json": map[string]any{"schema": jsonSchema(reflect.TypeOf(r.Response))},
+			}
+		} else {
+			content = map[string]any{
+				r.ContentType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}},
+			}
+		}
+		p := openAPIPath(r.Path)
+		if paths[p] == nil {
+			paths[p] = map[string]any{}
+		}
+		paths[p].(map[string]any)[strings.ToLower(r.Method)] = map[string]any{
+			"operationId": r.OperationID,
+			"summary":     r.Summary,
+			"parameters":  params,
+			"responses": map[string]any{
+				"200":     map[string]any{"description": r.Summary, "content": content},
+				"default": errorResponse,
+			},
+		}
+	}
+	var server = apiPrefix
+	if s.urlExt != nil {
+		server = strings.TrimRight(s.urlExt.String(), "/") + apiPrefix
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "gocfl display api",
+			"version": "1",
+		},
+		"servers": []any{map[string]any{"url": server}},
+		"paths":   paths,
+	}
+}
@@ -0,0 +1,504 @@
+package display
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"emperror.dev/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+)
+
+// sources of a search term. the values are used as bit mask and weight
+const (
+	SearchSourceFulltext uint8 = 1 << iota
+	SearchSourceMetafile
+	SearchSourcePath
+	SearchSourceID
+)
+
+var searchSourceNames = map[uint8]string{
+	SearchSourceID:       "id",
+	SearchSourcePath:     "path",
+	SearchSourceMetafile: "metafile",
+	SearchSourceFulltext: "fulltext",
+}
+
+const searchMaxResults = 500
+
+// SearchDocument is an object (Digest empty) or a content file of an object
+type SearchDocument struct {
+	ObjectID string
+	Digest   string
+	Paths    []string
+}
+
+// SearchIndex is an in-memory inverted index of object ids, logical paths of the head versions,
+// metafile fields and indexer full text
+type SearchIndex struct {
+	Docs    []*SearchDocument
+	Terms   map[string]map[int]uint8
+	Created time.Time
+	// Objects maps the indexed object folders to the sidecar of their root inventory
+	Objects map[string]string
+
+	lock    sync.RWMutex
+	ready   bool
+	objects int
+	err     error
+	logger  zLogger.ZLogger
+}
+
+// SearchResult is a document matching a query
+type SearchResult struct {
+	*SearchDocument
+	Score   int
+	Sources []string
+}
+
+func NewSearchIndex(logger zLogger.ZLogger) *SearchIndex {
+	return &SearchIndex{
+		Docs:    []*SearchDocument{},
+		Terms:   map[string]map[int]uint8{},
+		Objects: map[string]string{},
+		logger:  logger,
+	}
+}
+
+// searchTokens splits a text into lower case words
+func searchTokens(str string) []string {
+	return strings.FieldsFunc(strings.ToLower(str), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (idx *SearchIndex) add(docID int, source uint8, text string) {
+	for _, token := range searchTokens(text) {
+		idx.addTerm(docID, source, token)
+	}
+}
+
+func (idx *SearchIndex) addTerm(docID int, source uint8, term string) {
+	postings, ok := idx.Terms[term]
+	if !ok {
+		postings = map[int]uint8{}
+		idx.Terms[term] = postings
+	}
+	postings[docID] |= source
+}
+
+// addMetafile indexes all values of the metafile. values are also indexed as field:value
+func (idx *SearchIndex) addMetafile(docID int, field string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, val := range v {
+			name := key
+			if field != "" {
+				name = field + "." + key
+			}
+			idx.addMetafile(docID, name, val)
+		}
+	case []any:
+		for _, val := range v {
+			idx.addMetafile(docID, field, val)
+		}
+	case nil:
+	default:
+		str := fmt.Sprintf("%v", v)
+		for _, token := range searchTokens(str) {
+			idx.addTerm(docID, SearchSourceMetafile, token)
+			if field != "" {
+				idx.addTerm(docID, SearchSourceMetafile, strings.ToLower(field)+":"+token)
+			}
+		}
+	}
+}
+
+// addText indexes all strings of an indexer result (i.e. tika metadata or full text)
+func (idx *SearchIndex) addText(docID int, value any) {
+	switch v := value.(type) {
+	case string:
+		idx.add(docID, SearchSourceFulltext, v)
+	case map[string]any:
+		for _, val := range v {
+			idx.addText(docID, val)
+		}
+	case []any:
+		for _, val := range v {
+			idx.addText(docID, val)
+		}
+	case map[string][]string:
+		for _, val := range v {
+			for _, str := range val {
+				idx.add(docID, SearchSourceFulltext, str)
+			}
+		}
+	case []map[string][]string:
+		for _, m := range v {
+			idx.addText(docID, m)
+		}
+	case []string:
+		for _, str := range v {
+			idx.add(docID, SearchSourceFulltext, str)
+		}
+	}
+}
+
+// AddObject indexes the object and, if contentFiles is set, the content files of its head version
+func (idx *SearchIndex) AddObject(obj object.Object, metadata *object.ObjectMetadata, contentFiles bool) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	docID := len(idx.Docs)
+	idx.Docs = append(idx.Docs, &SearchDocument{ObjectID: metadata.ID})
+	idx.add(docID, SearchSourceID, metadata.ID)
+	idx.addTerm(docID, SearchSourceID, strings.ToLower(metadata.ID))
+	if extMap, ok := metadata.Extension.(map[string]any); ok {
+		if metafile, ok := extMap[extension.MetaFileName]; ok {
+			idx.addMetafile(docID, "", metafile)
+		}
+	}
+	if !contentFiles {
+		idx.objects++
+		return nil
+	}
+
+	var paths = map[string][]string{}
+	if err := obj.GetInventory().IterateStateFiles(metadata.Head, func(internals, externals []string, digest string) error {
+		paths[digest] = append(paths[digest], externals...)
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "cannot iterate state of object %s", metadata.ID)
+	}
+	for digest, externals := range paths {
+		sort.Strings(externals)
+		docID := len(idx.Docs)
+		idx.Docs = append(idx.Docs, &SearchDocument{ObjectID: metadata.ID, Digest: digest, Paths: externals})
+		for _, p := range externals {
+			idx.add(docID, SearchSourcePath, p)
+		}
+		file, ok := metadata.Files[digest]
+		if !ok {
+			continue
+		}
+		if ext := fileIndexer(file); ext != nil {
+			for _, action := range []string{"fulltext", "tika"} {
+				if data, ok := ext.Metadata[action]; ok {
+					idx.addText(docID, data)
+				}
+			}
+		}
+	}
+	idx.objects++
+	return nil
+}
+
+// Search returns the documents which contain all words of the query. words ending with '*'
+// are prefix searches, field:value searches metafile fields
+func (idx *SearchIndex) Search(query string) []*SearchResult {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	var hits map[int]uint8
+	var scores = map[int]int{}
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		var field string
+		if f, v, ok := strings.Cut(word, ":"); ok && f != "" {
+			field, word = f, v
+		}
+		prefix := strings.HasSuffix(word, "*")
+		var wordHits map[int]uint8
+		for _, token := range searchTokens(word) {
+			term := token
+			if field != "" {
+				term = field + ":" + token
+			}
+			var tokenHits = map[int]uint8{}
+			if prefix {
+				for t, postings := range idx.Terms {
+					if strings.HasPrefix(t, term) {
+						for docID, src := range postings {
+							tokenHits[docID] |= src
+						}
+					}
+				}
+			} else {
+				for docID, src := range idx.Terms[term] {
+					tokenHits[docID] |= src
+				}
+			}
+			wordHits = intersectHits(wordHits, tokenHits, wordHits == nil)
+		}
+		if len(searchTokens(word)) == 0 {
+			continue
+		}
+		hits = intersectHits(hits, wordHits, hits == nil)
+		for docID, src := range wordHits {
+			scores[docID] += int(src)
+		}
+	}
+	var result = []*SearchResult{}
+	for docID, src := range hits {
+		r := &SearchResult{SearchDocument: idx.Docs[docID], Score: scores[docID]}
+		for s := SearchSourceID; s > 0; s >>= 1 {
+			if src&s != 0 {
+				r.Sources = append(r.Sources, searchSourceNames[s])
+			}
+		}
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		if result[i].ObjectID != result[j].ObjectID {
+			return result[i].ObjectID < result[j].ObjectID
+		}
+		return result[i].Digest < result[j].Digest
+	})
+	return result
+}
+
+func intersectHits(a, b map[int]uint8, first bool) map[int]uint8 {
+	if first {
+		return b
+	}
+	var result = map[int]uint8{}
+	for docID, src := range a {
+		if src2, ok := b[docID]; ok {
+			result[docID] = src | src2
+		}
+	}
+	return result
+}
+
+// Status returns whether the index is complete, the number of indexed objects and the build error
+func (idx *SearchIndex) Status() (bool, int, error) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return idx.ready, idx.objects, idx.err
+}
+
+// Load reads an index written with Save
+func (idx *SearchIndex) Load(filename string) error {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open search index '%s'", filename)
+	}
+	defer fp.Close()
+	zr, err := gzip.NewReader(fp)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open gzip reader for '%s'", filename)
+	}
+	defer zr.Close()
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if err := gob.NewDecoder(zr).Decode(idx); err != nil {
+		return errors.Wrapf(err, "cannot decode search index '%s'", filename)
+	}
+	var objects = map[string]bool{}
+	for _, doc := range idx.Docs {
+		objects[doc.ObjectID] = true
+	}
+	idx.objects = len(objects)
+	return nil
+}
+
+// Save writes the index gzip compressed to filename
+func (idx *SearchIndex) Save(filename string) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrapf(err, "cannot create search index '%s'", filename)
+	}
+	defer fp.Close()
+	zw := gzip.NewWriter(fp)
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	if err := gob.NewEncoder(zw).Encode(idx); err != nil {
+		return errors.Wrapf(err, "cannot encode search index '%s'", filename)
+	}
+	return errors.Wrapf(zw.Close(), "cannot close gzip writer for '%s'", filename)
+}
+
+// readInventorySidecar returns the content of the sidecar of the root inventory or an empty string if there is none
+func readInventorySidecar(fsys fs.FS, folder string) (string, error) {
+	names, err := fs.Glob(fsys, path.Join(folder, "inventory.json.*"))
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot search sidecar in '%s'", folder)
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	sort.Strings(names)
+	data, err := fs.ReadFile(fsys, names[0])
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot read '%s'", names[0])
+	}
+	return string(data), nil
+}
+
+// Current checks whether the index contains exactly the object folders with unchanged root inventories
+func (idx *SearchIndex) Current(fsys fs.FS, folders []string) (bool, error) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	if len(folders) != len(idx.Objects) {
+		return false, nil
+	}
+	for _, folder := range folders {
+		indexed, ok := idx.Objects[folder]
+		if !ok {
+			return false, nil
+		}
+		sidecar, err := readInventorySidecar(fsys, folder)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if sidecar != indexed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (idx *SearchIndex) finish(err error) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.ready = true
+	idx.err = err
+}
+
+// StartSearchIndex loads the search index from filename or builds it in the background.
+// a new index is written to filename if given
+func (s *Server) StartSearchIndex(filename string, rebuild bool) {
+	s.searchIndex = NewSearchIndex(s.log)
+	if filename != "" && !rebuild {
+		if _, err := os.Stat(filename); err == nil {
+			if err := s.searchIndex.Load(filename); err != nil {
+				s.log.Error().Err(err).Msgf("cannot load search index '%s' - rebuilding", filename)
+				s.searchIndex = NewSearchIndex(s.log)
+			} else if current, err := s.searchIndexCurrent(); err != nil || !current {
+				if err != nil {
+					s.log.Error().Err(err).Msgf("cannot check search index '%s' - rebuilding", filename)
+				} else {
+					s.log.Info().Msgf("search index '%s' from %s is outdated - rebuilding", filename, s.searchIndex.Created.Format(time.RFC3339))
+				}
+				s.searchIndex = NewSearchIndex(s.log)
+			} else {
+				s.log.Info().Msgf("search index '%s' from %s loaded", filename, s.searchIndex.Created.Format(time.RFC3339))
+				s.searchIndex.finish(nil)
+				return
+			}
+		}
+	}
+	go func() {
+		err := s.buildSearchIndex(s.searchIndex)
+		if err == nil && filename != "" {
+			err = s.searchIndex.Save(filename)
+		}
+		if err != nil {
+			s.log.Error().Err(err).Msg("cannot build search index")
+		}
+		_, objects, _ := s.searchIndex.Status()
+		s.log.Info().Msgf("search index with %d objects created", objects)
+		s.searchIndex.finish(err)
+	}()
+}
+
+// searchIndexCurrent checks the loaded index against the objects of the storage root
+func (s *Server) searchIndexCurrent() (bool, error) {
+	folders, err := s.storageRoot.GetObjectFolders()
+	if err != nil {
+		return false, errors.Wrap(err, "cannot get object folders")
+	}
+	return s.searchIndex.Current(s.storageRoot.GetFS(), folders)
+}
+
+func (s *Server) buildSearchIndex(idx *SearchIndex) error {
+	idx.Created = time.Now()
+	folders, err := s.storageRoot.GetObjectFolders()
+	if err != nil {
+		return errors.Wrap(err, "cannot get object folders")
+	}
+	sort.Strings(folders)
+	for _, folder := range folders {
+		// read the sidecar before loading. a version written in between leads to a rebuild on the next start
+		sidecar, err := readInventorySidecar(s.storageRoot.GetFS(), folder)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		idx.lock.Lock()
+		idx.Objects[folder] = sidecar
+		idx.lock.Unlock()
+		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
+		if err != nil {
+			return errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)
+		}
+		obj, err := object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
+		if err != nil {
+			s.log.Warn().Err(err).Msgf("cannot load object in '%s' for search index", folder)
+			continue
+		}
+		metadata, err := obj.GetMetadata()
+		if err != nil {
+			s.log.Warn().Err(err).Msgf("cannot get metadata of object %s for search index", obj.GetID())
+			continue
+		}
+		if s.obfuscate {
+			if err := metadata.Obfuscate(); err != nil {
+				s.log.Warn().Err(err).Msgf("cannot obfuscate metadata of object %s", obj.GetID())
+				continue
+			}
+		}
+		// file names are not indexed for obfuscated objects
+		if err := idx.AddObject(obj, metadata, !s.obfuscate); err != nil {
+			s.log.Warn().Err(err).Msgf("cannot index object %s", obj.GetID())
+		}
+	}
+	return nil
+}
+
+func (s *Server) search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	var params = gin.H{
+		"title": "Search",
+		"query": query,
+	}
+	if s.searchIndex == nil {
+		params["error"] = "search is not enabled"
+		c.HTML(http.StatusOK, "search.gohtml", params)
+		return
+	}
+	ready, objects, err := s.searchIndex.Status()
+	params["ready"] = ready
+	params["objects"] = objects
+	if err != nil {
+		params["error"] = err.Error()
+	}
+	if query != "" {
+		var results = []*SearchResult{}
+		for _, r := range s.searchIndex.Search(query) {
+			if !s.allowed(c, r.ObjectID) {
+				continue
+			}
+			results = append(results, r)
+		}
+		params["total"] = len(results)
+		if len(results) > searchMaxResults {
+			results = results[:searchMaxResults]
+		}
+		params["results"] = results
+	}
+	c.HTML(http.StatusOK, "search.gohtml", params)
+}
@@ -0,0 +1,73 @@
+package display
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSearchIndexCurrent(t *testing.T) {
+	logger := zerolog.Nop()
+	fsys := fstest.MapFS{
+		"a/inventory.json.sha512": &fstest.MapFile{Data: []byte("1111 inventory.json")},
+		"b/inventory.json.sha256": &fstest.MapFile{Data: []byte("2222 inventory.json")},
+	}
+	idx := NewSearchIndex(&logger)
+	if current, err := idx.Current(fsys, []string{"a", "b"}); err != nil || current {
+		t.Errorf("empty index: current %v, %v", current, err)
+	}
+	idx.Objects["a"] = "1111 inventory.json"
+	idx.Objects["b"] = "2222 inventory.json"
+	if current, err := idx.Current(fsys, []string{"a", "b"}); err != nil || !current {
+		t.Errorf("unchanged objects: current %v, %v", current, err)
+	}
+
+	// new version of b
+	fsys["b/inventory.json.sha256"] = &fstest.MapFile{Data: []byte("3333 inventory.json")}
+	if current, err := idx.Current(fsys, []string{"a", "b"}); err != nil || current {
+		t.Errorf("changed object: current %v, %v", current, err)
+	}
+	fsys["b/inventory.json.sha256"] = &fstest.MapFile{Data: []byte("2222 inventory.json")}
+
+	// object c added, b removed
+	fsys["c/inventory.json.sha512"] = &fstest.MapFile{Data: []byte("4444 inventory.json")}
+	if current, err := idx.Current(fsys, []string{"a", "b", "c"}); err != nil || current {
+		t.Errorf("new object: current %v, %v", current, err)
+	}
+	if current, err := idx.Current(fsys, []string{"a"}); err != nil || current {
+		t.Errorf("removed object: current %v, %v", current, err)
+	}
+	if current, err := idx.Current(fsys, []string{"a", "c"}); err != nil || current {
+		t.Errorf("replaced object: current %v, %v", current, err)
+	}
+}
+
+func TestSearchIndexSaveLoad(t *testing.T) {
+	logger := zerolog.Nop()
+	idx := NewSearchIndex(&logger)
+	idx.Docs = append(idx.Docs, &SearchDocument{ObjectID: "id:a"}, &SearchDocument{ObjectID: "id:a", Digest: "1234", Paths: []string{"data/report.pdf"}})
+	idx.add(0, SearchSourceID, "id:a")
+	idx.add(1, SearchSourcePath, "data/report.pdf")
+	idx.Objects["a"] = "1111 inventory.json"
+
+	filename := filepath.Join(t.TempDir(), "search.gob.gz")
+	if err := idx.Save(filename); err != nil {
+		t.Fatalf("cannot save index: %v", err)
+	}
+	loaded := NewSearchIndex(&logger)
+	if err := loaded.Load(filename); err != nil {
+		t.Fatalf("cannot load index: %v", err)
+	}
+	if _, objects, _ := loaded.Status(); objects != 1 {
+		t.Errorf("expected 1 object, got %d", objects)
+	}
+	if loaded.Objects["a"] != "1111 inventory.json" {
+		t.Errorf("object sidecars not stored: %v", loaded.Objects)
+	}
+	results := loaded.Search("report")
+	if len(results) != 1 || results[0].Digest != "1234" || results[0].Sources[0] != "path" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
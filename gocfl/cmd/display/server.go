@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"emperror.dev/emperror"
@@ -21,16 +22,22 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/gin-contrib/multitemplate"
 	"github.com/gin-gonic/gin"
-	"github.com/je4/filesystem/v3/pkg/writefs"
 	dcert "github.com/je4/utils/v2/pkg/cert"
 	"github.com/je4/utils/v2/pkg/checksum"
 	iou "github.com/je4/utils/v2/pkg/io"
 	"github.com/je4/utils/v2/pkg/zLogger"
 	"github.com/ocfl-archive/gocfl/v2/pkg/extension"
 	extension2 "github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/logical"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
 	"github.com/ocfl-archive/indexer/v3/pkg/indexer"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	objectCacheSize    = 32
+	objectCacheRefresh = 5 * time.Second
 )
 
 type Server struct {
@@ -46,12 +53,17 @@ type Server struct {
 	accessLog        io.Writer
 	dataFS           fs.FS
 	storageRoot      storageroot.StorageRoot
-	object           object.Object
-	metadata         *object.ObjectMetadata
+	objects          *logical.ObjectCache
+	images           *ImageCache
 	templateFS       fs.FS
 	obfuscate        bool
-	objectFS         http.FileSystem
 	extensionFactory *extension2.ExtensionFactory
+	auth             *Auth
+	accessLogLock    sync.Mutex
+	searchIndex      *SearchIndex
+	dashboardData    *Dashboard
+	davFS            *DAVFS
+	davHandler       *webdav.Handler
 }
 
 func NewServer(storageRoot storageroot.StorageRoot, extensionFactory *extension2.ExtensionFactory, service, addr string, urlExt *url.URL, dataFS fs.FS, templateFS fs.FS, log zLogger.ZLogger, accessLog io.Writer) (*Server, error) {
@@ -72,6 +84,8 @@ func NewServer(storageRoot storageroot.StorageRoot, extensionFactory *extension2
 		accessLog:        accessLog,
 		storageRoot:      storageRoot,
 	}
+	srv.objects = logical.NewObjectCache(storageRoot, extensionFactory, objectCacheSize, objectCacheRefresh, srv.obfuscate, log)
+	srv.images = NewImageCache(imageCacheSize)
 
 	return srv, nil
 }
@@ -86,6 +100,8 @@ func (s *Server) ListenAndServe(cert, key string) (err error) {
 		c.String(http.StatusOK, "pong")
 	})
 
+	route.Use(s.authMiddleware)
+
 	mt := multitemplate.New()
 
 	var tplfiles []string = []string{
@@ -95,6 +111,10 @@ func (s *Server) ListenAndServe(cert, key string) (err error) {
 		"version.gohtml",
 		"detail.gohtml",
 		"report.gohtml",
+		"history.gohtml",
+		"diff.gohtml",
+		"search.gohtml",
+		"dashboard.gohtml",
 	}
 
 	for _, tplfile := range tplfiles {
@@ -121,17 +141,24 @@ func (s *Server) ListenAndServe(cert, key string) (err error) {
 
 	route.HTMLRender = mt
 	route.GET("/", s.storageroot)
-	//	route.GET("/:id", s.dashboard)
+	route.GET("/search", s.search)
+	route.GET("/dashboard", s.dashboard)
 	route.GET("/object/id/:id", s.loadObjectID)
 	route.GET("/object/id/:id/manifest", s.manifest)
 	route.GET("/object/id/:id/version/:version", s.version)
 	route.GET("/object/id/:id/detail/:checksum", s.detail)
 	route.GET("/object/id/:id/report", s.report)
+	route.GET("/object/id/:id/history", s.history)
+	route.GET("/object/id/:id/diff", s.diff)
 	route.GET("/object/id/:id/download/:checksum/:filename", s.download)
 	route.GET("/object/id/:id/extension/:extension/download/*path", s.downloadExtFile)
 	route.GET("/object/folder/*path", s.loadObjectPath)
 	route.GET("/object/id/:id/browse/*path", s.loadObjectBrowser)
 
+	s.initAPI(route)
+	s.initIIIF(route)
+	s.initDAV(route)
+
 	route.StaticFS("/static", http.FS(s.dataFS))
 
 	s.srv = &http.Server{
@@ -185,37 +212,13 @@ func (s *Server) downloadExtFile(c *gin.Context) {
 		return
 	}
 
-	if s.object != nil && s.object.GetID() == iop.ID {
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID)})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
+	obj := o.Object
 	pathStr := filepath.ToSlash(filepath.Join("extensions", iop.Extension, iop.Path))
-	fp, err := s.object.GetFS().Open(pathStr)
+	fp, err := obj.GetFS().Open(pathStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -229,15 +232,16 @@ func (s *Server) downloadExtFile(c *gin.Context) {
 
 	mimeReader, err := iou.NewMimeReader(fp)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot instantiate mimereader for object %s - %s", s.object.GetID(), pathStr).Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot instantiate mimereader for object %s - %s", obj.GetID(), pathStr).Error()})
 		return
 	}
 	contentType, err := mimeReader.DetectContentType()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot detect content-type for object %s - %s", s.object.GetID(), pathStr).Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot detect content-type for object %s - %s", obj.GetID(), pathStr).Error()})
 		return
 	}
 	c.DataFromReader(http.StatusOK, fi.Size(), contentType, mimeReader, map[string]string{})
+	s.logDownload(c, obj.GetID(), pathStr)
 }
 
 func (s *Server) download(c *gin.Context) {
@@ -257,55 +261,23 @@ func (s *Server) download(c *gin.Context) {
 		return
 	}
 
-	if s.object != nil && s.object.GetID() == iop.ID {
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID).Error()})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
+	obj, metadata := o.Object, o.Metadata
 
-	file, ok := s.metadata.Files[iop.Checksum]
+	file, ok := metadata.Files[iop.Checksum]
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Errorf("no file with checksum %s found", iop.Checksum).Error()})
 		return
 	}
 
-	fp, err := s.object.GetFS().Open(file.InternalName[0])
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	defer fp.Close()
-	fi, err := fp.Stat()
-	if err != nil {
+	if err := s.serveContent(c, obj.GetFS(), metadata, iop.Checksum, file); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.DataFromReader(http.StatusOK, fi.Size(), "application/octet-stream", fp, map[string]string{})
-
+	s.logDownload(c, obj.GetID(), file.InternalName[0])
 }
 
 func (s *Server) detail(c *gin.Context) {
@@ -325,37 +297,13 @@ func (s *Server) detail(c *gin.Context) {
 		return
 	}
 
-	if s.object != nil && s.object.GetID() == iop.ID {
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID).Error()})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
+	obj, metadata := o.Object, o.Metadata
 
-	file, ok := s.metadata.Files[iop.Checksum]
+	file, ok := metadata.Files[iop.Checksum]
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Errorf("no file with checksum %s found", iop.Checksum).Error()})
 		return
@@ -384,7 +332,7 @@ func (s *Server) detail(c *gin.Context) {
 
 	status := &detailStatus{
 		Checksum:        iop.Checksum,
-		DigestAlgorithm: s.metadata.DigestAlgorithm,
+		DigestAlgorithm: metadata.DigestAlgorithm,
 		InternalNames:   file.InternalName,
 		ExternalNames:   map[string][]*extFEntry{},
 		Fixity:          file.Checksums,
@@ -433,7 +381,7 @@ func (s *Server) detail(c *gin.Context) {
 	if extIndexer != nil {
 		iData, err := json.MarshalIndent(extIndexer.Metadata, "", "  ")
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot marshal indexer metadata for object %s", s.object.GetID()).Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot marshal indexer metadata for object %s", obj.GetID()).Error()})
 			return
 		}
 		//	extIndexer.Metadata = nil
@@ -463,9 +411,9 @@ func (s *Server) detail(c *gin.Context) {
 
 	var params = map[string]any{
 		"title":  "Detail",
-		"id":     s.object.GetID(),
+		"id":     obj.GetID(),
 		"status": status,
-		//		"metadata": s.metadata,
+		//		"metadata": metadata,
 		"file": file,
 	}
 
@@ -473,25 +421,8 @@ func (s *Server) detail(c *gin.Context) {
 
 }
 
-func (s *Server) dashboard(c *gin.Context) {
-
-	var id string
-	if s.object != nil {
-		id = s.object.GetID()
-	}
-	c.HTML(http.StatusOK, "object.gohtml", gin.H{
-		"title": "gocfl",
-		"id":    id,
-	})
-}
-
 func (s *Server) storageroot(c *gin.Context) {
 
-	var id string
-	if s.object != nil {
-		id = s.object.GetID()
-	}
-
 	if s.storageRoot == nil {
 		c.JSON(http.StatusInternalServerError, "no storage root loaded")
 		return
@@ -502,10 +433,24 @@ func (s *Server) storageroot(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
+	if s.auth.Enabled() {
+		// only list the objects the user has access to
+		var allowedFolders = []string{}
+		for _, folder := range folders {
+			id, err := s.objectIDFromFolder(folder)
+			if err != nil {
+				s.log.Warn().Err(err).Msgf("cannot get id of object in '%s'", folder)
+				continue
+			}
+			if s.allowed(c, id) {
+				allowedFolders = append(allowedFolders, folder)
+			}
+		}
+		folders = allowedFolders
+	}
 
 	c.HTML(http.StatusOK, "storageroot.gohtml", gin.H{
 		"title":       "gocfl",
-		"id":          id,
 		"folders":     folders,
 		"storageroot": s.storageRoot.String(),
 	})
@@ -527,35 +472,11 @@ func (s *Server) manifest(c *gin.Context) {
 		return
 	}
 
-	if s.object != nil && s.object.GetID() == iop.ID {
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID).Error()})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
+	obj, metadata := o.Object, o.Metadata
 
 	type fEntry struct {
 		Checksum  string
@@ -567,7 +488,7 @@ func (s *Server) manifest(c *gin.Context) {
 	var files = map[string]*fEntry{}
 	var filenames = []string{}
 
-	for checksum, file := range s.metadata.Files {
+	for checksum, file := range metadata.Files {
 		extMigrationAny, _ := file.Extension[extension.MigrationName]
 		var extMigration *extension.MigrationResult
 		if extMigrationAny != nil {
@@ -599,8 +520,8 @@ func (s *Server) manifest(c *gin.Context) {
 
 	var params = map[string]any{
 		"title":     "Manifest",
-		"id":        s.object.GetID(),
-		"versions":  s.metadata.Versions,
+		"id":        obj.GetID(),
+		"versions":  metadata.Versions,
 		"files":     files,
 		"filenames": filenames,
 	}
@@ -626,35 +547,11 @@ func (s *Server) version(c *gin.Context) {
 		return
 	}
 
-	if s.object != nil && s.object.GetID() == iop.ID {
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID).Error()})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
+	obj, metadata := o.Object, o.Metadata
 
 	type fEntry struct {
 		CTime     string
@@ -670,7 +567,7 @@ func (s *Server) version(c *gin.Context) {
 	var files = map[string]*fEntry{}
 	var filenames = []string{}
 
-	for checksum, file := range s.metadata.Files {
+	for checksum, file := range metadata.Files {
 		extMigrationAny, _ := file.Extension[extension.MigrationName]
 		var extMigration *extension.MigrationResult
 		if extMigrationAny != nil {
@@ -720,8 +617,8 @@ func (s *Server) version(c *gin.Context) {
 
 	var params = map[string]any{
 		"title":     "Version",
-		"id":        s.object.GetID(),
-		"versions":  s.metadata.Versions,
+		"id":        obj.GetID(),
+		"versions":  metadata.Versions,
 		"files":     files,
 		"filenames": filenames,
 		"version":   iop.Version,
@@ -746,42 +643,16 @@ func (s *Server) loadObjectID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot unescape '%s'", iop.ID).Error()})
 		return
 	}
-	if s.object != nil && s.object.GetID() == iop.ID {
-		// already loaded
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID).Error()})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
-	s.displayObject(c)
+	s.displayObject(c, o.Object, o.Metadata)
 }
 
-func (s *Server) displayObjectBrowse(c *gin.Context) {
+func (s *Server) displayObjectBrowse(c *gin.Context, objectFS http.FileSystem) {
 	path := c.Param("path")
-	c.FileFromFS(path, s.objectFS)
+	c.FileFromFS(path, objectFS)
 
 }
 func (s *Server) loadObjectPath(c *gin.Context) {
@@ -795,27 +666,22 @@ func (s *Server) loadObjectPath(c *gin.Context) {
 		return
 	}
 	folder := strings.Trim(iop.Path, "/")
-	fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-	}
-	s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
+	id, err := s.objectIDFromFolder(folder)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	s.metadata, err = s.object.GetMetadata()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
+	if !s.allowed(c, id) {
+		s.abortUnauthorized(c, getPrincipal(c))
 		return
 	}
-	c.Redirect(http.StatusPermanentRedirect, s.urlExt.String()+fmt.Sprintf("/object/id/%s", url.PathEscape(s.object.GetID())))
+	c.Redirect(http.StatusPermanentRedirect, s.urlExt.String()+fmt.Sprintf("/object/id/%s", url.PathEscape(id)))
 	//	s.displayObject(c)
 }
 
-func (s *Server) displayObject(c *gin.Context) {
+func (s *Server) displayObject(c *gin.Context, obj object.Object, metadata *object.ObjectMetadata) {
 
-	if s.metadata == nil {
+	if metadata == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no metadata loaded"})
 		return
 	}
@@ -824,7 +690,7 @@ func (s *Server) displayObject(c *gin.Context) {
 	var noSizeFiles int
 	var mimeTypes = make(map[string]int)
 	var pronoms = make(map[string]int)
-	for _, v := range s.metadata.Files {
+	for _, v := range metadata.Files {
 		numFiles += len(v.InternalName)
 		_fs, _ := v.Extension[extension.FilesystemName]
 		_idx, _ := v.Extension[extension.IndexerName]
@@ -864,11 +730,18 @@ func (s *Server) displayObject(c *gin.Context) {
 			noSizeFiles++
 		}
 	}
+	var signatures = map[string]*extension.SignatureInfo{}
+	if extMap, ok := metadata.Extension.(map[string]any); ok {
+		if sigs, ok := extMap[extension.SignatureName].(map[string]*extension.SignatureInfo); ok {
+			signatures = sigs
+		}
+	}
 	var params = map[string]any{
 		"title":          "gocfl",
-		"id":             s.object.GetID(),
-		"versions":       s.metadata.Versions,
-		"differentFiles": len(s.metadata.Files),
+		"id":             obj.GetID(),
+		"versions":       metadata.Versions,
+		"signatures":     signatures,
+		"differentFiles": len(metadata.Files),
 		"numFiles":       numFiles,
 		"size":           humanize.Bytes(size),
 		"noSizeFiles":    noSizeFiles,
@@ -894,57 +767,16 @@ func (s *Server) loadObjectBrowser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot unescape '%s'", iop.ID).Error()})
 		return
 	}
-	if s.object != nil && s.object.GetID() == iop.ID {
-		// already loaded
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-			if s.obfuscate {
-				if err := s.metadata.Obfuscate(); err != nil {
-					c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot obfuscate metadata").Error()})
-					return
-				}
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID)})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
-		if s.obfuscate {
-			if err := s.metadata.Obfuscate(); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot obfuscate metadata").Error()})
-				return
-			}
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
-	if s.objectFS == nil {
-		objectFS, err := NewObjectFS(s.object)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get filesystem for object %s", s.object.GetID()).Error()})
-			return
-		}
-		s.objectFS = http.FS(objectFS)
+	objectFS, err := cachedObjectFS(o)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	s.displayObjectBrowse(c)
+	s.displayObjectBrowse(c, objectFS)
 }
 
 func (s *Server) report(c *gin.Context) {
@@ -965,43 +797,19 @@ func (s *Server) report(c *gin.Context) {
 	}
 	full := c.DefaultQuery("full", "none") != "none"
 
-	if s.object != nil && s.object.GetID() == iop.ID {
-		if s.metadata == nil {
-			s.metadata, err = s.object.GetMetadata()
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-				return
-			}
-		}
-	} else {
-		folder, err := s.storageRoot.IdToFolder(iop.ID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get folder for object %s", iop.ID)})
-			return
-		}
-		fsys, err := writefs.Sub(s.storageRoot.GetFS(), folder)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot create subfs for %v / %s", s.storageRoot.GetFS(), folder)})
-		}
-		s.object, err = object.LoadObject(context.Background(), fsys, s.extensionFactory, s.log)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		s.metadata, err = s.object.GetMetadata()
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": errors.Wrapf(err, "cannot get metadata for object %s", s.object.GetID()).Error()})
-			return
-		}
+	o, ok := s.loadObject(c, iop.ID)
+	if !ok {
+		return
 	}
+	obj, metadata := o.Object, o.Metadata
 
-	if s.metadata == nil {
+	if metadata == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no metadata loaded"})
 		return
 	}
 
-	extManager := s.object.GetExtensionManager()
-	inventory := s.object.GetInventory()
+	extManager := obj.GetExtensionManager()
+	inventory := obj.GetInventory()
 
 	type mimeCount struct {
 		SizeStr string
@@ -1014,7 +822,7 @@ func (s *Server) report(c *gin.Context) {
 	var mimeTypes = make(map[string]*mimeCount)
 	var pronoms = make(map[string]*mimeCount)
 	var videoSecs uint
-	for _, v := range s.metadata.Files {
+	for _, v := range metadata.Files {
 		numFiles += len(v.InternalName)
 		_fs, _ := v.Extension[extension.FilesystemName]
 		_idx, _ := v.Extension[extension.IndexerName]
@@ -1073,7 +881,7 @@ func (s *Server) report(c *gin.Context) {
 	}
 
 	var objectpath string
-	if fsStringer, ok := s.object.GetFS().(fmt.Stringer); ok {
+	if fsStringer, ok := obj.GetFS().(fmt.Stringer); ok {
 		objectpath = fsStringer.String()
 	}
 
@@ -1115,16 +923,16 @@ func (s *Server) report(c *gin.Context) {
 			path = ""
 		}
 		fname := filepath.ToSlash(filepath.Join(path, metafileCfg.MetaName))
-		mPath, err := extManager.BuildObjectManifestPath(s.object, fname, area)
+		mPath, err := extManager.BuildObjectManifestPath(obj, fname, area)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": errors.Wrapf(err, "cannot map %s:%s", area, fname).Error()})
 			return
 		}
 
 		// search for info file
-		for ver, _ := range s.metadata.Versions {
+		for ver, _ := range metadata.Versions {
 			fullpath := filepath.ToSlash(filepath.Join(ver, "content", mPath))
-			jsonData, err := fs.ReadFile(s.object.GetFS(), fullpath)
+			jsonData, err := fs.ReadFile(obj.GetFS(), fullpath)
 			if err == nil && len(jsonData) > 0 {
 				infoBytes = jsonData
 			}
@@ -1179,7 +987,7 @@ func (s *Server) report(c *gin.Context) {
 		addToTree(parts[1:], newEdge)
 	}
 
-	for _, file := range s.metadata.Files {
+	for _, file := range metadata.Files {
 		for _, files := range file.VersionName {
 			for _, filename := range files {
 				filenames = append(filenames, filename)
@@ -1215,10 +1023,10 @@ func (s *Server) report(c *gin.Context) {
 
 	var files = map[string]*object.FileMetadata{}
 	if full {
-		files = s.metadata.Files
+		files = metadata.Files
 	}
 	var filesNoData int64
-	for _, file := range s.metadata.Files {
+	for _, file := range metadata.Files {
 		if file.Extension[extension.IndexerName] == nil && file.Extension[extension.FilesystemName] == nil {
 			filesNoData++
 		}
@@ -1228,9 +1036,9 @@ func (s *Server) report(c *gin.Context) {
 		"objectpath":     objectpath,
 		"gocfl":          "gocfl",
 		"head":           inventory.GetHead(),
-		"id":             s.object.GetID(),
-		"versions":       s.metadata.Versions,
-		"differentFiles": len(s.metadata.Files),
+		"id":             obj.GetID(),
+		"versions":       metadata.Versions,
+		"differentFiles": len(metadata.Files),
 		"numFiles":       numFiles,
 		"filesNoData":    filesNoData,
 		"size":           size,
@@ -1257,6 +1065,20 @@ func fmtDuration(d time.Duration) string {
 	return fmt.Sprintf("%d:%02d:%02d", h, m, s)
 }
 
+// loadObject returns the current state of the object with the given id
+func (s *Server) loadObject(c *gin.Context, id string) (*logical.CachedObject, bool) {
+	o, err := s.objects.Get(id)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, fs.ErrNotExist) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return o, true
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
 	return errors.WithStack(s.srv.Shutdown(ctx))
 }
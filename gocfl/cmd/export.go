@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/spf13/cobra"
+	ublogger "gitlab.switch.ch/ub-unibas/go-ublogger/v2"
+	"go.ub.unibas.ch/cloud/certloader/v2/pkg/loader"
+)
+
+var exportCmd = &cobra.Command{
+	Use:     "export [path to ocfl structure] [path to target folder]",
+	Aliases: []string{},
+	Short:   "export object version as E-ARK information package",
+	//Long:    "an utterly useless command for testing",
+	Example: "gocfl export ./archive.zip /tmp/sip --object-id id:abc --format eark-sip",
+	Args:    cobra.ExactArgs(2),
+	Run:     doExport,
+}
+
+func initExport() {
+	exportCmd.Flags().StringP("object-path", "p", "", "object path to export")
+	exportCmd.Flags().StringP("object-id", "i", "", "object id to export")
+	exportCmd.Flags().String("version", "latest", "version to export")
+	exportCmd.Flags().String("format", "eark-sip", "package format (eark-sip|eark-aip)")
+}
+
+func doExportConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "object-path"); str != "" {
+		conf.Export.ObjectPath = str
+	}
+	if str := getFlagString(cmd, "object-id"); str != "" {
+		conf.Export.ObjectID = str
+	}
+	if str := getFlagString(cmd, "version"); str != "" {
+		conf.Export.Version = str
+	}
+	if conf.Export.Version == "" {
+		conf.Export.Version = "latest"
+	}
+	if str := getFlagString(cmd, "format"); str != "" {
+		conf.Export.Format = str
+	}
+}
+
+// exportEARK writes the object version as E-ARK information package to destFS
+func exportEARK(obj object.Object, version, packageType string, destFS fs.FS, logger zLogger.ZLogger) error {
+	me, err := ocflextension.GetObjectMets(obj, logger)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get METS configuration of '%s'", obj.GetID())
+	}
+	return errors.WithStack(me.ExportEARK(obj, version, packageType, destFS))
+}
+
+func doExport(cmd *cobra.Command, args []string) {
+	// create logger instance
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("cannot get hostname: %v", err)
+	}
+
+	var loggerTLSConfig *tls.Config
+	var loggerLoader io.Closer
+	if conf.Log.Stash.TLS != nil {
+		loggerTLSConfig, loggerLoader, err = loader.CreateClientLoader(conf.Log.Stash.TLS, nil)
+		if err != nil {
+			log.Fatalf("cannot create client loader: %v", err)
+		}
+		defer loggerLoader.Close()
+	}
+
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	_logger, _logstash, _logfile, err := ublogger.CreateUbMultiLoggerTLS(conf.Log.Level, conf.Log.File,
+		ublogger.SetDataset(conf.Log.Stash.Dataset),
+		ublogger.SetLogStash(conf.Log.Stash.LogstashHost, conf.Log.Stash.LogstashPort, conf.Log.Stash.Namespace, conf.Log.Stash.LogstashTraceLevel),
+		ublogger.SetTLS(conf.Log.Stash.TLS != nil),
+		ublogger.SetTLSConfig(loggerTLSConfig),
+	)
+	if err != nil {
+		log.Fatalf("cannot create logger: %v", err)
+	}
+	if _logstash != nil {
+		defer _logstash.Close()
+	}
+
+	if _logfile != nil {
+		defer _logfile.Close()
+	}
+
+	l2 := _logger.With().Timestamp().Str("host", hostname).Logger() //.Output(output)
+	var logger zLogger.ZLogger = &l2
+
+	t := startTimer()
+	defer func() { logger.Info().Msgf("Duration: %s", t.String()) }()
+
+	ocflPath, err := util.Fullpath(args[0])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+	destPath, err := util.Fullpath(args[1])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+
+	doExportConf(cmd)
+
+	oPath := conf.Export.ObjectPath
+	oID := conf.Export.ObjectID
+	if oPath != "" && oID != "" {
+		cmd.Help()
+		cobra.CheckErr(errors.New("do not use object-path AND object-id at the same time"))
+		return
+	}
+	if oPath == "" && oID == "" {
+		cmd.Help()
+		cobra.CheckErr(errors.New("must specify either object-id or object-path"))
+		return
+	}
+	var packageType string
+	switch strings.ToLower(conf.Export.Format) {
+	case "eark-sip":
+		packageType = ocflextension.EARKPackageTypeSIP
+	case "eark-aip":
+		packageType = ocflextension.EARKPackageTypeAIP
+	default:
+		cmd.Help()
+		cobra.CheckErr(errors.Errorf("invalid format '%s' for flag 'format' or 'Format' config file entry", conf.Export.Format))
+		return
+	}
+
+	logger.Info().Msgf("exporting '%s'", ocflPath)
+
+	fsFactory, err := initializeFSFactory(nil, nil, nil, true, true, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot create filesystem factory")
+		return
+	}
+
+	ocflFS, err := fsFactory.Get(ocflPath, true)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
+		return
+	}
+	defer func() {
+		if err := writefs.Close(ocflFS); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot close filesystem for '%s'", ocflFS)
+		}
+	}()
+
+	destFS, err := fsFactory.Get(destPath, false)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", destPath)
+		return
+	}
+	defer func() {
+		if err := writefs.Close(destFS); err != nil {
+			logger.Error().Err(err).Msgf("cannot close filesystem: %v", destFS)
+		}
+	}()
+
+	extensionParams := GetExtensionParamValues(cmd, conf)
+	extensionFactory, err := InitExtensionFactory(extensionParams, "", false, nil, nil, nil, nil, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize extension factory")
+		return
+	}
+
+	ctx := validation.NewContextValidation(context.TODO())
+	sr, err := storageroot.LoadStorageRoot(ctx, ocflFS, extensionFactory, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot load storage root")
+		return
+	}
+	if oID != "" {
+		oPath, err = sr.IdToFolder(oID)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot get id folder for '%s'", oID)
+			return
+		}
+	}
+
+	dirs, err := fs.ReadDir(destFS, ".")
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot read target folder '%v'", destFS)
+		return
+	}
+	if len(dirs) > 0 {
+		fmt.Printf("target folder '%s' is not empty\n", destFS)
+		logger.Debug().Msgf("target folder '%s' is not empty", destFS)
+		return
+	}
+
+	if err := walkObjects(ctx, sr, oPath, extensionFactory, logger, func(obj object.Object) error {
+		return exportEARK(obj, conf.Export.Version, packageType, destFS, logger)
+	}); err != nil {
+		fmt.Printf("cannot export object: %v\n", err)
+		logger.Error().Stack().Err(err).Msg("cannot export object")
+		return
+	}
+	fmt.Printf("export done without errors\n")
+	_ = showStatus(ctx, logger)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/je4/filesystem/v3/pkg/writefs"
 	"github.com/je4/utils/v2/pkg/zLogger"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/progress"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
@@ -175,7 +176,7 @@ func doExtract(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	if err := object.Extract(context.Background(), destFS, sr.GetFS(), oPath, conf.Extract.Version, conf.Extract.Manifest, conf.Extract.Area, extensionFactory, logger); err != nil {
+	if err := object.Extract(progress.NewContext(context.Background(), newProgressReporter(conf.Progress, logger)), destFS, sr.GetFS(), oPath, conf.Extract.Version, conf.Extract.Manifest, conf.Extract.Area, extensionFactory, logger); err != nil {
 		fmt.Printf("cannot extract storage root: %v\n", err)
 		logger.Error().Stack().Err(err).Msg("cannot extract storage root")
 		return
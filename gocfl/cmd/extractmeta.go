@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
@@ -13,6 +14,8 @@ import (
 	"emperror.dev/errors"
 	"github.com/je4/filesystem/v3/pkg/writefs"
 	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
@@ -38,9 +41,10 @@ func initExtractMeta() {
 	extractMetaCmd.Flags().StringP("object-path", "p", "", "object path to extract")
 	extractMetaCmd.Flags().StringP("object-id", "i", "", "object id to extract")
 	extractMetaCmd.Flags().String("version", "latest", "version to extract")
-	extractMetaCmd.Flags().String("format", "json", "output format (json)")
+	extractMetaCmd.Flags().String("format", "json", "output format (json|ead3)")
 	extractMetaCmd.Flags().String("output", "", "output file (default stdout)")
 	extractMetaCmd.Flags().Bool("obfuscate", false, "obfuscate metadata")
+	extractMetaCmd.Flags().String("xmllint", "", "xmllint executable to validate the ead3 finding aid against ead3.xsd")
 }
 
 func doExtractMetaConf(cmd *cobra.Command) {
@@ -65,6 +69,49 @@ func doExtractMetaConf(cmd *cobra.Command) {
 	if b, ok := getFlagBool(cmd, "obfuscate"); ok {
 		conf.ExtractMeta.Obfuscate = b
 	}
+	if str := getFlagString(cmd, "xmllint"); str != "" {
+		conf.ExtractMeta.XMLLint = str
+	}
+}
+
+// extractEAD3 creates an ead3 finding aid of the object at objectPath or of all objects of the storage root.
+// with validator, the finding aid is checked against ead3.xsd
+func extractEAD3(ctx context.Context, sr storageroot.StorageRoot, objectPath string, extensionFactory *extension.ExtensionFactory, title string, obfuscate bool, validator *dilcis.XMLLint, logger zLogger.ZLogger) ([]byte, error) {
+	srMeta := &object.StorageRootMetadata{
+		Objects: map[string]*object.ObjectMetadata{},
+		Folders: map[string]string{},
+	}
+	if err := walkObjects(ctx, sr, objectPath, extensionFactory, logger, func(obj object.Object) error {
+		metadata, err := obj.GetMetadata()
+		if err != nil {
+			return errors.Wrapf(err, "cannot extract metadata from object '%s'", obj.GetID())
+		}
+		srMeta.Objects[obj.GetID()] = metadata
+		folder, err := sr.IdToFolder(obj.GetID())
+		if err != nil {
+			return errors.Wrapf(err, "cannot get folder of object '%s'", obj.GetID())
+		}
+		srMeta.Folders[obj.GetID()] = folder
+		return nil
+	}); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if obfuscate {
+		if err := srMeta.Obfuscate(); err != nil {
+			return nil, errors.Wrap(err, "cannot obfuscate metadata")
+		}
+	}
+	data, err := xml.MarshalIndent(srMeta.EAD3(title), "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal ead3")
+	}
+	data = append([]byte(xml.Header), data...)
+	if validator != nil {
+		if err := validator.Validate(dilcis.SchemaEAD3, data); err != nil {
+			return nil, errors.Wrap(err, "invalid ead3 finding aid")
+		}
+	}
+	return data, nil
 }
 
 func doExtractMeta(cmd *cobra.Command, args []string) {
@@ -123,18 +170,27 @@ func doExtractMeta(cmd *cobra.Command, args []string) {
 		cobra.CheckErr(errors.New("do not use object-path AND object-id at the same time"))
 		return
 	}
-	if oPath == "" && oID == "" {
+	format := strings.ToLower(conf.ExtractMeta.Format)
+	// ead3 finding aids may describe the whole storage root
+	if oPath == "" && oID == "" && format != "ead3" {
 		cmd.Help()
 		cobra.CheckErr(errors.New("must specify either object-id or object-path"))
 		return
 	}
-	format := strings.ToLower(conf.ExtractMeta.Format)
-	if format != "json" {
+	if format != "json" && format != "ead3" {
 		cmd.Help()
 		cobra.CheckErr(errors.Errorf("invalid format '%s' for flag 'format' or 'Format' config file entry", format))
 		return
 	}
 	output := conf.ExtractMeta.Output
+	var validator *dilcis.XMLLint
+	if format == "ead3" && conf.ExtractMeta.XMLLint != "" {
+		validator, err = dilcis.NewXMLLint(conf.ExtractMeta.XMLLint)
+		if err != nil {
+			cobra.CheckErr(err)
+			return
+		}
+	}
 
 	logger.Info().Msgf("extracting metadata from '%s'", ocflPath)
 
@@ -176,36 +232,47 @@ func doExtractMeta(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	metadata, err := object.ExtractMeta(ctx, sr.GetFS(), oPath, extensionFactory, logger)
-	if err != nil {
-		fmt.Printf("cannot extract metadata from storage root: %v\n", err)
-		logger.Error().Stack().Err(err).Msg("cannot extract metadata from storage root")
-		return
-	}
-	if conf.ExtractMeta.Obfuscate {
-		if err := metadata.Obfuscate(); err != nil {
-			fmt.Printf("cannot obfuscate metadata: %v\n", err)
-			logger.Error().Stack().Err(err).Msg("cannot obfuscate metadata")
+	var data []byte
+	switch format {
+	case "ead3":
+		data, err = extractEAD3(ctx, sr, oPath, extensionFactory, ocflPath, conf.ExtractMeta.Obfuscate, validator, logger)
+		if err != nil {
+			fmt.Printf("cannot create ead3 finding aid: %v\n", err)
+			logger.Error().Stack().Err(err).Msg("cannot create ead3 finding aid")
 			return
 		}
-	}
+	default:
+		metadata, err := object.ExtractMeta(ctx, sr.GetFS(), oPath, extensionFactory, logger)
+		if err != nil {
+			fmt.Printf("cannot extract metadata from storage root: %v\n", err)
+			logger.Error().Stack().Err(err).Msg("cannot extract metadata from storage root")
+			return
+		}
+		if conf.ExtractMeta.Obfuscate {
+			if err := metadata.Obfuscate(); err != nil {
+				fmt.Printf("cannot obfuscate metadata: %v\n", err)
+				logger.Error().Stack().Err(err).Msg("cannot obfuscate metadata")
+				return
+			}
+		}
 
-	jsonBytes, err := json.MarshalIndent(metadata, "", "  ")
-	if err != nil {
-		fmt.Printf("cannot marshal metadata")
-		logger.Error().Stack().Err(err).Msg("cannot marshal metadata")
-		return
+		data, err = json.MarshalIndent(metadata, "", "  ")
+		if err != nil {
+			fmt.Printf("cannot marshal metadata")
+			logger.Error().Stack().Err(err).Msg("cannot marshal metadata")
+			return
+		}
 	}
 	if output != "" {
-		if err := os.WriteFile(output, jsonBytes, 0644); err != nil {
-			fmt.Printf("cannot write json to file")
-			logger.Error().Stack().Err(err).Msgf("cannot write json to file '%s'", output)
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			fmt.Printf("cannot write %s to file", format)
+			logger.Error().Stack().Err(err).Msgf("cannot write %s to file '%s'", format, output)
 			return
 		}
 	} else {
-		if _, err := os.Stdout.Write(jsonBytes); err != nil {
-			fmt.Printf("cannot write json to file")
-			logger.Error().Stack().Err(err).Msg("cannot write json to file standard output")
+		if _, err := os.Stdout.Write(data); err != nil {
+			fmt.Printf("cannot write %s to file", format)
+			logger.Error().Stack().Err(err).Msgf("cannot write %s to standard output", format)
 			return
 		}
 		fmt.Print("\n")
@@ -2,12 +2,17 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"emperror.dev/errors"
+	"github.com/dustin/go-humanize"
 	"github.com/je4/filesystem/v3/pkg/osfsrw"
 	"github.com/je4/filesystem/v3/pkg/s3fsrw"
 	"github.com/je4/filesystem/v3/pkg/writefs"
@@ -16,12 +21,19 @@ import (
 	"github.com/je4/utils/v2/pkg/checksum"
 	"github.com/je4/utils/v2/pkg/keepass2kms"
 	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/ocfl-archive/gocfl/v2/config"
 	defaultextensions_object "github.com/ocfl-archive/gocfl/v2/data/defaultextensions/object"
 	defaultextensions_storageroot "github.com/ocfl-archive/gocfl/v2/data/defaultextensions/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/bagit"
 	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/dedup"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/link"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/progress"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
 	"github.com/ocfl-archive/gocfl/v2/pkg/subsystem/migration"
@@ -50,6 +62,119 @@ func (t *timer) String() string {
 	return delta.String()
 }
 
+// newProgressReporter creates the progress reporter for mode auto, bar, log or none.
+// auto shows a progress bar on a terminal and logs progress events otherwise
+func newProgressReporter(mode string, logger zLogger.ZLogger) progress.Reporter {
+	switch strings.ToLower(mode) {
+	case "", "auto":
+		if progress.IsTerminal(os.Stderr) {
+			return progress.NewTracker(progress.NewTTYSink(os.Stderr), time.Second)
+		}
+		return progress.NewTracker(progress.NewLogSink(logger), 30*time.Second)
+	case "bar":
+		return progress.NewTracker(progress.NewTTYSink(os.Stderr), time.Second)
+	case "log":
+		return progress.NewTracker(progress.NewLogSink(logger), 30*time.Second)
+	case "none":
+		return progress.Nil
+	default:
+		logger.Warn().Msgf("unknown progress mode '%s'", mode)
+		return progress.Nil
+	}
+}
+
+// doLinkConf sets the link mode for add, create and update
+func doLinkConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "link-mode"); str != "" {
+		conf.LinkMode = str
+	}
+}
+
+// newLinker returns the linker for the link mode or nil if content is copied.
+// sources maps the areas to their source paths. Only local folders can be linked
+func newLinker(mode string, ocflPath string, sources map[string]string, logger zLogger.ZLogger) (*link.Linker, error) {
+	m, err := link.ParseMode(mode)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if m == link.ModeCopy {
+		return nil, nil
+	}
+	if fi, err := os.Stat(ocflPath); err != nil || !fi.IsDir() {
+		logger.Warn().Msgf("'%s' is not a local folder, link mode %s not possible. copying content", ocflPath, m)
+		return nil, nil
+	}
+	linker := &link.Linker{
+		Mode:    m,
+		Sources: map[string]string{},
+		Root:    ocflPath,
+	}
+	for area, srcPath := range sources {
+		if fi, err := os.Stat(srcPath); err != nil || !fi.IsDir() {
+			logger.Warn().Msgf("'%s' is not a local folder, link mode %s not possible for area '%s'. copying content", srcPath, m, area)
+			continue
+		}
+		linker.Sources[area] = srcPath
+	}
+	return linker, nil
+}
+
+var zipRootRegexp = regexp.MustCompile(`\.zip$`)
+var s3RootRegexp = regexp.MustCompile(s3fsrw.ARNRegexStr)
+
+// newLocker returns the object locker of the storage root or nil if locking is disabled.
+// zip containers are written by one process only and are not locked. lock files in local folders
+// and on s3 are created atomically, other storage roots use the read back check of the locker
+func newLocker(ocflPath string, fsys fs.FS, owner string, logger zLogger.ZLogger) (*lock.Locker, error) {
+	if conf.Lock.Disabled {
+		return nil, nil
+	}
+	if zipRootRegexp.MatchString(ocflPath) {
+		logger.Debug().Msgf("'%s' is a container, no object locks", ocflPath)
+		return nil, nil
+	}
+	locker := lock.NewLocker(fsys, owner, time.Duration(conf.Lock.Expiry), logger)
+	if fi, err := os.Stat(ocflPath); err == nil && fi.IsDir() {
+		locker.SetCreate(lock.NewOSCreate(ocflPath))
+		return locker, nil
+	}
+	if conf.S3.Endpoint != "" && s3RootRegexp.MatchString(ocflPath) {
+		client, err := minio.New(string(conf.S3.Endpoint), &minio.Options{
+			Creds:  credentials.NewStaticV4(string(conf.S3.AccessKeyID), string(conf.S3.AccessKey), ""),
+			Secure: conf.S3.UseSSL,
+			Region: string(conf.S3.Region),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot create s3 client for '%s'", conf.S3.Endpoint)
+		}
+		_, bucketPath, _ := strings.Cut(ocflPath, ":")
+		bucket, prefix, _ := strings.Cut(bucketPath, "/")
+		locker.SetCreate(lock.NewS3Create(client, bucket, prefix))
+		return locker, nil
+	}
+	logger.Warn().Msgf("no atomic create of lock files in '%s'", ocflPath)
+	return locker, nil
+}
+
+// lockObject locks the object and renews the lock until unlock is called.
+// a nil locker does not lock
+func lockObject(locker *lock.Locker, id string, logger zLogger.ZLogger) (unlock func(), err error) {
+	if locker == nil {
+		return func() {}, nil
+	}
+	lck, err := locker.Lock(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot lock object %s", id)
+	}
+	stop := locker.KeepAlive(lck)
+	return func() {
+		stop()
+		if err := locker.Unlock(lck); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot unlock object %s", id)
+		}
+	}, nil
+}
+
 func InitExtensionFactory(extensionParams map[string]string, indexerAddr string, indexerLocalCache bool, indexerActions *ironmaiden.ActionDispatcher, migration *migration.Migration, thumbnail *thumbnail.Thumbnail, sourceFS fs.FS, logger zLogger.ZLogger) (*extension.ExtensionFactory, error) {
 	logger.Debug().Msgf("initializing ExtensionFactory")
 	extensionFactory, err := extension.NewExtensionFactory(extensionParams, logger)
@@ -73,6 +198,16 @@ func InitExtensionFactory(extensionParams map[string]string, indexerAddr string,
 		return ocflextension.NewDigestAlgorithmsFS(fsys)
 	})
 
+	logger.Debug().Msgf("adding creator for extension %s", ocflextension.ObjectLockName)
+	extensionFactory.AddCreator(ocflextension.ObjectLockName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewObjectLockFS(fsys)
+	})
+
+	logger.Debug().Msgf("adding creator for extension %s", ocflextension.DedupIndexName)
+	extensionFactory.AddCreator(ocflextension.DedupIndexName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewDedupIndexFS(fsys)
+	})
+
 	logger.Debug().Msgf("adding creator for extension %s", ocflextension.StorageLayoutFlatDirectName)
 	extensionFactory.AddCreator(ocflextension.StorageLayoutFlatDirectName, func(fsys fs.FS) (extension.Extension, error) {
 		return ocflextension.NewStorageLayoutFlatDirectFS(fsys)
@@ -133,6 +268,26 @@ func InitExtensionFactory(extensionParams map[string]string, indexerAddr string,
 		return ocflextension.NewTimestampFS(fsys, logger)
 	})
 
+	logger.Debug().Msgf("adding creator for extension %s", ocflextension.SignatureName)
+	extensionFactory.AddCreator(ocflextension.SignatureName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewSignatureFS(fsys, logger)
+	})
+
+	logger.Debug().Msgf("adding creator for extension %s", ocflextension.PremisName)
+	extensionFactory.AddCreator(ocflextension.PremisName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewPremisFS(fsys, logger)
+	})
+
+	logger.Debug().Msgf("adding creator for extension %s", ocflextension.VirusScanName)
+	extensionFactory.AddCreator(ocflextension.VirusScanName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewVirusScanFS(fsys, logger)
+	})
+
+	logger.Debug().Msgf("adding creator for extension %s", ocflextension.PolicyName)
+	extensionFactory.AddCreator(ocflextension.PolicyName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewPolicyFS(fsys, logger)
+	})
+
 	logger.Debug().Msgf("adding creator for extension %s", ocflextension.IndexerName)
 	extensionFactory.AddCreator(ocflextension.IndexerName, func(fsys fs.FS) (extension.Extension, error) {
 		ext, err := ocflextension.NewIndexerFS(fsys, indexerAddr, indexerActions, indexerLocalCache, logger)
@@ -165,6 +320,32 @@ func InitExtensionFactory(extensionParams map[string]string, indexerAddr string,
 	return extensionFactory, nil
 }
 
+// walkObjects loads all objects of the storage root or the object at objectPath and calls fn
+func walkObjects(ctx context.Context, sr storageroot.StorageRoot, objectPath string, extensionFactory *extension.ExtensionFactory, logger zLogger.ZLogger, fn func(obj object.Object) error) error {
+	var objectPaths = []string{objectPath}
+	if objectPath == "" {
+		var err error
+		objectPaths, err = sr.GetObjectFolders()
+		if err != nil {
+			return errors.Wrap(err, "cannot get object folders")
+		}
+	}
+	for _, p := range objectPaths {
+		objFsys, err := writefs.Sub(sr.GetFS(), p)
+		if err != nil {
+			return errors.Wrapf(err, "cannot open filesystem for '%s'", p)
+		}
+		obj, err := object.LoadObject(ctx, objFsys, extensionFactory, logger)
+		if err != nil {
+			return errors.Wrapf(err, "cannot open object for '%s'", p)
+		}
+		if err := fn(obj); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
 func GetExtensionParams() []*extension.ExtensionExternalParam {
 	var result = []*extension.ExtensionExternalParam{}
 
@@ -173,6 +354,9 @@ func GetExtensionParams() []*extension.ExtensionExternalParam {
 	result = append(result, ocflextension.GetMetsParams()...)
 	result = append(result, ocflextension.GetContentSubPathParams()...)
 	result = append(result, ocflextension.GetTimestampParams()...)
+	result = append(result, ocflextension.GetSignatureParams()...)
+	result = append(result, ocflextension.GetPremisParams()...)
+	result = append(result, ocflextension.GetVirusScanParams()...)
 
 	return result
 }
@@ -273,7 +457,7 @@ func initializeFSFactory(zipDigests []checksum.DigestAlgorithm, aesConfig *confi
 						string(s3Config.AccessKeyID),
 						string(s3Config.AccessKey),
 						string(s3Config.Endpoint),
-						true,
+						s3Config.UseSSL,
 					},
 				},
 				s3fsrw.ARNRegexStr,
@@ -319,7 +503,32 @@ func showStatus(ctx context.Context, logger zLogger.ZLogger) error {
 	return nil
 }
 
+// countValidationErrors returns the number of errors (not warnings) in the validation status of ctx
+func countValidationErrors(ctx context.Context) int {
+	status, err := validation.GetValidationStatus(ctx)
+	if err != nil {
+		return 0
+	}
+	var errs int
+	for _, err := range status.Errors {
+		if err.Code[0] == 'E' {
+			errs++
+		}
+	}
+	return errs
+}
+
+// addObjectValidationError adds an error, which is not part of the ocfl specification, to the validation status of ctx
+func addObjectValidationError(ctx context.Context, obj object.Object, format string, a ...any) {
+	valError := validation.GetValidationError(obj.GetVersion(), validation.E000).AppendDescription(format, a...).AppendContext("object '%v' - '%s'", obj.GetFS(), obj.GetID())
+	_ = validation.AddValidationErrors(ctx, valError)
+}
+
 func LoadObjectByID(sr storageroot.StorageRoot, extensionFactory *extension.ExtensionFactory, id string, logger zLogger.ZLogger) (object.Object, error) {
+	return loadObjectByID(context.Background(), sr, extensionFactory, id, logger)
+}
+
+func loadObjectByID(ctx context.Context, sr storageroot.StorageRoot, extensionFactory *extension.ExtensionFactory, id string, logger zLogger.ZLogger) (object.Object, error) {
 	folder, err := sr.IdToFolder(id)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot load object %s", id)
@@ -328,7 +537,7 @@ func LoadObjectByID(sr storageroot.StorageRoot, extensionFactory *extension.Exte
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot create subfs for %v / %s", sr.GetFS(), folder)
 	}
-	obj, err := object.LoadObject(context.Background(), fsys, extensionFactory, logger)
+	obj, err := object.LoadObject(ctx, fsys, extensionFactory, logger)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot load object %s", id)
 	}
@@ -345,18 +554,40 @@ func addObjectByPath(
 	sourceFS fs.FS, area string,
 	areaPaths map[string]fs.FS,
 	echo bool,
+	linker *link.Linker,
+	locker *lock.Locker,
+	checker *dedup.Checker,
+	knownDigests object.KnownDigests,
 	logger zLogger.ZLogger,
 ) (bool, error) {
 	if fixity == nil {
 		fixity = []checksum.DigestAlgorithm{}
 	}
+	ctx := progress.NewContext(context.Background(), newProgressReporter(conf.Progress, logger))
+	if locker != nil {
+		// the object locks itself while it is created or updated
+		ctx = lock.NewContext(ctx, locker)
+	}
+	if checker != nil {
+		ctx = dedup.NewContext(ctx, checker)
+	}
+	if linker != nil {
+		folder, err := sr.IdToFolder(id)
+		if err != nil {
+			return false, errors.Wrapf(err, "cannot get folder of %s", id)
+		}
+		ctx = link.NewContext(ctx, linker.ForObject(folder))
+	}
+	if knownDigests != nil {
+		ctx = object.NewKnownDigestsContext(ctx, knownDigests)
+	}
 	var o object.Object
 	exists, err := sr.ObjectExists(flagObjectID)
 	if err != nil {
 		return false, errors.Wrapf(err, "cannot check for existence of %s", id)
 	}
 	if exists {
-		o, err = LoadObjectByID(sr, extensionFactory, id, logger)
+		o, err = loadObjectByID(ctx, sr, extensionFactory, id, logger)
 		if err != nil {
 			return false, errors.Wrapf(err, "cannot load object %s", id)
 		}
@@ -366,11 +597,17 @@ func addObjectByPath(
 			fixity = append(fixity, alg)
 		}
 	} else {
-		o, err = object.CreateObject(context.Background(), id, sr.GetVersion(), sr.GetDigest(), fixity, extensionFactory, extensionManager, sr.GetFS(), logger)
+		o, err = object.CreateObject(ctx, id, sr.GetVersion(), sr.GetDigest(), fixity, extensionFactory, extensionManager, sr.GetFS(), logger)
 		if err != nil {
 			return false, errors.Wrapf(err, "cannot create object %s", id)
 		}
 	}
+	// Close releases the lock of the object, this releases it if the update fails
+	defer func() {
+		if err := o.Unlock(); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot unlock object %s", id)
+		}
+	}()
 	versionFS, err := o.StartUpdate(sourceFS, message, userName, userAddress, echo)
 	if err != nil {
 		return false, errors.Wrapf(err, "cannot start update for object %s", id)
@@ -396,3 +633,246 @@ func addObjectByPath(
 
 	return o.IsModified(), nil
 }
+
+// newDedupChecker returns the checker, which reports content of the object, which is already stored in other
+// objects, during the ingest. it returns nil, if dedup is disabled.
+// zip containers get no stored index, it is built in memory
+func newDedupChecker(sr storageroot.StorageRoot, ocflPath string, id string, locker *lock.Locker, logger zLogger.ZLogger) (*dedup.Checker, error) {
+	if !conf.Dedup.Enabled {
+		return nil, nil
+	}
+	folder, err := sr.IdToFolder(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get folder of '%s'", id)
+	}
+	var idx *dedup.Index
+	if zipRootRegexp.MatchString(ocflPath) {
+		objectFolders, err := sr.GetObjectFolders()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get object folders")
+		}
+		if idx, err = dedup.BuildIndex(sr.GetFS(), objectFolders, logger); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		if idx, err = loadDedupIndex(sr, locker, logger); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return dedup.NewChecker(idx, folder, logger), nil
+}
+
+// loadDedupIndex reads the content digest index of the storage root. without index, it is built
+// from all objects once and stored
+func loadDedupIndex(sr storageroot.StorageRoot, locker *lock.Locker, logger zLogger.ZLogger) (*dedup.Index, error) {
+	unlock, err := lockObject(locker, dedup.ExtensionName, logger)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer unlock()
+	idx, found, err := dedup.LoadIndex(sr.GetFS(), logger)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if found {
+		return idx, nil
+	}
+	logger.Info().Msg("building content digest index of storage root")
+	objectFolders, err := sr.GetObjectFolders()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get object folders")
+	}
+	if _, err := idx.Update(objectFolders); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := idx.Store(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return idx, nil
+}
+
+// checkCrossObjectDuplicates adds the modified object to the content digest index and reports its content,
+// which is already stored in other objects. cross object duplicates are only reported, they must not break the ingest
+func checkCrossObjectDuplicates(sr storageroot.StorageRoot, ocflPath string, id string, modified bool, checker *dedup.Checker, locker *lock.Locker, logger zLogger.ZLogger) {
+	if checker == nil || !modified {
+		return
+	}
+	if err := reportCrossObjectDuplicates(sr, checker, id, conf.Dedup.Report, !zipRootRegexp.MatchString(ocflPath), locker, logger); err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot check cross object duplicates of '%s'", id)
+	}
+}
+
+// reportCrossObjectDuplicates updates the index with the object only and writes the report of its duplicates.
+// the stored index is locked and reloaded for the update, other writers may have changed it during the ingest
+func reportCrossObjectDuplicates(sr storageroot.StorageRoot, checker *dedup.Checker, id string, reportFile string, storeIndex bool, locker *lock.Locker, logger zLogger.ZLogger) error {
+	folder, err := sr.IdToFolder(id)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get folder of '%s'", id)
+	}
+	idx := checker.Index()
+	if storeIndex {
+		unlock, err := lockObject(locker, dedup.ExtensionName, logger)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer unlock()
+		current, found, err := dedup.LoadIndex(sr.GetFS(), logger)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if found {
+			idx = current
+		}
+	}
+	if err := idx.AddObject(folder); err != nil {
+		return errors.WithStack(err)
+	}
+	report := idx.ObjectReport(folder)
+	if len(report.Duplicates) > 0 {
+		logger.Warn().Msgf("object '%s': %d files with %s are also stored in other objects", id, len(report.Duplicates), humanize.Bytes(uint64(report.SavableBytes)))
+	}
+	if reportFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "cannot marshal dedup report")
+		}
+		if err := os.WriteFile(reportFile, data, 0644); err != nil {
+			return errors.Wrapf(err, "cannot write dedup report '%s'", reportFile)
+		}
+	}
+	if storeIndex {
+		if err := idx.Store(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// dryRunObjectByPath runs the ingest pipeline of addObjectByPath on an in-memory inventory without writing anything
+func dryRunObjectByPath(
+	sr storageroot.StorageRoot,
+	fixity []checksum.DigestAlgorithm,
+	extensionFactory *extension.ExtensionFactory,
+	extensionManager object.ExtensionManager,
+	checkDuplicates bool,
+	id, userName, userAddress, message string,
+	sourceFS fs.FS, area string,
+	areaPaths map[string]fs.FS,
+	echo bool,
+	logger zLogger.ZLogger,
+) (*object.DryRunReport, error) {
+	if fixity == nil {
+		fixity = []checksum.DigestAlgorithm{}
+	}
+	folder, err := sr.IdToFolder(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build storage root path for %s", id)
+	}
+	var o object.Object
+	exists, err := sr.ObjectExists(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot check for existence of %s", id)
+	}
+	if exists {
+		o, err = LoadObjectByID(sr, extensionFactory, id, logger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot load object %s", id)
+		}
+	} else {
+		o, err = object.NewDryRunObject(context.Background(), id, sr.GetVersion(), sr.GetDigest(), fixity, extensionFactory, extensionManager, sr.GetFS(), logger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot create object %s", id)
+		}
+	}
+	var sources = map[string]fs.FS{area: sourceFS}
+	for a, aPath := range areaPaths {
+		sources[a] = aPath
+	}
+	report, err := o.DryRun(sources, checkDuplicates, message, userName, userAddress, echo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot simulate update of object %s", id)
+	}
+	report.ObjectPath = folder
+	return report, nil
+}
+
+// bagMetadataArea is the area of the tag files of a BagIt source
+const bagMetadataArea = "metadata"
+
+// bagSource is a validated BagIt bag used as source of add or create
+type bagSource struct {
+	bag       *bagit.Bag
+	payloadFS fs.FS
+	// area of the payload
+	area string
+	// bagInfo is a local copy of bag-info.txt for NNNN-metafile
+	bagInfo string
+}
+
+// openBagSource validates the bag. validation problems are printed, the error contains all of them
+func openBagSource(bagFS fs.FS, srcPath string, logger zLogger.ZLogger) (*bagSource, error) {
+	logger.Info().Msgf("checking bag '%s'", srcPath)
+	bag, err := bagit.Open(bagFS)
+	if err == nil {
+		err = bag.Validate()
+	}
+	if err != nil {
+		fmt.Printf("bag '%s' is not valid:\n", srcPath)
+		for _, e := range errors.GetErrors(err) {
+			fmt.Printf("   - %v\n", e)
+		}
+		return nil, errors.Wrapf(err, "bag '%s' is not valid", srcPath)
+	}
+	bs := &bagSource{bag: bag}
+	if bs.payloadFS, err = bag.PayloadFS(); err != nil {
+		return nil, errors.Wrapf(err, "cannot open payload of '%s'", srcPath)
+	}
+	if data, err := fs.ReadFile(bagFS, bagit.BagInfoFile); err == nil {
+		fp, err := os.CreateTemp("", "gocfl-bag-info-*.txt")
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create temporary file")
+		}
+		bs.bagInfo = fp.Name()
+		_, err = fp.Write(data)
+		if err := errors.Combine(err, fp.Close()); err != nil {
+			bs.Close()
+			return nil, errors.Wrapf(err, "cannot write '%s'", bs.bagInfo)
+		}
+	}
+	return bs, nil
+}
+
+// apply adds the tag files as metadata area and the bag-info.txt as NNNN-metafile parameter.
+// the payload is added to area
+func (bs *bagSource) apply(area string, areaPaths map[string]fs.FS, extensionParams map[string]string) error {
+	if _, ok := areaPaths[bagMetadataArea]; ok || area == bagMetadataArea {
+		return errors.Errorf("area '%s' is used for the tag files of the bag", bagMetadataArea)
+	}
+	areaPaths[bagMetadataArea] = bs.bag.TagFS()
+	bs.area = area
+	if bs.bagInfo != "" {
+		name := fmt.Sprintf("ext-%s-%s", ocflextension.MetaFileName, "bag-info")
+		if extensionParams[name] == "" {
+			extensionParams[name] = bs.bagInfo
+		}
+	}
+	return nil
+}
+
+// knownDigests returns the validated manifest digests of the bag, which are stored as fixity
+func (bs *bagSource) knownDigests(area, path string) map[checksum.DigestAlgorithm]string {
+	switch area {
+	case bs.area:
+		return bs.bag.PayloadDigests(path)
+	case bagMetadataArea:
+		return bs.bag.TagDigests(path)
+	}
+	return nil
+}
+
+func (bs *bagSource) Close() error {
+	if bs.bagInfo == "" {
+		return nil
+	}
+	return errors.WithStack(os.Remove(bs.bagInfo))
+}
@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/internal"
+	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/progress"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/ocfl-archive/gocfl/v2/pkg/subsystem/migration"
+	"github.com/ocfl-archive/gocfl/v2/pkg/subsystem/thumbnail"
+	ironmaiden "github.com/ocfl-archive/indexer/v3/pkg/indexer"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/spf13/cobra"
+	ublogger "gitlab.switch.ch/ub-unibas/go-ublogger/v2"
+	"go.ub.unibas.ch/cloud/certloader/v2/pkg/loader"
+)
+
+var ingestCmd = &cobra.Command{
+	Use:     "ingest [path to ocfl structure] [path to package]",
+	Aliases: []string{},
+	Short:   "ingests E-ARK information package into ocfl object",
+	Long:    "checks an E-ARK information package against its METS files and adds it as new object or new version of an existing object",
+	Example: "gocfl ingest ./archive.zip /tmp/sip --format eark-sip -u 'Jane Doe' -a 'mailto:user@domain'",
+	Args:    cobra.ExactArgs(2),
+	Run:     doIngest,
+}
+
+func initIngest() {
+	ingestCmd.Flags().StringVarP(&flagObjectID, "object-id", "i", "", "object id (default: OBJID of package)")
+	ingestCmd.Flags().String("format", "eark-sip", "package format (eark-sip|eark-aip)")
+	ingestCmd.Flags().String("default-object-extensions", "", "folder with initial extension configurations for new OCFL objects")
+	ingestCmd.Flags().StringP("message", "m", "", "message for new object version (default: package label)")
+	ingestCmd.Flags().StringP("user-name", "u", "", "user name for new object version")
+	ingestCmd.Flags().StringP("user-address", "a", "", "user address for new object version")
+	ingestCmd.Flags().StringP("fixity", "f", "", "comma separated list of digest algorithms for fixity")
+	ingestCmd.Flags().StringP("digest", "d", "", "digest to use for ocfl checksum")
+	ingestCmd.Flags().Bool("no-compress", false, "do not compress data in zip file")
+}
+
+// doIngestConf reads the ingest flags. the settings of new objects are shared with add
+func doIngestConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "format"); str != "" {
+		conf.Ingest.Format = str
+	}
+	if str := getFlagString(cmd, "message"); str != "" {
+		conf.Ingest.Message = str
+	}
+	if str := getFlagString(cmd, "fixity"); str != "" {
+		conf.Add.Fixity = strings.Split(str, ",")
+	}
+	for _, alg := range conf.Add.Fixity {
+		alg = strings.TrimSpace(strings.ToLower(alg))
+		if alg == "" {
+			continue
+		}
+		if _, err := checksum.GetHash(checksum.DigestAlgorithm(alg)); err != nil {
+			_ = cmd.Help()
+			cobra.CheckErr(errors.Errorf("invalid fixity '%s' for flag 'fixity' or 'Add.Fixity' config file entry", conf.Add.Fixity))
+		}
+	}
+	if str := getFlagString(cmd, "user-name"); str != "" {
+		conf.Add.User.Name = str
+	}
+	if str := getFlagString(cmd, "user-address"); str != "" {
+		conf.Add.User.Address = str
+	}
+	if str := getFlagString(cmd, "default-object-extensions"); str != "" {
+		conf.Add.ObjectExtensionFolder = str
+	}
+	if b, ok := getFlagBool(cmd, "no-compress"); ok {
+		conf.Add.NoCompress = b
+	}
+	if str := getFlagString(cmd, "digest"); str != "" {
+		conf.Add.Digest = checksum.DigestAlgorithm(str)
+	}
+	if conf.Add.Digest == "" {
+		conf.Add.Digest = checksum.DigestSHA512
+	}
+	if _, err := checksum.GetHash(conf.Add.Digest); err != nil {
+		_ = cmd.Help()
+		cobra.CheckErr(errors.Errorf("invalid digest '%s' for flag 'digest' or 'Add.Digest' config file entry", conf.Add.Digest))
+	}
+}
+
+// ingestEARK adds the files of the checked package as new object or new version of an existing object
+func ingestEARK(
+	sr storageroot.StorageRoot,
+	fixity []checksum.DigestAlgorithm,
+	extensionFactory *extension.ExtensionFactory,
+	extensionManager object.ExtensionManager,
+	id, userName, userAddress, message string,
+	packageFS fs.FS,
+	files []string,
+	locker *lock.Locker,
+	logger zLogger.ZLogger,
+) error {
+	ctx := progress.NewContext(context.Background(), newProgressReporter(conf.Progress, logger))
+	if locker != nil {
+		// the object locks itself while it is created or updated
+		ctx = lock.NewContext(ctx, locker)
+	}
+	var o object.Object
+	exists, err := sr.ObjectExists(id)
+	if err != nil {
+		return errors.Wrapf(err, "cannot check for existence of %s", id)
+	}
+	if exists {
+		o, err = loadObjectByID(ctx, sr, extensionFactory, id, logger)
+		if err != nil {
+			return errors.Wrapf(err, "cannot load object %s", id)
+		}
+		// if we update, fixity is taken from last object version
+		for alg := range o.GetInventory().GetFixity() {
+			fixity = append(fixity, alg)
+		}
+	} else {
+		o, err = object.CreateObject(ctx, id, sr.GetVersion(), sr.GetDigest(), fixity, extensionFactory, extensionManager, sr.GetFS(), logger)
+		if err != nil {
+			return errors.Wrapf(err, "cannot create object %s", id)
+		}
+	}
+	// Close releases the lock of the object, this releases it if the update fails
+	defer func() {
+		if err := o.Unlock(); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot unlock object %s", id)
+		}
+	}()
+	if _, err := o.StartUpdate(packageFS, message, userName, userAddress, false); err != nil {
+		return errors.Wrapf(err, "cannot start update for object %s", id)
+	}
+	me, err := ocflextension.GetObjectMets(o, logger)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get METS configuration of '%s'", id)
+	}
+	if err := me.ImportEARK(o, packageFS, files); err != nil {
+		return errors.Wrapf(err, "cannot ingest package into '%s'", id)
+	}
+	if err := o.EndUpdate(); err != nil {
+		return errors.Wrapf(err, "cannot end update for object '%s'", id)
+	}
+	if err := o.Close(); err != nil {
+		return errors.Wrapf(err, "cannot close object '%s'", id)
+	}
+	return nil
+}
+
+func doIngest(cmd *cobra.Command, args []string) {
+	// create logger instance
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("cannot get hostname: %v", err)
+	}
+
+	var loggerTLSConfig *tls.Config
+	var loggerLoader io.Closer
+	if conf.Log.Stash.TLS != nil {
+		loggerTLSConfig, loggerLoader, err = loader.CreateClientLoader(conf.Log.Stash.TLS, nil)
+		if err != nil {
+			log.Fatalf("cannot create client loader: %v", err)
+		}
+		defer loggerLoader.Close()
+	}
+
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	_logger, _logstash, _logfile, err := ublogger.CreateUbMultiLoggerTLS(conf.Log.Level, conf.Log.File,
+		ublogger.SetDataset(conf.Log.Stash.Dataset),
+		ublogger.SetLogStash(conf.Log.Stash.LogstashHost, conf.Log.Stash.LogstashPort, conf.Log.Stash.Namespace, conf.Log.Stash.LogstashTraceLevel),
+		ublogger.SetTLS(conf.Log.Stash.TLS != nil),
+		ublogger.SetTLSConfig(loggerTLSConfig),
+	)
+	if err != nil {
+		log.Fatalf("cannot create logger: %v", err)
+	}
+	if _logstash != nil {
+		defer _logstash.Close()
+	}
+
+	if _logfile != nil {
+		defer _logfile.Close()
+	}
+
+	l2 := _logger.With().Timestamp().Str("host", hostname).Logger() //.Output(output)
+	var logger zLogger.ZLogger = &l2
+
+	t := startTimer()
+	defer func() { logger.Info().Msgf("Duration: %s", t.String()) }()
+
+	ocflPath, err := util.Fullpath(args[0])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+	packagePath, err := util.Fullpath(args[1])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+
+	doIngestConf(cmd)
+
+	var packageType string
+	switch strings.ToLower(conf.Ingest.Format) {
+	case "eark-sip":
+		packageType = ocflextension.EARKPackageTypeSIP
+	case "eark-aip":
+		packageType = ocflextension.EARKPackageTypeAIP
+	default:
+		cmd.Help()
+		cobra.CheckErr(errors.Errorf("invalid format '%s' for flag 'format' or 'Format' config file entry", conf.Ingest.Format))
+		return
+	}
+
+	var fixityAlgs = []checksum.DigestAlgorithm{}
+	for _, alg := range conf.Add.Fixity {
+		alg = strings.TrimSpace(strings.ToLower(alg))
+		if alg == "" {
+			continue
+		}
+		fixityAlgs = append(fixityAlgs, checksum.DigestAlgorithm(alg))
+	}
+
+	fsFactory, err := initializeFSFactory([]checksum.DigestAlgorithm{conf.Add.Digest}, nil, nil, conf.Add.NoCompress, false, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot create filesystem factory")
+		exitCode = 1
+		return
+	}
+
+	packageFS, err := fsFactory.Get(packagePath, true)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", packagePath)
+		exitCode = 1
+		return
+	}
+	defer func() {
+		if err := writefs.Close(packageFS); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot close filesystem for '%s'", packageFS)
+		}
+	}()
+
+	logger.Info().Msgf("checking package '%s'", packagePath)
+	m, files, err := ocflextension.ReadEARK(packageFS)
+	if err == nil && string(m.MetsHdr.CSIPOAISPACKAGETYPEAttr) != packageType {
+		err = errors.Errorf("package type is '%s', not '%s'", m.MetsHdr.CSIPOAISPACKAGETYPEAttr, packageType)
+	}
+	if err != nil {
+		fmt.Printf("package '%s' is not valid:\n", packagePath)
+		for _, e := range errors.GetErrors(err) {
+			fmt.Printf("   - %v\n", e)
+		}
+		logger.Error().Err(err).Msgf("package '%s' is not valid", packagePath)
+		exitCode = 1
+		return
+	}
+
+	id := flagObjectID
+	if id == "" {
+		id = m.OBJIDAttr
+	}
+	message := conf.Ingest.Message
+	if message == "" {
+		message = m.LABELAttr
+	}
+	if message == "" {
+		message = fmt.Sprintf("ingest of E-ARK %s '%s'", packageType, m.OBJIDAttr)
+	}
+
+	destFS, err := fsFactory.Get(ocflPath, false)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
+		exitCode = 1
+		return
+	}
+	var doNotClose = false
+	defer func() {
+		if doNotClose {
+			logger.Panic().Msgf("filesystem '%s' not closed", destFS)
+		} else {
+			if err := writefs.Close(destFS); err != nil {
+				logger.Panic().Stack().Msgf("error closing filesystem '%s'", destFS)
+			}
+		}
+	}()
+
+	var fss = map[string]fs.FS{"internal": internal.InternalFS}
+	indexerActions, err := ironmaiden.InitActionDispatcher(fss, *conf.Indexer, logger)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Err(err).Msg("cannot init indexer")
+	}
+	mig, err := migration.GetMigrations(conf)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Msg("cannot get migrations")
+	}
+	mig.SetSourceFS(packageFS)
+	thumb, err := thumbnail.GetThumbnails(conf)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Err(err).Msg("cannot get thumbnails")
+	}
+	thumb.SetSourceFS(packageFS)
+
+	extensionParams := GetExtensionParamValues(cmd, conf)
+	extensionFactory, err := InitExtensionFactory(extensionParams, "", false, indexerActions, mig, thumb, packageFS, logger)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Err(err).Msg("cannot initialize extension factory")
+	}
+	_, objectExtensionManager, err := initDefaultExtensions(extensionFactory, "", conf.Add.ObjectExtensionFolder, logger)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Msg("cannot initialize default extensions")
+	}
+
+	ctx := validation.NewContextValidation(context.TODO())
+	storageRoot, err := storageroot.LoadStorageRoot(ctx, destFS, extensionFactory, logger)
+	if err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Err(err).Msg("cannot open storage root")
+	}
+	if storageRoot.GetDigest() == "" {
+		storageRoot.SetDigest(conf.Add.Digest)
+	} else if storageRoot.GetDigest() != conf.Add.Digest {
+		doNotClose = true
+		logger.Panic().Msgf("storageroot already uses digest '%s' not '%s'", storageRoot.GetDigest(), conf.Add.Digest)
+	}
+
+	logger.Info().Msgf("ingesting '%s' as '%s'", packagePath, id)
+	locker, err := newLocker(ocflPath, storageRoot.GetFS(), conf.Add.User.Name, logger)
+	if err != nil {
+		logger.Panic().Stack().Err(err).Msg("cannot initialize object locks")
+	}
+	if err := ingestEARK(storageRoot, fixityAlgs, extensionFactory, objectExtensionManager, id, conf.Add.User.Name, conf.Add.User.Address, message, packageFS, files, locker, logger); err != nil {
+		doNotClose = true
+		logger.Panic().Stack().Err(err).Msgf("error ingesting '%s' into storageroot filesystem '%s'", packagePath, destFS)
+	}
+	fmt.Printf("ingest of '%s' as '%s' done without errors\n", packagePath, id)
+	_ = showStatus(ctx, logger)
+}
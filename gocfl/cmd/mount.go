@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/gocfl/cmd/mount"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/spf13/cobra"
+	ublogger "gitlab.switch.ch/ub-unibas/go-ublogger/v2"
+	"go.ub.unibas.ch/cloud/certloader/v2/pkg/loader"
+)
+
+var mountCmd = &cobra.Command{
+	Use:     "mount [path to ocfl structure] [mountpoint]",
+	Aliases: []string{},
+	Short:   "mounts the logical view of an ocfl structure read-only",
+	Long: "mounts the versions of all objects as read-only folders with the logical filenames (fuse).\n" +
+		"content is read from the storage root, also from zip containers, and verified on first read",
+	Example: "gocfl mount ./archive.zip /mnt/archive",
+	Args:    cobra.MinimumNArgs(2),
+	Run:     doMount,
+}
+
+func initMount() {
+	mountCmd.Flags().String("area", "", "data area to show (default \"content\")")
+	mountCmd.Flags().Bool("allow-other", false, "allow access for other users")
+}
+
+func doMountConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "area"); str != "" {
+		conf.Mount.Area = str
+	}
+	if b, ok := getFlagBool(cmd, "allow-other"); ok {
+		conf.Mount.AllowOther = b
+	}
+	if conf.Mount.Area == "" {
+		conf.Mount.Area = "content"
+	}
+}
+
+func doMount(cmd *cobra.Command, args []string) {
+	ocflPath, err := util.Fullpath(args[0])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+	mountpoint, err := util.Fullpath(args[1])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+
+	// create logger instance
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("cannot get hostname: %v", err)
+	}
+
+	var loggerTLSConfig *tls.Config
+	var loggerLoader io.Closer
+	if conf.Log.Stash.TLS != nil {
+		loggerTLSConfig, loggerLoader, err = loader.CreateClientLoader(conf.Log.Stash.TLS, nil)
+		if err != nil {
+			log.Fatalf("cannot create client loader: %v", err)
+		}
+		defer loggerLoader.Close()
+	}
+
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	_logger, _logstash, _logfile, err := ublogger.CreateUbMultiLoggerTLS(conf.Log.Level, conf.Log.File,
+		ublogger.SetDataset(conf.Log.Stash.Dataset),
+		ublogger.SetLogStash(conf.Log.Stash.LogstashHost, conf.Log.Stash.LogstashPort, conf.Log.Stash.Namespace, conf.Log.Stash.LogstashTraceLevel),
+		ublogger.SetTLS(conf.Log.Stash.TLS != nil),
+		ublogger.SetTLSConfig(loggerTLSConfig),
+	)
+	if err != nil {
+		log.Fatalf("cannot create logger: %v", err)
+	}
+	if _logstash != nil {
+		defer _logstash.Close()
+	}
+
+	if _logfile != nil {
+		defer _logfile.Close()
+	}
+
+	l2 := _logger.With().Timestamp().Str("host", hostname).Logger() //.Output(output)
+	var logger zLogger.ZLogger = &l2
+
+	doMountConf(cmd)
+
+	logger.Info().Msgf("opening '%s'", ocflPath)
+
+	fsFactory, err := initializeFSFactory(nil, nil, nil, true, true, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot create filesystem factory")
+		return
+	}
+
+	ocflFS, err := fsFactory.Get(ocflPath, true)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
+		return
+	}
+	defer func() {
+		if err := writefs.Close(ocflFS); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot close filesystem for '%s'", ocflFS)
+		}
+	}()
+
+	extensionParams := GetExtensionParamValues(cmd, conf)
+	extensionFactory, err := InitExtensionFactory(extensionParams, "", false, nil, nil, nil, nil, (logger))
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot initialize extension factory")
+		return
+	}
+
+	ctx := validation.NewContextValidation(context.TODO())
+	storageRoot, err := storageroot.LoadStorageRoot(ctx, ocflFS, extensionFactory, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot load storage root")
+		return
+	}
+
+	mfs := mount.NewFS(storageRoot, extensionFactory, conf.Mount.Area, logger)
+	server, err := mfs.Mount(mountpoint, conf.Mount.AllowOther)
+	if err != nil {
+		fmt.Printf("cannot mount '%s': %v\n", mountpoint, err)
+		logger.Error().Stack().Err(err).Msgf("cannot mount '%s'", mountpoint)
+		return
+	}
+	fmt.Printf("'%s' mounted at '%s' - press ctrl+c to unmount\n", ocflPath, mountpoint)
+
+	go func() {
+		sigint := make(chan os.Signal, 1)
+		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
+		<-sigint
+		logger.Info().Msg("interrupt signal received")
+		if err := server.Unmount(); err != nil {
+			logger.Error().Err(err).Msgf("cannot unmount '%s'", mountpoint)
+		}
+	}()
+
+	server.Wait()
+	logger.Info().Msgf("'%s' unmounted", mountpoint)
+}
@@ -0,0 +1,319 @@
+//go:build linux || darwin
+
+package mount
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/logical"
+)
+
+const headLink = "head"
+
+// Mount mounts the storage root read-only at mountpoint
+func (f *FS) Mount(mountpoint string, allowOther bool) (Server, error) {
+	timeout := time.Minute
+	server, err := fs.Mount(mountpoint, &rootNode{fs: f}, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			AllowOther: allowOther,
+			FsName:     "gocfl",
+			Name:       "gocfl",
+			Options:    []string{"ro"},
+		},
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+func setDirAttr(out *fuse.Attr, modTime time.Time) {
+	out.Mode = fuse.S_IFDIR | 0555
+	out.SetTimes(nil, &modTime, &modTime)
+}
+
+func setLinkAttr(out *fuse.Attr, target []byte) {
+	out.Mode = fuse.S_IFLNK | 0777
+	out.Size = uint64(len(target))
+}
+
+// rootNode lists the objects of the storage root
+type rootNode struct {
+	fs.Inode
+	fs *FS
+}
+
+func (n *rootNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	ids, err := n.fs.ObjectIDs()
+	if err != nil {
+		n.fs.logger.Error().Err(err).Msg("cannot list objects")
+		return nil, syscall.EIO
+	}
+	var entries = []fuse.DirEntry{}
+	for _, id := range ids {
+		entries = append(entries, fuse.DirEntry{Name: url.PathEscape(id), Mode: fuse.S_IFDIR})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return fs.NewListDirStream(entries), fs.OK
+}
+
+func (n *rootNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if child := n.GetChild(name); child != nil {
+		return child, fs.OK
+	}
+	id, err := url.PathUnescape(name)
+	if err != nil {
+		return nil, syscall.ENOENT
+	}
+	node := &objectNode{fs: n.fs, id: id}
+	o, errno := node.object()
+	if errno != fs.OK {
+		return nil, errno
+	}
+	setDirAttr(&out.Attr, modTime(o))
+	return n.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFDIR}), fs.OK
+}
+
+func modTime(o *logical.CachedObject) time.Time {
+	inv := o.Object.GetInventory()
+	if v, ok := inv.GetVersions()[inv.GetHead()]; ok && v.Created != nil {
+		return v.Created.Time
+	}
+	return time.Time{}
+}
+
+// objectNode lists the versions of an object and the head symlink.
+// the object is taken from the cache on every access to show new versions
+type objectNode struct {
+	fs.Inode
+	fs *FS
+	id string
+}
+
+func (n *objectNode) object() (*logical.CachedObject, syscall.Errno) {
+	o, err := n.fs.LoadObject(n.id)
+	if err != nil {
+		n.fs.logger.Debug().Err(err).Msgf("cannot load object '%s'", n.id)
+		return nil, syscall.ENOENT
+	}
+	return o, fs.OK
+}
+
+func (n *objectNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	o, errno := n.object()
+	if errno != fs.OK {
+		return errno
+	}
+	setDirAttr(&out.Attr, modTime(o))
+	return fs.OK
+}
+
+func (n *objectNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	o, errno := n.object()
+	if errno != fs.OK {
+		return nil, errno
+	}
+	var entries = []fuse.DirEntry{}
+	for _, ver := range o.Object.GetInventory().GetVersionStrings() {
+		entries = append(entries, fuse.DirEntry{Name: ver, Mode: fuse.S_IFDIR})
+	}
+	entries = append(entries, fuse.DirEntry{Name: headLink, Mode: fuse.S_IFLNK})
+	return fs.NewListDirStream(entries), fs.OK
+}
+
+func (n *objectNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	// versions are immutable, the head link resolves the current version itself
+	if child := n.GetChild(name); child != nil {
+		return child, fs.OK
+	}
+	if name == headLink {
+		link := &headNode{object: n}
+		target, errno := link.Readlink(ctx)
+		if errno != fs.OK {
+			return nil, errno
+		}
+		setLinkAttr(&out.Attr, target)
+		return n.NewInode(ctx, link, fs.StableAttr{Mode: fuse.S_IFLNK}), fs.OK
+	}
+	o, errno := n.object()
+	if errno != fs.OK {
+		return nil, errno
+	}
+	if _, ok := o.Object.GetInventory().GetVersions()[name]; !ok {
+		return nil, syscall.ENOENT
+	}
+	tree, err := n.fs.Tree(o, name)
+	if err != nil {
+		n.fs.logger.Error().Err(err).Msgf("cannot build logical view of %s version %s", n.id, name)
+		return nil, syscall.EIO
+	}
+	setDirAttr(&out.Attr, tree.ModTime)
+	return n.NewInode(ctx, &dirNode{fs: n.fs, object: o, tree: tree}, fs.StableAttr{Mode: fuse.S_IFDIR}), fs.OK
+}
+
+// headNode is the symlink to the newest version of an object
+type headNode struct {
+	fs.Inode
+	object *objectNode
+}
+
+func (n *headNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	head, err := n.object.fs.Head(n.object.id)
+	if err != nil {
+		n.object.fs.logger.Debug().Err(err).Msgf("cannot load object '%s'", n.object.id)
+		return nil, syscall.ENOENT
+	}
+	return []byte(head), fs.OK
+}
+
+func (n *headNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	target, errno := n.Readlink(ctx)
+	if errno != fs.OK {
+		return errno
+	}
+	setLinkAttr(&out.Attr, target)
+	return fs.OK
+}
+
+// dirNode is a folder of the logical view of a version
+type dirNode struct {
+	fs.Inode
+	fs     *FS
+	object *logical.CachedObject
+	tree   *logical.Tree
+	path   string
+}
+
+func (n *dirNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	setDirAttr(&out.Attr, n.tree.ModTime)
+	return fs.OK
+}
+
+func (n *dirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	names, ok := n.tree.Children(n.path)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	var entries = []fuse.DirEntry{}
+	for _, name := range names {
+		mode := uint32(fuse.S_IFDIR)
+		if _, ok := n.tree.Files[path.Join(n.path, name)]; ok {
+			mode = fuse.S_IFREG
+		}
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: mode})
+	}
+	return fs.NewListDirStream(entries), fs.OK
+}
+
+func (n *dirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if child := n.GetChild(name); child != nil {
+		return child, fs.OK
+	}
+	p := path.Join(n.path, name)
+	if file, ok := n.tree.Files[p]; ok {
+		node := &fileNode{fs: n.fs, object: n.object, file: file, modTime: n.tree.ModTime}
+		node.size = n.fs.Size(n.object, file)
+		node.setAttr(&out.Attr)
+		return n.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFREG}), fs.OK
+	}
+	if _, ok := n.tree.Dirs[p]; ok {
+		setDirAttr(&out.Attr, n.tree.ModTime)
+		return n.NewInode(ctx, &dirNode{fs: n.fs, object: n.object, tree: n.tree, path: p}, fs.StableAttr{Mode: fuse.S_IFDIR}), fs.OK
+	}
+	return nil, syscall.ENOENT
+}
+
+// fileNode is a content file. the digest is verified on first open
+type fileNode struct {
+	fs.Inode
+	fs      *FS
+	object  *logical.CachedObject
+	file    *logical.File
+	size    int64
+	modTime time.Time
+}
+
+func (n *fileNode) setAttr(out *fuse.Attr) {
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(n.size)
+	out.SetTimes(nil, &n.modTime, &n.modTime)
+}
+
+func (n *fileNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	n.setAttr(&out.Attr)
+	return fs.OK
+}
+
+func (n *fileNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_APPEND|syscall.O_TRUNC) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	if err := n.fs.Verify(n.object, n.file); err != nil {
+		n.fs.logger.Error().Err(err).Msgf("cannot verify %s '%s'", n.object.Object.GetID(), n.file.Internal)
+		return nil, 0, syscall.EIO
+	}
+	rsc, err := n.fs.Open(n.object, n.file)
+	if err != nil {
+		n.fs.logger.Error().Err(err).Msgf("cannot open %s '%s'", n.object.Object.GetID(), n.file.Internal)
+		return nil, 0, syscall.EIO
+	}
+	return &fileHandle{file: rsc}, fuse.FOPEN_KEEP_CACHE, fs.OK
+}
+
+type fileHandle struct {
+	lock sync.Mutex
+	file io.ReadSeekCloser
+}
+
+func (h *fileHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, err := h.file.Seek(off, io.SeekStart); err != nil {
+		return nil, syscall.EIO
+	}
+	n, err := io.ReadFull(h.file, dest)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, syscall.EIO
+	}
+	return fuse.ReadResultData(dest[:n]), fs.OK
+}
+
+func (h *fileHandle) Release(ctx context.Context) syscall.Errno {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if err := h.file.Close(); err != nil {
+		return syscall.EIO
+	}
+	return fs.OK
+}
+
+// check interface satisfaction
+var (
+	_ fs.NodeReaddirer  = &rootNode{}
+	_ fs.NodeLookuper   = &rootNode{}
+	_ fs.NodeGetattrer  = &objectNode{}
+	_ fs.NodeReaddirer  = &objectNode{}
+	_ fs.NodeLookuper   = &objectNode{}
+	_ fs.NodeReadlinker = &headNode{}
+	_ fs.NodeGetattrer  = &headNode{}
+	_ fs.NodeGetattrer  = &dirNode{}
+	_ fs.NodeReaddirer  = &dirNode{}
+	_ fs.NodeLookuper   = &dirNode{}
+	_ fs.NodeGetattrer  = &fileNode{}
+	_ fs.NodeOpener     = &fileNode{}
+	_ fs.FileReader     = &fileHandle{}
+	_ fs.FileReleaser   = &fileHandle{}
+)
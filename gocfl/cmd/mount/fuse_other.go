@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package mount
+
+import (
+	"emperror.dev/errors"
+)
+
+// Mount is not supported without fuse
+func (f *FS) Mount(mountpoint string, allowOther bool) (Server, error) {
+	return nil, errors.New("mount is only supported on linux and macos")
+}
@@ -0,0 +1,151 @@
+package mount
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/logical"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+)
+
+// Server is a mounted filesystem
+type Server interface {
+	Unmount() error
+	Wait()
+}
+
+const (
+	objectCacheSize = 32
+	// objectRefresh is the interval after which the root inventory of a cached object is checked for new versions
+	objectRefresh = 5 * time.Second
+)
+
+// FS is the read-only logical view of a storage root: /<escaped object id>/<version>/<logical path>
+// and a symlink head to the newest version of every object
+type FS struct {
+	storageRoot storageroot.StorageRoot
+	area        string
+	logger      zLogger.ZLogger
+	objects     *logical.ObjectCache
+	verifyLock  sync.Mutex
+	verified    map[string]bool
+}
+
+func NewFS(storageRoot storageroot.StorageRoot, extensionFactory *extension.ExtensionFactory, area string, logger zLogger.ZLogger) *FS {
+	return &FS{
+		storageRoot: storageRoot,
+		area:        area,
+		logger:      logger,
+		objects:     logical.NewObjectCache(storageRoot, extensionFactory, objectCacheSize, objectRefresh, false, logger),
+		verified:    map[string]bool{},
+	}
+}
+
+// ObjectIDs returns the ids of all objects of the storage root
+func (f *FS) ObjectIDs() ([]string, error) {
+	folders, err := f.storageRoot.GetObjectFolders()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get object folders")
+	}
+	var ids = []string{}
+	for _, folder := range folders {
+		// only the id of the root inventory is needed
+		name := path.Join(folder, "inventory.json")
+		data, err := fs.ReadFile(f.storageRoot.GetFS(), name)
+		if err != nil {
+			f.logger.Warn().Err(err).Msgf("cannot read '%s'", name)
+			continue
+		}
+		var inv = struct {
+			ID string `json:"id"`
+		}{}
+		if err := json.Unmarshal(data, &inv); err != nil {
+			f.logger.Warn().Err(err).Msgf("cannot unmarshal '%s'", name)
+			continue
+		}
+		ids = append(ids, inv.ID)
+	}
+	return ids, nil
+}
+
+// LoadObject returns the current state of an object. a cached object is reloaded if its root inventory has changed
+func (f *FS) LoadObject(id string) (*logical.CachedObject, error) {
+	o, err := f.objects.Get(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return o, nil
+}
+
+// Head returns the newest version of an object, which is the target of its head symlink
+func (f *FS) Head(id string) (string, error) {
+	o, err := f.LoadObject(id)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return o.Object.GetInventory().GetHead(), nil
+}
+
+// Tree returns the logical view of a version
+func (f *FS) Tree(o *logical.CachedObject, version string) (*logical.Tree, error) {
+	return o.Tree(version, f.area)
+}
+
+// Verify checks the digest of a content file on first access
+func (f *FS) Verify(o *logical.CachedObject, file *logical.File) error {
+	key := o.Object.GetID() + "/" + file.Digest
+	f.verifyLock.Lock()
+	ok := f.verified[key]
+	f.verifyLock.Unlock()
+	if ok {
+		return nil
+	}
+	digestAlg := o.Object.GetInventory().GetDigestAlgorithm()
+	fp, err := o.Object.GetFS().Open(file.Internal)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open '%s'", file.Internal)
+	}
+	defer fp.Close()
+	digests, err := checksum.Copy([]checksum.DigestAlgorithm{digestAlg}, fp, io.Discard)
+	if err != nil {
+		return errors.Wrapf(err, "cannot read '%s'", file.Internal)
+	}
+	if digests[digestAlg] != file.Digest {
+		return errors.Errorf("invalid digest for '%s' - [%s] != [%s]", file.Internal, digests[digestAlg], file.Digest)
+	}
+	f.verifyLock.Lock()
+	f.verified[key] = true
+	f.verifyLock.Unlock()
+	return nil
+}
+
+// Open opens a content file. files in zip or encrypted containers are made seekable
+func (f *FS) Open(o *logical.CachedObject, file *logical.File) (io.ReadSeekCloser, error) {
+	fp, err := logical.OpenSeekable(o.Object.GetFS(), file.Internal)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open '%s'", file.Internal)
+	}
+	rsc, ok := fp.(io.ReadSeekCloser)
+	if !ok {
+		fp.Close()
+		return nil, errors.Errorf("'%s' is not seekable", file.Internal)
+	}
+	return rsc, nil
+}
+
+// Size returns the size of a content file
+func (f *FS) Size(o *logical.CachedObject, file *logical.File) int64 {
+	fi, err := fs.Stat(o.Object.GetFS(), file.Internal)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
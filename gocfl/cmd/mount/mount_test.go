@@ -0,0 +1,242 @@
+package mount
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/osfsrw"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/checksum"
+	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/logical"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/version"
+	"github.com/rs/zerolog"
+)
+
+// testRoot is a storage root with flat direct layout in a temporary folder
+type testRoot struct {
+	t       *testing.T
+	dir     string
+	sr      storageroot.StorageRoot
+	factory *extension.ExtensionFactory
+}
+
+func newExtensionManager(t *testing.T, exts ...extension.Extension) *ocflextension.GOCFLExtensionManager {
+	manager, err := ocflextension.NewGOCFLExtensionManager(&extension.ExtensionManagerConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: ocflextension.GOCFLExtensionManagerName},
+		Sort:            map[string][]string{},
+		Exclusion:       map[string][][]string{},
+	})
+	if err != nil {
+		t.Fatalf("cannot create extension manager: %v", err)
+	}
+	initial, err := ocflextension.NewInitialFS(nil)
+	if err != nil {
+		t.Fatalf("cannot create initial extension: %v", err)
+	}
+	manager.SetInitial(initial)
+	for _, ext := range exts {
+		if err := manager.Add(ext); err != nil {
+			t.Fatalf("cannot add extension '%s': %v", ext.GetName(), err)
+		}
+	}
+	manager.Finalize()
+	return manager
+}
+
+func newTestRoot(t *testing.T) *testRoot {
+	logger := zerolog.Nop()
+	dir := t.TempDir()
+	fsys, err := osfsrw.NewFS(dir, true, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	factory, err := extension.NewExtensionFactory(map[string]string{}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create extension factory: %v", err)
+	}
+	factory.AddCreator(ocflextension.InitialName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewInitialFS(fsys)
+	})
+	factory.AddCreator(ocflextension.GOCFLExtensionManagerName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewGOCFLExtensionManagerFS(fsys)
+	})
+	factory.AddCreator(ocflextension.StorageLayoutFlatDirectName, func(fsys fs.FS) (extension.Extension, error) {
+		return ocflextension.NewStorageLayoutFlatDirectFS(fsys)
+	})
+	layout, err := ocflextension.NewStorageLayoutFlatDirect(&ocflextension.StorageLayoutFlatDirectConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: ocflextension.StorageLayoutFlatDirectName},
+	})
+	if err != nil {
+		t.Fatalf("cannot create storage layout: %v", err)
+	}
+	ctx := validation.NewContextValidation(context.Background())
+	sr, err := storageroot.CreateStorageRoot(ctx, fsys, version.Version1_1, factory, newExtensionManager(t, layout), checksum.DigestSHA512, &logger)
+	if err != nil {
+		t.Fatalf("cannot create storage root: %v", err)
+	}
+	return &testRoot{t: t, dir: dir, sr: sr, factory: factory}
+}
+
+// addVersion creates the object or adds a version with the files
+func (tr *testRoot) addVersion(id string, files map[string]string) {
+	t := tr.t
+	logger := zerolog.Nop()
+	ctx := validation.NewContextValidation(context.Background())
+	folder, err := tr.sr.IdToFolder(id)
+	if err != nil {
+		t.Fatalf("cannot get folder of %s: %v", id, err)
+	}
+	objFS, err := writefs.SubFSCreate(tr.sr.GetFS(), folder)
+	if err != nil {
+		t.Fatalf("cannot create object filesystem: %v", err)
+	}
+	var obj object.Object
+	exists, err := tr.sr.ObjectExists(id)
+	if err != nil {
+		t.Fatalf("cannot check for object %s: %v", id, err)
+	}
+	if exists {
+		obj, err = object.LoadObject(ctx, objFS, tr.factory, &logger)
+	} else {
+		obj, err = object.CreateObject(ctx, id, version.Version1_1, checksum.DigestSHA512, nil, tr.factory, newExtensionManager(t), objFS, &logger)
+	}
+	if err != nil {
+		t.Fatalf("cannot open object %s: %v", id, err)
+	}
+	if _, err := obj.StartUpdate(nil, "test", "test", "test", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	for name, content := range files {
+		if err := obj.AddData([]byte(content), name, false, "content", false, false); err != nil {
+			t.Fatalf("cannot add '%s': %v", name, err)
+		}
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+}
+
+// newTestFS returns the mount filesystem, which checks the root inventory of cached objects on every access
+func newTestFS(tr *testRoot) *FS {
+	logger := zerolog.Nop()
+	f := NewFS(tr.sr, tr.factory, "content", &logger)
+	f.objects = logical.NewObjectCache(tr.sr, tr.factory, objectCacheSize, 0, false, &logger)
+	return f
+}
+
+func TestFSHeadReload(t *testing.T) {
+	tr := newTestRoot(t)
+	tr.addVersion("test01", map[string]string{"a.txt": "version 1"})
+	f := newTestFS(tr)
+
+	ids, err := f.ObjectIDs()
+	if err != nil {
+		t.Fatalf("cannot get object ids: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "test01" {
+		t.Errorf("unexpected object ids %v", ids)
+	}
+	head, err := f.Head("test01")
+	if err != nil {
+		t.Fatalf("cannot get head: %v", err)
+	}
+	if head != "v1" {
+		t.Errorf("head %s, expected v1", head)
+	}
+	if _, err := f.Head("unknown"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("head of unknown object should not exist: %v", err)
+	}
+
+	// a new version is visible without remount
+	tr.addVersion("test01", map[string]string{"b.txt": "version 2"})
+	if head, err = f.Head("test01"); err != nil || head != "v2" {
+		t.Fatalf("head %s after update, expected v2: %v", head, err)
+	}
+	o, err := f.LoadObject("test01")
+	if err != nil {
+		t.Fatalf("cannot load object: %v", err)
+	}
+	tree, err := f.Tree(o, "v2")
+	if err != nil {
+		t.Fatalf("cannot build tree of v2: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, ok := tree.Files[name]; !ok {
+			t.Errorf("'%s' missing in v2", name)
+		}
+	}
+	tree, err = f.Tree(o, "v1")
+	if err != nil {
+		t.Fatalf("cannot build tree of v1: %v", err)
+	}
+	if _, ok := tree.Files["b.txt"]; ok {
+		t.Errorf("'b.txt' of v2 found in v1")
+	}
+}
+
+func TestFSVerify(t *testing.T) {
+	tr := newTestRoot(t)
+	tr.addVersion("test01", map[string]string{"a.txt": "content a", "b.txt": "content b"})
+	f := newTestFS(tr)
+
+	o, err := f.LoadObject("test01")
+	if err != nil {
+		t.Fatalf("cannot load object: %v", err)
+	}
+	tree, err := f.Tree(o, "v1")
+	if err != nil {
+		t.Fatalf("cannot build tree: %v", err)
+	}
+	fileA, fileB := tree.Files["a.txt"], tree.Files["b.txt"]
+	if fileA == nil || fileB == nil {
+		t.Fatalf("files missing in tree %v", tree.Files)
+	}
+	if err := f.Verify(o, fileA); err != nil {
+		t.Fatalf("cannot verify 'a.txt': %v", err)
+	}
+	if size := f.Size(o, fileA); size != int64(len("content a")) {
+		t.Errorf("size of 'a.txt' %d", size)
+	}
+	rsc, err := f.Open(o, fileA)
+	if err != nil {
+		t.Fatalf("cannot open 'a.txt': %v", err)
+	}
+	if _, err := rsc.Seek(8, io.SeekStart); err != nil {
+		t.Fatalf("cannot seek: %v", err)
+	}
+	data, err := io.ReadAll(rsc)
+	rsc.Close()
+	if err != nil || string(data) != "a" {
+		t.Errorf("read '%s' at offset 8: %v", string(data), err)
+	}
+
+	// a changed content file fails the verification
+	folder, err := tr.sr.IdToFolder("test01")
+	if err != nil {
+		t.Fatalf("cannot get object folder: %v", err)
+	}
+	name := filepath.Join(tr.dir, filepath.FromSlash(folder), filepath.FromSlash(fileB.Internal))
+	if err := os.WriteFile(name, []byte("changed"), 0644); err != nil {
+		t.Fatalf("cannot change '%s': %v", name, err)
+	}
+	if err := f.Verify(o, fileB); err == nil {
+		t.Errorf("changed content of 'b.txt' verified")
+	}
+	// verified files are not read again
+	if err := f.Verify(o, fileA); err != nil {
+		t.Errorf("verified file checked again: %v", err)
+	}
+}
@@ -88,6 +88,7 @@ var persistentFlagErrorConfig string
 
 var persistentFlagLogfile string
 var persistentFlagLoglevel string
+var persistentFlagProgress string
 
 var persistenFlagS3Endpoint string
 var persistenFlagS3AccessKeyID string
@@ -169,6 +170,9 @@ func initConfig() {
 	if persistentFlagLoglevel != "" {
 		conf.Log.Level = persistentFlagLoglevel
 	}
+	if persistentFlagProgress != "" {
+		conf.Progress = persistentFlagProgress
+	}
 	if persistenFlagS3Endpoint != "" {
 		conf.S3.Endpoint = configutil.EnvString(persistenFlagS3Endpoint)
 	}
@@ -227,6 +231,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&persistentFlagErrorConfig, "error-config", "", "error config file (default is embedded)")
 	rootCmd.PersistentFlags().StringVar(&persistentFlagLogfile, "log-file", "", "log output file (default is console)")
 	rootCmd.PersistentFlags().StringVar(&persistentFlagLoglevel, "log-level", "", "log level (CRITICAL|ERROR|WARNING|NOTICE|INFO|DEBUG)")
+	rootCmd.PersistentFlags().StringVar(&persistentFlagProgress, "progress", "", "progress reporting (auto|bar|log|none)")
 	rootCmd.PersistentFlags().StringVar(&persistenFlagS3Endpoint, "s3-endpoint", "", "Endpoint for S3 Buckets")
 	rootCmd.PersistentFlags().StringVar(&persistenFlagS3AccessKeyID, "s3-access-key-id", "", "Access Key ID for S3 Buckets")
 	rootCmd.PersistentFlags().StringVar(&persistenFlagS3SecretAccessKey, "s3-secret-access-key", "", "Secret Access Key for S3 Buckets")
@@ -240,15 +245,28 @@ func init() {
 	initStat()
 	initExtract()
 	initExtractMeta()
+	initExport()
+	initIngest()
 	initDisplay()
-
-	setExtensionFlags(validateCmd, initCmd, createCmd, addCmd, updateCmd, statCmd, extractCmd, extractMetaCmd, displayCmd)
-	rootCmd.AddCommand(validateCmd, initCmd, createCmd, addCmd, updateCmd, statCmd, extractCmd, extractMetaCmd, displayCmd)
+	initUnlock()
+	initDedupReport()
+	initMount()
+	initVerifyTimestamps()
+	initTimestampFlush()
+
+	setExtensionFlags(validateCmd, initCmd, createCmd, addCmd, updateCmd, statCmd, extractCmd, extractMetaCmd, exportCmd, ingestCmd, displayCmd, mountCmd, verifyTimestampsCmd, timestampFlushCmd)
+	rootCmd.AddCommand(validateCmd, initCmd, createCmd, addCmd, updateCmd, statCmd, extractCmd, extractMetaCmd, exportCmd, ingestCmd, displayCmd, unlockCmd, dedupReportCmd, mountCmd, verifyTimestampsCmd, timestampFlushCmd)
 }
 
+// exitCode is set by commands, which finished with an invalid result
+var exitCode int
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
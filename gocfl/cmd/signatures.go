@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"emperror.dev/errors"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+)
+
+// loadSignatureTrust reads the trusted certificates and ed25519 public keys of the pem files and folders.
+// without certificates and keys no signer is trusted
+func loadSignatureTrust(paths []string) (*x509.CertPool, []ed25519.PublicKey, error) {
+	var files = []string{}
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot stat '%s'", p)
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot read folder '%s'", p)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+	var pool *x509.CertPool
+	var keys = []ed25519.PublicKey{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot read '%s'", file)
+		}
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			switch block.Type {
+			case "CERTIFICATE":
+				cert, err := x509.ParseCertificate(block.Bytes)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "cannot parse certificate in '%s'", file)
+				}
+				if pool == nil {
+					pool = x509.NewCertPool()
+				}
+				pool.AddCert(cert)
+			case "PUBLIC KEY":
+				pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+				if err != nil {
+					return nil, nil, errors.Wrapf(err, "cannot parse public key in '%s'", file)
+				}
+				if key, ok := pub.(ed25519.PublicKey); ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return pool, keys, nil
+}
+
+// verifyObjectSignatures logs the signer of every version, adds missing or invalid signatures and
+// signatures of untrusted signers to the validation status of ctx and returns their number
+func verifyObjectSignatures(ctx context.Context, obj object.Object, roots *x509.CertPool, keys []ed25519.PublicKey, logger zLogger.ZLogger) (int, error) {
+	var sig *ocflextension.Signature
+	for _, ext := range obj.GetExtensionManager().GetExtensions() {
+		if s, ok := ext.(*ocflextension.Signature); ok {
+			sig = s
+			break
+		}
+	}
+	if sig == nil {
+		return 0, nil
+	}
+	results, err := sig.VerifySignatures(obj, roots, keys)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot verify signatures of '%s'", obj.GetID())
+	}
+	var invalid int
+	for _, result := range results {
+		if result.Err != nil {
+			invalid++
+			logger.Error().Err(result.Err).Msgf("signature of '%s' version %s not valid", obj.GetID(), result.Version)
+			addObjectValidationError(ctx, obj, "signature of version %s missing or not valid: %v", result.Version, result.Err)
+			continue
+		}
+		if !result.Trusted {
+			invalid++
+			logger.Error().Msgf("'%s' version %s: signature present, signer untrusted '%s' (%s)", obj.GetID(), result.Version, result.Info.Signer, result.Info.Fingerprint)
+			addObjectValidationError(ctx, obj, "signature of version %s present, signer untrusted '%s' (%s)", result.Version, result.Info.Signer, result.Info.Fingerprint)
+			continue
+		}
+		logger.Info().Msgf("'%s' version %s signed by trusted '%s' (%s)", obj.GetID(), result.Version, result.Info.Signer, result.Info.Fingerprint)
+	}
+	return invalid, nil
+}
+
+// verifyStorageRootSignatures verifies the signatures of all objects or the object at objectPath
+func verifyStorageRootSignatures(ctx context.Context, sr storageroot.StorageRoot, objectPath string, extensionFactory *extension.ExtensionFactory, roots *x509.CertPool, keys []ed25519.PublicKey, logger zLogger.ZLogger) (int, error) {
+	var invalid int
+	err := walkObjects(ctx, sr, objectPath, extensionFactory, logger, func(obj object.Object) error {
+		num, err := verifyObjectSignatures(ctx, obj, roots, keys, logger)
+		invalid += num
+		return err
+	})
+	return invalid, errors.WithStack(err)
+}
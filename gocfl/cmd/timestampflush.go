@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/spf13/cobra"
+	ublogger "gitlab.switch.ch/ub-unibas/go-ublogger/v2"
+	"go.ub.unibas.ch/cloud/certloader/v2/pkg/loader"
+)
+
+var timestampFlushCmd = &cobra.Command{
+	Use:     "timestamp-flush [path to ocfl structure]",
+	Aliases: []string{},
+	Short:   "submits pending timestamp requests of NNNN-timestamp",
+	Long: "sends the timestamp requests stored in offline mode to the timestamp authorities\n" +
+		"and stores the responses in the extension folder of the objects",
+	Example: "gocfl timestamp-flush ./archive --object-id 'id:abc123'",
+	Args:    cobra.ExactArgs(1),
+	Run:     doTimestampFlush,
+}
+
+func initTimestampFlush() {
+	timestampFlushCmd.Flags().StringP("object-path", "o", "", "flush only the object at the specified path in storage root")
+	timestampFlushCmd.Flags().String("object-id", "", "flush only the object with the specified id in storage root")
+}
+
+func doTimestampFlushConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "object-path"); str != "" {
+		conf.Timestamp.ObjectPath = str
+	}
+	if str := getFlagString(cmd, "object-id"); str != "" {
+		conf.Timestamp.ObjectID = str
+	}
+}
+
+// flushObjectTimestamps submits the pending timestamp requests of the object and writes the result to w.
+// it returns the number of failed requests
+func flushObjectTimestamps(obj object.Object, locker *lock.Locker, w io.Writer, logger zLogger.ZLogger) (int, error) {
+	var ts *ocflextension.Timestamp
+	for _, ext := range obj.GetExtensionManager().GetExtensions() {
+		if t, ok := ext.(*ocflextension.Timestamp); ok {
+			ts = t
+			break
+		}
+	}
+	if ts == nil {
+		return 0, nil
+	}
+	pending, err := ts.PendingTimestamps()
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot get pending timestamps of '%s'", obj.GetID())
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	unlock, err := lockObject(locker, obj.GetID(), logger)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer unlock()
+	results, err := ts.FlushTimestamps(nil)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot flush timestamps of '%s'", obj.GetID())
+	}
+	fmt.Fprintf(w, "object '%s'\n", obj.GetID())
+	var failed int
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "   %s %s: failed - %v\n", result.Version, result.Authority, result.Err)
+			logger.Error().Err(result.Err).Msgf("cannot flush timestamp of '%s' version %s", obj.GetID(), result.Version)
+			continue
+		}
+		fmt.Fprintf(w, "   %s %s: %s\n", result.Version, result.Authority, result.Genesis.Format(time.RFC3339))
+	}
+	return failed, nil
+}
+
+// flushStorageRootTimestamps flushes the timestamps of all objects or the object at objectPath
+func flushStorageRootTimestamps(ctx context.Context, sr storageroot.StorageRoot, objectPath string, extensionFactory *extension.ExtensionFactory, locker *lock.Locker, w io.Writer, logger zLogger.ZLogger) (int, error) {
+	var failed int
+	err := walkObjects(ctx, sr, objectPath, extensionFactory, logger, func(obj object.Object) error {
+		num, err := flushObjectTimestamps(obj, locker, w, logger)
+		failed += num
+		return err
+	})
+	return failed, errors.WithStack(err)
+}
+
+func doTimestampFlush(cmd *cobra.Command, args []string) {
+	ocflPath, err := util.Fullpath(args[0])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+
+	// create logger instance
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("cannot get hostname: %v", err)
+	}
+
+	var loggerTLSConfig *tls.Config
+	var loggerLoader io.Closer
+	if conf.Log.Stash.TLS != nil {
+		loggerTLSConfig, loggerLoader, err = loader.CreateClientLoader(conf.Log.Stash.TLS, nil)
+		if err != nil {
+			log.Fatalf("cannot create client loader: %v", err)
+		}
+		defer loggerLoader.Close()
+	}
+
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	_logger, _logstash, _logfile, err := ublogger.CreateUbMultiLoggerTLS(conf.Log.Level, conf.Log.File,
+		ublogger.SetDataset(conf.Log.Stash.Dataset),
+		ublogger.SetLogStash(conf.Log.Stash.LogstashHost, conf.Log.Stash.LogstashPort, conf.Log.Stash.Namespace, conf.Log.Stash.LogstashTraceLevel),
+		ublogger.SetTLS(conf.Log.Stash.TLS != nil),
+		ublogger.SetTLSConfig(loggerTLSConfig),
+	)
+	if err != nil {
+		log.Fatalf("cannot create logger: %v", err)
+	}
+	if _logstash != nil {
+		defer _logstash.Close()
+	}
+
+	if _logfile != nil {
+		defer _logfile.Close()
+	}
+
+	l2 := _logger.With().Timestamp().Str("host", hostname).Logger() //.Output(output)
+	var logger zLogger.ZLogger = &l2
+
+	doTimestampFlushConf(cmd)
+
+	logger.Info().Msgf("flushing pending timestamps of '%s'", ocflPath)
+
+	extensionParams := GetExtensionParamValues(cmd, conf)
+	extensionFactory, err := InitExtensionFactory(extensionParams, "", false, nil, nil, nil, nil, (logger))
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize extension factory")
+		return
+	}
+
+	fsFactory, err := initializeFSFactory(nil, &conf.AES, &conf.S3, true, false, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot create filesystem factory")
+		return
+	}
+
+	destFS, err := fsFactory.Get(ocflPath, false)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
+		return
+	}
+	defer func() {
+		if err := writefs.Close(destFS); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot close filesystem for '%s'", destFS)
+		}
+	}()
+
+	ctx := validation.NewContextValidation(context.TODO())
+	sr, err := storageroot.LoadStorageRoot(ctx, destFS, extensionFactory, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot load storageroot")
+		return
+	}
+	objectID := conf.Timestamp.ObjectID
+	objectPath := conf.Timestamp.ObjectPath
+	if objectID != "" && objectPath != "" {
+		logger.Error().Msg("do not use object-path AND object-id at the same time")
+		return
+	}
+	if objectID != "" {
+		objectPath, err = sr.IdToFolder(objectID)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot get object-path for '%s'", objectID)
+			return
+		}
+	}
+	locker, err := newLocker(ocflPath, destFS, "timestamp-flush", logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize object locks")
+		return
+	}
+	failed, err := flushStorageRootTimestamps(ctx, sr, objectPath, extensionFactory, locker, os.Stdout, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot flush timestamps")
+		return
+	}
+	if failed > 0 {
+		logger.Error().Msgf("%d timestamp requests failed", failed)
+		return
+	}
+	logger.Info().Msg("all pending timestamps flushed")
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/spf13/cobra"
+	ublogger "gitlab.switch.ch/ub-unibas/go-ublogger/v2"
+	"go.ub.unibas.ch/cloud/certloader/v2/pkg/loader"
+)
+
+var unlockCmd = &cobra.Command{
+	Use:     "unlock [path to ocfl structure]",
+	Aliases: []string{},
+	Short:   "lists or removes object locks",
+	Long: "lists the object locks of an ocfl structure or removes the lock of an object.\n" +
+		"without --force only stale locks are removed",
+	Example: "gocfl unlock ./archive.zip --object-id 'id:abc123' --force",
+	Args:    cobra.ExactArgs(1),
+	Run:     doUnlock,
+}
+
+func initUnlock() {
+	unlockCmd.Flags().StringP("object-id", "i", "", "object id to unlock")
+	unlockCmd.Flags().Bool("list", false, "list all locks")
+	unlockCmd.Flags().Bool("force", false, "remove lock even if it is not stale")
+}
+
+func doUnlock(cmd *cobra.Command, args []string) {
+	ocflPath, err := util.Fullpath(args[0])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+
+	// create logger instance
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("cannot get hostname: %v", err)
+	}
+
+	var loggerTLSConfig *tls.Config
+	var loggerLoader io.Closer
+	if conf.Log.Stash.TLS != nil {
+		loggerTLSConfig, loggerLoader, err = loader.CreateClientLoader(conf.Log.Stash.TLS, nil)
+		if err != nil {
+			log.Fatalf("cannot create client loader: %v", err)
+		}
+		defer loggerLoader.Close()
+	}
+
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	_logger, _logstash, _logfile, err := ublogger.CreateUbMultiLoggerTLS(conf.Log.Level, conf.Log.File,
+		ublogger.SetDataset(conf.Log.Stash.Dataset),
+		ublogger.SetLogStash(conf.Log.Stash.LogstashHost, conf.Log.Stash.LogstashPort, conf.Log.Stash.Namespace, conf.Log.Stash.LogstashTraceLevel),
+		ublogger.SetTLS(conf.Log.Stash.TLS != nil),
+		ublogger.SetTLSConfig(loggerTLSConfig),
+	)
+	if err != nil {
+		log.Fatalf("cannot create logger: %v", err)
+	}
+	if _logstash != nil {
+		defer _logstash.Close()
+	}
+
+	if _logfile != nil {
+		defer _logfile.Close()
+	}
+
+	l2 := _logger.With().Timestamp().Str("host", hostname).Logger() //.Output(output)
+	var logger zLogger.ZLogger = &l2
+
+	objectID := getFlagString(cmd, "object-id")
+	list, _ := getFlagBool(cmd, "list")
+	force, _ := getFlagBool(cmd, "force")
+	if objectID == "" && !list {
+		_ = cmd.Help()
+		cobra.CheckErr(errors.New("either --object-id or --list is required"))
+		return
+	}
+
+	logger.Info().Msgf("opening '%s'", ocflPath)
+
+	fsFactory, err := initializeFSFactory(nil, &conf.AES, &conf.S3, true, false, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot create filesystem factory")
+		return
+	}
+	destFS, err := fsFactory.Get(ocflPath, false)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
+		return
+	}
+	defer func() {
+		if err := writefs.Close(destFS); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot close filesystem '%s'", destFS)
+		}
+	}()
+
+	locker := lock.NewLocker(destFS, "", time.Duration(conf.Lock.Expiry), logger)
+
+	if list {
+		locks, err := locker.List()
+		if err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot list locks")
+			return
+		}
+		if len(locks) == 0 {
+			fmt.Println("no locks found")
+		}
+		for _, lck := range locks {
+			var stale string
+			if lck.IsStale() {
+				stale = " [stale]"
+			}
+			fmt.Printf("%s%s\n", lck.String(), stale)
+		}
+	}
+
+	if objectID == "" {
+		return
+	}
+	lck, err := locker.Get(objectID)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get lock of object '%s'", objectID)
+		return
+	}
+	if lck == nil {
+		fmt.Printf("object '%s' is not locked\n", objectID)
+		return
+	}
+	if !lck.IsStale() && !force {
+		fmt.Printf("lock is not stale, use --force to remove it: %s\n", lck.String())
+		return
+	}
+	if _, err := locker.ForceUnlock(objectID); err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot unlock object '%s'", objectID)
+		return
+	}
+	logger.Info().Msgf("removed lock %s", lck.String())
+	fmt.Printf("removed lock %s\n", lck.String())
+}
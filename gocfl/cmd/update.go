@@ -48,6 +48,9 @@ func initUpdate() {
 	updateCmd.Flags().Bool("no-compress", false, "do not compress data in zip file")
 	updateCmd.Flags().Bool("encrypt-aes", false, "set flag to create encrypted container (only for container target)")
 	updateCmd.Flags().String("aes-key", "", "key to use for encrypted container in hex format (64 chars, empty: generate random key")
+	updateCmd.Flags().Bool("dry-run", false, "show what would be done without writing anything")
+	updateCmd.Flags().String("dedup-report", "", "check for content stored in other objects and write json report to file")
+	updateCmd.Flags().String("link-mode", "", "put content into the object with copy, hardlink or reflink (default: copy)")
 	updateCmd.Flags().String("aes-iv", "", "initialisation vector to use for encrypted container in hex format (32 charsempty: generate random vector")
 }
 
@@ -86,6 +89,13 @@ func doUpdateConf(cmd *cobra.Command) {
 			conf.Update.Echo = b
 		}
 	}
+	if b, ok := getFlagBool(cmd, "dry-run"); b {
+		if ok {
+			conf.Update.DryRun = b
+		}
+	}
+	doDedupConf(cmd)
+	doLinkConf(cmd)
 
 }
 
@@ -170,7 +180,7 @@ func doUpdate(cmd *cobra.Command, args []string) {
 	if err != nil {
 		logger.Panic().Stack().Err(err).Msgf("cannot get filesystem for '%s'", srcPath)
 	}
-	destFS, err := fsFactory.Get(ocflPath, false)
+	destFS, err := fsFactory.Get(ocflPath, conf.Update.DryRun)
 	if err != nil {
 		logger.Panic().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
 	}
@@ -190,12 +200,14 @@ func doUpdate(cmd *cobra.Command, args []string) {
 		area = "content"
 	}
 	var areaPaths = map[string]fs.FS{}
+	var linkSources = map[string]string{area: srcPath}
 	for i := 2; i < len(args); i++ {
 		matches := areaPathRegexp.FindStringSubmatch(args[i])
 		if matches == nil {
 			logger.Error().Msgf("invalid areapath '%s'", args[i])
 			continue
 		}
+		linkSources[matches[1]] = matches[2]
 		areaPaths[matches[1]], err = fsFactory.Get(matches[2], true)
 		if err != nil {
 			doNotClose = true
@@ -270,7 +282,57 @@ func doUpdate(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	_, err = addObjectByPath(
+	if conf.Update.DryRun {
+		report, err := dryRunObjectByPath(
+			storageRoot,
+			nil,
+			extensionFactory,
+			objectExtensions,
+			conf.Update.Deduplicate,
+			flagObjectID,
+			conf.Update.User.Name,
+			conf.Update.User.Address,
+			conf.Update.Message,
+			sourceFS,
+			area,
+			areaPaths,
+			conf.Update.Echo,
+			logger,
+		)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot simulate update of storageroot filesystem '%s'", destFS)
+			doNotClose = true
+			return
+		}
+		if err := report.Write(os.Stdout); err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot write dry run report")
+		}
+		_ = showStatus(ctx, logger)
+		return
+	}
+
+	linker, err := newLinker(conf.LinkMode, ocflPath, linkSources, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize link mode")
+		doNotClose = true
+		return
+	}
+
+	locker, err := newLocker(ocflPath, storageRoot.GetFS(), conf.Update.User.Name, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize object locks")
+		doNotClose = true
+		return
+	}
+
+	checker, err := newDedupChecker(storageRoot, ocflPath, flagObjectID, locker, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot load content digest index")
+		doNotClose = true
+		return
+	}
+
+	modified, err := addObjectByPath(
 		storageRoot,
 		nil,
 		extensionFactory,
@@ -284,12 +346,17 @@ func doUpdate(cmd *cobra.Command, args []string) {
 		area,
 		areaPaths,
 		conf.Update.Echo,
+		linker,
+		locker,
+		checker,
+		nil,
 		logger,
 	)
 	if err != nil {
 		logger.Error().Stack().Err(err).Msgf("cannot write content to storageroot filesystem '%s'", destFS)
 		doNotClose = true
 	}
+	checkCrossObjectDuplicates(storageRoot, ocflPath, flagObjectID, modified, checker, locker, logger)
 	_ = showStatus(ctx, logger)
 
 }
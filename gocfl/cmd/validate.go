@@ -6,10 +6,12 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/je4/filesystem/v3/pkg/writefs"
 	"github.com/je4/utils/v2/pkg/zLogger"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/progress"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
@@ -33,6 +35,10 @@ var validateCmd = &cobra.Command{
 func initValidate() {
 	validateCmd.Flags().StringP("object-path", "o", "", "validate only the object at the specified path in storage root")
 	validateCmd.Flags().String("object-id", "", "validate only the object with the specified id in storage root")
+	validateCmd.Flags().Bool("timestamps", false, "verify the trusted timestamps of NNNN-timestamp")
+	validateCmd.Flags().String("trust-store", "", "comma separated list of pem files or folders with trusted certificates")
+	validateCmd.Flags().Bool("signatures", true, "verify the version signatures of NNNN-signature")
+	validateCmd.Flags().String("signature-trust-store", "", "comma separated list of pem files or folders with trusted signer certificates or ed25519 keys")
 }
 
 func doValidateConf(cmd *cobra.Command) {
@@ -42,6 +48,21 @@ func doValidateConf(cmd *cobra.Command) {
 	if str := getFlagString(cmd, "object-id"); str != "" {
 		conf.Validate.ObjectID = str
 	}
+	if b, ok := getFlagBool(cmd, "timestamps"); ok {
+		conf.Validate.Timestamps = b
+	}
+	doTrustStoreConf(cmd)
+	if b, ok := getFlagBool(cmd, "signatures"); ok {
+		conf.Validate.Signatures = b
+	}
+	if str := getFlagString(cmd, "signature-trust-store"); str != "" {
+		conf.Signature.TrustStore = []string{}
+		for _, p := range strings.Split(str, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				conf.Signature.TrustStore = append(conf.Signature.TrustStore, p)
+			}
+		}
+	}
 }
 
 func validate(cmd *cobra.Command, args []string) {
@@ -119,7 +140,7 @@ func validate(cmd *cobra.Command, args []string) {
 		}
 	}()
 
-	ctx := validation.NewContextValidation(context.TODO())
+	ctx := progress.NewContext(validation.NewContextValidation(context.TODO()), newProgressReporter(conf.Progress, logger))
 	sr, err := storageroot.LoadStorageRoot(ctx, destFS, extensionFactory, logger)
 	if err != nil {
 		logger.Error().Stack().Err(err).Msg("cannot load storageroot")
@@ -160,5 +181,42 @@ func validate(cmd *cobra.Command, args []string) {
 		}
 
 	}
+	if conf.Validate.Timestamps {
+		roots, certs, err := loadTrustStore(conf.Timestamp.TrustStore)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot load trust store")
+			exitCode = 1
+			return
+		}
+		invalid, err := verifyStorageRootTimestamps(ctx, sr, objectPath, extensionFactory, roots, certs, io.Discard, logger)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot verify timestamps")
+			exitCode = 1
+			return
+		}
+		if invalid > 0 {
+			logger.Error().Msgf("%d timestamps missing or not valid", invalid)
+		}
+	}
+	if conf.Validate.Signatures {
+		roots, keys, err := loadSignatureTrust(conf.Signature.TrustStore)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot load signature trust store")
+			exitCode = 1
+			return
+		}
+		invalid, err := verifyStorageRootSignatures(ctx, sr, objectPath, extensionFactory, roots, keys, logger)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msg("cannot verify signatures")
+			exitCode = 1
+			return
+		}
+		if invalid > 0 {
+			logger.Error().Msgf("%d signatures missing, not valid or untrusted", invalid)
+		}
+	}
 	_ = showStatus(ctx, logger)
+	if countValidationErrors(ctx) > 0 {
+		exitCode = 1
+	}
 }
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	ocflextension "github.com/ocfl-archive/gocfl/v2/pkg/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+	"github.com/spf13/cobra"
+	ublogger "gitlab.switch.ch/ub-unibas/go-ublogger/v2"
+	"go.ub.unibas.ch/cloud/certloader/v2/pkg/loader"
+)
+
+var verifyTimestampsCmd = &cobra.Command{
+	Use:     "verify-timestamps [path to ocfl structure]",
+	Aliases: []string{},
+	Short:   "verifies the trusted timestamps of NNNN-timestamp",
+	Long: "verifies the stored RFC 3161 timestamps of all versions against the version inventories.\n" +
+		"the certificate chain of the timestamp authority is checked against a local trust store",
+	Example: "gocfl verify-timestamps ./archive.zip --trust-store ./tsa-ca.pem",
+	Args:    cobra.ExactArgs(1),
+	Run:     doVerifyTimestamps,
+}
+
+func initVerifyTimestamps() {
+	verifyTimestampsCmd.Flags().StringP("object-path", "o", "", "verify only the object at the specified path in storage root")
+	verifyTimestampsCmd.Flags().String("object-id", "", "verify only the object with the specified id in storage root")
+	verifyTimestampsCmd.Flags().String("trust-store", "", "comma separated list of pem files or folders with trusted certificates")
+}
+
+func doVerifyTimestampsConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "object-path"); str != "" {
+		conf.Timestamp.ObjectPath = str
+	}
+	if str := getFlagString(cmd, "object-id"); str != "" {
+		conf.Timestamp.ObjectID = str
+	}
+	doTrustStoreConf(cmd)
+}
+
+func doTrustStoreConf(cmd *cobra.Command) {
+	if str := getFlagString(cmd, "trust-store"); str != "" {
+		conf.Timestamp.TrustStore = []string{}
+		for _, p := range strings.Split(str, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				conf.Timestamp.TrustStore = append(conf.Timestamp.TrustStore, p)
+			}
+		}
+	}
+}
+
+// loadTrustStore reads all certificates of the pem files and folders.
+// every certificate is trusted, so the tsa certificate can be used as anchor too
+func loadTrustStore(paths []string) (*x509.CertPool, []*x509.Certificate, error) {
+	if len(paths) == 0 {
+		return nil, nil, errors.New("no trust store configured")
+	}
+	var files = []string{}
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot stat '%s'", p)
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot read folder '%s'", p)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+	pool := x509.NewCertPool()
+	var certs = []*x509.Certificate{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot read '%s'", file)
+		}
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "cannot parse certificate in '%s'", file)
+			}
+			pool.AddCert(cert)
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) == 0 {
+		return nil, nil, errors.Errorf("no certificates found in trust store %v", paths)
+	}
+	return pool, certs, nil
+}
+
+// verifyObjectTimestamps writes the genesis time of every version of the object to w,
+// adds missing or invalid timestamps to the validation status of ctx and returns their number
+func verifyObjectTimestamps(ctx context.Context, obj object.Object, roots *x509.CertPool, certs []*x509.Certificate, w io.Writer, logger zLogger.ZLogger) (int, error) {
+	var ts *ocflextension.Timestamp
+	for _, ext := range obj.GetExtensionManager().GetExtensions() {
+		if t, ok := ext.(*ocflextension.Timestamp); ok {
+			ts = t
+			break
+		}
+	}
+	fmt.Fprintf(w, "object '%s'\n", obj.GetID())
+	if ts == nil {
+		fmt.Fprintf(w, "   no %s extension\n", ocflextension.TimestampName)
+		return 0, nil
+	}
+	results, err := ts.VerifyTimestamps(obj, roots, certs)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot verify timestamps of '%s'", obj.GetID())
+	}
+	var invalid int
+	for _, result := range results {
+		if result.Err != nil {
+			invalid++
+			fmt.Fprintf(w, "   %s %s: invalid - %v\n", result.Version, result.Authority, result.Err)
+			logger.Error().Err(result.Err).Msgf("timestamp of '%s' version %s not valid", obj.GetID(), result.Version)
+			addObjectValidationError(ctx, obj, "timestamp of version %s missing or not valid: %v", result.Version, result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "   %s %s: %s\n", result.Version, result.Authority, result.Genesis.Format(time.RFC3339))
+	}
+	return invalid, nil
+}
+
+// verifyStorageRootTimestamps verifies the timestamps of all objects or the object at objectPath
+func verifyStorageRootTimestamps(ctx context.Context, sr storageroot.StorageRoot, objectPath string, extensionFactory *extension.ExtensionFactory, roots *x509.CertPool, certs []*x509.Certificate, w io.Writer, logger zLogger.ZLogger) (int, error) {
+	var invalid int
+	err := walkObjects(ctx, sr, objectPath, extensionFactory, logger, func(obj object.Object) error {
+		num, err := verifyObjectTimestamps(ctx, obj, roots, certs, w, logger)
+		invalid += num
+		return err
+	})
+	return invalid, errors.WithStack(err)
+}
+
+func doVerifyTimestamps(cmd *cobra.Command, args []string) {
+	ocflPath, err := util.Fullpath(args[0])
+	if err != nil {
+		cobra.CheckErr(err)
+		return
+	}
+
+	// create logger instance
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("cannot get hostname: %v", err)
+	}
+
+	var loggerTLSConfig *tls.Config
+	var loggerLoader io.Closer
+	if conf.Log.Stash.TLS != nil {
+		loggerTLSConfig, loggerLoader, err = loader.CreateClientLoader(conf.Log.Stash.TLS, nil)
+		if err != nil {
+			log.Fatalf("cannot create client loader: %v", err)
+		}
+		defer loggerLoader.Close()
+	}
+
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	_logger, _logstash, _logfile, err := ublogger.CreateUbMultiLoggerTLS(conf.Log.Level, conf.Log.File,
+		ublogger.SetDataset(conf.Log.Stash.Dataset),
+		ublogger.SetLogStash(conf.Log.Stash.LogstashHost, conf.Log.Stash.LogstashPort, conf.Log.Stash.Namespace, conf.Log.Stash.LogstashTraceLevel),
+		ublogger.SetTLS(conf.Log.Stash.TLS != nil),
+		ublogger.SetTLSConfig(loggerTLSConfig),
+	)
+	if err != nil {
+		log.Fatalf("cannot create logger: %v", err)
+	}
+	if _logstash != nil {
+		defer _logstash.Close()
+	}
+
+	if _logfile != nil {
+		defer _logfile.Close()
+	}
+
+	l2 := _logger.With().Timestamp().Str("host", hostname).Logger() //.Output(output)
+	var logger zLogger.ZLogger = &l2
+
+	doVerifyTimestampsConf(cmd)
+
+	roots, certs, err := loadTrustStore(conf.Timestamp.TrustStore)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot load trust store")
+		return
+	}
+
+	logger.Info().Msgf("verifying timestamps of '%s'", ocflPath)
+
+	extensionParams := GetExtensionParamValues(cmd, conf)
+	extensionFactory, err := InitExtensionFactory(extensionParams, "", false, nil, nil, nil, nil, (logger))
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot initialize extension factory")
+		return
+	}
+
+	fsFactory, err := initializeFSFactory(nil, nil, nil, true, true, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot create filesystem factory")
+		return
+	}
+
+	destFS, err := fsFactory.Get(ocflPath, true)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("cannot get filesystem for '%s'", ocflPath)
+		return
+	}
+	defer func() {
+		if err := writefs.Close(destFS); err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot close filesystem for '%s'", destFS)
+		}
+	}()
+
+	ctx := validation.NewContextValidation(context.TODO())
+	sr, err := storageroot.LoadStorageRoot(ctx, destFS, extensionFactory, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot load storageroot")
+		return
+	}
+	objectID := conf.Timestamp.ObjectID
+	objectPath := conf.Timestamp.ObjectPath
+	if objectID != "" && objectPath != "" {
+		logger.Error().Msg("do not use object-path AND object-id at the same time")
+		return
+	}
+	if objectID != "" {
+		objectPath, err = sr.IdToFolder(objectID)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("cannot get object-path for '%s'", objectID)
+			return
+		}
+	}
+	invalid, err := verifyStorageRootTimestamps(ctx, sr, objectPath, extensionFactory, roots, certs, os.Stdout, logger)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("cannot verify timestamps")
+		return
+	}
+	if invalid > 0 {
+		logger.Error().Msgf("%d timestamps missing or not valid", invalid)
+		exitCode = 1
+		return
+	}
+	logger.Info().Msg("all timestamps valid")
+}
@@ -0,0 +1,318 @@
+// Package bagit reads and validates BagIt bags (RFC 8493)
+package bagit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/je4/utils/v2/pkg/checksum"
+)
+
+const (
+	DeclarationFile = "bagit.txt"
+	BagInfoFile     = "bag-info.txt"
+	PayloadFolder   = "data"
+)
+
+var manifestRegexp = regexp.MustCompile(`^(tag)?manifest-([a-z0-9]+)\.txt$`)
+
+type Manifest map[string]string
+
+type Bag struct {
+	fsys         fs.FS
+	Version      string
+	Encoding     string
+	Manifests    map[checksum.DigestAlgorithm]Manifest
+	TagManifests map[checksum.DigestAlgorithm]Manifest
+	// Payload contains the files below data/ relative to the bag
+	Payload []string
+	// TagFiles contains all other files of the bag
+	TagFiles []string
+}
+
+// IsBag checks for the bag declaration
+func IsBag(fsys fs.FS) bool {
+	_, err := fs.Stat(fsys, DeclarationFile)
+	return err == nil
+}
+
+// Open reads declaration and manifests of the bag
+func Open(fsys fs.FS) (*Bag, error) {
+	bag := &Bag{
+		fsys:         fsys,
+		Manifests:    map[checksum.DigestAlgorithm]Manifest{},
+		TagManifests: map[checksum.DigestAlgorithm]Manifest{},
+	}
+	declaration, err := fs.ReadFile(fsys, DeclarationFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read '%s'", DeclarationFile)
+	}
+	tags, err := ParseTags(declaration)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse '%s'", DeclarationFile)
+	}
+	if len(tags) != 2 || tags[0][0] != "BagIt-Version" || tags[1][0] != "Tag-File-Character-Encoding" {
+		return nil, errors.Errorf("'%s' must contain 'BagIt-Version' and 'Tag-File-Character-Encoding'", DeclarationFile)
+	}
+	bag.Version, bag.Encoding = tags[0][1], tags[1][1]
+	if !regexp.MustCompile(`^[0-9]+\.[0-9]+$`).MatchString(bag.Version) {
+		return nil, errors.Errorf("invalid BagIt-Version '%s'", bag.Version)
+	}
+	if !strings.EqualFold(bag.Encoding, "UTF-8") {
+		return nil, errors.Errorf("unsupported Tag-File-Character-Encoding '%s'", bag.Encoding)
+	}
+
+	if err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(name, PayloadFolder+"/") {
+			bag.Payload = append(bag.Payload, name)
+			return nil
+		}
+		bag.TagFiles = append(bag.TagFiles, name)
+		matches := manifestRegexp.FindStringSubmatch(name)
+		if matches == nil {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return errors.Wrapf(err, "cannot read '%s'", name)
+		}
+		manifest, err := ParseManifest(data)
+		if err != nil {
+			return errors.Wrapf(err, "cannot parse '%s'", name)
+		}
+		if matches[1] == "tag" {
+			bag.TagManifests[checksum.DigestAlgorithm(matches[2])] = manifest
+		} else {
+			bag.Manifests[checksum.DigestAlgorithm(matches[2])] = manifest
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "cannot walk bag '%v'", fsys)
+	}
+	if len(bag.Manifests) == 0 {
+		return nil, errors.New("no payload manifest found")
+	}
+	return bag, nil
+}
+
+// ParseTags parses a tag file with 'label: value' lines. indented lines continue the value
+func ParseTags(data []byte) ([][2]string, error) {
+	var result = [][2]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	num := 0
+	for scanner.Scan() {
+		num++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if len(result) == 0 {
+				return nil, errors.Errorf("line %d: continuation without tag", num)
+			}
+			result[len(result)-1][1] += " " + strings.TrimSpace(line)
+			continue
+		}
+		label, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, errors.Errorf("line %d: no tag '%s'", num, line)
+		}
+		result = append(result, [2]string{strings.TrimSpace(label), strings.TrimSpace(value)})
+	}
+	return result, errors.WithStack(scanner.Err())
+}
+
+// ParseManifest parses 'checksum filepath' lines
+func ParseManifest(data []byte) (Manifest, error) {
+	var result = Manifest{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	num := 0
+	for scanner.Scan() {
+		num++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		digest, name, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, errors.Errorf("line %d: invalid manifest entry '%s'", num, line)
+		}
+		name = strings.TrimLeft(name, " \t")
+		name = strings.ReplaceAll(strings.ReplaceAll(name, "%0D", "\r"), "%0A", "\n")
+		name = strings.ReplaceAll(name, "%25", "%")
+		name = path.Clean(strings.TrimPrefix(name, "./"))
+		if _, ok := result[name]; ok {
+			return nil, errors.Errorf("line %d: duplicate entry '%s'", num, name)
+		}
+		result[name] = strings.ToLower(digest)
+	}
+	return result, errors.WithStack(scanner.Err())
+}
+
+// FixityAlgorithms returns the payload manifest algorithms, which are supported by checksum
+func (bag *Bag) FixityAlgorithms() []checksum.DigestAlgorithm {
+	var result = []checksum.DigestAlgorithm{}
+	for alg := range bag.Manifests {
+		if checksum.HashExists(alg) {
+			result = append(result, alg)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// PayloadDigests returns the manifest digests of a payload file with supported algorithms.
+// name is relative to the payload folder
+func (bag *Bag) PayloadDigests(name string) map[checksum.DigestAlgorithm]string {
+	return digestsOf(bag.Manifests, PayloadFolder+"/"+name)
+}
+
+// TagDigests returns the tag manifest digests of a tag file with supported algorithms
+func (bag *Bag) TagDigests(name string) map[checksum.DigestAlgorithm]string {
+	return digestsOf(bag.TagManifests, name)
+}
+
+func digestsOf(manifests map[checksum.DigestAlgorithm]Manifest, name string) map[checksum.DigestAlgorithm]string {
+	var result = map[checksum.DigestAlgorithm]string{}
+	for alg, manifest := range manifests {
+		if !checksum.HashExists(alg) {
+			continue
+		}
+		if digest, ok := manifest[name]; ok {
+			result[alg] = digest
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// Validate checks completeness of the payload and all digests of supported algorithms
+func (bag *Bag) Validate() error {
+	var errs = []error{}
+	algs := bag.FixityAlgorithms()
+	if len(algs) == 0 {
+		errs = append(errs, errors.Errorf("no supported payload manifest algorithm"))
+	}
+	for alg, manifest := range bag.Manifests {
+		for _, name := range bag.Payload {
+			if _, ok := manifest[name]; !ok {
+				errs = append(errs, errors.Errorf("'%s' not in manifest-%s.txt", name, alg))
+			}
+		}
+		for name := range manifest {
+			if !strings.HasPrefix(name, PayloadFolder+"/") {
+				errs = append(errs, errors.Errorf("'%s' of manifest-%s.txt is not in payload folder", name, alg))
+			}
+		}
+	}
+	errs = append(errs, bag.checkDigests(bag.Manifests)...)
+	errs = append(errs, bag.checkDigests(bag.TagManifests)...)
+	return errors.Combine(errs...)
+}
+
+// checkDigests verifies the files of the manifests with supported algorithms in one pass per file
+func (bag *Bag) checkDigests(manifests map[checksum.DigestAlgorithm]Manifest) []error {
+	var errs = []error{}
+	var algs = []checksum.DigestAlgorithm{}
+	for alg := range manifests {
+		if checksum.HashExists(alg) {
+			algs = append(algs, alg)
+		}
+	}
+	slices.Sort(algs)
+	var files = map[string][]checksum.DigestAlgorithm{}
+	for _, alg := range algs {
+		for name := range manifests[alg] {
+			files[name] = append(files[name], alg)
+		}
+	}
+	var names = make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := func() error {
+			fp, err := bag.fsys.Open(name)
+			if err != nil {
+				return errors.Wrapf(err, "cannot open '%s'", name)
+			}
+			defer fp.Close()
+			digests, err := checksum.Copy(files[name], fp)
+			if err != nil {
+				return errors.Wrapf(err, "cannot read '%s'", name)
+			}
+			for _, alg := range files[name] {
+				if digests[alg] != manifests[alg][name] {
+					return errors.Errorf("invalid %s checksum of '%s'", alg, name)
+				}
+			}
+			return nil
+		}(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// PayloadFS returns the content of the payload folder
+func (bag *Bag) PayloadFS() (fs.FS, error) {
+	return fs.Sub(bag.fsys, PayloadFolder)
+}
+
+// TagFS returns the bag without payload folder
+func (bag *Bag) TagFS() fs.FS {
+	return &tagFS{fsys: bag.fsys}
+}
+
+type tagFS struct {
+	fsys fs.FS
+}
+
+func (t *tagFS) isPayload(name string) bool {
+	return name == PayloadFolder || strings.HasPrefix(name, PayloadFolder+"/")
+}
+
+func (t *tagFS) Open(name string) (fs.File, error) {
+	if t.isPayload(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return t.fsys.Open(name)
+}
+
+func (t *tagFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if t.isPayload(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := fs.ReadDir(t.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if name != "." {
+		return entries, nil
+	}
+	return slices.DeleteFunc(entries, func(e fs.DirEntry) bool { return e.Name() == PayloadFolder }), nil
+}
+
+func (t *tagFS) String() string {
+	return fmt.Sprintf("%v [tag files]", t.fsys)
+}
+
+var (
+	_ fs.ReadDirFS = (*tagFS)(nil)
+)
@@ -0,0 +1,110 @@
+package bagit
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+)
+
+func newTestBag(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{
+		DeclarationFile: &fstest.MapFile{Data: []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")},
+		BagInfoFile:     &fstest.MapFile{Data: []byte("Source-Organization: Example Archive\nExternal-Description: a long\n  description\n")},
+	}
+	var sha512Manifest, sha256Manifest, tagManifest string
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+		sha512Manifest += fmt.Sprintf("%x  %s\n", sha512.Sum512([]byte(content)), name)
+		sha256Manifest += fmt.Sprintf("%x %s\n", sha256.Sum256([]byte(content)), name)
+	}
+	fsys["manifest-sha512.txt"] = &fstest.MapFile{Data: []byte(sha512Manifest)}
+	fsys["manifest-sha256.txt"] = &fstest.MapFile{Data: []byte(sha256Manifest)}
+	for _, name := range []string{DeclarationFile, BagInfoFile} {
+		tagManifest += fmt.Sprintf("%x %s\n", sha512.Sum512(fsys[name].Data), name)
+	}
+	fsys["tagmanifest-sha512.txt"] = &fstest.MapFile{Data: []byte(tagManifest)}
+	return fsys
+}
+
+func TestBag(t *testing.T) {
+	fsys := newTestBag(map[string]string{
+		"data/a.txt":     "a",
+		"data/sub/b.txt": "b",
+	})
+	bag, err := Open(fsys)
+	if err != nil {
+		t.Fatalf("cannot open bag: %v", err)
+	}
+	if err := bag.Validate(); err != nil {
+		t.Fatalf("bag not valid: %v", err)
+	}
+	if algs := bag.FixityAlgorithms(); len(algs) != 2 || algs[0] != checksum.DigestSHA256 || algs[1] != checksum.DigestSHA512 {
+		t.Errorf("unexpected fixity algorithms %v", algs)
+	}
+	if len(bag.Payload) != 2 || len(bag.TagFiles) != 5 {
+		t.Errorf("expected 2 payload and 5 tag files, got %v and %v", bag.Payload, bag.TagFiles)
+	}
+
+	digests := bag.PayloadDigests("sub/b.txt")
+	if len(digests) != 2 || digests[checksum.DigestSHA256] != fmt.Sprintf("%x", sha256.Sum256([]byte("b"))) {
+		t.Errorf("unexpected payload digests %v", digests)
+	}
+	if digests := bag.PayloadDigests("missing.txt"); digests != nil {
+		t.Errorf("digests of missing file: %v", digests)
+	}
+	if digests := bag.TagDigests(BagInfoFile); len(digests) != 1 || digests[checksum.DigestSHA512] == "" {
+		t.Errorf("unexpected tag digests %v", digests)
+	}
+
+	tags, err := ParseTags(fsys[BagInfoFile].Data)
+	if err != nil {
+		t.Fatalf("cannot parse %s: %v", BagInfoFile, err)
+	}
+	if len(tags) != 2 || tags[1][1] != "a long description" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	var tagFiles = []string{}
+	if err := fs.WalkDir(bag.TagFS(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			tagFiles = append(tagFiles, name)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("cannot walk tag files: %v", err)
+	}
+	if len(tagFiles) != len(bag.TagFiles) {
+		t.Errorf("tag filesystem contains %v", tagFiles)
+	}
+
+	fsys["data/c.txt"] = &fstest.MapFile{Data: []byte("c")}
+	fsys["data/a.txt"] = &fstest.MapFile{Data: []byte("x")}
+	fsys[BagInfoFile] = &fstest.MapFile{Data: []byte("Source-Organization: Other Archive\n")}
+	bag, err = Open(fsys)
+	if err != nil {
+		t.Fatalf("cannot open bag: %v", err)
+	}
+	err = bag.Validate()
+	if err == nil {
+		t.Fatalf("invalid bag not detected")
+	}
+	for _, str := range []string{"'data/c.txt' not in manifest-sha512.txt", "invalid sha256 checksum of 'data/a.txt'", "checksum of 'bag-info.txt'"} {
+		if !strings.Contains(err.Error(), str) {
+			t.Errorf("error does not report '%s': %v", str, err)
+		}
+	}
+
+	delete(fsys, DeclarationFile)
+	if _, err := Open(fsys); err == nil {
+		t.Errorf("missing %s not detected", DeclarationFile)
+	}
+}
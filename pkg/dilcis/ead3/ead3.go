@@ -12,6 +12,7 @@ type AvBoolean bool
 // Ead ...
 type Ead struct {
 	XMLName             xml.Name `xml:"ead"`
+	XMLNS               string   `xml:"xmlns,attr"`
 	AmCommon            *AmCommon
 	RelatedencodingAttr string       `xml:"relatedencoding,attr,omitempty"`
 	BaseAttr            string       `xml:"base,attr,omitempty"`
@@ -26,14 +27,16 @@ type Archdesc struct {
 	AmCommon   *AmCommon
 	AmDescBase *AmDescBase
 	// ALevel              *ALevel
-	LevelAttr           string `xml:"level,attr"`
-	LocaltypeAttr       string `xml:"localtype,attr,omitempty"`
-	RelatedencodingAttr string `xml:"relatedencoding,attr,omitempty"`
-	BaseAttr            string `xml:"base,attr,omitempty"`
-	MDescBaseDescgrp    []*MDescBaseDescgrp
+	LevelAttr           string    `xml:"level,attr"`
+	LocaltypeAttr       string    `xml:"localtype,attr,omitempty"`
+	RelatedencodingAttr string    `xml:"relatedencoding,attr,omitempty"`
+	BaseAttr            string    `xml:"base,attr,omitempty"`
 	Runner              []*Runner `xml:"runner"`
 	Did                 *Did      `xml:"did"`
-	Dsc                 []*Dsc    `xml:"dsc"`
+	MDescBaseDescgrp    []*MDescBaseDescgrp
+	Scopecontent        []*Scopecontent  `xml:"scopecontent"`
+	Controlaccess       []*Controlaccess `xml:"controlaccess"`
+	Dsc                 []*Dsc           `xml:"dsc"`
 }
 
 // Dsc ...
@@ -48,14 +51,17 @@ type Dsc struct {
 	MCOrC01            *MCOrC01
 	Head               *Head  `xml:"head"`
 	Thead              *Thead `xml:"thead"`
+	C                  []*C   `xml:"c"`
 }
 
 // C ...
 type C struct {
-	XMLName xml.Name `xml:"c"`
-	Thead   *Thead   `xml:"thead"`
-	C       []*C     `xml:"c"`
+	XMLName        xml.Name `xml:"c"`
+	LevelAttr      string   `xml:"level,attr,omitempty"`
+	OtherlevelAttr string   `xml:"otherlevel,attr,omitempty"`
 	*MCBaseDescgrp
+	Thead *Thead `xml:"thead"`
+	C     []*C   `xml:"c"`
 }
 
 // C01 ...
@@ -170,6 +176,7 @@ type Unittitle struct {
 	Bibseries           []*Bibseries `xml:"bibseries"`
 	Edition             []*Edition   `xml:"edition"`
 	Unitdate            []*Unitdate  `xml:"unitdate"`
+	Value               string       `xml:",chardata"`
 }
 
 // Physdesc ...
@@ -188,6 +195,7 @@ type Physdesc struct {
 	Extent              []*Extent     `xml:"extent"`
 	Physfacet           []*Physfacet  `xml:"physfacet"`
 	Dimensions          []*Dimensions `xml:"dimensions"`
+	Value               string        `xml:",chardata"`
 }
 
 // Bibref ...
@@ -244,6 +252,7 @@ type Recordid struct {
 	AmCommon           *AmCommon
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
 	InstanceurlAttr    string `xml:"instanceurl,attr,omitempty"`
+	Value              string `xml:",chardata"`
 }
 
 // Otherrecordid ...
@@ -438,6 +447,7 @@ type Eventdatetime struct {
 	AmCommon             *AmCommon
 	EncodinganalogAttr   string `xml:"encodinganalog,attr,omitempty"`
 	StandarddatetimeAttr string `xml:"standarddatetime,attr,omitempty"`
+	Value                string `xml:",chardata"`
 }
 
 // Agenttype ...
@@ -453,6 +463,7 @@ type Agent struct {
 	XMLName            xml.Name `xml:"agent"`
 	AmCommon           *AmCommon
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
+	Value              string `xml:",chardata"`
 }
 
 // Eventdescription ...
@@ -501,6 +512,7 @@ type Titleproper struct {
 	RenderAttr         string `xml:"render,attr,omitempty"`
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
 	*MMixedBasic
+	Value string `xml:",chardata"`
 }
 
 // Subtitle ...
@@ -591,6 +603,7 @@ type Agencyname struct {
 	AmCommon           *AmCommon
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
 	LocaltypeAttr      string `xml:"localtype,attr,omitempty"`
+	Value              string `xml:",chardata"`
 }
 
 // Citation ...
@@ -608,8 +621,14 @@ type Did struct {
 	XMLName            xml.Name `xml:"did"`
 	AmCommon           *AmCommon
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
+	Head               *Head  `xml:"head"`
 	MDid               []*MDid
-	Head               *Head `xml:"head"`
+	Unittitle          []*Unittitle   `xml:"unittitle"`
+	Unitid             []*Unitid      `xml:"unitid"`
+	Unitdate           []*Unitdate    `xml:"unitdate"`
+	Origination        []*Origination `xml:"origination"`
+	Physdesc           []*Physdesc    `xml:"physdesc"`
+	Dao                []*Dao         `xml:"dao"`
 }
 
 // Abstract ...
@@ -648,6 +667,7 @@ type Dao struct {
 	DaotypeAttr        string           `xml:"daotype,attr"`
 	OtherdaotypeAttr   string           `xml:"otherdaotype,attr,omitempty"`
 	CoverageAttr       string           `xml:"coverage,attr,omitempty"`
+	HrefAttr           string           `xml:"href,attr,omitempty"`
 	Descriptivenote    *Descriptivenote `xml:"descriptivenote"`
 }
 
@@ -789,6 +809,8 @@ type Unitdate struct {
 	CertaintyAttr      string `xml:"certainty,attr,omitempty"`
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
 	*MMixedBasic
+	NormalAttr string `xml:"normal,attr,omitempty"`
+	Value      string `xml:",chardata"`
 }
 
 // Unitdatestructured ...
@@ -818,6 +840,7 @@ type Unitid struct {
 	IdentifierAttr     interface{} `xml:"identifier,attr,omitempty"`
 	EncodinganalogAttr string      `xml:"encodinganalog,attr,omitempty"`
 	*MMixedBasic
+	Value string `xml:",chardata"`
 }
 
 // Accessrestrict ...
@@ -920,6 +943,7 @@ type Controlaccess struct {
 	MAccess            []*MAccess
 	Head               *Head            `xml:"head"`
 	Controlaccess      []*Controlaccess `xml:"controlaccess"`
+	Subject            []*Subject       `xml:"subject"`
 }
 
 // Custodhist ...
@@ -1080,8 +1104,9 @@ type Scopecontent struct {
 	AmCommon           *AmCommon
 	LocaltypeAttr      string `xml:"localtype,attr,omitempty"`
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
+	Head               *Head  `xml:"head"`
 	MBlocks            []*MBlocks
-	Head               *Head           `xml:"head"`
+	P                  []*P            `xml:"p"`
 	Scopecontent       []*Scopecontent `xml:"scopecontent"`
 }
 
@@ -1236,6 +1261,7 @@ type Part struct {
 	EncodinganalogAttr string `xml:"encodinganalog,attr,omitempty"`
 	LocaltypeAttr      string `xml:"localtype,attr,omitempty"`
 	*MMixedBasicDate
+	Value string `xml:",chardata"`
 }
 
 // P ...
@@ -1243,6 +1269,7 @@ type P struct {
 	XMLName  xml.Name `xml:"p"`
 	AmCommon *AmCommon
 	*MParaContent
+	Value string `xml:",chardata"`
 }
 
 // Blockquote ...
@@ -2259,9 +2286,11 @@ type MCBaseDescgrp struct {
 	XMLName          xml.Name `xml:"m.c.base.descgrp"`
 	AmDescC          *AmDescC
 	TpatternAttr     string `xml:"tpattern,attr,omitempty"`
+	Head             *Head  `xml:"head"`
+	Did              *Did   `xml:"did"`
 	MDescBaseDescgrp []*MDescBaseDescgrp
-	Head             *Head `xml:"head"`
-	Did              *Did  `xml:"did"`
+	Scopecontent     []*Scopecontent  `xml:"scopecontent"`
+	Controlaccess    []*Controlaccess `xml:"controlaccess"`
 }
 
 // Imprint ...
@@ -0,0 +1,48 @@
+package mets
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// attribute prefixes used in the struct tags of the generated types
+var attrPrefixes = map[string]string{
+	"http://www.w3.org/1999/xlink":                 "xlink",
+	"http://www.w3.org/2001/XMLSchema-instance":    "xsi",
+	"https://DILCIS.eu/XML/METS/CSIPExtensionMETS": "csip",
+	"https://DILCIS.eu/XML/METS/SIPExtensionMETS":  "sip",
+}
+
+// prefixReader renames namespaced attributes to the prefixed names of the struct tags.
+// encoding/xml does not match attributes like `xml:"xlink:href,attr"` on unmarshal
+type prefixReader struct {
+	d *xml.Decoder
+}
+
+func (pr *prefixReader) Token() (xml.Token, error) {
+	t, err := pr.d.Token()
+	if err != nil {
+		return nil, err
+	}
+	if se, ok := t.(xml.StartElement); ok {
+		attrs := make([]xml.Attr, 0, len(se.Attr))
+		for _, attr := range se.Attr {
+			if prefix, ok := attrPrefixes[attr.Name.Space]; ok {
+				attr.Name = xml.Name{Local: prefix + ":" + attr.Name.Local}
+			}
+			attrs = append(attrs, attr)
+		}
+		se.Attr = attrs
+		return se, nil
+	}
+	return t, nil
+}
+
+// Decode reads a METS document
+func Decode(r io.Reader) (*Mets, error) {
+	m := &Mets{}
+	if err := xml.NewTokenDecoder(&prefixReader{d: xml.NewDecoder(r)}).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
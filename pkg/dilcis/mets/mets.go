@@ -11,13 +11,16 @@ type Mets struct {
 	XMLNSXSI          string   `xml:"xmlns:xsi,attr"`
 	XSISchemaLocation string   `xml:"xsi:schemaLocation,attr"`
 	XMLXLinkNS        string   `xml:"xmlns:xlink,attr"`
+	XMLNSCSIP         string   `xml:"xmlns:csip,attr,omitempty"`
+	XMLNSSIP          string   `xml:"xmlns:sip,attr,omitempty"`
 	*MetsType
 }
 
 // Note ...
 type Note struct {
-	XMLName xml.Name `xml:"note"`
-	Value   string   `xml:",chardata"`
+	XMLName          xml.Name `xml:"note"`
+	CSIPNOTETYPEAttr NOTETYPE `xml:"csip:NOTETYPE,attr,omitempty"`
+	Value            string   `xml:",chardata"`
 }
 
 // Agent is The element <name> can be used to record the full name of the document agent.
@@ -50,15 +53,17 @@ type MetsDocumentID struct {
 
 // MetsHdr ...
 type MetsHdr struct {
-	XMLName          xml.Name        `xml:"metsHdr"`
-	IDAttr           string          `xml:"ID,attr,omitempty"`
-	ADMIDAttr        []string        `xml:"ADMID,attr,omitempty"`
-	CREATEDATEAttr   string          `xml:"CREATEDATE,attr,omitempty"`
-	LASTMODDATEAttr  string          `xml:"LASTMODDATE,attr,omitempty"`
-	RECORDSTATUSAttr string          `xml:"RECORDSTATUS,attr,omitempty"`
-	Agent            []*Agent        `xml:"agent"`
-	AltRecordID      []*AltRecordID  `xml:"altRecordID"`
-	MetsDocumentID   *MetsDocumentID `xml:"metsDocumentID"`
+	XMLName          xml.Name `xml:"metsHdr"`
+	IDAttr           string   `xml:"ID,attr,omitempty"`
+	ADMIDAttr        []string `xml:"ADMID,attr,omitempty"`
+	CREATEDATEAttr   string   `xml:"CREATEDATE,attr,omitempty"`
+	LASTMODDATEAttr  string   `xml:"LASTMODDATE,attr,omitempty"`
+	RECORDSTATUSAttr string   `xml:"RECORDSTATUS,attr,omitempty"`
+	// CSIP extension
+	CSIPOAISPACKAGETYPEAttr OAISPACKAGETYPE `xml:"csip:OAISPACKAGETYPE,attr,omitempty"`
+	Agent                   []*Agent        `xml:"agent"`
+	AltRecordID             []*AltRecordID  `xml:"altRecordID"`
+	MetsDocumentID          *MetsDocumentID `xml:"metsDocumentID"`
 }
 
 // FileGrp ...
@@ -83,19 +88,22 @@ type StructLink struct {
 // MetsType is metsType: Complex Type for METS Sections
 // A METS document consists of seven possible subsidiary sections: metsHdr (METS document header), dmdSec (descriptive metadata section), amdSec (administrative metadata section), fileGrp (file inventory group), structLink (structural map linking), structMap (structural map) and behaviorSec (behaviors section).
 type MetsType struct {
-	XMLName     xml.Name           `xml:"metsType"`
-	IDAttr      string             `xml:"ID,attr,omitempty"`
-	OBJIDAttr   string             `xml:"OBJID,attr,omitempty"`
-	LABELAttr   string             `xml:"LABEL,attr,omitempty"`
-	TYPEAttr    string             `xml:"TYPE,attr,omitempty"`
-	PROFILEAttr string             `xml:"PROFILE,attr,omitempty"`
-	MetsHdr     *MetsHdr           `xml:"metsHdr"`
-	DmdSec      []*MdSecType       `xml:"dmdSec"`
-	AmdSec      []*AmdSecType      `xml:"amdSec"`
-	FileSec     *FileSec           `xml:"fileSec"`
-	StructMap   []*StructMapType   `xml:"structMap"`
-	StructLink  *StructLink        `xml:"structLink"`
-	BehaviorSec []*BehaviorSecType `xml:"behaviorSec"`
+	XMLName     xml.Name `xml:"metsType"`
+	IDAttr      string   `xml:"ID,attr,omitempty"`
+	OBJIDAttr   string   `xml:"OBJID,attr,omitempty"`
+	LABELAttr   string   `xml:"LABEL,attr,omitempty"`
+	TYPEAttr    string   `xml:"TYPE,attr,omitempty"`
+	PROFILEAttr string   `xml:"PROFILE,attr,omitempty"`
+	// CSIP extension
+	CSIPOTHERTYPEAttr              OTHERTYPE              `xml:"csip:OTHERTYPE,attr,omitempty"`
+	CSIPCONTENTINFORMATIONTYPEAttr CONTENTINFORMATIONTYPE `xml:"csip:CONTENTINFORMATIONTYPE,attr,omitempty"`
+	MetsHdr                        *MetsHdr               `xml:"metsHdr"`
+	DmdSec                         []*MdSecType           `xml:"dmdSec"`
+	AmdSec                         []*AmdSecType          `xml:"amdSec"`
+	FileSec                        *FileSec               `xml:"fileSec"`
+	StructMap                      []*StructMapType       `xml:"structMap"`
+	StructLink                     *StructLink            `xml:"structLink"`
+	BehaviorSec                    []*BehaviorSecType     `xml:"behaviorSec"`
 }
 
 // AmdSecType is A digital provenance metadata element <digiprovMD> can be used to record any preservation-related actions taken on the various files which comprise a digital object (e.g., those subsequent to the initial digitization of the files such as transformation or migrations) or, in the case of born digital materials, the files’ creation. In short, digital provenance should be used to record information that allows both archival/library staff and scholars to understand what modifications have been made to a digital object and/or its constituent parts during its life cycle. This information can then be used to judge how those processes might have altered or corrupted the object’s ability to accurately represent the original item. One might, for example, record master derivative relationships and the process by which those derivations have been created. Or the <digiprovMD> element could contain information regarding the migration/transformation of a file from its original digitization (e.g., OCR, TEI, etc.,)to its current incarnation as a digital object (e.g., JPEG2000). The <digiprovMD> element conforms to same generic datatype as the <dmdSec>,  <techMD>, <rightsMD>, and <sourceMD> elements, and supports the same sub-elements and attributes. A digital provenance metadata element can either wrap the metadata  (mdWrap) or reference it in an external location (mdRef) or both.  METS allows multiple <digiprovMD> elements; and digital provenance metadata can be associated with any METS element that supports an ADMID attribute. Digital provenance metadata can be expressed according to current digital provenance description standards (such as PREMIS) or a locally produced XML schema.
@@ -0,0 +1,65 @@
+package dilcis
+
+import (
+	"bytes"
+	"embed"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"emperror.dev/errors"
+)
+
+// schemas which can be used without network access
+//
+//go:embed ead3.xsd premis-v3-0.xsd
+var schemaFS embed.FS
+
+const (
+	SchemaEAD3   = "ead3.xsd"
+	SchemaPremis = "premis-v3-0.xsd"
+)
+
+// XMLLint validates xml documents against the schemas of this package with xmllint of libxml2.
+// there is no xml schema validator for go without cgo
+type XMLLint struct {
+	xmllint string
+}
+
+// NewXMLLint returns a validator, which uses the xmllint executable
+func NewXMLLint(xmllint string) (*XMLLint, error) {
+	path, err := exec.LookPath(xmllint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot find xmllint '%s'", xmllint)
+	}
+	return &XMLLint{xmllint: path}, nil
+}
+
+// Validate checks data against schema (SchemaEAD3 or SchemaPremis)
+func (x *XMLLint) Validate(schema string, data []byte) error {
+	xsd, err := schemaFS.ReadFile(schema)
+	if err != nil {
+		return errors.Wrapf(err, "unknown schema '%s'", schema)
+	}
+	dir, err := os.MkdirTemp("", "gocfl-xsd-")
+	if err != nil {
+		return errors.Wrap(err, "cannot create temporary folder")
+	}
+	defer os.RemoveAll(dir)
+	schemaFile := filepath.Join(dir, schema)
+	if err := os.WriteFile(schemaFile, xsd, 0644); err != nil {
+		return errors.Wrapf(err, "cannot write '%s'", schemaFile)
+	}
+	cmd := exec.Command(x.xmllint, "--noout", "--nonet", "--schema", schemaFile, "-")
+	cmd.Stdin = bytes.NewReader(data)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return errors.Wrapf(err, "cannot run '%s'", x.xmllint)
+		}
+		return errors.Errorf("document not valid against %s: %s", schema, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
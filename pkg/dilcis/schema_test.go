@@ -0,0 +1,65 @@
+package dilcis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXMLLint(t *testing.T) {
+	if _, err := NewXMLLint("gocfl-no-such-xmllint"); err == nil {
+		t.Errorf("missing xmllint not detected")
+	}
+	x, err := NewXMLLint("xmllint")
+	if err != nil {
+		t.Skip("xmllint not found - skipping schema validation")
+	}
+	tests := []struct {
+		name   string
+		schema string
+		data   string
+		err    string
+	}{
+		{
+			name:   "valid premis",
+			schema: SchemaPremis,
+			data: `<premis xmlns="http://www.loc.gov/premis/v3" version="3.0">
+  <object xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="file">
+    <objectIdentifier><objectIdentifierType>local</objectIdentifierType><objectIdentifierValue>v1/content/a.txt</objectIdentifierValue></objectIdentifier>
+    <objectCharacteristics><format><formatDesignation><formatName>text/plain</formatName></formatDesignation></format></objectCharacteristics>
+  </object>
+</premis>`,
+		},
+		{
+			name:   "invalid premis",
+			schema: SchemaPremis,
+			data:   `<premis xmlns="http://www.loc.gov/premis/v3" version="3.0"><event/></premis>`,
+			err:    "not valid against premis-v3-0.xsd",
+		},
+		{
+			name:   "no xml",
+			schema: SchemaEAD3,
+			data:   "no xml",
+			err:    "not valid against ead3.xsd",
+		},
+		{
+			name:   "unknown schema",
+			schema: "mets.xsd",
+			data:   "<mets/>",
+			err:    "unknown schema",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := x.Validate(tt.schema, []byte(tt.data))
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error '%s', got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/dedup"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+)
+
+// DedupIndexName is the storage root extension folder of the content digest index (see package dedup)
+const DedupIndexName = dedup.ExtensionName
+
+func NewDedupIndexFS(fsys fs.FS) (*DedupIndex, error) {
+	fp, err := fsys.Open("config.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open config.json")
+	}
+	defer fp.Close()
+	data, err := io.ReadAll(fp)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read config.json")
+	}
+
+	var config = &DedupIndexConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal DedupIndexConfig '%s'", string(data))
+	}
+	di, err := NewDedupIndex(config)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	di.fsys = fsys
+	return di, nil
+}
+
+func NewDedupIndex(config *DedupIndexConfig) (*DedupIndex, error) {
+	di := &DedupIndex{DedupIndexConfig: config}
+	if config.ExtensionName != di.GetName() {
+		return nil, errors.New(fmt.Sprintf("invalid extension name'%s'for extension %s", config.ExtensionName, di.GetName()))
+	}
+	return di, nil
+}
+
+type DedupIndexConfig struct {
+	*extension.ExtensionConfig
+}
+
+// DedupIndex declares the folder of the content digest index as extension. the index is managed by dedup.Index
+type DedupIndex struct {
+	*DedupIndexConfig
+	fsys fs.FS
+}
+
+func (di *DedupIndex) Terminate() error {
+	return nil
+}
+
+func (di *DedupIndex) GetFS() fs.FS {
+	return di.fsys
+}
+
+func (di *DedupIndex) GetConfig() any {
+	return di.DedupIndexConfig
+}
+
+func (di *DedupIndex) IsRegistered() bool {
+	return false
+}
+
+func (di *DedupIndex) SetFS(fsys fs.FS, create bool) {
+	di.fsys = fsys
+}
+
+func (di *DedupIndex) SetParams(params map[string]string) error {
+	return nil
+}
+
+func (di *DedupIndex) GetName() string { return DedupIndexName }
+
+func (di *DedupIndex) WriteConfig() error {
+	if di.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	configWriter, err := writefs.Create(di.fsys, "config.json")
+	if err != nil {
+		return errors.Wrap(err, "cannot open config.json")
+	}
+	defer configWriter.Close()
+	jenc := json.NewEncoder(configWriter)
+	jenc.SetIndent("", "   ")
+	if err := jenc.Encode(di.ExtensionConfig); err != nil {
+		return errors.Wrapf(err, "cannot encode config to file")
+	}
+	return nil
+}
+
+// check interface satisfaction
+var (
+	_ extension.Extension = &DedupIndex{}
+)
@@ -68,6 +68,8 @@ type GOCFLExtensionManager struct {
 	objectContentPath  []object.ExtensionObjectContentPath
 	objectExternalPath []object.ExtensionObjectStatePath
 	contentChange      []object.ExtensionContentChange
+	contentInspection  []object.ExtensionContentInspection
+	dryRun             []object.ExtensionDryRun
 	objectChange       []object.ExtensionObjectChange
 	fixityDigest       []object.ExtensionFixityDigest
 	objectExtractPath  []object.ExtensionObjectExtractPath
@@ -75,6 +77,8 @@ type GOCFLExtensionManager struct {
 	area               []object.ExtensionArea
 	stream             []object.ExtensionStream
 	newVersion         []object.ExtensionNewVersion
+	versionCheck       []object.ExtensionVersionCheck
+	versionDone        []object.ExtensionVersionDone
 	fsys               fs.FS
 	initial            extension.ExtensionInitial
 }
@@ -130,6 +134,12 @@ func (manager *GOCFLExtensionManager) Add(ext extension.Extension) error {
 	if occ, ok := ext.(object.ExtensionContentChange); ok {
 		manager.contentChange = append(manager.contentChange, occ)
 	}
+	if oci, ok := ext.(object.ExtensionContentInspection); ok {
+		manager.contentInspection = append(manager.contentInspection, oci)
+	}
+	if odr, ok := ext.(object.ExtensionDryRun); ok {
+		manager.dryRun = append(manager.dryRun, odr)
+	}
 	if occ, ok := ext.(object.ExtensionObjectChange); ok {
 		manager.objectChange = append(manager.objectChange, occ)
 	}
@@ -154,6 +164,12 @@ func (manager *GOCFLExtensionManager) Add(ext extension.Extension) error {
 	if newversion, ok := ext.(object.ExtensionNewVersion); ok {
 		manager.newVersion = append(manager.newVersion, newversion)
 	}
+	if versioncheck, ok := ext.(object.ExtensionVersionCheck); ok {
+		manager.versionCheck = append(manager.versionCheck, versioncheck)
+	}
+	if versiondone, ok := ext.(object.ExtensionVersionDone); ok {
+		manager.versionDone = append(manager.versionDone, versiondone)
+	}
 	return nil
 }
 
@@ -275,12 +291,16 @@ func (manager *GOCFLExtensionManager) Finalize() {
 	manager.objectExtractPath = organize(manager, manager.objectExtractPath, object.ExtensionObjectExtractPathName)
 	manager.objectExternalPath = organize(manager, manager.objectExternalPath, object.ExtensionObjectExternalPathName)
 	manager.contentChange = organize(manager, manager.contentChange, object.ExtensionContentChangeName)
+	manager.contentInspection = organize(manager, manager.contentInspection, object.ExtensionContentInspectionName)
+	manager.dryRun = organize(manager, manager.dryRun, object.ExtensionDryRunName)
 	manager.objectChange = organize(manager, manager.objectChange, object.ExtensionObjectChangeName)
 	manager.fixityDigest = organize(manager, manager.fixityDigest, object.ExtensionFixityDigestName)
 	manager.metadata = organize(manager, manager.metadata, object.ExtensionMetadataName)
 	manager.area = organize(manager, manager.area, object.ExtensionAreaName)
 	manager.stream = organize(manager, manager.stream, object.ExtensionStreamName)
 	manager.newVersion = organize(manager, manager.newVersion, object.ExtensionNewVersionName)
+	manager.versionCheck = organize(manager, manager.versionCheck, object.ExtensionVersionCheckName)
+	manager.versionDone = organize(manager, manager.versionDone, object.ExtensionVersionDoneName)
 }
 
 // Extension
@@ -405,14 +425,23 @@ func (manager *GOCFLExtensionManager) BuildObjectStatePath(object object.Object,
 }
 
 // ContentChange
-func (manager *GOCFLExtensionManager) AddFileBefore(object object.Object, sourceFS fs.FS, source string, dest string, area string, isDir bool) error {
+func (manager *GOCFLExtensionManager) AddFileBefore(obj object.Object, sourceFS fs.FS, source string, dest string, area string, isDir bool) error {
 	var errs = []error{}
+	var skip bool
 	for _, ocp := range manager.contentChange {
-		if err := ocp.AddFileBefore(object, sourceFS, source, dest, area, isDir); err != nil {
+		if err := ocp.AddFileBefore(obj, sourceFS, source, dest, area, isDir); err != nil {
+			if errors.Is(err, object.ErrSkipFile) {
+				skip = true
+				continue
+			}
 			errs = append(errs, err)
 			continue
 		}
 	}
+	// real errors take precedence over skipping the file
+	if len(errs) == 0 && skip {
+		return object.ErrSkipFile
+	}
 	return errors.Combine(errs...)
 }
 func (manager *GOCFLExtensionManager) UpdateFileBefore(object object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
@@ -466,6 +495,36 @@ func (manager *GOCFLExtensionManager) DeleteFileAfter(object object.Object, dest
 	return errors.Combine(errs...)
 }
 
+// ContentInspection
+func (manager *GOCFLExtensionManager) InspectContent() bool {
+	for _, ext := range manager.contentInspection {
+		if ext.InspectContent() {
+			return true
+		}
+	}
+	return false
+}
+
+// DryRun
+func (manager *GOCFLExtensionManager) DryRunFile(obj object.Object, sourceFS fs.FS, source string, area string) error {
+	var errs = []error{}
+	var skip bool
+	for _, odr := range manager.dryRun {
+		if err := odr.DryRunFile(obj, sourceFS, source, area); err != nil {
+			if errors.Is(err, object.ErrSkipFile) {
+				skip = true
+				continue
+			}
+			errs = append(errs, err)
+		}
+	}
+	// real errors take precedence over skipping the file
+	if len(errs) == 0 && skip {
+		return object.ErrSkipFile
+	}
+	return errors.Combine(errs...)
+}
+
 // ObjectChange
 func (manager *GOCFLExtensionManager) UpdateObjectBefore(object object.Object) error {
 	var errs = []error{}
@@ -570,6 +629,27 @@ func (manager *GOCFLExtensionManager) DoNewVersion(object object.Object) error {
 	return nil
 }
 
+// VersionCheck
+func (manager *GOCFLExtensionManager) CheckVersion(object object.Object) error {
+	for _, ext := range manager.versionCheck {
+		if err := ext.CheckVersion(object); err != nil {
+			return errors.Wrapf(err, "cannot call CheckVersion() from extension '%s'", ext.GetName())
+		}
+	}
+	return nil
+}
+
+// VersionDone
+func (manager *GOCFLExtensionManager) VersionDone(object object.Object) error {
+	var errs = []error{}
+	for _, ext := range manager.versionDone {
+		if err := ext.VersionDone(object); err != nil {
+			errs = append(errs, errors.Wrapf(err, "cannot call VersionDone() from extension '%s'", ext.GetName()))
+		}
+	}
+	return errors.Combine(errs...)
+}
+
 // Stream
 func (manager *GOCFLExtensionManager) StreamObject(obj object.Object, reader io.Reader, stateFiles []string, dest string) error {
 	if len(manager.stream) == 0 {
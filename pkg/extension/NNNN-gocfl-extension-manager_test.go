@@ -0,0 +1,112 @@
+package extension
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+)
+
+func TestManagerAddFileBeforeSkip(t *testing.T) {
+	errFailed := errors.New("failed")
+	for _, test := range []struct {
+		name string
+		errs []error
+		skip bool
+		fail bool
+	}{
+		{name: "no error", errs: []error{nil, nil}},
+		{name: "skip", errs: []error{nil, object.ErrSkipFile}, skip: true},
+		{name: "wrapped skip", errs: []error{errors.Wrap(object.ErrSkipFile, "rejected"), nil}, skip: true},
+		{name: "error and skip", errs: []error{object.ErrSkipFile, errFailed}, fail: true},
+		{name: "error", errs: []error{errFailed, nil}, fail: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			manager, err := NewGOCFLExtensionManager(&extension.ExtensionManagerConfig{
+				ExtensionConfig: &extension.ExtensionConfig{ExtensionName: GOCFLExtensionManagerName},
+			})
+			if err != nil {
+				t.Fatalf("cannot create extension manager: %v", err)
+			}
+			var exts []*testContentChange
+			for i, e := range test.errs {
+				ext := &testContentChange{name: string(rune('a' + i)), errs: map[string]error{"file.txt": e}}
+				exts = append(exts, ext)
+				if err := manager.Add(ext); err != nil {
+					t.Fatalf("cannot add extension: %v", err)
+				}
+			}
+			manager.Finalize()
+			err = manager.AddFileBefore(nil, nil, "file.txt", "file.txt", "content", false)
+			switch {
+			case test.fail:
+				if err == nil || errors.Is(err, object.ErrSkipFile) {
+					t.Errorf("expected error, got %v", err)
+				}
+			case test.skip:
+				if !errors.Is(err, object.ErrSkipFile) {
+					t.Errorf("expected ErrSkipFile, got %v", err)
+				}
+			default:
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+			}
+			// all extensions are called, even if one of them skips the file
+			for _, ext := range exts {
+				if len(ext.before) != 1 {
+					t.Errorf("extension '%s' called %d times", ext.name, len(ext.before))
+				}
+			}
+		})
+	}
+}
+
+func TestObjectSkipFile(t *testing.T) {
+	skipper := &testContentChange{name: "skipper", errs: map[string]error{
+		"skip.txt":        object.ErrSkipFile,
+		"skip-reader.txt": object.ErrSkipFile,
+		"skip-data.txt":   object.ErrSkipFile,
+	}}
+	obj, _ := newTestObject(t, "test:skip", skipper)
+	if _, err := obj.StartUpdate(nil, "skip test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	source := writeTestFiles(t, map[string]string{"keep.txt": "keep", "skip.txt": "skip"})
+	if err := obj.AddFolder(source, nil, false, "content"); err != nil {
+		t.Fatalf("cannot add folder: %v", err)
+	}
+	for _, name := range []string{"keep-reader.txt", "skip-reader.txt"} {
+		digest, err := obj.AddReader(io.NopCloser(bytes.NewBufferString(name)), []string{name}, "content", false, false)
+		if err != nil {
+			t.Fatalf("cannot add reader '%s': %v", name, err)
+		}
+		if (digest == "") != (name == "skip-reader.txt") {
+			t.Errorf("AddReader('%s') returned digest '%s'", name, digest)
+		}
+	}
+	for _, name := range []string{"keep-data.txt", "skip-data.txt"} {
+		if err := obj.AddData([]byte(name), name, false, "content", false, false); err != nil {
+			t.Fatalf("cannot add data '%s': %v", name, err)
+		}
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+
+	expected := []string{"v1/content/keep-data.txt", "v1/content/keep-reader.txt", "v1/content/keep.txt"}
+	if paths := manifestPaths(obj); !slices.Equal(paths, expected) {
+		t.Errorf("expected manifest %v, got %v", expected, paths)
+	}
+	slices.Sort(skipper.after)
+	if !slices.Equal(skipper.after, []string{"keep-data.txt", "keep-reader.txt", "keep.txt"}) {
+		t.Errorf("AddFileAfter called for %v", skipper.after)
+	}
+}
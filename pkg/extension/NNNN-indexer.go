@@ -311,6 +311,23 @@ func (sl *Indexer) GetMetadata(object object.Object) (map[string]any, error) {
 	return result, nil
 }
 
+// Identify runs the format identification on a file, which is not yet part of the object
+func (sl *Indexer) Identify(sourceFS fs.FS, source string) (*ironmaiden.ResultV2, error) {
+	if sl.indexerActions == nil {
+		return nil, errors.New("Please enable indexer in config file")
+	}
+	fp, err := sourceFS.Open(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open '%v/%s'", sourceFS, source)
+	}
+	defer fp.Close()
+	result, err := sl.indexerActions.Stream(fp, []string{source}, []string{"siegfried"})
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot identify '%s'", source)
+	}
+	return result, nil
+}
+
 func (sl *Indexer) StreamObject(object object.Object, reader io.Reader, stateFiles []string, dest string) error {
 	if !sl.active {
 		return nil
@@ -9,13 +9,16 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"emperror.dev/errors"
 	"github.com/BurntSushi/toml"
 	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/ocfl-archive/gocfl/v2/pkg/bagit"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -35,6 +38,12 @@ func GetMetaFileParams() []*extension.ExtensionExternalParam {
 			//File:          "Source",
 			Description: "url with metadata file. $ID will be replaced with object ID i.e. file:///c:/temp/$ID.json",
 		},
+		{
+			ExtensionName: MetaFileName,
+			Functions:     []string{"add", "create"},
+			Param:         "bag-info",
+			Description:   "url with bag-info.txt to store next to the metadata file (set automatically for BagIt sources)",
+		},
 		{
 			ExtensionName: MetaFileName,
 			Functions:     []string{"extract", "objectextension"},
@@ -111,6 +120,7 @@ type MetaFile struct {
 	*MetaFileConfig
 	schema         []byte
 	metadataSource *url.URL
+	bagInfoSource  *url.URL
 	fsys           fs.FS
 	compiledSchema *jsonschema.Schema
 	stored         bool
@@ -133,6 +143,29 @@ func (sl *MetaFile) IsRegistered() bool {
 	return false
 }
 
+// parseSourceURL parses an url or a local path
+func parseSourceURL(urlString string) (*url.URL, error) {
+	u, err := url.Parse(urlString)
+	if err != nil || u.Scheme == "" {
+		if urlString[0] == '/' {
+			u, err = url.Parse("file://" + urlString)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot parse '%s'", urlString)
+			}
+		} else {
+			d, err := os.Getwd()
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot get working directory")
+			}
+			u, err = url.Parse("file://" + filepath.ToSlash(filepath.Join(d, urlString)))
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot parse '%s'", urlString)
+			}
+		}
+	}
+	return u, nil
+}
+
 func (sl *MetaFile) SetParams(params map[string]string) error {
 	if params != nil {
 		name := fmt.Sprintf("ext-%s-%s", MetaFileName, "source")
@@ -141,26 +174,20 @@ func (sl *MetaFile) SetParams(params map[string]string) error {
 			if urlString == "" {
 				return errors.Errorf("no value for parameter '%s'", name)
 			}
-			u, err := url.Parse(urlString)
-			if err != nil || u.Scheme == "" {
-				if urlString[0] == '/' {
-					u, err = url.Parse("file://" + urlString)
-					if err != nil {
-						return errors.Wrapf(err, "cannot parse '%s'", urlString)
-					}
-				} else {
-					d, err := os.Getwd()
-					if err != nil {
-						return errors.Wrap(err, "cannot get working directory")
-					}
-					u, err = url.Parse("file://" + filepath.ToSlash(filepath.Join(d, urlString)))
-					if err != nil {
-						return errors.Wrapf(err, "cannot parse '%s'", urlString)
-					}
-				}
+			u, err := parseSourceURL(urlString)
+			if err != nil {
+				return err
 			}
 			sl.metadataSource = u
 		}
+		name = fmt.Sprintf("ext-%s-%s", MetaFileName, "bag-info")
+		if urlString := strings.TrimSpace(params[name]); urlString != "" {
+			u, err := parseSourceURL(urlString)
+			if err != nil {
+				return err
+			}
+			sl.bagInfoSource = u
+		}
 	}
 	return nil
 }
@@ -192,147 +219,277 @@ func (sl *MetaFile) WriteConfig() error {
 	return nil
 }
 
-func toStringKeys(val interface{}) (interface{}, error) {
-	var err error
+// normalizeMetadata converts decoded yaml or toml data to json types.
+// pointer is the json pointer of val, used for error messages
+func normalizeMetadata(val any, pointer string) (any, error) {
 	switch val := val.(type) {
-	case map[interface{}]interface{}:
-		m := make(map[string]interface{})
+	case map[any]any:
+		m := make(map[string]any, len(val))
 		for k, v := range val {
-			k, ok := k.(string)
-			if !ok {
-				return nil, errors.New("found non-string key")
+			var key string
+			switch k := k.(type) {
+			case string:
+				key = k
+			case bool, int, int64, uint64, float64:
+				key = fmt.Sprint(k)
+			default:
+				return nil, errors.Errorf("'%s': key of type %T not allowed", pointer, k)
+			}
+			nv, err := normalizeMetadata(v, pointer+"/"+jsonPointerEscape(key))
+			if err != nil {
+				return nil, err
 			}
-			m[k], err = toStringKeys(v)
+			m[key] = nv
+		}
+		return m, nil
+	case map[string]any:
+		m := make(map[string]any, len(val))
+		for k, v := range val {
+			nv, err := normalizeMetadata(v, pointer+"/"+jsonPointerEscape(k))
 			if err != nil {
 				return nil, err
 			}
+			m[k] = nv
 		}
 		return m, nil
-	case []interface{}:
-		var l = make([]interface{}, len(val))
+	case []map[string]any:
+		l := make([]any, len(val))
 		for i, v := range val {
-			l[i], err = toStringKeys(v)
+			nv, err := normalizeMetadata(v, fmt.Sprintf("%s/%d", pointer, i))
 			if err != nil {
 				return nil, err
 			}
+			l[i] = nv
 		}
 		return l, nil
+	case []any:
+		l := make([]any, len(val))
+		for i, v := range val {
+			nv, err := normalizeMetadata(v, fmt.Sprintf("%s/%d", pointer, i))
+			if err != nil {
+				return nil, err
+			}
+			l[i] = nv
+		}
+		return l, nil
+	case time.Time:
+		// toml local date and time types have no timezone
+		switch val.Location().String() {
+		case "date-local":
+			return val.Format("2006-01-02"), nil
+		case "time-local":
+			return val.Format("15:04:05.999999999"), nil
+		case "datetime-local":
+			return val.Format("2006-01-02T15:04:05.999999999"), nil
+		}
+		return val.Format(time.RFC3339Nano), nil
 	default:
 		return val, nil
 	}
 }
 
-func (sl *MetaFile) UpdateObjectBefore(object object.Object) error {
-	if sl.metadataSource.Path == "" {
-		return nil
+func jsonPointerEscape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// decodeMetadata decodes json, yaml or toml data and normalizes it to json
+func decodeMetadata(data []byte, ext string) (any, error) {
+	var info any
+	switch ext {
+	case ".json":
+		// json is not normalized, but numbers are kept as they are
+		jr := json.NewDecoder(bytes.NewReader(data))
+		jr.UseNumber()
+		if err := jr.Decode(&info); err != nil {
+			return nil, errors.Wrap(err, "cannot decode json")
+		}
+		return info, nil
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &info); err != nil {
+			return nil, errors.Wrap(err, "cannot decode yaml")
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(data), &info); err != nil {
+			return nil, errors.Wrap(err, "cannot decode toml")
+		}
+	default:
+		return nil, errors.Errorf("unknown file extension '%s' only .json, .toml and .yaml supported", ext)
+	}
+	info, err := normalizeMetadata(info, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot normalize to json")
+	}
+	// roundtrip to get json types only
+	data, err = json.Marshal(info)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal to json")
+	}
+	jr := json.NewDecoder(bytes.NewReader(data))
+	jr.UseNumber()
+	info = nil
+	if err := jr.Decode(&info); err != nil {
+		return nil, errors.Wrap(err, "cannot decode normalized json")
 	}
+	return info, nil
+}
+
+// schemaErrors lists all schema violations with the json pointer of the value
+func schemaErrors(err error) error {
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	var msgs = []string{}
+	var collect func(ve *jsonschema.ValidationError)
+	collect = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			msgs = append(msgs, fmt.Sprintf("'%s': %s", ve.InstanceLocation, ve.Message))
+			return
+		}
+		for _, cause := range ve.Causes {
+			collect(cause)
+		}
+	}
+	collect(verr)
+	return errors.Errorf("schema violation: %s", strings.Join(msgs, "; "))
+}
+
+// ValidateMetadata decodes json, yaml or toml data, validates it against the schema and
+// returns the normalized json
+func (sl *MetaFile) ValidateMetadata(data []byte, ext string) ([]byte, error) {
+	info, err := decodeMetadata(data, strings.ToLower(ext))
+	if err != nil {
+		return nil, err
+	}
+	if err := sl.compiledSchema.Validate(info); err != nil {
+		return nil, errors.Wrap(schemaErrors(err), "cannot validate info file")
+	}
+	infoData, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal info json")
+	}
+	return infoData, nil
+}
+
+func (sl *MetaFile) UpdateObjectBefore(object object.Object) error {
 	if sl.stored {
 		return nil
 	}
-	sl.stored = true
 	var err error
 	inventory := object.GetInventory()
 	if inventory == nil {
 		return errors.New("no inventory available")
 	}
-	if sl.metadataSource == nil {
+	hasMetadata := sl.metadataSource != nil && sl.metadataSource.Path != ""
+	if !hasMetadata && sl.bagInfoSource == nil {
 		// only a problem, if first version
-		if len(inventory.GetVersionStrings()) < 2 {
+		if sl.metadataSource == nil && len(inventory.GetVersionStrings()) < 2 {
 			return errors.New("no metadata source configured")
 		}
 		return nil
 	}
+	sl.stored = true
 	if sl.fsys == nil {
 		return errors.New("no filesystem set")
 	}
-	var rc io.ReadCloser
-	var fname string
-	switch strings.ToLower(sl.metadataSource.Scheme) {
-	case "http":
-		fname = strings.Replace(sl.metadataSource.String(), "$ID", object.GetID(), -1)
-		resp, err := http.Get(fname)
-		if err != nil {
-			return errors.Wrapf(err, "cannot get '%s'", fname)
+	if sl.bagInfoSource != nil {
+		if err := sl.storeBagInfo(object); err != nil {
+			return err
 		}
-		rc = resp.Body
-	case "https":
-		fname = strings.Replace(sl.metadataSource.String(), "$ID", object.GetID(), -1)
+	}
+	if !hasMetadata {
+		// bag-info.txt only
+		return nil
+	}
+	rc, fname, err := openSource(sl.metadataSource, object.GetID())
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	source, err := io.ReadAll(rc)
+	if err != nil {
+		return errors.Wrapf(err, "cannot read '%s'", fname)
+	}
+	ext := strings.ToLower(path.Ext(sl.metadataSource.Path))
+	infoData, err := sl.ValidateMetadata(source, ext)
+	if err != nil {
+		return errors.Wrapf(err, "invalid metadata in '%s'", fname)
+	}
+	if err := sl.store(object, sl.MetaName, infoData); err != nil {
+		return err
+	}
+	if ext != ".json" {
+		// keep the original yaml or toml
+		originalName := strings.TrimSuffix(sl.MetaName, path.Ext(sl.MetaName)) + ext
+		if originalName != sl.MetaName {
+			if err := sl.store(object, originalName, source); err != nil {
+				return err
+			}
+		}
+	}
+
+	// remember the content
+	sl.info[inventory.GetHead()] = infoData
+	return nil
+}
+
+// storeBagInfo stores the bag-info.txt of a BagIt source next to the metadata file
+func (sl *MetaFile) storeBagInfo(object object.Object) error {
+	rc, fname, err := openSource(sl.bagInfoSource, object.GetID())
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return errors.Wrapf(err, "cannot read '%s'", fname)
+	}
+	if _, err := bagit.ParseTags(data); err != nil {
+		return errors.Wrapf(err, "invalid bag info in '%s'", fname)
+	}
+	return sl.store(object, bagit.BagInfoFile, data)
+}
+
+// openSource opens a http(s) or file url. $ID is replaced with the object id
+func openSource(u *url.URL, id string) (rc io.ReadCloser, fname string, err error) {
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		fname = strings.Replace(u.String(), "$ID", id, -1)
 		resp, err := http.Get(fname)
 		if err != nil {
-			return errors.Wrapf(err, "cannot get '%s'", fname)
+			return nil, "", errors.Wrapf(err, "cannot get '%s'", fname)
 		}
 		rc = resp.Body
 	case "file":
-		fname = strings.Replace(sl.metadataSource.Path, "$ID", object.GetID(), -1)
+		fname = strings.Replace(u.Path, "$ID", id, -1)
 		fname = "/" + strings.TrimLeft(fname, "/")
 		if windowsPathWithDrive.Match([]byte(fname)) {
 			fname = strings.TrimLeft(fname, "/")
 		}
 		rc, err = os.Open(fname)
 		if err != nil {
-			return errors.Wrapf(err, "cannot open '%s'", fname)
+			return nil, "", errors.Wrapf(err, "cannot open '%s'", fname)
 		}
 	case "":
-		fname = strings.Replace(sl.metadataSource.Path, "$ID", object.GetID(), -1)
+		fname = strings.Replace(u.Path, "$ID", id, -1)
 		fname = "/" + strings.TrimLeft(fname, "/")
 		rc, err = os.Open(fname)
 		if err != nil {
-			return errors.Wrapf(err, "cannot open '%s'", fname)
+			return nil, "", errors.Wrapf(err, "cannot open '%s'", fname)
 		}
 	default:
-		return errors.Errorf("url scheme '%s' not supported", sl.metadataSource.Scheme)
-	}
-	defer rc.Close()
-
-	var infoData []byte
-	var info any
-
-	switch strings.ToLower(filepath.Ext(fname)) {
-	case ".json":
-		jr := json.NewDecoder(rc)
-		if err := jr.Decode(&info); err != nil {
-			return errors.Wrap(err, "cannot decode info file")
-		}
-		if err := sl.compiledSchema.Validate(info); err != nil {
-			return errors.Wrap(err, "cannot validate info file")
-		}
-	case ".yaml":
-		jr := yaml.NewDecoder(rc)
-		if err := jr.Decode(&info); err != nil {
-			return errors.Wrap(err, "cannot decode info file")
-		}
-		info, err = toStringKeys(info)
-		if err != nil {
-			return errors.Wrap(err, "cannot convert map[any]any to map[string]any")
-		}
-		if err := sl.compiledSchema.Validate(info); err != nil {
-			return errors.Wrap(err, "cannot validate info file")
-		}
-	case ".toml":
-		jr := toml.NewDecoder(rc)
-		if _, err := jr.Decode(&info); err != nil {
-			return errors.Wrap(err, "cannot decode info file")
-		}
-		info, err = toStringKeys(info)
-		if err != nil {
-			return errors.Wrap(err, "cannot convert map[any]any to map[string]any")
-		}
-		if err := sl.compiledSchema.Validate(info); err != nil {
-			return errors.Wrap(err, "cannot validate info file")
-		}
-	default:
-		return errors.Errorf("unknown file extension in '%s' only .json, .toml and .yaml supported", fname)
-	}
-
-	infoData, err = json.MarshalIndent(info, "", "  ")
-	if err != nil {
-		return errors.Wrap(err, "cannot marshal info json")
+		return nil, "", errors.Errorf("url scheme '%s' not supported", u.Scheme)
 	}
+	return rc, fname, nil
+}
 
+// store writes a file to the configured storage location
+func (sl *MetaFile) store(object object.Object, name string, data []byte) error {
 	switch strings.ToLower(sl.StorageType) {
 	case "area":
-		targetname := strings.TrimLeft(sl.MetaName, "/")
-		if _, err := object.AddReader(io.NopCloser(bytes.NewBuffer(infoData)), []string{targetname}, sl.StorageName, true, false); err != nil {
+		targetname := strings.TrimLeft(name, "/")
+		if _, err := object.AddReader(io.NopCloser(bytes.NewBuffer(data)), []string{targetname}, sl.StorageName, true, false); err != nil {
 			return errors.Wrapf(err, "cannot write '%s'", targetname)
 		}
 	case "path":
@@ -340,23 +497,18 @@ func (sl *MetaFile) UpdateObjectBefore(object object.Object) error {
 		if err != nil {
 			return errors.Wrapf(err, "cannot get area path for '%s'", "content")
 		}
-		targetname := strings.TrimLeft(filepath.ToSlash(filepath.Join(path, sl.StorageName, sl.MetaName)), "/")
-
-		//targetname := fmt.Sprintf("%s/%s_%s.jsonl%s", name, storageName, head, ext)
-		if _, err := object.AddReader(io.NopCloser(bytes.NewBuffer(infoData)), []string{targetname}, "", true, false); err != nil {
+		targetname := strings.TrimLeft(filepath.ToSlash(filepath.Join(path, sl.StorageName, name)), "/")
+		if _, err := object.AddReader(io.NopCloser(bytes.NewBuffer(data)), []string{targetname}, "", true, false); err != nil {
 			return errors.Wrapf(err, "cannot write '%s'", targetname)
 		}
 	case "extension":
-		targetname := strings.TrimLeft(filepath.ToSlash(filepath.Join(sl.StorageName, sl.MetaName)), "/")
-		if _, err := writefs.WriteFile(sl.fsys, targetname, infoData); err != nil {
+		targetname := strings.TrimLeft(filepath.ToSlash(filepath.Join(sl.StorageName, name)), "/")
+		if _, err := writefs.WriteFile(sl.fsys, targetname, data); err != nil {
 			return errors.Wrapf(err, "cannot write file '%v/%s'", sl.fsys, targetname)
 		}
 	default:
 		return errors.Errorf("unsupported storage type '%s'", sl.StorageType)
 	}
-
-	// remember the content
-	sl.info[inventory.GetHead()] = infoData
 	return nil
 }
 
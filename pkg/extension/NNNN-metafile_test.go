@@ -0,0 +1,256 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"emperror.dev/errors"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/ocfl-archive/gocfl/v2/pkg/bagit"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func newTestMetaFile(t *testing.T, params map[string]string) *MetaFile {
+	sl, err := NewMetaFile(&MetaFileConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: MetaFileName},
+		StorageType:     "extension",
+		StorageName:     "metadata",
+		MetaName:        "info.json",
+		MetaSchemaUrl:   "https://example.org/info.schema.json",
+	}, []byte(`{"type": "object"}`))
+	if err != nil {
+		t.Fatalf("cannot create extension: %v", err)
+	}
+	if err := sl.SetParams(params); err != nil {
+		t.Fatalf("cannot set parameters: %v", err)
+	}
+	return sl
+}
+
+func TestMetaFileBagInfoOnly(t *testing.T) {
+	bagInfo := filepath.Join(t.TempDir(), bagit.BagInfoFile)
+	if err := os.WriteFile(bagInfo, []byte("Source-Organization: Example Archive\n"), 0644); err != nil {
+		t.Fatalf("cannot write %s: %v", bagit.BagInfoFile, err)
+	}
+	sl := newTestMetaFile(t, map[string]string{"ext-" + MetaFileName + "-bag-info": bagInfo})
+	obj, dir := newTestObject(t, "id:bag", sl)
+	if _, err := obj.StartUpdate(nil, "metafile test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "extensions", MetaFileName, "metadata", bagit.BagInfoFile))
+	if err != nil {
+		t.Fatalf("%s not stored: %v", bagit.BagInfoFile, err)
+	}
+	if string(data) != "Source-Organization: Example Archive\n" {
+		t.Errorf("unexpected %s: '%s'", bagit.BagInfoFile, string(data))
+	}
+}
+
+func TestMetaFileNoSource(t *testing.T) {
+	sl := newTestMetaFile(t, nil)
+	obj, _ := newTestObject(t, "id:nosource", sl)
+	if _, err := obj.StartUpdate(nil, "metafile test", "test", "mailto:test@example.org", false); err == nil {
+		t.Errorf("missing metadata source of first version not detected")
+	}
+}
+
+func TestKnownDigests(t *testing.T) {
+	// sha256 of "a"
+	const sha256A = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	known := map[string]map[checksum.DigestAlgorithm]string{
+		"a.txt": {checksum.DigestSHA256: sha256A},
+		"b.txt": {checksum.DigestSHA256: sha256A},
+	}
+	ctx := object.NewKnownDigestsContext(context.Background(), func(area, path string) map[checksum.DigestAlgorithm]string {
+		if area != "content" {
+			return nil
+		}
+		return known[path]
+	})
+
+	obj, _ := newTestObjectContext(t, ctx, "id:known")
+	if _, err := obj.StartUpdate(nil, "known digests test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(writeTestFiles(t, map[string]string{"a.txt": "a"}), nil, false, "content"); err != nil {
+		t.Fatalf("cannot add folder: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if digests := obj.GetInventory().GetFixity().Checksums("v1/content/a.txt"); digests[checksum.DigestSHA256] != sha256A {
+		t.Errorf("known digest not stored as fixity: %v", digests)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+
+	// digests of the source are verified, if the algorithm is computed anyway
+	known["b.txt"] = map[checksum.DigestAlgorithm]string{checksum.DigestSHA512: "0000"}
+	obj, _ = newTestObjectContext(t, ctx, "id:changed")
+	if _, err := obj.StartUpdate(nil, "known digests test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(writeTestFiles(t, map[string]string{"b.txt": "b"}), nil, false, "content"); err == nil {
+		t.Errorf("changed source file not detected")
+	}
+}
+
+func TestDecodeMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		ext  string
+		want string
+		err  string
+	}{
+		{
+			name: "json numbers",
+			data: `{"size": 12345678901234567890, "ratio": 1.50}`,
+			ext:  ".json",
+			want: `{"ratio":1.50,"size":12345678901234567890}`,
+		},
+		{
+			name: "yaml",
+			data: "title: Test\nkeywords:\n  - a\n  - b\nnested:\n  count: 3\n",
+			ext:  ".yaml",
+			want: `{"keywords":["a","b"],"nested":{"count":3},"title":"Test"}`,
+		},
+		{
+			name: "yaml non-string keys",
+			data: "1: one\ntrue: yes\n2.5: half\n",
+			ext:  ".yml",
+			want: `{"1":"one","2.5":"half","true":true}`,
+		},
+		{
+			name: "yaml null key",
+			data: "list:\n  - ~: x\n",
+			ext:  ".yaml",
+			err:  "'/list/0': key of type <nil> not allowed",
+		},
+		{
+			name: "toml local dates",
+			data: "date = 2024-03-01\ntime = 07:32:00\nlocal = 2024-03-01T07:32:00\noffset = 2024-03-01T07:32:00+01:00\n",
+			ext:  ".toml",
+			want: `{"date":"2024-03-01","local":"2024-03-01T07:32:00","offset":"2024-03-01T07:32:00+01:00","time":"07:32:00"}`,
+		},
+		{
+			name: "toml array of tables",
+			data: "[[items]]\nname = \"a\"\n[[items]]\nname = \"b\"\n",
+			ext:  ".toml",
+			want: `{"items":[{"name":"a"},{"name":"b"}]}`,
+		},
+		{
+			name: "unknown extension",
+			data: "title: Test",
+			ext:  ".txt",
+			err:  "unknown file extension '.txt'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := decodeMetadata([]byte(tt.data), tt.ext)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error '%s', got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cannot decode: %v", err)
+			}
+			data, err := json.Marshal(info)
+			if err != nil {
+				t.Fatalf("cannot marshal: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, string(data))
+			}
+		})
+	}
+}
+
+func TestNormalizeMetadataPointer(t *testing.T) {
+	tests := []struct {
+		name string
+		val  any
+		err  string
+	}{
+		{"root", map[any]any{nil: 1}, "'': key of type <nil> not allowed"},
+		{"escaped key", map[string]any{"a/b~c": map[any]any{1.5: map[any]any{nil: 1}}}, "'/a~1b~0c/1.5': key of type <nil> not allowed"},
+		{"list", map[string]any{"items": []map[string]any{{}, {"x": []any{map[any]any{nil: 1}}}}}, "'/items/1/x/0': key of type <nil> not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := normalizeMetadata(tt.val, "")
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expected error '%s', got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	schema, err := jsonschema.CompileString("test.schema.json", `{
+		"type": "object",
+		"required": ["title"],
+		"properties": {
+			"title": {"type": "string"},
+			"items": {"type": "array", "items": {"type": "object", "properties": {"size": {"type": "integer"}}}}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("cannot compile schema: %v", err)
+	}
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"valid", "title: Test\nitems:\n  - size: 1\n", nil},
+		{"missing property", "items: []\n", []string{"'': missing properties: 'title'"}},
+		{"nested values", "title: 1\nitems:\n  - size: 1\n  - size: big\n", []string{"'/title': expected string", "'/items/1/size': expected integer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := decodeMetadata([]byte(tt.data), ".yaml")
+			if err != nil {
+				t.Fatalf("cannot decode: %v", err)
+			}
+			err = schema.Validate(info)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("schema violation not detected")
+			}
+			msg := schemaErrors(err).Error()
+			if !strings.HasPrefix(msg, "schema violation: ") {
+				t.Errorf("unexpected message '%s'", msg)
+			}
+			for _, str := range tt.want {
+				if !strings.Contains(msg, str) {
+					t.Errorf("'%s' not in '%s'", str, msg)
+				}
+			}
+		})
+	}
+	if err := schemaErrors(errors.New("other")); err.Error() != "other" {
+		t.Errorf("non validation error changed: %v", err)
+	}
+}
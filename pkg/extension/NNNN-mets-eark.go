@@ -0,0 +1,846 @@
+package extension
+
+import (
+	"crypto/sha512"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/google/uuid"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/data/specs"
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis/mets"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/version"
+	"github.com/ocfl-archive/indexer/v3/pkg/indexer"
+)
+
+const (
+	EARKPackageTypeSIP = "SIP"
+	EARKPackageTypeAIP = "AIP"
+
+	EARKMetsFile = "METS.xml"
+
+	earkSIPProfile    = "https://earksip.dilcis.eu/profile/E-ARK-SIP.xml"
+	earkAIPProfile    = "https://earkaip.dilcis.eu/profile/E-ARK-AIP.xml"
+	earkCSIPNamespace = "https://DILCIS.eu/XML/METS/CSIPExtensionMETS"
+	earkSIPNamespace  = "https://DILCIS.eu/XML/METS/SIPExtensionMETS"
+)
+
+// earkFile is a file of the exported information package
+type earkFile struct {
+	path         string
+	mimetype     string
+	created      string
+	size         int64
+	checksum     string
+	checksumType string
+	mdType       string
+	otherMDType  string
+}
+
+// earkPackage contains everything needed to build the root METS file of an information package
+type earkPackage struct {
+	objectID     string
+	label        string
+	packageType  string
+	created      time.Time
+	organisation string
+	archivist    string
+	archivistID  string
+	files        []*earkFile
+}
+
+// GetObjectMets returns the NNNN-mets extension of the object or a default configuration, if the object does not use it
+func GetObjectMets(obj object.Object, logger zLogger.ZLogger) (*Mets, error) {
+	for _, ext := range obj.GetExtensionManager().GetExtensions() {
+		if me, ok := ext.(*Mets); ok {
+			return me, nil
+		}
+	}
+	return NewMets(&MetsConfig{
+		ExtensionConfig:            &extension.ExtensionConfig{ExtensionName: METSName},
+		StorageType:                "area",
+		StorageName:                "metadata",
+		PrimaryDescriptiveMetadata: "metadata:info.json",
+		MetsFile:                   "mets.xml",
+		PremisFile:                 "premis.xml",
+	}, logger)
+}
+
+// earkTarget maps the logical path of an object file to its path inside the information package.
+// an empty target means, that the file is not exported
+func (me *Mets) earkTarget(logicalPath, metadataPrefix string, contentSubPath map[string]ContentSubPathEntry) string {
+	if metadataPrefix != "" && strings.HasPrefix(logicalPath, metadataPrefix+"/") {
+		rel := strings.TrimPrefix(logicalPath, metadataPrefix+"/")
+		switch {
+		case rel == me.MetsFile:
+			// replaced by the root METS file
+			return ""
+		case strings.HasPrefix(rel, "schemas/"):
+			return rel
+		case rel == me.PremisFile:
+			return path.Join("metadata/preservation", rel)
+		default:
+			return path.Join("metadata/descriptive", rel)
+		}
+	}
+	for area, cse := range contentSubPath {
+		if cse.Path != "" && strings.HasPrefix(logicalPath, cse.Path+"/") {
+			return path.Join("representations", area, "data", strings.TrimPrefix(logicalPath, cse.Path+"/"))
+		}
+	}
+	return path.Join("representations", "content", "data", logicalPath)
+}
+
+// ExportEARK writes the version of the object as E-ARK SIP or AIP to fsys.
+// the content areas become representations, the metadata area is split into descriptive and preservation metadata
+func (me *Mets) ExportEARK(obj object.Object, version, packageType string, fsys fs.FS) error {
+	packageType = strings.ToUpper(packageType)
+	if packageType != EARKPackageTypeSIP && packageType != EARKPackageTypeAIP {
+		return errors.Errorf("invalid package type '%s'", packageType)
+	}
+	inventory := obj.GetInventory()
+	if version == "" || version == "latest" {
+		version = inventory.GetHead()
+	}
+	v, ok := inventory.GetVersions()[version]
+	if !ok {
+		return errors.Errorf("object '%s' has no version '%s'", obj.GetID(), version)
+	}
+	metadata, err := obj.GetMetadata()
+	if err != nil {
+		return errors.Wrap(err, "cannot get metadata from object")
+	}
+
+	var contentSubPath = map[string]ContentSubPathEntry{}
+	var organisation, archivist, archivistID string
+	if extensionMap, _ := metadata.Extension.(map[string]any); extensionMap != nil {
+		if contentSubPathAny, ok := extensionMap[ContentSubPathName]; ok {
+			contentSubPath, _ = contentSubPathAny.(map[string]ContentSubPathEntry)
+		}
+		if metaFile, ok := extensionMap[MetaFileName].(map[string]any); ok {
+			organisation, _ = metaFile["organisation"].(string)
+			archivist, _ = metaFile["user"].(string)
+			archivistID, _ = metaFile["address"].(string)
+		}
+	}
+	if archivist == "" {
+		archivist = v.User.Name.String()
+		archivistID = v.User.Address.String()
+	}
+
+	var metadataPrefix string
+	switch strings.ToLower(me.StorageType) {
+	case "area":
+		if cse, ok := contentSubPath[me.StorageName]; ok {
+			metadataPrefix = cse.Path
+		}
+	case "path":
+		metadataPrefix = me.StorageName
+		if cse, ok := contentSubPath["content"]; ok {
+			metadataPrefix = path.Join(cse.Path, me.StorageName)
+		}
+	}
+	var primaryMetaFilename, primaryMetaType, primaryOtherMetaType string
+	if me.PrimaryDescriptiveMetadata != "" {
+		primaryMetaFilename, primaryMetaType, primaryOtherMetaType, err = me.primaryDescriptiveMetadata(contentSubPath)
+		if err != nil {
+			me.logger.Warn().Err(err).Msgf("cannot resolve primary descriptive metadata of '%s'", obj.GetID())
+		}
+	}
+
+	digestAlg := inventory.GetDigestAlgorithm()
+	metsAlg := digestAlg
+	if checksumTypeToMets(string(metsAlg)) == "" {
+		metsAlg = checksum.DigestSHA512
+	}
+	algs := []checksum.DigestAlgorithm{digestAlg}
+	if metsAlg != digestAlg {
+		algs = append(algs, metsAlg)
+	}
+
+	pkg := &earkPackage{
+		objectID:     obj.GetID(),
+		label:        fmt.Sprintf("%s version %s - %s", obj.GetID(), version, v.Message.String()),
+		packageType:  packageType,
+		created:      v.Created.Time,
+		organisation: organisation,
+		archivist:    archivist,
+		archivistID:  archivistID,
+	}
+	written := map[string]bool{}
+	if err := inventory.IterateStateFiles(version, func(internals, externals []string, digest string) error {
+		if len(internals) == 0 {
+			return errors.Errorf("no internal paths for '%v'", externals)
+		}
+		var mimetype = "application/octet-stream"
+		var created string
+		if fm, ok := metadata.Files[digest]; ok {
+			if idx, ok := fm.Extension[IndexerName].(*indexer.ResultV2); ok && idx != nil && idx.Mimetype != "" {
+				mimetype = idx.Mimetype
+			}
+			for _, ver := range inventory.GetVersionStrings() {
+				if _, ok := fm.VersionName[ver]; ok {
+					if vm, ok := metadata.Versions[ver]; ok {
+						created = vm.Created.Format("2006-01-02T15:04:05")
+					}
+					break
+				}
+			}
+		}
+		for _, external := range externals {
+			target := me.earkTarget(external, metadataPrefix, contentSubPath)
+			if target == "" {
+				continue
+			}
+			file := &earkFile{
+				path:         target,
+				mimetype:     mimetype,
+				created:      created,
+				checksumType: string(metsAlg),
+			}
+			if strings.HasPrefix(target, "metadata/preservation/") {
+				file.mdType = "PREMIS"
+			} else if external == primaryMetaFilename {
+				file.mdType = primaryMetaType
+				file.otherMDType = primaryOtherMetaType
+			}
+			if err := func() error {
+				src, err := obj.GetFS().Open(internals[0])
+				if err != nil {
+					return errors.Wrapf(err, "cannot open '%v/%s'", obj.GetFS(), internals[0])
+				}
+				defer src.Close()
+				if fi, err := src.Stat(); err == nil {
+					file.size = fi.Size()
+				}
+				dst, err := writefs.Create(fsys, target)
+				if err != nil {
+					return errors.Wrapf(err, "cannot create '%v/%s'", fsys, target)
+				}
+				defer dst.Close()
+				digests, err := checksum.Copy(algs, src, dst)
+				if err != nil {
+					return errors.Wrapf(err, "error copying '%v/%s' -> '%v/%s'", obj.GetFS(), internals[0], fsys, target)
+				}
+				if digests[digestAlg] != digest {
+					return errors.Errorf("invalid digest for '%s' - [%s] != [%s]", internals[0], digests[digestAlg], digest)
+				}
+				file.checksum = digests[metsAlg]
+				return nil
+			}(); err != nil {
+				return err
+			}
+			written[target] = true
+			pkg.files = append(pkg.files, file)
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "cannot export files of '%s'", obj.GetID())
+	}
+
+	schemas := map[string][]byte{
+		"schemas/mets.xsd":                   specs.METSXSD,
+		"schemas/xlink.xsd":                  specs.XLinkXSD,
+		"schemas/DILCISExtensionMETS.xsd":    specs.CSIPExtensionMETSXSD,
+		"schemas/DILCISExtensionSIPMETS.xsd": specs.SIPExtensionMETSXSD,
+	}
+	for _, file := range pkg.files {
+		if file.mdType == "PREMIS" {
+			schemas["schemas/premis.xsd"] = specs.PremisXSD
+			break
+		}
+	}
+	for name, data := range schemas {
+		if written[name] {
+			continue
+		}
+		if _, err := writefs.WriteFile(fsys, name, data); err != nil {
+			return errors.Wrapf(err, "cannot write file '%v/%s'", fsys, name)
+		}
+		pkg.files = append(pkg.files, &earkFile{
+			path:         name,
+			mimetype:     "application/xml",
+			created:      time.Now().Format("2006-01-02T15:04:05"),
+			size:         int64(len(data)),
+			checksum:     fmt.Sprintf("%x", sha512.Sum512(data)),
+			checksumType: string(checksum.DigestSHA512),
+		})
+	}
+
+	m := buildEARKMets(pkg)
+	metsBytes, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal METS")
+	}
+	if _, err := writefs.WriteFile(fsys, EARKMetsFile, append([]byte(xml.Header), metsBytes...)); err != nil {
+		return errors.Wrapf(err, "cannot write file '%v/%s'", fsys, EARKMetsFile)
+	}
+	if err := CheckCSIP(m, fsys); err != nil {
+		return errors.Wrapf(err, "package of '%s' does not conform to E-ARK CSIP", obj.GetID())
+	}
+	return nil
+}
+
+// earkFileGroup returns the fileGrp USE of a file which is not metadata
+func earkFileGroup(filePath string) string {
+	parts := strings.Split(filePath, "/")
+	switch {
+	case parts[0] == "schemas":
+		return "Schemas"
+	case parts[0] == "representations" && len(parts) > 2:
+		return "Representations/" + parts[1]
+	default:
+		return "Documentation"
+	}
+}
+
+func buildEARKMets(pkg *earkPackage) *mets.Mets {
+	slices.SortFunc(pkg.files, func(a, b *earkFile) int { return strings.Compare(a.path, b.path) })
+
+	var dmdSecs = []*mets.MdSecType{}
+	var digiprovMDs = []*mets.MdSecType{}
+	var fileGrpNames = []string{}
+	var fileGrps = map[string]*mets.FileGrpType{}
+	for _, file := range pkg.files {
+		id := "uuid-" + uuid.NewString()
+		switch {
+		case strings.HasPrefix(file.path, "metadata/descriptive/"):
+			sec := newMDSec(id, "", file.path, "URL", "", file.mimetype, file.created, file.size, file.mdType, file.otherMDType, file.checksum, file.checksumType)
+			sec.STATUSAttr = "CURRENT"
+			dmdSecs = append(dmdSecs, sec)
+		case strings.HasPrefix(file.path, "metadata/preservation/"):
+			sec := newMDSec(id, "", file.path, "URL", "", file.mimetype, file.created, file.size, file.mdType, file.otherMDType, file.checksum, file.checksumType)
+			sec.STATUSAttr = "CURRENT"
+			digiprovMDs = append(digiprovMDs, sec)
+		default:
+			use := earkFileGroup(file.path)
+			grp, ok := fileGrps[use]
+			if !ok {
+				grp = &mets.FileGrpType{
+					IDAttr:  "uuid-" + uuid.NewString(),
+					USEAttr: use,
+					File:    []*mets.FileType{},
+				}
+				fileGrps[use] = grp
+				fileGrpNames = append(fileGrpNames, use)
+			}
+			grp.File = append(grp.File, &mets.FileType{
+				FILECORE: &mets.FILECORE{
+					MIMETYPEAttr:     file.mimetype,
+					SIZEAttr:         file.size,
+					CREATEDAttr:      file.created,
+					CHECKSUMAttr:     file.checksum,
+					CHECKSUMTYPEAttr: checksumTypeToMets(file.checksumType),
+				},
+				IDAttr: id,
+				FLocat: []*mets.FLocat{
+					{
+						LOCATION: &mets.LOCATION{
+							LOCTYPEAttr: "URL",
+						},
+						SimpleLink: &mets.SimpleLink{
+							TypeAttr:      "simple",
+							XlinkHrefAttr: file.path,
+						},
+					},
+				},
+			})
+		}
+	}
+	slices.Sort(fileGrpNames)
+
+	rootDiv := &mets.DivType{
+		ORDERLABELS: &mets.ORDERLABELS{LABELAttr: pkg.objectID},
+		IDAttr:      "uuid-" + uuid.NewString(),
+		Div:         []*mets.DivType{},
+	}
+	var amdSecs = []*mets.AmdSecType{}
+	if len(dmdSecs)+len(digiprovMDs) > 0 {
+		metadataDiv := &mets.DivType{
+			ORDERLABELS: &mets.ORDERLABELS{LABELAttr: "Metadata"},
+			IDAttr:      "uuid-" + uuid.NewString(),
+		}
+		for _, sec := range dmdSecs {
+			metadataDiv.DMDIDAttr = append(metadataDiv.DMDIDAttr, sec.IDAttr)
+		}
+		if len(digiprovMDs) > 0 {
+			amdSecs = append(amdSecs, &mets.AmdSecType{
+				IDAttr:     "uuid-" + uuid.NewString(),
+				DigiprovMD: digiprovMDs,
+			})
+			for _, sec := range digiprovMDs {
+				metadataDiv.ADMIDAttr = append(metadataDiv.ADMIDAttr, sec.IDAttr)
+			}
+		}
+		rootDiv.Div = append(rootDiv.Div, metadataDiv)
+	}
+	var metsFileGrps = []*mets.FileGrp{}
+	for _, use := range fileGrpNames {
+		grp := fileGrps[use]
+		metsFileGrps = append(metsFileGrps, &mets.FileGrp{FileGrpType: grp})
+		rootDiv.Div = append(rootDiv.Div, &mets.DivType{
+			ORDERLABELS: &mets.ORDERLABELS{LABELAttr: use},
+			IDAttr:      "uuid-" + uuid.NewString(),
+			Fptr:        []*mets.Fptr{{FILEIDAttr: grp.IDAttr}},
+		})
+	}
+
+	agents := []*mets.Agent{
+		{
+			ROLEAttr:      "CREATOR",
+			TYPEAttr:      "OTHER",
+			OTHERTYPEAttr: "SOFTWARE",
+			Name:          "gocfl",
+			Note: []*mets.Note{
+				{
+					CSIPNOTETYPEAttr: "SOFTWARE VERSION",
+					Value:            version.Version,
+				},
+			},
+		},
+	}
+	if pkg.organisation != "" {
+		agents = append(agents, &mets.Agent{
+			ROLEAttr: "CREATOR",
+			TYPEAttr: "ORGANIZATION",
+			Name:     pkg.organisation,
+		})
+	}
+	if pkg.archivist != "" {
+		agent := &mets.Agent{
+			ROLEAttr: "ARCHIVIST",
+			TYPEAttr: "INDIVIDUAL",
+			Name:     pkg.archivist,
+		}
+		if pkg.archivistID != "" {
+			agent.Note = []*mets.Note{{Value: pkg.archivistID}}
+		}
+		agents = append(agents, agent)
+	}
+
+	profile := earkAIPProfile
+	schemaLocation := "http://www.loc.gov/METS/\nschemas/mets.xsd\nhttp://www.w3.org/1999/xlink\nschemas/xlink.xsd\n" + earkCSIPNamespace + "\nschemas/DILCISExtensionMETS.xsd"
+	var sipNamespace string
+	if pkg.packageType == EARKPackageTypeSIP {
+		profile = earkSIPProfile
+		sipNamespace = earkSIPNamespace
+		schemaLocation += "\n" + earkSIPNamespace + "\nschemas/DILCISExtensionSIPMETS.xsd"
+	}
+
+	return &mets.Mets{
+		XMLNS:             "http://www.loc.gov/METS/",
+		XMLXLinkNS:        "http://www.w3.org/1999/xlink",
+		XMLNSXSI:          "http://www.w3.org/2001/XMLSchema-instance",
+		XMLNSCSIP:         earkCSIPNamespace,
+		XMLNSSIP:          sipNamespace,
+		XSISchemaLocation: schemaLocation,
+		MetsType: &mets.MetsType{
+			OBJIDAttr:   pkg.objectID,
+			LABELAttr:   pkg.label,
+			TYPEAttr:    "Mixed",
+			PROFILEAttr: profile,
+			MetsHdr: &mets.MetsHdr{
+				CREATEDATEAttr:          pkg.created.Format("2006-01-02T15:04:05"),
+				RECORDSTATUSAttr:        "NEW",
+				CSIPOAISPACKAGETYPEAttr: mets.OAISPACKAGETYPE(pkg.packageType),
+				Agent:                   agents,
+			},
+			DmdSec: dmdSecs,
+			AmdSec: amdSecs,
+			FileSec: &mets.FileSec{
+				IDAttr:  "uuid-" + uuid.NewString(),
+				FileGrp: metsFileGrps,
+			},
+			StructMap: []*mets.StructMapType{
+				{
+					IDAttr:    "uuid-" + uuid.NewString(),
+					TYPEAttr:  "PHYSICAL",
+					LABELAttr: "CSIP",
+					Div:       rootDiv,
+				},
+			},
+		},
+	}
+}
+
+// metsToChecksumType converts a METS CHECKSUMTYPE to a digest algorithm
+func metsToChecksumType(t string) checksum.DigestAlgorithm {
+	return checksum.DigestAlgorithm(strings.ToLower(strings.ReplaceAll(t, "-", "")))
+}
+
+// earkHref converts the xlink:href of a METS file to a path inside the package
+func earkHref(href string) string {
+	if str, err := url.PathUnescape(href); err == nil {
+		href = str
+	}
+	return strings.TrimPrefix(path.Clean(strings.TrimPrefix(href, "file:")), "/")
+}
+
+// checkCSIPFile checks the location, size and checksum of a file referenced by the METS file
+func checkCSIPFile(fsys fs.FS, id, locType, href string, size int64, cs, csType string) error {
+	if locType != "URL" {
+		return errors.Errorf("'%s': LOCTYPE must be URL, not '%s'", id, locType)
+	}
+	if href == "" {
+		return errors.Errorf("'%s': no xlink:href", id)
+	}
+	href = earkHref(href)
+	if cs == "" || csType == "" {
+		return errors.Errorf("'%s': CHECKSUM and CHECKSUMTYPE required", id)
+	}
+	alg := metsToChecksumType(csType)
+	if !checksum.HashExists(alg) {
+		return errors.Errorf("'%s': unsupported CHECKSUMTYPE '%s'", id, csType)
+	}
+	fp, err := fsys.Open(href)
+	if err != nil {
+		return errors.Wrapf(err, "'%s': cannot open '%s'", id, href)
+	}
+	defer fp.Close()
+	fi, err := fp.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "'%s': cannot stat '%s'", id, href)
+	}
+	if fi.Size() != size {
+		return errors.Errorf("'%s': size of '%s' is %d, not %d", id, href, fi.Size(), size)
+	}
+	digest, err := checksum.Checksum(fp, alg)
+	if err != nil {
+		return errors.Wrapf(err, "'%s': cannot calculate checksum of '%s'", id, href)
+	}
+	if digest != cs {
+		return errors.Errorf("'%s': invalid checksum of '%s' - [%s] != [%s]", id, href, digest, cs)
+	}
+	return nil
+}
+
+// CheckCSIP checks the root METS file of an information package and the referenced files
+// against the structural requirements of E-ARK CSIP
+func CheckCSIP(m *mets.Mets, fsys fs.FS) error {
+	if m == nil || m.MetsType == nil {
+		return errors.New("empty METS")
+	}
+	var errs = []error{}
+	if _, err := fs.Stat(fsys, EARKMetsFile); err != nil {
+		errs = append(errs, errors.Wrapf(err, "no %s in package root", EARKMetsFile))
+	}
+	if m.OBJIDAttr == "" {
+		errs = append(errs, errors.New("mets: OBJID missing"))
+	}
+	if m.TYPEAttr == "" {
+		errs = append(errs, errors.New("mets: TYPE missing"))
+	}
+	if m.TYPEAttr == "OTHER" && m.CSIPOTHERTYPEAttr == "" {
+		errs = append(errs, errors.New("mets: csip:OTHERTYPE missing for TYPE OTHER"))
+	}
+	if m.PROFILEAttr == "" {
+		errs = append(errs, errors.New("mets: PROFILE missing"))
+	}
+	if m.MetsHdr == nil {
+		errs = append(errs, errors.New("metsHdr missing"))
+	} else {
+		if m.MetsHdr.CREATEDATEAttr == "" {
+			errs = append(errs, errors.New("metsHdr: CREATEDATE missing"))
+		}
+		if m.MetsHdr.CSIPOAISPACKAGETYPEAttr == "" {
+			errs = append(errs, errors.New("metsHdr: csip:OAISPACKAGETYPE missing"))
+		}
+		var software bool
+		for _, agent := range m.MetsHdr.Agent {
+			if agent.ROLEAttr != "CREATOR" || agent.TYPEAttr != "OTHER" || agent.OTHERTYPEAttr != "SOFTWARE" || agent.Name == "" {
+				continue
+			}
+			for _, note := range agent.Note {
+				if note.CSIPNOTETYPEAttr == "SOFTWARE VERSION" && note.Value != "" {
+					software = true
+				}
+			}
+		}
+		if !software {
+			errs = append(errs, errors.New("metsHdr: no creating software agent with version note"))
+		}
+	}
+
+	ids := map[string]bool{}
+	checkID := func(kind, id string) {
+		if id == "" {
+			errs = append(errs, errors.Errorf("%s without ID", kind))
+			return
+		}
+		if ids[id] {
+			errs = append(errs, errors.Errorf("duplicate ID '%s'", id))
+		}
+		ids[id] = true
+	}
+	checkMDSec := func(kind string, sec *mets.MdSecType) {
+		checkID(kind, sec.IDAttr)
+		if sec.MdRef == nil {
+			if sec.MdWrap == nil {
+				errs = append(errs, errors.Errorf("%s '%s': no mdRef or mdWrap", kind, sec.IDAttr))
+			}
+			return
+		}
+		if sec.MdRef.MDTYPEAttr == "" {
+			errs = append(errs, errors.Errorf("%s '%s': MDTYPE missing", kind, sec.IDAttr))
+		}
+		if sec.MdRef.TypeAttr != "simple" {
+			errs = append(errs, errors.Errorf("%s '%s': xlink:type must be simple", kind, sec.IDAttr))
+		}
+		if sec.MdRef.MIMETYPEAttr == "" || sec.MdRef.CREATEDAttr == "" {
+			errs = append(errs, errors.Errorf("%s '%s': MIMETYPE and CREATED required", kind, sec.IDAttr))
+		}
+		if err := checkCSIPFile(fsys, sec.IDAttr, sec.MdRef.LOCTYPEAttr, sec.MdRef.XlinkHrefAttr, sec.MdRef.SIZEAttr, sec.MdRef.CHECKSUMAttr, sec.MdRef.CHECKSUMTYPEAttr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	dmdIDs := []string{}
+	for _, sec := range m.DmdSec {
+		checkMDSec("dmdSec", sec)
+		dmdIDs = append(dmdIDs, sec.IDAttr)
+	}
+	admIDs := []string{}
+	for _, amdSec := range m.AmdSec {
+		checkID("amdSec", amdSec.IDAttr)
+		for _, sec := range amdSec.DigiprovMD {
+			checkMDSec("digiprovMD", sec)
+			admIDs = append(admIDs, sec.IDAttr)
+		}
+	}
+	fileGrpIDs := []string{}
+	if m.FileSec == nil {
+		errs = append(errs, errors.New("fileSec missing"))
+	} else {
+		for _, grp := range m.FileSec.FileGrp {
+			if grp.FileGrpType == nil {
+				continue
+			}
+			checkID("fileGrp", grp.IDAttr)
+			fileGrpIDs = append(fileGrpIDs, grp.IDAttr)
+			if grp.USEAttr == "" {
+				errs = append(errs, errors.Errorf("fileGrp '%s': USE missing", grp.IDAttr))
+			}
+			for _, file := range grp.File {
+				checkID("file", file.IDAttr)
+				if file.FILECORE == nil || file.MIMETYPEAttr == "" || file.CREATEDAttr == "" {
+					errs = append(errs, errors.Errorf("file '%s': MIMETYPE and CREATED required", file.IDAttr))
+					continue
+				}
+				if len(file.FLocat) != 1 || file.FLocat[0].LOCATION == nil || file.FLocat[0].SimpleLink == nil {
+					errs = append(errs, errors.Errorf("file '%s': exactly one FLocat required", file.IDAttr))
+					continue
+				}
+				if file.FLocat[0].TypeAttr != "simple" {
+					errs = append(errs, errors.Errorf("file '%s': xlink:type must be simple", file.IDAttr))
+				}
+				if err := checkCSIPFile(fsys, file.IDAttr, file.FLocat[0].LOCTYPEAttr, file.FLocat[0].XlinkHrefAttr, file.SIZEAttr, file.CHECKSUMAttr, file.CHECKSUMTYPEAttr); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	var physical *mets.StructMapType
+	for _, sm := range m.StructMap {
+		if sm.TYPEAttr == "PHYSICAL" && sm.LABELAttr == "CSIP" {
+			physical = sm
+			break
+		}
+	}
+	if physical == nil || physical.Div == nil {
+		errs = append(errs, errors.New("no structMap with TYPE PHYSICAL and LABEL CSIP"))
+		return errors.Combine(errs...)
+	}
+	var fptrs, dmdRefs, admRefs []string
+	for _, div := range physical.Div.Div {
+		if div.ORDERLABELS == nil || div.LABELAttr == "" {
+			errs = append(errs, errors.Errorf("structMap div '%s': LABEL missing", div.IDAttr))
+		}
+		dmdRefs = append(dmdRefs, div.DMDIDAttr...)
+		admRefs = append(admRefs, div.ADMIDAttr...)
+		for _, fptr := range div.Fptr {
+			fptrs = append(fptrs, fptr.FILEIDAttr)
+		}
+	}
+	for _, id := range fileGrpIDs {
+		if !slices.Contains(fptrs, id) {
+			errs = append(errs, errors.Errorf("fileGrp '%s' not referenced in structMap", id))
+		}
+	}
+	for _, id := range dmdIDs {
+		if !slices.Contains(dmdRefs, id) {
+			errs = append(errs, errors.Errorf("dmdSec '%s' not referenced in structMap", id))
+		}
+	}
+	for _, id := range admIDs {
+		if !slices.Contains(admRefs, id) {
+			errs = append(errs, errors.Errorf("digiprovMD '%s' not referenced in structMap", id))
+		}
+	}
+	return errors.Combine(errs...)
+}
+
+// earkMetsHrefs returns the package paths of all files referenced in the METS file
+func earkMetsHrefs(m *mets.Mets) []string {
+	var hrefs = []string{}
+	for _, sec := range m.DmdSec {
+		if sec.MdRef != nil && sec.MdRef.XlinkHrefAttr != "" {
+			hrefs = append(hrefs, earkHref(sec.MdRef.XlinkHrefAttr))
+		}
+	}
+	for _, amdSec := range m.AmdSec {
+		for _, secs := range [][]*mets.MdSecType{amdSec.TechMD, amdSec.RightsMD, amdSec.SourceMD, amdSec.DigiprovMD} {
+			for _, sec := range secs {
+				if sec.MdRef != nil && sec.MdRef.XlinkHrefAttr != "" {
+					hrefs = append(hrefs, earkHref(sec.MdRef.XlinkHrefAttr))
+				}
+			}
+		}
+	}
+	if m.FileSec != nil {
+		for _, grp := range m.FileSec.FileGrp {
+			if grp.FileGrpType == nil {
+				continue
+			}
+			for _, file := range grp.File {
+				for _, loc := range file.FLocat {
+					if loc.SimpleLink != nil && loc.XlinkHrefAttr != "" {
+						hrefs = append(hrefs, earkHref(loc.XlinkHrefAttr))
+					}
+				}
+			}
+		}
+	}
+	return hrefs
+}
+
+func readEARKMets(fsys fs.FS, name string) (*mets.Mets, error) {
+	fp, err := fsys.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open '%v/%s'", fsys, name)
+	}
+	defer fp.Close()
+	m, err := mets.Decode(fp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot decode '%v/%s'", fsys, name)
+	}
+	return m, nil
+}
+
+// ReadEARK reads the root METS file of an E-ARK information package and checks it and the METS files
+// of the representations against CSIP. every file of the package must be referenced.
+// it returns the root METS and the paths of all files of the package
+func ReadEARK(fsys fs.FS) (*mets.Mets, []string, error) {
+	m, err := readEARKMets(fsys, EARKMetsFile)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	var errs = []error{}
+	if err := CheckCSIP(m, fsys); err != nil {
+		errs = append(errs, errors.GetErrors(err)...)
+	}
+	var files = map[string]bool{EARKMetsFile: true}
+	for _, href := range earkMetsHrefs(m) {
+		files[href] = true
+		dir, name := path.Split(href)
+		if name != EARKMetsFile || !strings.HasPrefix(dir, "representations/") {
+			continue
+		}
+		repFS, err := fs.Sub(fsys, strings.TrimSuffix(dir, "/"))
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "cannot open representation '%s'", dir))
+			continue
+		}
+		repMets, err := readEARKMets(fsys, href)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := CheckCSIP(repMets, repFS); err != nil {
+			for _, repErr := range errors.GetErrors(err) {
+				errs = append(errs, errors.Wrapf(repErr, "'%s'", href))
+			}
+		}
+		for _, repHref := range earkMetsHrefs(repMets) {
+			files[path.Join(dir, repHref)] = true
+		}
+	}
+	if err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !files[name] {
+			errs = append(errs, errors.Errorf("'%s' not referenced in METS", name))
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot walk '%v'", fsys)
+	}
+	var result = []string{}
+	for name := range files {
+		if _, err := fs.Stat(fsys, name); err == nil {
+			result = append(result, name)
+		}
+	}
+	slices.Sort(result)
+	return m, result, errors.Combine(errs...)
+}
+
+func earkHasArea(obj object.Object, area string) bool {
+	p, err := obj.GetAreaPath(area)
+	return err == nil && p != ""
+}
+
+// earkImportTarget maps a file of an information package to area and path inside the object
+func (me *Mets) earkImportTarget(obj object.Object, file, metaArea, metaPrefix string) (string, string) {
+	parts := strings.SplitN(file, "/", 4)
+	if parts[0] == "representations" && len(parts) == 4 && parts[2] == "data" {
+		name := parts[1]
+		if name == "content" || (name != metaArea && earkHasArea(obj, name)) {
+			return name, parts[3]
+		}
+		return "content", path.Join(name, parts[3])
+	}
+	if file == EARKMetsFile {
+		return metaArea, path.Join(metaPrefix, "submission", EARKMetsFile)
+	}
+	// metadata, schemas and documentation of package and representations
+	return metaArea, path.Join(metaPrefix, strings.TrimPrefix(file, "metadata/"))
+}
+
+// ImportEARK adds the files of an E-ARK information package to the object, which must be in update mode.
+// representations are stored in the area with the same name or in a folder of the content area,
+// metadata, schemas and documentation are stored in the metadata area
+func (me *Mets) ImportEARK(obj object.Object, fsys fs.FS, files []string) error {
+	metaArea, metaPrefix := "content", me.StorageName
+	if strings.ToLower(me.StorageType) == "area" && earkHasArea(obj, me.StorageName) {
+		metaArea, metaPrefix = me.StorageName, ""
+	}
+	for _, file := range files {
+		area, target := me.earkImportTarget(obj, file, metaArea, metaPrefix)
+		if err := func() error {
+			fp, err := fsys.Open(file)
+			if err != nil {
+				return errors.Wrapf(err, "cannot open '%v/%s'", fsys, file)
+			}
+			defer fp.Close()
+			if _, err := obj.AddReader(fp, []string{target}, area, false, false); err != nil {
+				return errors.Wrapf(err, "cannot add '%s' as '%s:%s'", file, area, target)
+			}
+			return nil
+		}(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
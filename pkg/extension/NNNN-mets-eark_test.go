@@ -0,0 +1,228 @@
+package extension
+
+import (
+	"crypto/sha512"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/rs/zerolog"
+)
+
+func newTestEARKPackage(packageType string, files map[string]string) (*earkPackage, fstest.MapFS) {
+	fsys := fstest.MapFS{}
+	pkg := &earkPackage{
+		objectID:     "id:abc",
+		label:        "id:abc version v1",
+		packageType:  packageType,
+		created:      time.Now(),
+		organisation: "Example Archive",
+		archivist:    "Jane Doe",
+		archivistID:  "mailto:jane@example.org",
+	}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+		file := &earkFile{
+			path:         name,
+			mimetype:     "text/plain",
+			created:      pkg.created.Format("2006-01-02T15:04:05"),
+			size:         int64(len(content)),
+			checksum:     fmt.Sprintf("%x", sha512.Sum512([]byte(content))),
+			checksumType: string(checksum.DigestSHA512),
+		}
+		if strings.HasPrefix(name, "metadata/preservation/") {
+			file.mdType = "PREMIS"
+		}
+		pkg.files = append(pkg.files, file)
+	}
+	return pkg, fsys
+}
+
+func TestBuildEARKMets(t *testing.T) {
+	pkg, fsys := newTestEARKPackage(EARKPackageTypeSIP, map[string]string{
+		"metadata/descriptive/info.json":     `{"title":"test"}`,
+		"metadata/preservation/premis.xml":   "<premis/>",
+		"schemas/mets.xsd":                   "<schema/>",
+		"representations/content/data/a.txt": "a",
+		"representations/content/data/b.txt": "b",
+		"representations/ocr/data/a.txt":     "ocr a",
+	})
+	m := buildEARKMets(pkg)
+	metsBytes, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		t.Fatalf("cannot marshal METS: %v", err)
+	}
+	fsys[EARKMetsFile] = &fstest.MapFile{Data: metsBytes}
+
+	if err := CheckCSIP(m, fsys); err != nil {
+		t.Fatalf("package not valid: %v", err)
+	}
+	if len(m.DmdSec) != 1 || len(m.AmdSec) != 1 || len(m.AmdSec[0].DigiprovMD) != 1 {
+		t.Errorf("expected one dmdSec and one digiprovMD")
+	}
+	// Representations/content, Representations/ocr, Schemas
+	if len(m.FileSec.FileGrp) != 3 {
+		t.Fatalf("expected 3 file groups, got %d", len(m.FileSec.FileGrp))
+	}
+	if m.FileSec.FileGrp[0].USEAttr != "Representations/content" || len(m.FileSec.FileGrp[0].File) != 2 {
+		t.Errorf("unexpected file group '%s'", m.FileSec.FileGrp[0].USEAttr)
+	}
+	for _, str := range []string{`csip:OAISPACKAGETYPE="SIP"`, `xmlns:sip="` + earkSIPNamespace + `"`, `PROFILE="` + earkSIPProfile + `"`, `csip:NOTETYPE="SOFTWARE VERSION"`} {
+		if !strings.Contains(string(metsBytes), str) {
+			t.Errorf("METS does not contain '%s'", str)
+		}
+	}
+
+	// corrupt a representation file
+	fsys["representations/ocr/data/a.txt"] = &fstest.MapFile{Data: []byte("ocr b")}
+	if err := CheckCSIP(m, fsys); err == nil {
+		t.Errorf("corrupted file not detected")
+	}
+}
+
+func TestCheckCSIPStructMap(t *testing.T) {
+	pkg, fsys := newTestEARKPackage(EARKPackageTypeAIP, map[string]string{
+		"representations/content/data/a.txt": "a",
+	})
+	m := buildEARKMets(pkg)
+	fsys[EARKMetsFile] = &fstest.MapFile{Data: []byte("<mets/>")}
+	if err := CheckCSIP(m, fsys); err != nil {
+		t.Fatalf("package not valid: %v", err)
+	}
+	m.StructMap[0].Div.Div = nil
+	if err := CheckCSIP(m, fsys); err == nil {
+		t.Errorf("unreferenced file group not detected")
+	}
+	m.StructMap = nil
+	if err := CheckCSIP(m, fsys); err == nil {
+		t.Errorf("missing structMap not detected")
+	}
+}
+
+func TestReadEARK(t *testing.T) {
+	pkg, fsys := newTestEARKPackage(EARKPackageTypeSIP, map[string]string{
+		"metadata/descriptive/dc.xml":         "<dc/>",
+		"representations/rep1/data/a.txt":     "a",
+		"representations/rep1/data/sub/b.txt": "b",
+	})
+	metsBytes, err := xml.MarshalIndent(buildEARKMets(pkg), "", "  ")
+	if err != nil {
+		t.Fatalf("cannot marshal METS: %v", err)
+	}
+	fsys[EARKMetsFile] = &fstest.MapFile{Data: append([]byte(xml.Header), metsBytes...)}
+
+	m, files, err := ReadEARK(fsys)
+	if err != nil {
+		t.Fatalf("package not valid: %v", err)
+	}
+	if m.OBJIDAttr != pkg.objectID || m.MetsHdr.CSIPOAISPACKAGETYPEAttr != "SIP" {
+		t.Errorf("METS attributes not decoded")
+	}
+	if len(files) != 4 {
+		t.Errorf("expected 4 files, got %v", files)
+	}
+
+	fsys["representations/rep1/data/c.txt"] = &fstest.MapFile{Data: []byte("c")}
+	fsys["representations/rep1/data/a.txt"] = &fstest.MapFile{Data: []byte("x")}
+	_, _, err = ReadEARK(fsys)
+	if err == nil {
+		t.Fatalf("unreferenced and corrupted file not detected")
+	}
+	for _, str := range []string{"'representations/rep1/data/c.txt' not referenced", "invalid checksum of 'representations/rep1/data/a.txt'"} {
+		if !strings.Contains(err.Error(), str) {
+			t.Errorf("error does not report '%s': %v", str, err)
+		}
+	}
+}
+
+func TestImportEARK(t *testing.T) {
+	pkg, fsys := newTestEARKPackage(EARKPackageTypeSIP, map[string]string{
+		"metadata/descriptive/dc.xml":          "<dc/>",
+		"schemas/dc.xsd":                       "<schema/>",
+		"representations/content/data/a.txt":   "a",
+		"representations/ocr/data/sub/b.txt":   "ocr b",
+		"representations/rep1/data/c.txt":      "c",
+		"representations/metadata/data/d.json": "{}",
+	})
+	metsBytes, err := xml.MarshalIndent(buildEARKMets(pkg), "", "  ")
+	if err != nil {
+		t.Fatalf("cannot marshal METS: %v", err)
+	}
+	fsys[EARKMetsFile] = &fstest.MapFile{Data: append([]byte(xml.Header), metsBytes...)}
+	_, files, err := ReadEARK(fsys)
+	if err != nil {
+		t.Fatalf("package not valid: %v", err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		paths    map[string]ContentSubPathEntry
+		manifest []string
+	}{
+		// representations with an area of the same name are stored there, metadata in the metadata area
+		{"areas", map[string]ContentSubPathEntry{
+			"content":  {Path: "payload"},
+			"ocr":      {Path: "ocr"},
+			"metadata": {Path: "meta"},
+		}, []string{
+			"v1/content/meta/descriptive/dc.xml",
+			"v1/content/meta/schemas/dc.xsd",
+			"v1/content/meta/submission/METS.xml",
+			"v1/content/ocr/sub/b.txt",
+			"v1/content/payload/a.txt",
+			"v1/content/payload/metadata/d.json",
+			"v1/content/payload/rep1/c.txt",
+		}},
+		// without areas everything is stored in the content, metadata in the metadata folder
+		{"no areas", nil, []string{
+			"v1/content/a.txt",
+			"v1/content/metadata/d.json",
+			"v1/content/metadata/descriptive/dc.xml",
+			"v1/content/metadata/schemas/dc.xsd",
+			"v1/content/metadata/submission/METS.xml",
+			"v1/content/ocr/sub/b.txt",
+			"v1/content/rep1/c.txt",
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var exts []extension.Extension
+			if test.paths != nil {
+				subpath, err := NewContentSubPath(&ContentSubPathConfig{
+					ExtensionConfig: &extension.ExtensionConfig{ExtensionName: ContentSubPathName},
+					Paths:           test.paths,
+				})
+				if err != nil {
+					t.Fatalf("cannot create content subpath extension: %v", err)
+				}
+				exts = append(exts, subpath)
+			}
+			obj, _ := newTestObject(t, pkg.objectID, exts...)
+			if _, err := obj.StartUpdate(fsys, "ingest test", "test", "mailto:test@example.org", false); err != nil {
+				t.Fatalf("cannot start update: %v", err)
+			}
+			logger := zerolog.Nop()
+			me, err := GetObjectMets(obj, &logger)
+			if err != nil {
+				t.Fatalf("cannot get METS configuration: %v", err)
+			}
+			if err := me.ImportEARK(obj, fsys, files); err != nil {
+				t.Fatalf("cannot import package: %v", err)
+			}
+			if err := obj.EndUpdate(); err != nil {
+				t.Fatalf("cannot end update: %v", err)
+			}
+			if err := obj.Close(); err != nil {
+				t.Fatalf("cannot close object: %v", err)
+			}
+			if paths := manifestPaths(obj); !slices.Equal(paths, test.manifest) {
+				t.Errorf("expected manifest %v, got %v", test.manifest, paths)
+			}
+		})
+	}
+}
@@ -854,34 +854,9 @@ func (me *Mets) UpdateObjectAfter(obj object.Object) error {
 	}
 
 	if me.PrimaryDescriptiveMetadata != "" {
-		var metaFilename string
-		var metaType string
-		var metaArea string
-
-		parts := strings.Split(me.PrimaryDescriptiveMetadata, ":")
-		switch len(parts) {
-		case 2:
-			metaType = parts[0]
-			metaArea = "content"
-			if len(contentSubPath) == 0 {
-				metaFilename = filepath.ToSlash(filepath.Clean(parts[1]))
-			} else {
-				if path, ok := contentSubPath[metaArea]; ok {
-					metaFilename = filepath.ToSlash(filepath.Join(path.Path, parts[1]))
-				} else {
-					return errors.Errorf("cannot find content sub path '%s' for file '%s'", metaArea, me.PrimaryDescriptiveMetadata)
-				}
-			}
-		case 3:
-			metaType = parts[0]
-			metaArea = parts[1]
-			if path, ok := contentSubPath[metaArea]; ok {
-				metaFilename = filepath.ToSlash(filepath.Join(path.Path, parts[2]))
-			} else {
-				return errors.Errorf("cannot find content sub path '%s' for file '%s'", metaArea, me.PrimaryDescriptiveMetadata)
-			}
-		default:
-			return errors.Errorf("invalid descriptive metadata '%s'", me.PrimaryDescriptiveMetadata)
+		metaFilename, metaType, otherMetaType, err := me.primaryDescriptiveMetadata(contentSubPath)
+		if err != nil {
+			return errors.WithStack(err)
 		}
 		var found *object.FileMetadata
 		var foundChecksum string
@@ -903,12 +878,6 @@ func (me *Mets) UpdateObjectAfter(obj object.Object) error {
 		if found == nil {
 			return errors.Errorf("cannot find descriptive metadata file '%s'", me.PrimaryDescriptiveMetadata)
 		}
-		metaType = strings.ToUpper(metaType)
-		var otherMetaType string
-		if !slices.Contains(metsMDTypes, metaType) {
-			otherMetaType = metaType
-			metaType = "OTHER"
-		}
 		/*
 			switch metaType {
 			case "MARC":
@@ -1367,6 +1336,42 @@ func (me *Mets) UpdateObjectAfter(obj object.Object) error {
 	return nil
 }
 
+// primaryDescriptiveMetadata returns the logical path and the METS metadata type of the primary descriptive metadata
+func (me *Mets) primaryDescriptiveMetadata(contentSubPath map[string]ContentSubPathEntry) (metaFilename, metaType, otherMetaType string, err error) {
+	var metaArea string
+	parts := strings.Split(me.PrimaryDescriptiveMetadata, ":")
+	switch len(parts) {
+	case 2:
+		metaType = parts[0]
+		metaArea = "content"
+		if len(contentSubPath) == 0 {
+			metaFilename = filepath.ToSlash(filepath.Clean(parts[1]))
+		} else {
+			if path, ok := contentSubPath[metaArea]; ok {
+				metaFilename = filepath.ToSlash(filepath.Join(path.Path, parts[1]))
+			} else {
+				return "", "", "", errors.Errorf("cannot find content sub path '%s' for file '%s'", metaArea, me.PrimaryDescriptiveMetadata)
+			}
+		}
+	case 3:
+		metaType = parts[0]
+		metaArea = parts[1]
+		if path, ok := contentSubPath[metaArea]; ok {
+			metaFilename = filepath.ToSlash(filepath.Join(path.Path, parts[2]))
+		} else {
+			return "", "", "", errors.Errorf("cannot find content sub path '%s' for file '%s'", metaArea, me.PrimaryDescriptiveMetadata)
+		}
+	default:
+		return "", "", "", errors.Errorf("invalid descriptive metadata '%s'", me.PrimaryDescriptiveMetadata)
+	}
+	metaType = strings.ToUpper(metaType)
+	if !slices.Contains(metsMDTypes, metaType) {
+		otherMetaType = metaType
+		metaType = "OTHER"
+	}
+	return metaFilename, metaType, otherMetaType, nil
+}
+
 func newMDSec(id, groupid, href, loctype, otherloctype, mimetype, created string, size int64, mdType, othermdtype, checksum, checksumType string) *mets.MdSecType {
 	if mdType == "" {
 		mdType = "OTHER"
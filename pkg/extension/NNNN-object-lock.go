@@ -0,0 +1,105 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
+)
+
+// ObjectLockName is the storage root extension folder of the object lock files (see package lock)
+const ObjectLockName = lock.ExtensionName
+
+func NewObjectLockFS(fsys fs.FS) (*ObjectLock, error) {
+	fp, err := fsys.Open("config.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open config.json")
+	}
+	defer fp.Close()
+	data, err := io.ReadAll(fp)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read config.json")
+	}
+
+	var config = &ObjectLockConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal ObjectLockConfig '%s'", string(data))
+	}
+	ol, err := NewObjectLock(config)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ol.fsys = fsys
+	return ol, nil
+}
+
+func NewObjectLock(config *ObjectLockConfig) (*ObjectLock, error) {
+	ol := &ObjectLock{ObjectLockConfig: config}
+	if config.ExtensionName != ol.GetName() {
+		return nil, errors.New(fmt.Sprintf("invalid extension name'%s'for extension %s", config.ExtensionName, ol.GetName()))
+	}
+	return ol, nil
+}
+
+type ObjectLockConfig struct {
+	*extension.ExtensionConfig
+}
+
+// ObjectLock declares the folder of the lock files as extension. the lock files are managed by lock.Locker
+type ObjectLock struct {
+	*ObjectLockConfig
+	fsys fs.FS
+}
+
+func (ol *ObjectLock) Terminate() error {
+	return nil
+}
+
+func (ol *ObjectLock) GetFS() fs.FS {
+	return ol.fsys
+}
+
+func (ol *ObjectLock) GetConfig() any {
+	return ol.ObjectLockConfig
+}
+
+func (ol *ObjectLock) IsRegistered() bool {
+	return false
+}
+
+func (ol *ObjectLock) SetFS(fsys fs.FS, create bool) {
+	ol.fsys = fsys
+}
+
+func (ol *ObjectLock) SetParams(params map[string]string) error {
+	return nil
+}
+
+func (ol *ObjectLock) GetName() string { return ObjectLockName }
+
+func (ol *ObjectLock) WriteConfig() error {
+	if ol.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	configWriter, err := writefs.Create(ol.fsys, "config.json")
+	if err != nil {
+		return errors.Wrap(err, "cannot open config.json")
+	}
+	defer configWriter.Close()
+	jenc := json.NewEncoder(configWriter)
+	jenc.SetIndent("", "   ")
+	if err := jenc.Encode(ol.ExtensionConfig); err != nil {
+		return errors.Wrapf(err, "cannot encode config to file")
+	}
+	return nil
+}
+
+// check interface satisfaction
+var (
+	_ extension.Extension = &ObjectLock{}
+)
@@ -0,0 +1,96 @@
+package extension
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/osfsrw"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
+	"github.com/rs/zerolog"
+)
+
+func TestObjectLock(t *testing.T) {
+	logger := zerolog.Nop()
+	rootDir := t.TempDir()
+	rootFS, err := osfsrw.NewFS(rootDir, true, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	locker := lock.NewLocker(rootFS, "test", time.Hour, &logger)
+	locker.SetCreate(lock.NewOSCreate(rootDir))
+	other := lock.NewLocker(rootFS, "other", time.Hour, &logger)
+
+	obj, _ := newTestObjectContext(t, lock.NewContext(context.Background(), locker), "id:1")
+	// the new object is locked from its creation
+	if _, err := other.Lock("id:1"); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("created object not locked: %v", err)
+	}
+	if _, err := obj.StartUpdate(nil, "test", "test", "test", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(writeTestFiles(t, map[string]string{"a.txt": "a"}), nil, false, "content"); err != nil {
+		t.Fatalf("cannot add folder: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if _, err := other.Lock("id:1"); !errors.Is(err, lock.ErrLocked) {
+		t.Errorf("object not locked until close: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	if current, err := locker.Get("id:1"); err != nil || current != nil {
+		t.Errorf("lock not released by close: %v, %v", current, err)
+	}
+
+	// the lock folder is an extension of the storage root
+	extFS, err := osfsrw.NewFS(filepath.Join(rootDir, filepath.FromSlash(lock.Folder)), false, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	ext, err := NewObjectLockFS(extFS)
+	if err != nil {
+		t.Fatalf("cannot load lock extension: %v", err)
+	}
+	if ext.GetName() != ObjectLockName {
+		t.Errorf("unexpected extension name %s", ext.GetName())
+	}
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		t.Fatalf("cannot read storage root: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "extensions" {
+			t.Errorf("unexpected entry '%s' in storage root", entry.Name())
+		}
+	}
+}
+
+// callers release the lock with Unlock, if the update fails before Close
+func TestObjectUnlock(t *testing.T) {
+	logger := zerolog.Nop()
+	rootFS, err := osfsrw.NewFS(t.TempDir(), true, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	locker := lock.NewLocker(rootFS, "test", time.Hour, &logger)
+
+	obj, _ := newTestObjectContext(t, lock.NewContext(context.Background(), locker), "id:1")
+	if _, err := obj.StartUpdate(nil, "test", "test", "test", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.Unlock(); err != nil {
+		t.Errorf("cannot unlock object: %v", err)
+	}
+	if current, err := locker.Get("id:1"); err != nil || current != nil {
+		t.Errorf("lock not released by unlock: %v, %v", current, err)
+	}
+	if err := obj.Unlock(); err != nil {
+		t.Errorf("second unlock failed: %v", err)
+	}
+}
@@ -0,0 +1,544 @@
+package extension
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/andybalholm/brotli"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"golang.org/x/exp/slices"
+)
+
+const PolicyName = "NNNN-policy"
+const PolicyDescription = "file level ingest rules"
+
+const (
+	PolicyRuleForbiddenExtension = "forbiddenExtension"
+	PolicyRuleMaxSize            = "maxSize"
+	PolicyRuleAllowedPronom      = "allowedPronom"
+	PolicyRuleMaxPathLength      = "maxPathLength"
+	PolicyRulePathCharacters     = "pathCharacters"
+	PolicyRuleNoHiddenFiles      = "noHiddenFiles"
+	PolicyRuleNoEmptyFiles       = "noEmptyFiles"
+)
+
+const (
+	PolicySeverityWarning = "warning"
+	PolicySeverityReject  = "reject"
+	PolicySeverityError   = "error"
+)
+
+var policyRuleTypes = []string{
+	PolicyRuleForbiddenExtension,
+	PolicyRuleMaxSize,
+	PolicyRuleAllowedPronom,
+	PolicyRuleMaxPathLength,
+	PolicyRulePathCharacters,
+	PolicyRuleNoHiddenFiles,
+	PolicyRuleNoEmptyFiles,
+}
+
+var policySeverities = []string{PolicySeverityWarning, PolicySeverityReject, PolicySeverityError}
+
+func NewPolicyFS(fsys fs.FS, logger zLogger.ZLogger) (*Policy, error) {
+	data, err := fs.ReadFile(fsys, "config.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read config.json")
+	}
+
+	var config = &PolicyConfig{
+		Compress: "none",
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal PolicyConfig '%s'", string(data))
+	}
+	return NewPolicy(config, logger)
+}
+
+func NewPolicy(config *PolicyConfig, logger zLogger.ZLogger) (*Policy, error) {
+	sl := &Policy{
+		PolicyConfig: config,
+		logger:       logger,
+		buffer:       map[string]*bytes.Buffer{},
+		patterns:     map[*PolicyRule]*regexp.Regexp{},
+	}
+	if config.ExtensionName != sl.GetName() {
+		return nil, errors.New(fmt.Sprintf("invalid extension name'%s'for extension %s", config.ExtensionName, sl.GetName()))
+	}
+	if config.Compress == "" {
+		config.Compress = "none"
+	}
+	if !slices.Contains(compress, config.Compress) {
+		return nil, errors.Errorf("invalid compression '%s' in config file", config.Compress)
+	}
+	for num, rule := range config.Rules {
+		if rule.Severity == "" {
+			rule.Severity = PolicySeverityError
+		}
+		rule.Severity = strings.ToLower(rule.Severity)
+		if !slices.Contains(policySeverities, rule.Severity) {
+			return nil, errors.Errorf("rule #%d: invalid severity '%s'", num, rule.Severity)
+		}
+		switch rule.Type {
+		case PolicyRuleForbiddenExtension, PolicyRuleAllowedPronom:
+			if len(rule.Values) == 0 {
+				return nil, errors.Errorf("rule #%d: no values for '%s'", num, rule.Type)
+			}
+		case PolicyRuleMaxSize, PolicyRuleMaxPathLength:
+			if rule.Max <= 0 {
+				return nil, errors.Errorf("rule #%d: no max for '%s'", num, rule.Type)
+			}
+		case PolicyRulePathCharacters:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "rule #%d: invalid pattern '%s'", num, rule.Pattern)
+			}
+			sl.patterns[rule] = re
+		case PolicyRuleNoHiddenFiles, PolicyRuleNoEmptyFiles:
+		default:
+			return nil, errors.Errorf("rule #%d: invalid type '%s' - allowed: %v", num, rule.Type, policyRuleTypes)
+		}
+	}
+	return sl, nil
+}
+
+type PolicyRule struct {
+	Name     string   `json:"name,omitempty"`
+	Type     string   `json:"type"`
+	Severity string   `json:"severity"`
+	Areas    []string `json:"areas,omitempty"`
+	Values   []string `json:"values,omitempty"`
+	Max      int64    `json:"max,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+}
+
+func (rule *PolicyRule) String() string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return rule.Type
+}
+
+type PolicyConfig struct {
+	*extension.ExtensionConfig
+	Rules       []*PolicyRule `json:"rules"`
+	StorageType string        `json:"storageType"`
+	StorageName string        `json:"storageName"`
+	Compress    string        `json:"compress"`
+}
+
+// PolicyViolation is one line of the policy report
+type PolicyViolation struct {
+	Path     string    `json:"path"`
+	Area     string    `json:"area"`
+	Rule     string    `json:"rule"`
+	Type     string    `json:"type"`
+	Severity string    `json:"severity"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+}
+
+func (v *PolicyViolation) String() string {
+	return fmt.Sprintf("%s:%s: [%s] %s", v.Area, v.Path, v.Rule, v.Message)
+}
+
+type Policy struct {
+	*PolicyConfig
+	fsys        fs.FS
+	logger      zLogger.ZLogger
+	patterns    map[*PolicyRule]*regexp.Regexp
+	currentHead string
+	buffer      map[string]*bytes.Buffer
+	writer      *brotli.Writer
+}
+
+func (sl *Policy) Terminate() error {
+	return nil
+}
+
+func (sl *Policy) GetFS() fs.FS {
+	return sl.fsys
+}
+
+func (sl *Policy) GetConfig() any {
+	return sl.PolicyConfig
+}
+
+func (sl *Policy) IsRegistered() bool {
+	return false
+}
+
+func (sl *Policy) SetFS(fsys fs.FS, create bool) {
+	sl.fsys = fsys
+}
+
+func (sl *Policy) SetParams(params map[string]string) error {
+	return nil
+}
+
+func (sl *Policy) GetName() string { return PolicyName }
+
+func (sl *Policy) WriteConfig() error {
+	if sl.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	configWriter, err := writefs.Create(sl.fsys, "config.json")
+	if err != nil {
+		return errors.Wrap(err, "cannot open config.json")
+	}
+	defer configWriter.Close()
+	jenc := json.NewEncoder(configWriter)
+	jenc.SetIndent("", "   ")
+	if err := jenc.Encode(sl.PolicyConfig); err != nil {
+		return errors.Wrapf(err, "cannot encode config to file")
+	}
+
+	return nil
+}
+
+// policyFile contains everything the rules need to know about a file
+type policyFile struct {
+	path string
+	area string
+	// size is -1, if unknown
+	size int64
+	// pronom returns the identification, "" if not available
+	pronom func() string
+}
+
+// Check evaluates all rules for the file and returns the violations
+func (sl *Policy) Check(file *policyFile) []*PolicyViolation {
+	var result = []*PolicyViolation{}
+	for _, rule := range sl.Rules {
+		if len(rule.Areas) > 0 && !slices.Contains(rule.Areas, file.area) {
+			continue
+		}
+		var msg string
+		switch rule.Type {
+		case PolicyRuleForbiddenExtension:
+			ext := strings.ToLower(strings.TrimPrefix(path.Ext(file.path), "."))
+			if slices.ContainsFunc(rule.Values, func(v string) bool { return strings.ToLower(strings.TrimPrefix(v, ".")) == ext }) {
+				msg = fmt.Sprintf("extension '%s' not allowed", ext)
+			}
+		case PolicyRuleMaxSize:
+			if file.size < 0 {
+				msg = "unknown size"
+			} else if file.size > rule.Max {
+				msg = fmt.Sprintf("size %d exceeds %d bytes", file.size, rule.Max)
+			}
+		case PolicyRuleNoEmptyFiles:
+			if file.size < 0 {
+				msg = "unknown size"
+			} else if file.size == 0 {
+				msg = "empty file"
+			}
+		case PolicyRuleMaxPathLength:
+			if l := int64(len(file.path)); l > rule.Max {
+				msg = fmt.Sprintf("path length %d exceeds %d", l, rule.Max)
+			}
+		case PolicyRulePathCharacters:
+			if !sl.patterns[rule].MatchString(file.path) {
+				msg = fmt.Sprintf("path does not match '%s'", rule.Pattern)
+			}
+		case PolicyRuleNoHiddenFiles:
+			if slices.ContainsFunc(strings.Split(file.path, "/"), func(part string) bool { return strings.HasPrefix(part, ".") }) {
+				msg = "hidden file"
+			}
+		case PolicyRuleAllowedPronom:
+			pronom := file.pronom()
+			if pronom == "" {
+				msg = "no format identification"
+			} else if !slices.Contains(rule.Values, pronom) {
+				msg = fmt.Sprintf("format '%s' not allowed", pronom)
+			}
+		}
+		if msg != "" {
+			result = append(result, &PolicyViolation{
+				Path:     file.path,
+				Area:     file.area,
+				Rule:     rule.String(),
+				Type:     rule.Type,
+				Severity: rule.Severity,
+				Message:  msg,
+				Time:     time.Now(),
+			})
+		}
+	}
+	return result
+}
+
+// indexer returns the indexer extension of the object or nil
+func (sl *Policy) indexer(object object.Object) *Indexer {
+	for _, ext := range object.GetExtensionManager().GetExtensions() {
+		if idx, ok := ext.(*Indexer); ok {
+			return idx
+		}
+	}
+	return nil
+}
+
+func (sl *Policy) writeViolation(object object.Object, violation *PolicyViolation) error {
+	head := object.GetInventory().GetHead()
+	if _, ok := sl.buffer[head]; !ok {
+		sl.buffer[head] = &bytes.Buffer{}
+	}
+	if sl.currentHead != head {
+		sl.writer = brotli.NewWriter(sl.buffer[head])
+		sl.currentHead = head
+	}
+	data, err := json.Marshal(violation)
+	if err != nil {
+		return errors.Errorf("cannot marshal violation %v", violation)
+	}
+	if _, err := sl.writer.Write(append(data, []byte("\n")...)); err != nil {
+		return errors.Errorf("cannot brotli %s", string(data))
+	}
+	return nil
+}
+
+// checkFile returns the violations of the file, which would be added
+func (sl *Policy) checkFile(obj object.Object, sourceFS fs.FS, source string, area string) ([]*PolicyViolation, error) {
+	statePath, err := obj.GetExtensionManager().BuildObjectStatePath(obj, source, area)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot build state path for '%s'", source)
+	}
+	var file = &policyFile{
+		path:   statePath,
+		area:   area,
+		size:   -1,
+		pronom: func() string { return "" },
+	}
+	if sourceFS != nil {
+		fi, err := fs.Stat(sourceFS, source)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot stat '%v/%s'", sourceFS, source)
+		}
+		file.size = fi.Size()
+		if idx := sl.indexer(obj); idx != nil {
+			var pronom *string
+			file.pronom = func() string {
+				if pronom == nil {
+					pronom = new(string)
+					result, err := idx.Identify(sourceFS, source)
+					if err != nil {
+						sl.logger.Error().Err(err).Msgf("cannot identify '%s'", source)
+					} else if result != nil {
+						*pronom = result.Pronom
+					}
+				}
+				return *pronom
+			}
+		}
+	}
+	return sl.Check(file), nil
+}
+
+func (sl *Policy) AddFileBefore(obj object.Object, sourceFS fs.FS, source string, dest string, area string, isDir bool) error {
+	if isDir || len(sl.Rules) == 0 {
+		return nil
+	}
+	violations, err := sl.checkFile(obj, sourceFS, source, area)
+	if err != nil {
+		return err
+	}
+
+	var reject bool
+	var errs = []error{}
+	for _, violation := range violations {
+		if err := sl.writeViolation(obj, violation); err != nil {
+			return err
+		}
+		switch violation.Severity {
+		case PolicySeverityError:
+			sl.logger.Error().Msgf("[%s] policy violation %s", obj.GetID(), violation)
+			errs = append(errs, errors.New(violation.String()))
+		case PolicySeverityReject:
+			sl.logger.Warn().Msgf("[%s] policy violation %s - file rejected", obj.GetID(), violation)
+			reject = true
+		default:
+			sl.logger.Warn().Msgf("[%s] policy violation %s", obj.GetID(), violation)
+		}
+	}
+	if len(errs) > 0 {
+		// abort before the file is stored, the report of the failed version stays in the extension folder
+		head := obj.GetInventory().GetHead()
+		if err := sl.writeReport(obj, "extension"); err != nil {
+			sl.logger.Error().Err(err).Msgf("[%s] cannot write policy report of version %s", obj.GetID(), head)
+		}
+		return errors.Wrapf(errors.Combine(errs...), "policy violation in '%s' version %s", obj.GetID(), head)
+	}
+	if reject {
+		return object.ErrSkipFile
+	}
+	return nil
+}
+
+// DryRunFile checks the policy like AddFileBefore without writing violations or reports
+func (sl *Policy) DryRunFile(obj object.Object, sourceFS fs.FS, source string, area string) error {
+	if len(sl.Rules) == 0 {
+		return nil
+	}
+	violations, err := sl.checkFile(obj, sourceFS, source, area)
+	if err != nil {
+		return err
+	}
+	var reject bool
+	var errs = []error{}
+	for _, violation := range violations {
+		switch violation.Severity {
+		case PolicySeverityError:
+			errs = append(errs, errors.New(violation.String()))
+		case PolicySeverityReject:
+			reject = true
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrapf(errors.Combine(errs...), "policy violation in '%s'", obj.GetID())
+	}
+	if reject {
+		return object.ErrSkipFile
+	}
+	return nil
+}
+
+// InspectContent is true, if there are rules which need the size or the format of the file
+func (sl *Policy) InspectContent() bool {
+	return slices.ContainsFunc(sl.Rules, func(rule *PolicyRule) bool {
+		return slices.Contains([]string{PolicyRuleMaxSize, PolicyRuleNoEmptyFiles, PolicyRuleAllowedPronom}, rule.Type)
+	})
+}
+
+func (sl *Policy) UpdateFileBefore(object object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *Policy) DeleteFileBefore(object object.Object, dest string, area string) error {
+	return nil
+}
+
+func (sl *Policy) AddFileAfter(object object.Object, sourceFS fs.FS, source []string, internalPath, digest, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *Policy) UpdateFileAfter(object object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *Policy) DeleteFileAfter(object object.Object, dest string, area string) error {
+	return nil
+}
+
+func (sl *Policy) UpdateObjectBefore(object object.Object) error {
+	return nil
+}
+
+// UpdateObjectAfter stores the report. versions with errors are aborted in AddFileBefore
+func (sl *Policy) UpdateObjectAfter(object object.Object) error {
+	return sl.writeReport(object, sl.StorageType)
+}
+
+// writeReport stores the violations of the current version
+func (sl *Policy) writeReport(object object.Object, storageType string) error {
+	if sl.writer == nil {
+		return nil
+	}
+	head := object.GetInventory().GetHead()
+	if head == "" {
+		return errors.Errorf("no head for object '%s'", object.GetID())
+	}
+	if sl.currentHead != head {
+		return nil
+	}
+	if err := sl.writer.Flush(); err != nil {
+		return errors.Wrap(err, "cannot flush brotli writer")
+	}
+	if err := sl.writer.Close(); err != nil {
+		return errors.Wrap(err, "cannot close brotli writer")
+	}
+	sl.writer = nil
+	sl.currentHead = ""
+	buffer, ok := sl.buffer[head]
+	if !ok {
+		return nil
+	}
+	if err := WriteJsonL(
+		object,
+		"policy",
+		buffer.Bytes(),
+		sl.Compress,
+		storageType,
+		sl.StorageName,
+		sl.fsys,
+	); err != nil {
+		return errors.Wrap(err, "cannot write jsonl")
+	}
+	return nil
+}
+
+func (sl *Policy) GetMetadata(object object.Object) (map[string]any, error) {
+	var err error
+	var result = map[string]any{}
+
+	inventory := object.GetInventory()
+	for v := range inventory.GetVersions() {
+		var data []byte
+		if buf, ok := sl.buffer[v]; ok && buf.Len() > 0 {
+			reader := brotli.NewReader(bytes.NewBuffer(buf.Bytes()))
+			data, err = io.ReadAll(reader)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot read buffer for '%s' '%s'", object.GetID(), v)
+			}
+		} else {
+			data, err = ReadJsonL(object, "policy", v, sl.Compress, sl.StorageType, sl.StorageName, sl.fsys)
+			if err != nil {
+				continue
+			}
+		}
+		var lines = map[string][]*PolicyViolation{}
+		r := bufio.NewScanner(bytes.NewReader(data))
+		r.Buffer(make([]byte, 128*1024), 16*1024*1024)
+		for r.Scan() {
+			var line = &PolicyViolation{}
+			if err := json.Unmarshal(r.Bytes(), line); err != nil {
+				return nil, errors.Wrapf(err, "cannot unmarshal line from for '%s' %s - [%s]", object.GetID(), v, r.Text())
+			}
+			lines[line.Path] = append(lines[line.Path], line)
+		}
+		if err := r.Err(); err != nil {
+			return nil, errors.Wrapf(err, "cannot scan lines for '%s' %s", object.GetID(), v)
+		}
+		if err := inventory.IterateStateFiles(v, func(internals, externals []string, digest string) error {
+			for _, external := range externals {
+				if line, ok := lines[external]; ok {
+					result[digest] = line
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, errors.Wrapf(err, "cannot iterate state files for '%s' version '%s'", object.GetID(), v)
+		}
+	}
+	return result, nil
+}
+
+// check interface satisfaction
+var (
+	_ extension.Extension               = &Policy{}
+	_ object.ExtensionContentChange     = &Policy{}
+	_ object.ExtensionContentInspection = &Policy{}
+	_ object.ExtensionDryRun            = &Policy{}
+	_ object.ExtensionObjectChange      = &Policy{}
+	_ object.ExtensionMetadata          = &Policy{}
+)
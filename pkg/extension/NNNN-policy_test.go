@@ -0,0 +1,208 @@
+package extension
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/rs/zerolog"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	config := &PolicyConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: PolicyName},
+		Rules: []*PolicyRule{
+			{Type: PolicyRuleForbiddenExtension, Values: []string{".exe", "bat"}},
+			{Type: PolicyRuleMaxSize, Max: 100, Severity: PolicySeverityWarning},
+			{Name: "pdfa only", Type: PolicyRuleAllowedPronom, Areas: []string{"content"}, Values: []string{"fmt/95", "fmt/354"}},
+			{Type: PolicyRuleMaxPathLength, Max: 20},
+			{Type: PolicyRulePathCharacters, Pattern: `^[a-zA-Z0-9._/-]+$`, Severity: PolicySeverityReject},
+			{Type: PolicyRuleNoHiddenFiles},
+			{Type: PolicyRuleNoEmptyFiles},
+		},
+	}
+	policy, err := NewPolicy(config, nil)
+	if err != nil {
+		t.Fatalf("cannot create policy: %v", err)
+	}
+	if config.Rules[0].Severity != PolicySeverityError {
+		t.Errorf("default severity is '%s'", config.Rules[0].Severity)
+	}
+
+	pdfa := func() string { return "fmt/95" }
+	for _, test := range []struct {
+		file  *policyFile
+		rules []string
+	}{
+		{&policyFile{path: "a.pdf", area: "content", size: 10, pronom: pdfa}, []string{}},
+		{&policyFile{path: "setup.EXE", area: "metadata", size: 10, pronom: pdfa}, []string{PolicyRuleForbiddenExtension}},
+		{&policyFile{path: "a.pdf", area: "content", size: 1000, pronom: func() string { return "fmt/18" }}, []string{PolicyRuleMaxSize, "pdfa only"}},
+		{&policyFile{path: "a.pdf", area: "metadata", size: 0, pronom: func() string { return "fmt/18" }}, []string{PolicyRuleNoEmptyFiles}},
+		{&policyFile{path: "sub/.git/config.pdf", area: "content", size: 10, pronom: pdfa}, []string{PolicyRuleNoHiddenFiles}},
+		{&policyFile{path: "a very long file name.pdf", area: "content", size: 10, pronom: pdfa}, []string{PolicyRuleMaxPathLength, PolicyRulePathCharacters}},
+		// rules which cannot be evaluated are violated
+		{&policyFile{path: "b.pdf", area: "content", size: -1, pronom: pdfa}, []string{PolicyRuleMaxSize, PolicyRuleNoEmptyFiles}},
+		{&policyFile{path: "c.pdf", area: "content", size: 10, pronom: func() string { return "" }}, []string{"pdfa only"}},
+	} {
+		violations := policy.Check(test.file)
+		if len(violations) != len(test.rules) {
+			t.Errorf("%s: expected violations %v, got %v", test.file.path, test.rules, violations)
+			continue
+		}
+		for i, v := range violations {
+			if v.Rule != test.rules[i] {
+				t.Errorf("%s: expected rule '%s', got %v", test.file.path, test.rules[i], v)
+			}
+		}
+	}
+
+	for _, rule := range []*PolicyRule{
+		{Type: "unknown"},
+		{Type: PolicyRuleMaxSize},
+		{Type: PolicyRuleAllowedPronom},
+		{Type: PolicyRulePathCharacters, Pattern: "[a-"},
+		{Type: PolicyRuleNoEmptyFiles, Severity: "fatal"},
+	} {
+		if _, err := NewPolicy(&PolicyConfig{
+			ExtensionConfig: &extension.ExtensionConfig{ExtensionName: PolicyName},
+			Rules:           []*PolicyRule{rule},
+		}, nil); err == nil {
+			t.Errorf("invalid rule %v not detected", rule)
+		}
+	}
+}
+
+func newTestPolicy(t *testing.T) *Policy {
+	logger := zerolog.Nop()
+	policy, err := NewPolicy(&PolicyConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: PolicyName},
+		Rules: []*PolicyRule{
+			{Type: PolicyRuleForbiddenExtension, Values: []string{"exe"}},
+			{Type: PolicyRuleNoHiddenFiles, Severity: PolicySeverityReject},
+			{Type: PolicyRuleNoEmptyFiles, Severity: PolicySeverityWarning},
+		},
+		StorageType: "extension",
+		Compress:    "none",
+	}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create policy: %v", err)
+	}
+	return policy
+}
+
+// readPolicyReport returns the rules of the violations by path
+func readPolicyReport(t *testing.T, dir, v string) map[string][]string {
+	data, err := os.ReadFile(filepath.Join(dir, "extensions", PolicyName, "policy_"+v+".jsonl"))
+	if err != nil {
+		t.Fatalf("cannot read report: %v", err)
+	}
+	var report = map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var violation = &PolicyViolation{}
+		if err := json.Unmarshal([]byte(line), violation); err != nil {
+			t.Fatalf("cannot unmarshal '%s': %v", line, err)
+		}
+		report[violation.Path] = append(report[violation.Path], violation.Rule)
+	}
+	return report
+}
+
+func TestPolicyObject(t *testing.T) {
+	policy := newTestPolicy(t)
+	if !policy.InspectContent() {
+		t.Errorf("noEmptyFiles rule needs content inspection")
+	}
+	obj, dir := newTestObject(t, "test:policy", policy)
+	if _, err := obj.StartUpdate(nil, "policy test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(writeTestFiles(t, map[string]string{"a.txt": "a", ".hidden": "x"}), nil, false, "content"); err != nil {
+		t.Fatalf("cannot add folder: %v", err)
+	}
+	// streamed content is buffered, the size based rule is evaluated
+	if err := obj.AddData([]byte{}, "empty.txt", false, "content", false, false); err != nil {
+		t.Fatalf("cannot add data: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	expected := []string{"v1/content/a.txt", "v1/content/empty.txt"}
+	if paths := manifestPaths(obj); !slices.Equal(paths, expected) {
+		t.Errorf("expected manifest %v, got %v", expected, paths)
+	}
+	report := readPolicyReport(t, dir, "v1")
+	if !slices.Equal(report[".hidden"], []string{PolicyRuleNoHiddenFiles}) || !slices.Equal(report["empty.txt"], []string{PolicyRuleNoEmptyFiles}) || len(report) != 2 {
+		t.Errorf("unexpected report %v", report)
+	}
+}
+
+func TestPolicyObjectError(t *testing.T) {
+	policy := newTestPolicy(t)
+	obj, dir := newTestObject(t, "test:policyerror", policy)
+	if _, err := obj.StartUpdate(nil, "policy test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(writeTestFiles(t, map[string]string{"setup.exe": "x"}), nil, false, "content"); err == nil {
+		t.Fatalf("forbidden extension must abort the update")
+	}
+	// the file is never stored
+	if _, err := os.Stat(filepath.Join(dir, "v1", "content", "setup.exe")); !os.IsNotExist(err) {
+		t.Errorf("content of aborted version stored: %v", err)
+	}
+	if paths := manifestPaths(obj); len(paths) != 0 {
+		t.Errorf("file of aborted version in manifest: %v", paths)
+	}
+	// the report of the failed version is persisted
+	if report := readPolicyReport(t, dir, "v1"); !slices.Equal(report["setup.exe"], []string{PolicyRuleForbiddenExtension}) {
+		t.Errorf("unexpected report %v", report)
+	}
+}
+
+// content which is already in the object is stored as virtual copy. the path rules are checked anyway
+func TestPolicyObjectDuplicates(t *testing.T) {
+	policy := newTestPolicy(t)
+	obj, dir := newTestObject(t, "test:policyduplicates", policy)
+	if _, err := obj.StartUpdate(nil, "policy test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(writeTestFiles(t, map[string]string{"a.txt": "x", "b/.copy": "x", "b/copy.txt": "x"}), nil, true, "content"); err != nil {
+		t.Fatalf("cannot add folder: %v", err)
+	}
+	if err := obj.AddData([]byte("x"), "c/.copy", true, "content", false, false); err != nil {
+		t.Fatalf("cannot add data: %v", err)
+	}
+	if err := obj.AddData([]byte("x"), "setup.exe", true, "content", false, false); err == nil {
+		t.Fatalf("forbidden extension of virtual copy not detected")
+	}
+	var state = []string{}
+	if err := obj.GetInventory().IterateStateFiles(obj.GetInventory().GetHead(), func(internal []string, external []string, digest string) error {
+		state = append(state, external...)
+		return nil
+	}); err != nil {
+		t.Fatalf("cannot iterate state: %v", err)
+	}
+	slices.Sort(state)
+	for _, p := range state {
+		if strings.Contains(p, ".copy") || strings.Contains(p, "setup.exe") {
+			t.Errorf("rejected virtual copy '%s' in state %v", p, state)
+		}
+	}
+	if len(state) != 2 {
+		t.Errorf("expected a.txt and its virtual copy in state, got %v", state)
+	}
+	if paths := manifestPaths(obj); !slices.Equal(paths, []string{"v1/content/a.txt"}) {
+		t.Errorf("unexpected manifest %v", paths)
+	}
+	// the report is written by the aborting violation
+	report := readPolicyReport(t, dir, "v1")
+	if !slices.Equal(report["b/.copy"], []string{PolicyRuleNoHiddenFiles}) || !slices.Equal(report["c/.copy"], []string{PolicyRuleNoHiddenFiles}) || !slices.Equal(report["setup.exe"], []string{PolicyRuleForbiddenExtension}) {
+		t.Errorf("unexpected report %v", report)
+	}
+}
@@ -0,0 +1,703 @@
+package extension
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/google/uuid"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis"
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis/premis"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/version"
+	"github.com/ocfl-archive/indexer/v3/pkg/indexer"
+)
+
+const PremisName = "NNNN-premis"
+const PremisDescription = "PREMIS event log of preservation actions"
+
+const premisSoftwareAgent = "https://github.com/ocfl-archive/gocfl"
+
+// premis event types
+const (
+	PremisEventIngestion            = "ingestion"
+	PremisEventMessageDigest        = "message digest calculation"
+	PremisEventFixityCheck          = "fixity check"
+	PremisEventFormatIdentification = "format identification"
+	PremisEventVirusCheck           = "virus check"
+	PremisEventMigration            = "migration"
+	PremisEventDeletion             = "deletion"
+)
+
+// codes of the loc preservation vocabularies (http://id.loc.gov/vocabulary/preservation)
+var premisVocabularyCodes = map[string]map[string]string{
+	"eventType": {
+		PremisEventIngestion:            "ing",
+		PremisEventMessageDigest:        "mes",
+		PremisEventFixityCheck:          "fix",
+		PremisEventFormatIdentification: "for",
+		PremisEventVirusCheck:           "vir",
+		PremisEventMigration:            "mig",
+		PremisEventDeletion:             "del",
+	},
+	"eventOutcome": {
+		"success": "suc",
+		"fail":    "fai",
+		"warning": "war",
+	},
+	"eventRelatedAgentRole": {
+		"implementer":       "imp",
+		"executing program": "exe",
+	},
+	"eventRelatedObjectRole": {
+		"source":  "sou",
+		"outcome": "out",
+	},
+	"agentType": {
+		"person":   "per",
+		"software": "sof",
+	},
+	"formatRegistryRole": {
+		"specification": "spe",
+	},
+}
+
+func newPremisVocabulary(authority, value string) *premis.StringPlusAuthority {
+	code, ok := premisVocabularyCodes[authority][value]
+	if !ok {
+		return premis.NewStringPlusAuthority(value, "", "", "")
+	}
+	uri := "http://id.loc.gov/vocabulary/preservation/" + authority
+	return premis.NewStringPlusAuthority(value, authority, uri, uri+"/"+code)
+}
+
+func GetPremisParams() []*extension.ExtensionExternalParam {
+	return []*extension.ExtensionExternalParam{
+		{
+			ExtensionName: PremisName,
+			Functions:     []string{"add", "update", "create", "ingest"},
+			Param:         "xmllint",
+			Description:   "xmllint executable to validate the premis file against premis-v3-0.xsd before it is written",
+		},
+	}
+}
+
+func NewPremisFS(fsys fs.FS, logger zLogger.ZLogger) (*Premis, error) {
+	data, err := fs.ReadFile(fsys, "config.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read config.json")
+	}
+
+	var config = &PremisConfig{
+		PremisFile: "premis.xml",
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal PremisConfig '%s'", string(data))
+	}
+	return NewPremis(config, logger)
+}
+func NewPremis(config *PremisConfig, logger zLogger.ZLogger) (*Premis, error) {
+	sl := &Premis{
+		PremisConfig: config,
+		logger:       logger,
+		records:      []*PremisRecord{},
+	}
+	if config.ExtensionName != sl.GetName() {
+		return nil, errors.New(fmt.Sprintf("invalid extension name'%s'for extension %s", config.ExtensionName, sl.GetName()))
+	}
+	if config.PremisFile == "" {
+		config.PremisFile = "premis.xml"
+	}
+	return sl, nil
+}
+
+type PremisConfig struct {
+	*extension.ExtensionConfig
+	PremisFile  string `json:"PremisFile,omitempty"`
+	FixityCheck bool   `json:"FixityCheck"` // read the new files of a version back and compare the digests
+}
+
+// PremisRecord is a preservation action on a file of the current version
+type PremisRecord struct {
+	Event   string
+	Path    string // manifest path
+	Digest  string
+	Size    int64
+	Time    time.Time
+	Outcome string
+	Detail  string
+	Note    string
+	Agent   string // software agent besides gocfl (i.e. migration)
+	Source  string // manifest path of the source file of a migration
+}
+
+type Premis struct {
+	*PremisConfig
+	fsys      fs.FS
+	logger    zLogger.ZLogger
+	records   []*PremisRecord
+	validator *dilcis.XMLLint
+	// event log of the current version, built by CheckVersion
+	premis []byte
+}
+
+func (sl *Premis) Terminate() error {
+	return nil
+}
+
+func (sl *Premis) GetFS() fs.FS {
+	return sl.fsys
+}
+
+func (sl *Premis) GetConfig() any {
+	return sl.PremisConfig
+}
+
+func (sl *Premis) IsRegistered() bool {
+	return false
+}
+
+func (sl *Premis) SetFS(fsys fs.FS, create bool) {
+	sl.fsys = fsys
+}
+
+func (sl *Premis) SetParams(params map[string]string) error {
+	name := fmt.Sprintf("ext-%s-%s", PremisName, "xmllint")
+	if xmllint := strings.TrimSpace(params[name]); xmllint != "" {
+		validator, err := dilcis.NewXMLLint(xmllint)
+		if err != nil {
+			return errors.Wrap(err, "cannot initialize premis validation")
+		}
+		sl.validator = validator
+	}
+	return nil
+}
+
+func (sl *Premis) GetName() string { return PremisName }
+
+func (sl *Premis) WriteConfig() error {
+	if sl.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	configWriter, err := writefs.Create(sl.fsys, "config.json")
+	if err != nil {
+		return errors.Wrap(err, "cannot open config.json")
+	}
+	defer configWriter.Close()
+	jenc := json.NewEncoder(configWriter)
+	jenc.SetIndent("", "   ")
+	if err := jenc.Encode(sl.PremisConfig); err != nil {
+		return errors.Wrapf(err, "cannot encode config to file")
+	}
+
+	return nil
+}
+
+// AddRecord adds a preservation action to the event log of the current version
+func (sl *Premis) AddRecord(record *PremisRecord) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if record.Outcome == "" {
+		record.Outcome = "success"
+	}
+	sl.records = append(sl.records, record)
+}
+
+func (sl *Premis) AddFileBefore(object object.Object, sourceFS fs.FS, source string, dest string, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *Premis) UpdateFileBefore(object object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *Premis) DeleteFileBefore(object object.Object, dest string, area string) error {
+	return nil
+}
+
+func (sl *Premis) AddFileAfter(object object.Object, sourceFS fs.FS, source []string, internalPath, digest, area string, isDir bool) error {
+	if isDir || digest == "" {
+		return nil
+	}
+	var size int64
+	if sourceFS != nil && len(source) > 0 {
+		if fi, err := fs.Stat(sourceFS, source[0]); err == nil {
+			size = fi.Size()
+		}
+	}
+	sl.AddRecord(&PremisRecord{
+		Event:  PremisEventIngestion,
+		Path:   internalPath,
+		Digest: digest,
+		Size:   size,
+		Detail: strings.Join(source, ", "),
+	})
+	return nil
+}
+
+func (sl *Premis) UpdateFileAfter(object object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *Premis) DeleteFileAfter(object object.Object, dest string, area string) error {
+	return nil
+}
+
+// deletionRecords returns the files of the previous version, which are not part of the head version
+func (sl *Premis) deletionRecords(obj object.Object) []*PremisRecord {
+	inventory := obj.GetInventory()
+	versionStrings := inventory.GetVersionStrings()
+	if len(versionStrings) < 2 {
+		return nil
+	}
+	versions := inventory.GetVersions()
+	head, ok := versions[inventory.GetHead()]
+	if !ok || head.State == nil {
+		return nil
+	}
+	prev, ok := versions[versionStrings[len(versionStrings)-2]]
+	if !ok || prev.State == nil {
+		return nil
+	}
+	var headFiles = map[string]bool{}
+	for _, names := range head.State.State {
+		for _, name := range names {
+			headFiles[name] = true
+		}
+	}
+	var deletions = []*PremisRecord{}
+	manifest := inventory.GetManifest()
+	for digest, names := range prev.State.State {
+		for _, name := range names {
+			if headFiles[name] {
+				continue
+			}
+			path := name
+			if paths, ok := manifest[digest]; ok && len(paths) > 0 {
+				path = paths[0]
+			}
+			deletions = append(deletions, &PremisRecord{
+				Event:   PremisEventDeletion,
+				Path:    path,
+				Digest:  digest,
+				Time:    head.Created.Time,
+				Outcome: "success",
+				Detail:  name,
+			})
+		}
+	}
+	slices.SortFunc(deletions, func(a, b *PremisRecord) int { return strings.Compare(a.Detail, b.Detail) })
+	return deletions
+}
+
+// fixityCheck reads the file back from the object and compares the digest
+func (sl *Premis) fixityCheck(obj object.Object, record *PremisRecord) *PremisRecord {
+	digestAlgorithm := obj.GetInventory().GetDigestAlgorithm()
+	result := &PremisRecord{
+		Event:   PremisEventFixityCheck,
+		Path:    record.Path,
+		Digest:  record.Digest,
+		Outcome: "success",
+		Detail:  string(digestAlgorithm),
+	}
+	fp, err := obj.GetFS().Open(record.Path)
+	result.Time = time.Now()
+	if err != nil {
+		sl.logger.Warn().Err(err).Msgf("cannot open '%s' for fixity check", record.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			result.Outcome = "fail"
+			result.Note = fmt.Sprintf("file not found: %v", err)
+		} else {
+			// i.e. the object filesystem cannot be read while it is written
+			result.Outcome = "warning"
+			result.Note = fmt.Sprintf("fixity check not performed: %v", err)
+		}
+		return result
+	}
+	defer fp.Close()
+	digest, err := checksum.Checksum(fp, digestAlgorithm)
+	result.Time = time.Now()
+	if err != nil {
+		result.Outcome = "fail"
+		result.Note = err.Error()
+		return result
+	}
+	if digest != record.Digest {
+		result.Outcome = "fail"
+		result.Note = fmt.Sprintf("digest %s does not match manifest %s", digest, record.Digest)
+	}
+	return result
+}
+
+// versionRecords completes the recorded actions with the results of the other extensions
+func (sl *Premis) versionRecords(obj object.Object) ([]*PremisRecord, error) {
+	metadata, err := obj.GetMetadata()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get metadata from object")
+	}
+	var records = []*PremisRecord{}
+	for _, record := range sl.records {
+		if record.Event != PremisEventIngestion {
+			records = append(records, record)
+			continue
+		}
+		var fileMeta *object.FileMetadata
+		if metadata.Files != nil {
+			fileMeta = metadata.Files[record.Digest]
+		}
+		var migration *MigrationResult
+		if fileMeta != nil {
+			migration, _ = fileMeta.Extension[MigrationName].(*MigrationResult)
+		}
+		if migration != nil {
+			// migrated files are not ingested but created by the migration
+			mig := &PremisRecord{
+				Event:   PremisEventMigration,
+				Path:    record.Path,
+				Digest:  record.Digest,
+				Size:    record.Size,
+				Time:    record.Time,
+				Outcome: "success",
+				Agent:   migration.ID,
+				Source:  migration.Source,
+			}
+			if migration.Error != "" {
+				mig.Outcome = "fail"
+				mig.Note = migration.Error
+			}
+			records = append(records, mig)
+		} else {
+			records = append(records, record)
+		}
+		records = append(records, &PremisRecord{
+			Event:   PremisEventMessageDigest,
+			Path:    record.Path,
+			Digest:  record.Digest,
+			Time:    record.Time,
+			Outcome: "success",
+			Detail:  string(metadata.DigestAlgorithm),
+			Note:    record.Digest,
+		})
+		if fileMeta != nil {
+			if result, ok := fileMeta.Extension[IndexerName].(*indexer.ResultV2); ok && result != nil {
+				formatRecord := &PremisRecord{
+					Event:   PremisEventFormatIdentification,
+					Path:    record.Path,
+					Digest:  record.Digest,
+					Time:    record.Time,
+					Outcome: "success",
+					Detail:  result.Mimetype,
+					Note:    result.Pronom,
+				}
+				if len(result.Errors) > 0 {
+					formatRecord.Outcome = "fail"
+					var errs = []string{}
+					for name, msg := range result.Errors {
+						errs = append(errs, fmt.Sprintf("%s: %s", name, msg))
+					}
+					slices.Sort(errs)
+					formatRecord.Note = strings.Join(errs, "; ")
+				}
+				records = append(records, formatRecord)
+				if record.Size == 0 {
+					record.Size = int64(result.Size)
+				}
+			}
+		}
+		if sl.FixityCheck {
+			records = append(records, sl.fixityCheck(obj, record))
+		}
+	}
+	records = append(records, sl.deletionRecords(obj)...)
+	return records, nil
+}
+
+// PremisAgent is the person, who created the version
+type PremisAgent struct {
+	Name    string
+	Address string
+}
+
+// BuildPremis creates the premis document of a version. the inventory of the version is always part of the objects
+func BuildPremis(versionString string, user *PremisAgent, digestAlgorithm checksum.DigestAlgorithm, inventoryDigest string, inventorySize int64, records []*PremisRecord) *premis.PremisComplexType {
+	inventoryPath := versionString + "/inventory.json"
+	userIdentifier := user.Address
+	if userIdentifier == "" {
+		userIdentifier = user.Name
+	}
+
+	var objects = []*premis.File{
+		newPremisFile(inventoryPath, digestAlgorithm, inventoryDigest, inventorySize, "application/json", ""),
+	}
+	var objectEvents = map[string][]string{}
+	var events = []*premis.EventComplexType{}
+	var softwareAgents = []string{}
+	for _, record := range records {
+		eventID := "uuid-" + uuid.NewString()
+		event := &premis.EventComplexType{
+			EventIdentifier: &premis.EventIdentifierComplexType{
+				EventIdentifierType:  premis.NewStringPlusAuthority("uuid", "", "", ""),
+				EventIdentifierValue: eventID,
+			},
+			EventType:     newPremisVocabulary("eventType", record.Event),
+			EventDateTime: record.Time.Format(time.RFC3339),
+			EventOutcomeInformation: []*premis.EventOutcomeInformationComplexType{
+				{
+					EventOutcome: newPremisVocabulary("eventOutcome", record.Outcome),
+				},
+			},
+			LinkingAgentIdentifier: []*premis.LinkingAgentIdentifierComplexType{
+				newPremisLinkingAgent("local", userIdentifier, "implementer"),
+				newPremisLinkingAgent("uri", premisSoftwareAgent, "executing program"),
+			},
+			LinkingObjectIdentifier: []*premis.LinkingObjectIdentifierComplexType{},
+		}
+		if record.Detail != "" {
+			event.EventDetailInformation = []*premis.EventDetailInformationComplexType{
+				{EventDetail: record.Detail},
+			}
+		}
+		if record.Note != "" {
+			event.EventOutcomeInformation[0].EventOutcomeDetail = []*premis.EventOutcomeDetailComplexType{
+				{EventOutcomeDetailNote: record.Note},
+			}
+		}
+		if record.Agent != "" {
+			event.LinkingAgentIdentifier = append(event.LinkingAgentIdentifier,
+				newPremisLinkingAgent("local", record.Agent, "executing program"))
+			if !slices.Contains(softwareAgents, record.Agent) {
+				softwareAgents = append(softwareAgents, record.Agent)
+			}
+		}
+		if record.Source != "" {
+			event.LinkingObjectIdentifier = append(event.LinkingObjectIdentifier,
+				newPremisLinkingObject(record.Source, "source"))
+		}
+		var objectRole string
+		if record.Event == PremisEventMigration {
+			objectRole = "outcome"
+		}
+		event.LinkingObjectIdentifier = append(event.LinkingObjectIdentifier, newPremisLinkingObject(record.Path, objectRole))
+		events = append(events, event)
+
+		if record.Event == PremisEventDeletion {
+			continue
+		}
+		if _, ok := objectEvents[record.Path]; !ok {
+			objects = append(objects, newPremisFile(record.Path, digestAlgorithm, record.Digest, record.Size, "", ""))
+		}
+		objectEvents[record.Path] = append(objectEvents[record.Path], eventID)
+		if record.Event == PremisEventFormatIdentification && record.Outcome == "success" {
+			for _, obj := range objects {
+				if obj.ObjectIdentifier[0].ObjectIdentifierValue == record.Path {
+					obj.ObjectCharacteristics[0].Format = []*premis.FormatComplexType{newPremisFormat(record.Detail, record.Note)}
+				}
+			}
+		}
+	}
+	for _, obj := range objects {
+		for _, eventID := range objectEvents[obj.ObjectIdentifier[0].ObjectIdentifierValue] {
+			obj.LinkingEventIdentifier = append(obj.LinkingEventIdentifier, &premis.LinkingEventIdentifierComplexType{
+				LinkingEventIdentifierType:  premis.NewStringPlusAuthority("uuid", "", "", ""),
+				LinkingEventIdentifierValue: eventID,
+			})
+		}
+	}
+
+	var agents = []*premis.AgentComplexType{
+		newPremisAgent("local", userIdentifier, user.Name, "person", ""),
+		newPremisAgent("uri", premisSoftwareAgent, "gocfl - Go OCFL implementation", "software", version.Version),
+	}
+	for _, agent := range softwareAgents {
+		agents = append(agents, newPremisAgent("local", agent, agent, "software", ""))
+	}
+
+	return &premis.PremisComplexType{
+		XMLNS:             "http://www.loc.gov/premis/v3",
+		XMLXLinkNS:        "http://www.w3.org/1999/xlink",
+		XMLNSXSI:          "http://www.w3.org/2001/XMLSchema-instance",
+		XSISchemaLocation: "http://www.loc.gov/premis/v3 https://www.loc.gov/standards/premis/v3/premis-v3-0.xsd",
+		VersionAttr:       "3.0",
+		Object:            objects,
+		Event:             events,
+		Agent:             agents,
+		Rights:            []*premis.RightsComplexType{},
+	}
+}
+
+func newPremisFile(path string, digestAlgorithm checksum.DigestAlgorithm, digest string, size int64, mimetype, pronom string) *premis.File {
+	if mimetype == "" {
+		mimetype = "application/octet-stream"
+	}
+	return &premis.File{
+		XSIType: "file",
+		ObjectIdentifier: []*premis.ObjectIdentifierComplexType{
+			{
+				ObjectIdentifierType:  premis.NewStringPlusAuthority("local", "", "", ""),
+				ObjectIdentifierValue: path,
+			},
+		},
+		SignificantProperties: []*premis.SignificantPropertiesComplexType{},
+		ObjectCharacteristics: []*premis.ObjectCharacteristicsComplexType{
+			{
+				Fixity: []*premis.FixityComplexType{
+					premis.NewFixityComplexType(string(digestAlgorithm), digest, "gocfl "+version.Version),
+				},
+				Size:   size,
+				Format: []*premis.FormatComplexType{newPremisFormat(mimetype, pronom)},
+			},
+		},
+		Storage: []*premis.StorageComplexType{
+			{
+				ContentLocation: &premis.ContentLocationComplexType{
+					ContentLocationType:  premis.NewStringPlusAuthority("internal", "", "", ""),
+					ContentLocationValue: path,
+				},
+				StorageMedium: premis.NewStringPlusAuthority("OCFL Object Root", "", "", ""),
+			},
+		},
+		LinkingEventIdentifier: []*premis.LinkingEventIdentifierComplexType{},
+	}
+}
+
+func newPremisFormat(mimetype, pronom string) *premis.FormatComplexType {
+	if mimetype == "" {
+		mimetype = "application/octet-stream"
+	}
+	format := &premis.FormatComplexType{
+		FormatDesignation: &premis.FormatDesignationComplexType{
+			FormatName: premis.NewStringPlusAuthority(mimetype, "", "", ""),
+		},
+		FormatNote: []string{"IANA MIME-type"},
+	}
+	if pronom != "" {
+		format.FormatRegistry = &premis.FormatRegistryComplexType{
+			FormatRegistryName: premis.NewStringPlusAuthority("PRONOM", "", "", ""),
+			FormatRegistryKey:  premis.NewStringPlusAuthority(pronom, "", "", ""),
+			FormatRegistryRole: newPremisVocabulary("formatRegistryRole", "specification"),
+		}
+	}
+	return format
+}
+
+func newPremisLinkingAgent(identifierType, identifier, role string) *premis.LinkingAgentIdentifierComplexType {
+	return &premis.LinkingAgentIdentifierComplexType{
+		LinkingAgentIdentifierType:  premis.NewStringPlusAuthority(identifierType, "", "", ""),
+		LinkingAgentIdentifierValue: identifier,
+		LinkingAgentRole: []*premis.StringPlusAuthority{
+			newPremisVocabulary("eventRelatedAgentRole", role),
+		},
+	}
+}
+
+func newPremisLinkingObject(path, role string) *premis.LinkingObjectIdentifierComplexType {
+	lo := &premis.LinkingObjectIdentifierComplexType{
+		LinkingObjectIdentifierType:  premis.NewStringPlusAuthority("local", "", "", ""),
+		LinkingObjectIdentifierValue: path,
+	}
+	if role != "" {
+		lo.LinkingObjectRole = []*premis.StringPlusAuthority{newPremisVocabulary("eventRelatedObjectRole", role)}
+	}
+	return lo
+}
+
+func newPremisAgent(identifierType, identifier, name, agentType, agentVersion string) *premis.AgentComplexType {
+	agent := &premis.AgentComplexType{
+		AgentIdentifier: []*premis.AgentIdentifierComplexType{
+			{
+				AgentIdentifierType:  premis.NewStringPlusAuthority(identifierType, "", "", ""),
+				AgentIdentifierValue: identifier,
+			},
+		},
+		AgentType:    newPremisVocabulary("agentType", agentType),
+		AgentVersion: agentVersion,
+	}
+	if name != "" {
+		agent.AgentName = []*premis.StringPlusAuthority{premis.NewStringPlusAuthority(name, "", "", "")}
+	}
+	return agent
+}
+
+// versionPremis builds the event log of the head version
+func (sl *Premis) versionPremis(obj object.Object) ([]byte, error) {
+	inventory := obj.GetInventory()
+	head := inventory.GetHead()
+	v, ok := inventory.GetVersions()[head]
+	if !ok {
+		return nil, errors.Errorf("object has no version %s", head)
+	}
+	inventoryBytes, inventoryDigest, err := obj.GetInventoryContent()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get inventory content")
+	}
+	records, err := sl.versionRecords(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var user = &PremisAgent{}
+	if v.User != nil {
+		user.Name = v.User.Name.String()
+		if v.User.Address != nil {
+			user.Address = v.User.Address.String()
+		}
+	}
+	premisStruct := BuildPremis(head, user, inventory.GetDigestAlgorithm(), inventoryDigest, int64(len(inventoryBytes)), records)
+	premisBytes, err := xml.MarshalIndent(premisStruct, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal PREMIS")
+	}
+	return append([]byte(xml.Header), premisBytes...), nil
+}
+
+// CheckVersion builds the event log with the final inventory before it is stored.
+// if xmllint is configured, an event log which is not valid against premis-v3-0.xsd fails the version
+func (sl *Premis) CheckVersion(obj object.Object) error {
+	premisBytes, err := sl.versionPremis(obj)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sl.validator != nil {
+		if err := sl.validator.Validate(dilcis.SchemaPremis, premisBytes); err != nil {
+			return errors.Wrapf(err, "invalid PREMIS of version %s", obj.GetInventory().GetHead())
+		}
+	}
+	sl.premis = premisBytes
+	return nil
+}
+
+// VersionDone writes the event log of the new version to data/<version>/premis.xml
+func (sl *Premis) VersionDone(obj object.Object) error {
+	defer func() {
+		sl.records = []*PremisRecord{}
+		sl.premis = nil
+	}()
+	if sl.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	if sl.premis == nil {
+		return errors.Errorf("no PREMIS built for version %s", obj.GetInventory().GetHead())
+	}
+	premisFile := fmt.Sprintf("data/%s/%s", obj.GetInventory().GetHead(), sl.PremisFile)
+	if _, err := writefs.WriteFile(sl.fsys, premisFile, sl.premis); err != nil {
+		return errors.Wrapf(err, "cannot write '%s'", premisFile)
+	}
+	return nil
+}
+
+// check interface satisfaction
+var (
+	_ extension.Extension           = &Premis{}
+	_ object.ExtensionContentChange = &Premis{}
+	_ object.ExtensionVersionCheck  = &Premis{}
+	_ object.ExtensionVersionDone   = &Premis{}
+)
@@ -0,0 +1,174 @@
+package extension
+
+import (
+	"crypto/sha512"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/rs/zerolog"
+)
+
+func TestBuildPremis(t *testing.T) {
+	now := time.Now()
+	records := []*PremisRecord{
+		{Event: PremisEventIngestion, Path: "v2/content/data/a.tif", Digest: "aaaa", Size: 10, Time: now, Outcome: "success", Detail: "data/a.tif"},
+		{Event: PremisEventMessageDigest, Path: "v2/content/data/a.tif", Digest: "aaaa", Time: now, Outcome: "success", Detail: "sha512", Note: "aaaa"},
+		{Event: PremisEventFormatIdentification, Path: "v2/content/data/a.tif", Digest: "aaaa", Time: now, Outcome: "success", Detail: "image/tiff", Note: "fmt/353"},
+		{Event: PremisEventFixityCheck, Path: "v2/content/data/a.tif", Digest: "aaaa", Time: now, Outcome: "fail", Detail: "sha512", Note: "digest bbbb does not match manifest aaaa"},
+		{Event: PremisEventMigration, Path: "v2/content/data/a.pdf", Digest: "cccc", Time: now, Outcome: "success", Agent: "tiff2pdf", Source: "v1/content/data/a.tif"},
+		{Event: PremisEventDeletion, Path: "v1/content/data/b.txt", Digest: "dddd", Time: now, Outcome: "success", Detail: "data/b.txt"},
+	}
+	ps := BuildPremis("v2", &PremisAgent{Name: "Jane Doe", Address: "mailto:jane@example.org"}, checksum.DigestSHA512, "ffff", 1234, records)
+
+	// inventory, a.tif and a.pdf
+	if len(ps.Object) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(ps.Object))
+	}
+	if len(ps.Event) != len(records) {
+		t.Fatalf("expected %d events, got %d", len(records), len(ps.Event))
+	}
+	// user, gocfl and migration tool
+	if len(ps.Agent) != 3 {
+		t.Fatalf("expected 3 agents, got %d", len(ps.Agent))
+	}
+	for _, event := range ps.Event {
+		if event.LinkingAgentIdentifier[0].LinkingAgentIdentifierValue != "mailto:jane@example.org" {
+			t.Errorf("event '%s' not linked to version user", event.EventType.Value)
+		}
+	}
+	tif := ps.Object[1]
+	if tif.ObjectIdentifier[0].ObjectIdentifierValue != "v2/content/data/a.tif" {
+		t.Fatalf("unexpected object '%s'", tif.ObjectIdentifier[0].ObjectIdentifierValue)
+	}
+	if len(tif.LinkingEventIdentifier) != 4 {
+		t.Errorf("expected 4 events linked to a.tif, got %d", len(tif.LinkingEventIdentifier))
+	}
+	if format := tif.ObjectCharacteristics[0].Format[0]; format.FormatDesignation.FormatName.Value != "image/tiff" || format.FormatRegistry.FormatRegistryKey.Value != "fmt/353" {
+		t.Errorf("format identification not set on object")
+	}
+
+	data, err := xml.MarshalIndent(ps, "", "  ")
+	if err != nil {
+		t.Fatalf("cannot marshal premis: %v", err)
+	}
+
+	validator, err := dilcis.NewXMLLint("xmllint")
+	if err != nil {
+		t.Skip("xmllint not found - skipping schema validation")
+	}
+	if err := validator.Validate(dilcis.SchemaPremis, append([]byte(xml.Header), data...)); err != nil {
+		t.Errorf("premis.xml not valid: %v", err)
+	}
+}
+
+func TestPremisFixityCheck(t *testing.T) {
+	logger := zerolog.Nop()
+	sl, err := NewPremis(&PremisConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: PremisName},
+		FixityCheck:     true,
+	}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create extension: %v", err)
+	}
+	obj, dir := newTestObject(t, "id:fixity")
+	if err := os.MkdirAll(filepath.Join(dir, "v1", "content"), 0755); err != nil {
+		t.Fatalf("cannot create content folder: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "v1", "content", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("cannot write content file: %v", err)
+	}
+	digest := fmt.Sprintf("%x", sha512.Sum512([]byte("a")))
+
+	for _, test := range []struct {
+		path    string
+		digest  string
+		outcome string
+	}{
+		{"v1/content/a.txt", digest, "success"},
+		{"v1/content/a.txt", "0000", "fail"},
+		// a missing file is a failed check, not a missing event
+		{"v1/content/missing.txt", digest, "fail"},
+	} {
+		result := sl.fixityCheck(obj, &PremisRecord{Event: PremisEventIngestion, Path: test.path, Digest: test.digest})
+		if result == nil {
+			t.Errorf("%s: no fixity check recorded", test.path)
+			continue
+		}
+		if result.Event != PremisEventFixityCheck || result.Outcome != test.outcome || result.Time.IsZero() {
+			t.Errorf("%s: expected outcome %s, got %+v", test.path, test.outcome, result)
+		}
+		if test.outcome == "fail" && result.Note == "" {
+			t.Errorf("%s: failed check without note", test.path)
+		}
+	}
+}
+
+func TestPremisCheckVersion(t *testing.T) {
+	newTestPremis := func(xmllint string) *Premis {
+		logger := zerolog.Nop()
+		sl, err := NewPremis(&PremisConfig{
+			ExtensionConfig: &extension.ExtensionConfig{ExtensionName: PremisName},
+		}, &logger)
+		if err != nil {
+			t.Fatalf("cannot create extension: %v", err)
+		}
+		if err := sl.SetParams(map[string]string{"ext-" + PremisName + "-xmllint": xmllint}); err != nil {
+			t.Fatalf("cannot set parameters: %v", err)
+		}
+		return sl
+	}
+	addVersion := func(obj object.Object) error {
+		if _, err := obj.StartUpdate(nil, "premis test", "test", "mailto:test@example.org", false); err != nil {
+			t.Fatalf("cannot start update: %v", err)
+		}
+		if err := obj.AddFolder(writeTestFiles(t, map[string]string{"a.txt": "a"}), nil, false, "content"); err != nil {
+			t.Fatalf("cannot add folder: %v", err)
+		}
+		return obj.EndUpdate()
+	}
+
+	if runtime.GOOS != "windows" {
+		// an invalid event log fails the version before the inventory is stored
+		script := filepath.Join(t.TempDir(), "xmllint")
+		if err := os.WriteFile(script, []byte("#!/bin/sh\ncat >/dev/null\necho 'premis: element event not expected' >&2\nexit 3\n"), 0755); err != nil {
+			t.Fatalf("cannot write '%s': %v", script, err)
+		}
+		obj, dir := newTestObject(t, "id:invalid", newTestPremis(script))
+		if err := addVersion(obj); err == nil || !strings.Contains(err.Error(), "not valid against premis-v3-0.xsd") {
+			t.Errorf("invalid premis did not fail the version: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "v1", "inventory.json")); err == nil {
+			t.Errorf("version with invalid premis stored")
+		}
+	}
+
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint not found - skipping schema validation")
+	}
+	obj, dir := newTestObject(t, "id:valid", newTestPremis(xmllint))
+	if err := addVersion(obj); err != nil {
+		t.Fatalf("cannot add version: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "extensions", PremisName, "data", "v1", "premis.xml"))
+	if err != nil {
+		t.Fatalf("premis.xml not written: %v", err)
+	}
+	if !strings.Contains(string(data), "v1/content/a.txt") {
+		t.Errorf("ingested file missing in premis.xml")
+	}
+}
@@ -0,0 +1,571 @@
+package extension
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/digitorus/pkcs7"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"golang.org/x/crypto/pkcs12"
+)
+
+const SignatureName = "NNNN-signature"
+const SignatureDescription = "detached signatures of ocfl versions"
+
+const (
+	SignatureMethodPKCS7   = "pkcs7"
+	SignatureMethodEd25519 = "ed25519"
+)
+
+func GetSignatureParams() []*extension.ExtensionExternalParam {
+	return []*extension.ExtensionExternalParam{
+		{
+			ExtensionName: SignatureName,
+			Functions:     []string{"add", "update", "create"},
+			Param:         "key",
+			Description:   "pem file with private key or pkcs#12 keystore (.p12, .pfx) for signing versions",
+		},
+		{
+			ExtensionName: SignatureName,
+			Functions:     []string{"add", "update", "create"},
+			Param:         "certificate",
+			Description:   "pem file with signer certificate and chain (pkcs7 with pem key)",
+		},
+		{
+			ExtensionName: SignatureName,
+			Functions:     []string{"add", "update", "create"},
+			Param:         "password",
+			Description:   "password of the pkcs#12 keystore",
+		},
+		{
+			ExtensionName: SignatureName,
+			Functions:     []string{"add", "update", "create"},
+			Param:         "signer",
+			Description:   "name of the signer (ed25519)",
+		},
+	}
+}
+
+func NewSignatureFS(fsys fs.FS, logger zLogger.ZLogger) (*Signature, error) {
+	data, err := fs.ReadFile(fsys, "config.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read config.json")
+	}
+
+	var config = &SignatureConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal SignatureConfig '%s'", string(data))
+	}
+	return NewSignature(config, logger)
+}
+func NewSignature(config *SignatureConfig, logger zLogger.ZLogger) (*Signature, error) {
+	sl := &Signature{
+		SignatureConfig: config,
+		logger:          logger,
+	}
+	if config.ExtensionName != sl.GetName() {
+		return nil, errors.New(fmt.Sprintf("invalid extension name'%s'for extension %s", config.ExtensionName, sl.GetName()))
+	}
+	switch config.Method {
+	case "":
+		config.Method = SignatureMethodPKCS7
+	case SignatureMethodPKCS7, SignatureMethodEd25519:
+	default:
+		return nil, errors.Errorf("invalid signature method '%s'", config.Method)
+	}
+	return sl, nil
+}
+
+type SignatureConfig struct {
+	*extension.ExtensionConfig
+	Method string `json:"Method"` // pkcs7 or ed25519
+}
+
+// SignatureInfo describes the signer of a version
+type SignatureInfo struct {
+	Method      string    `json:"method"`
+	Signer      string    `json:"signer"`
+	Issuer      string    `json:"issuer,omitempty"`
+	Serial      string    `json:"serial,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+	Signed      time.Time `json:"signed"`
+}
+
+type Signature struct {
+	*SignatureConfig
+	fsys   fs.FS
+	logger zLogger.ZLogger
+	key    crypto.Signer
+	chain  []*x509.Certificate
+	signer string
+}
+
+// loadSigningKey reads a pem file with private key (and certificates) or a pkcs#12 keystore
+func loadSigningKey(keyFile, password string) (crypto.Signer, []*x509.Certificate, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot read '%s'", keyFile)
+	}
+	switch strings.ToLower(filepath.Ext(keyFile)) {
+	case ".p12", ".pfx":
+		key, cert, err := pkcs12.Decode(data, password)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot decode keystore '%s'", keyFile)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, errors.Errorf("unsupported key type %T in '%s'", key, keyFile)
+		}
+		return signer, []*x509.Certificate{cert}, nil
+	}
+	var signer crypto.Signer
+	var certs = []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "cannot parse private key in '%s'", keyFile)
+			}
+			var ok bool
+			if signer, ok = key.(crypto.Signer); !ok {
+				return nil, nil, errors.Errorf("unsupported key type %T in '%s'", key, keyFile)
+			}
+		case "EC PRIVATE KEY":
+			if signer, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, nil, errors.Wrapf(err, "cannot parse private key in '%s'", keyFile)
+			}
+		case "RSA PRIVATE KEY":
+			if signer, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+				return nil, nil, errors.Wrapf(err, "cannot parse private key in '%s'", keyFile)
+			}
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "cannot parse certificate in '%s'", keyFile)
+			}
+			certs = append(certs, cert)
+		}
+	}
+	if signer == nil {
+		return nil, nil, errors.Errorf("no private key found in '%s'", keyFile)
+	}
+	return signer, certs, nil
+}
+
+// loadCertificates reads all certificates of a pem file
+func loadCertificates(certFile string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read '%s'", certFile)
+	}
+	var certs = []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse certificate in '%s'", certFile)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func fingerprint(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// signatureDigestOID uses the inventory digest algorithm for the message digest if possible
+func signatureDigestOID(digestAlgorithm string) asn1.ObjectIdentifier {
+	switch strings.ToLower(digestAlgorithm) {
+	case "sha512":
+		return pkcs7.OIDDigestAlgorithmSHA512
+	default:
+		return pkcs7.OIDDigestAlgorithmSHA256
+	}
+}
+
+// SignPKCS7 creates a detached pkcs#7 signature of the inventory
+func SignPKCS7(inventory []byte, digestAlgorithm string, key crypto.Signer, chain []*x509.Certificate) ([]byte, *SignatureInfo, error) {
+	if len(chain) == 0 {
+		return nil, nil, errors.New("no signer certificate")
+	}
+	sd, err := pkcs7.NewSignedData(inventory)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot create signed data")
+	}
+	sd.SetDigestAlgorithm(signatureDigestOID(digestAlgorithm))
+	if err := sd.AddSignerChain(chain[0], key, chain[1:], pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, nil, errors.Wrap(err, "cannot add signer")
+	}
+	sd.Detach()
+	sig, err := sd.Finish()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot finish signature")
+	}
+	return sig, &SignatureInfo{
+		Method:      SignatureMethodPKCS7,
+		Signer:      chain[0].Subject.String(),
+		Issuer:      chain[0].Issuer.String(),
+		Serial:      chain[0].SerialNumber.String(),
+		Fingerprint: fingerprint(chain[0].Raw),
+		Signed:      time.Now().UTC(),
+	}, nil
+}
+
+// VerifyPKCS7 verifies a detached pkcs#7 signature of the inventory. the signer is trusted,
+// if its certificate chains to roots at signing time. without roots no signer is trusted
+func VerifyPKCS7(sig []byte, inventory []byte, roots *x509.CertPool) (*x509.Certificate, bool, error) {
+	p7, err := pkcs7.Parse(sig)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "cannot parse signature")
+	}
+	p7.Content = inventory
+	if err := p7.Verify(); err != nil {
+		return nil, false, errors.Wrap(err, "cannot verify signature")
+	}
+	signer := p7.GetOnlySigner()
+	if signer == nil {
+		return nil, false, errors.New("no single signer")
+	}
+	if roots == nil {
+		return signer, false, nil
+	}
+	return signer, p7.VerifyWithChain(roots) == nil, nil
+}
+
+func (sl *Signature) sign(object object.Object) error {
+	if sl.key == nil {
+		return errors.Errorf("no signing key set (ext-%s-key)", SignatureName)
+	}
+	inventory, _, err := object.GetInventoryContent()
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal inventory")
+	}
+	head := object.GetInventory().GetHead()
+	var info *SignatureInfo
+	switch sl.Method {
+	case SignatureMethodEd25519:
+		key, ok := sl.key.(ed25519.PrivateKey)
+		if !ok {
+			return errors.Errorf("key %T is not an ed25519 key", sl.key)
+		}
+		pub, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			return errors.Wrap(err, "cannot marshal public key")
+		}
+		info = &SignatureInfo{
+			Method:      SignatureMethodEd25519,
+			Signer:      sl.signer,
+			Fingerprint: fingerprint(pub),
+			Signed:      time.Now().UTC(),
+		}
+		if info.Signer == "" {
+			info.Signer = info.Fingerprint
+		}
+		sigFile := fmt.Sprintf("data/%s.sig", head)
+		if _, err := writefs.WriteFile(sl.fsys, sigFile, ed25519.Sign(key, inventory)); err != nil {
+			return errors.Wrapf(err, "cannot write signature file '%s'", sigFile)
+		}
+		pubFile := fmt.Sprintf("data/%s.pub", head)
+		if _, err := writefs.WriteFile(sl.fsys, pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})); err != nil {
+			return errors.Wrapf(err, "cannot write public key file '%s'", pubFile)
+		}
+	default:
+		var sig []byte
+		sig, info, err = SignPKCS7(inventory, string(object.GetInventory().GetDigestAlgorithm()), sl.key, sl.chain)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		sigFile := fmt.Sprintf("data/%s.p7s", head)
+		if _, err := writefs.WriteFile(sl.fsys, sigFile, sig); err != nil {
+			return errors.Wrapf(err, "cannot write signature file '%s'", sigFile)
+		}
+	}
+	data, err := json.MarshalIndent(info, "", "   ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal signature info")
+	}
+	infoFile := fmt.Sprintf("data/%s.json", head)
+	if _, err := writefs.WriteFile(sl.fsys, infoFile, data); err != nil {
+		return errors.Wrapf(err, "cannot write signature info '%s'", infoFile)
+	}
+	return nil
+}
+
+// UpdateObjectBefore fails without signing key, before a version is created which cannot be signed
+func (sl *Signature) UpdateObjectBefore(object object.Object) error {
+	if sl.key == nil {
+		return errors.Errorf("no signing key set (ext-%s-key)", SignatureName)
+	}
+	return nil
+}
+
+func (sl *Signature) UpdateObjectAfter(object object.Object) error {
+	return nil
+}
+
+func (sl *Signature) VersionDone(object object.Object) error {
+	if sl.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	return errors.WithStack(sl.sign(object))
+}
+
+func (sl *Signature) readInfo(version string) (*SignatureInfo, error) {
+	infoFile := fmt.Sprintf("data/%s.json", version)
+	data, err := fs.ReadFile(sl.fsys, infoFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read '%s'", infoFile)
+	}
+	var info = &SignatureInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal '%s'", infoFile)
+	}
+	return info, nil
+}
+
+// SignatureResult is the verification result of a version signature.
+// Err is set for missing or invalid signatures, Trusted if the signer is trusted
+type SignatureResult struct {
+	Version string
+	Info    *SignatureInfo
+	Trusted bool
+	Err     error
+}
+
+// VerifySignatures verifies the signatures of all versions against the version inventories.
+// pkcs7 signers are trusted, if their certificate chains to roots, ed25519 signers, if their
+// key is one of keys
+func (sl *Signature) VerifySignatures(object object.Object, roots *x509.CertPool, keys []ed25519.PublicKey) ([]*SignatureResult, error) {
+	if sl.fsys == nil {
+		return nil, errors.New("no filesystem set")
+	}
+	var results = []*SignatureResult{}
+	for _, version := range object.GetInventory().GetVersionStrings() {
+		result := &SignatureResult{Version: version}
+		results = append(results, result)
+		info, err := sl.readInfo(version)
+		if err != nil {
+			result.Err = errors.Wrapf(err, "no signature for version %s", version)
+			continue
+		}
+		result.Info = info
+		inventoryName := path.Join(version, "inventory.json")
+		inventory, err := fs.ReadFile(object.GetFS(), inventoryName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read '%s'", inventoryName)
+		}
+		switch info.Method {
+		case SignatureMethodPKCS7:
+			sigFile := fmt.Sprintf("data/%s.p7s", version)
+			sig, err := fs.ReadFile(sl.fsys, sigFile)
+			if err != nil {
+				result.Err = errors.Wrapf(err, "cannot read '%s'", sigFile)
+				continue
+			}
+			cert, trusted, err := VerifyPKCS7(sig, inventory, roots)
+			if err != nil {
+				result.Err = errors.Wrapf(err, "invalid signature '%s'", sigFile)
+				continue
+			}
+			if fp := fingerprint(cert.Raw); fp != info.Fingerprint {
+				result.Err = errors.Errorf("signer certificate %s does not match signature info %s", fp, info.Fingerprint)
+				continue
+			}
+			result.Trusted = trusted
+		case SignatureMethodEd25519:
+			sigFile := fmt.Sprintf("data/%s.sig", version)
+			sig, err := fs.ReadFile(sl.fsys, sigFile)
+			if err != nil {
+				result.Err = errors.Wrapf(err, "cannot read '%s'", sigFile)
+				continue
+			}
+			pubFile := fmt.Sprintf("data/%s.pub", version)
+			pubData, err := fs.ReadFile(sl.fsys, pubFile)
+			if err != nil {
+				result.Err = errors.Wrapf(err, "cannot read '%s'", pubFile)
+				continue
+			}
+			block, _ := pem.Decode(pubData)
+			if block == nil {
+				result.Err = errors.Errorf("no public key in '%s'", pubFile)
+				continue
+			}
+			pubAny, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				result.Err = errors.Wrapf(err, "cannot parse public key '%s'", pubFile)
+				continue
+			}
+			pub, ok := pubAny.(ed25519.PublicKey)
+			if !ok {
+				result.Err = errors.Errorf("public key %T in '%s' is not ed25519", pubAny, pubFile)
+				continue
+			}
+			if !ed25519.Verify(pub, inventory, sig) {
+				result.Err = errors.Errorf("invalid signature '%s'", sigFile)
+				continue
+			}
+			if fp := fingerprint(block.Bytes); fp != info.Fingerprint {
+				result.Err = errors.Errorf("public key %s does not match signature info %s", fp, info.Fingerprint)
+				continue
+			}
+			for _, key := range keys {
+				if key.Equal(pub) {
+					result.Trusted = true
+					break
+				}
+			}
+		default:
+			result.Err = errors.Errorf("unknown signature method '%s'", info.Method)
+		}
+	}
+	return results, nil
+}
+
+func (sl *Signature) Terminate() error {
+	return nil
+}
+
+func (sl *Signature) GetMetadata(object object.Object) (map[string]any, error) {
+	var signatures = map[string]*SignatureInfo{}
+	if sl.fsys == nil {
+		return map[string]any{"": signatures}, nil
+	}
+	for _, version := range object.GetInventory().GetVersionStrings() {
+		info, err := sl.readInfo(version)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, errors.WithStack(err)
+		}
+		signatures[version] = info
+	}
+	return map[string]any{"": signatures}, nil
+}
+
+func (sl *Signature) GetFS() fs.FS {
+	return sl.fsys
+}
+
+func (sl *Signature) GetConfig() any {
+	return sl.SignatureConfig
+}
+
+func (sl *Signature) IsRegistered() bool {
+	return false
+}
+
+func (sl *Signature) SetFS(fsys fs.FS, create bool) {
+	sl.fsys = fsys
+}
+
+func (sl *Signature) SetParams(params map[string]string) error {
+	if params == nil {
+		return nil
+	}
+	sl.signer = strings.TrimSpace(params[fmt.Sprintf("ext-%s-%s", SignatureName, "signer")])
+	keyFile := strings.TrimSpace(params[fmt.Sprintf("ext-%s-%s", SignatureName, "key")])
+	if keyFile == "" {
+		return nil
+	}
+	key, chain, err := loadSigningKey(keyFile, params[fmt.Sprintf("ext-%s-%s", SignatureName, "password")])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if certFile := strings.TrimSpace(params[fmt.Sprintf("ext-%s-%s", SignatureName, "certificate")]); certFile != "" {
+		certs, err := loadCertificates(certFile)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		chain = append(certs, chain...)
+	}
+	switch sl.Method {
+	case SignatureMethodEd25519:
+		if _, ok := key.(ed25519.PrivateKey); !ok {
+			return errors.Errorf("key in '%s' is not an ed25519 key", keyFile)
+		}
+	default:
+		if len(chain) == 0 {
+			return errors.Errorf("no signer certificate for key '%s'", keyFile)
+		}
+		if !publicKeyEqual(chain[0].PublicKey, key.Public()) {
+			return errors.Errorf("certificate '%s' does not match key '%s'", chain[0].Subject.String(), keyFile)
+		}
+	}
+	sl.key = key
+	sl.chain = chain
+	return nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	pa, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	pb, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(pa, pb)
+}
+
+func (sl *Signature) GetName() string { return SignatureName }
+
+func (sl *Signature) WriteConfig() error {
+	if sl.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	configWriter, err := writefs.Create(sl.fsys, "config.json")
+	if err != nil {
+		return errors.Wrap(err, "cannot open config.json")
+	}
+	defer configWriter.Close()
+	jenc := json.NewEncoder(configWriter)
+	jenc.SetIndent("", "   ")
+	if err := jenc.Encode(sl.SignatureConfig); err != nil {
+		return errors.Wrapf(err, "cannot encode config to file")
+	}
+
+	return nil
+}
+
+// check interface satisfaction
+var (
+	_ extension.Extension          = &Signature{}
+	_ object.ExtensionObjectChange = &Signature{}
+	_ object.ExtensionVersionDone  = &Signature{}
+	_ object.ExtensionMetadata     = &Signature{}
+)
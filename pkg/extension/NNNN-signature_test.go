@@ -0,0 +1,283 @@
+package extension
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/rs/zerolog"
+)
+
+type testPKI struct {
+	caCert     *x509.Certificate
+	signerCert *x509.Certificate
+	signerKey  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, name string, serial int64, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"gocfl test"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("cannot create certificate '%s': %v", name, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("cannot parse certificate '%s': %v", name, err)
+	}
+	return cert
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	signerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	caCert := newTestCertificate(t, "test ca", 1, caKey, nil, nil)
+	return &testPKI{
+		caCert:     caCert,
+		signerCert: newTestCertificate(t, "ingest", 2, signerKey, caCert, caKey),
+		signerKey:  signerKey,
+	}
+}
+
+// writePEM writes the pem blocks of the key and the certificates
+func writePEM(t *testing.T, filename string, key crypto.Signer, certs ...*x509.Certificate) string {
+	var data []byte
+	if key != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("cannot marshal key: %v", err)
+		}
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})...)
+	}
+	for _, cert := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	filename = filepath.Join(t.TempDir(), filename)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		t.Fatalf("cannot write '%s': %v", filename, err)
+	}
+	return filename
+}
+
+func newTestSignature(t *testing.T, method string, params map[string]string) *Signature {
+	logger := zerolog.Nop()
+	sl, err := NewSignature(&SignatureConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: SignatureName},
+		Method:          method,
+	}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create signature extension: %v", err)
+	}
+	if err := sl.SetParams(params); err != nil {
+		t.Fatalf("cannot set params: %v", err)
+	}
+	return sl
+}
+
+// newSignedObject creates an object with one signed version and returns the object and its folder
+func newSignedObject(t *testing.T, sl *Signature) (object.Object, string) {
+	obj, dir := newTestObject(t, "test:signature", sl)
+	if _, err := obj.StartUpdate(nil, "signature test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddData([]byte("content"), "file.txt", false, "content", false, false); err != nil {
+		t.Fatalf("cannot add data: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	return obj, dir
+}
+
+func tamperInventory(t *testing.T, dir string) {
+	filename := filepath.Join(dir, "v1", "inventory.json")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("cannot read inventory: %v", err)
+	}
+	if err := os.WriteFile(filename, append(data, ' '), 0644); err != nil {
+		t.Fatalf("cannot write inventory: %v", err)
+	}
+}
+
+func verifyOne(t *testing.T, sl *Signature, obj object.Object, roots *x509.CertPool, keys []ed25519.PublicKey) *SignatureResult {
+	results, err := sl.VerifySignatures(obj, roots, keys)
+	if err != nil {
+		t.Fatalf("cannot verify signatures: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	return results[0]
+}
+
+func TestSignaturePKCS7(t *testing.T) {
+	pki := newTestPKI(t)
+	sl := newTestSignature(t, SignatureMethodPKCS7, map[string]string{
+		"ext-NNNN-signature-key": writePEM(t, "signer.pem", pki.signerKey, pki.signerCert, pki.caCert),
+	})
+	obj, dir := newSignedObject(t, sl)
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(pki.caCert)
+	other := x509.NewCertPool()
+	other.AddCert(newTestPKI(t).caCert)
+	for _, test := range []struct {
+		name    string
+		roots   *x509.CertPool
+		trusted bool
+	}{
+		{name: "empty trust store"},
+		{name: "trusted root", roots: trusted, trusted: true},
+		{name: "wrong root", roots: other},
+	} {
+		result := verifyOne(t, sl, obj, test.roots, nil)
+		if result.Err != nil {
+			t.Errorf("%s: valid signature not verified: %v", test.name, result.Err)
+			continue
+		}
+		if result.Trusted != test.trusted {
+			t.Errorf("%s: expected trusted %v", test.name, test.trusted)
+		}
+		if result.Info.Signer != pki.signerCert.Subject.String() {
+			t.Errorf("%s: unexpected signer '%s'", test.name, result.Info.Signer)
+		}
+	}
+
+	tamperInventory(t, dir)
+	if result := verifyOne(t, sl, obj, trusted, nil); result.Err == nil {
+		t.Errorf("tampered inventory not detected")
+	}
+}
+
+func TestSignatureEd25519(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	sl := newTestSignature(t, SignatureMethodEd25519, map[string]string{
+		"ext-NNNN-signature-key":    writePEM(t, "signer.pem", key),
+		"ext-NNNN-signature-signer": "ingest",
+	})
+	obj, dir := newSignedObject(t, sl)
+
+	for _, test := range []struct {
+		name    string
+		keys    []ed25519.PublicKey
+		trusted bool
+	}{
+		{name: "empty trust store"},
+		{name: "trusted key", keys: []ed25519.PublicKey{otherPub, pub}, trusted: true},
+		{name: "wrong key", keys: []ed25519.PublicKey{otherPub}},
+	} {
+		result := verifyOne(t, sl, obj, nil, test.keys)
+		if result.Err != nil {
+			t.Errorf("%s: valid signature not verified: %v", test.name, result.Err)
+			continue
+		}
+		if result.Trusted != test.trusted {
+			t.Errorf("%s: expected trusted %v", test.name, test.trusted)
+		}
+		if result.Info.Signer != "ingest" {
+			t.Errorf("%s: unexpected signer '%s'", test.name, result.Info.Signer)
+		}
+	}
+
+	tamperInventory(t, dir)
+	if result := verifyOne(t, sl, obj, nil, []ed25519.PublicKey{pub}); result.Err == nil {
+		t.Errorf("tampered inventory not detected")
+	}
+}
+
+func TestSignatureNoKey(t *testing.T) {
+	sl := newTestSignature(t, SignatureMethodPKCS7, nil)
+	obj, dir := newTestObject(t, "test:nokey", sl)
+	if _, err := obj.StartUpdate(nil, "signature test", "test", "mailto:test@example.org", false); err == nil {
+		t.Fatalf("update without signing key must fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "v1", "inventory.json")); !os.IsNotExist(err) {
+		t.Errorf("unsigned version inventory written: %v", err)
+	}
+}
+
+func TestSignatureKeyMismatch(t *testing.T) {
+	pki := newTestPKI(t)
+	other := newTestPKI(t)
+	logger := zerolog.Nop()
+	sl, err := NewSignature(&SignatureConfig{ExtensionConfig: &extension.ExtensionConfig{ExtensionName: SignatureName}}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create signature extension: %v", err)
+	}
+	if err := sl.SetParams(map[string]string{
+		"ext-NNNN-signature-key": writePEM(t, "signer.pem", pki.signerKey, other.signerCert),
+	}); err == nil {
+		t.Errorf("certificate of another key not detected")
+	}
+}
+
+func TestLoadSigningKeyP12(t *testing.T) {
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl not available")
+	}
+	pki := newTestPKI(t)
+	keyFile := writePEM(t, "signer.pem", pki.signerKey, pki.signerCert)
+	p12File := filepath.Join(t.TempDir(), "signer.p12")
+	// golang.org/x/crypto/pkcs12 supports only the legacy algorithms
+	cmd := exec.Command(openssl, "pkcs12", "-export", "-in", keyFile, "-out", p12File,
+		"-keypbe", "PBE-SHA1-3DES", "-certpbe", "PBE-SHA1-3DES", "-macalg", "sha1", "-passout", "pass:secret")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("cannot create pkcs#12 keystore: %v - %s", err, string(out))
+	}
+
+	key, chain, err := loadSigningKey(p12File, "secret")
+	if err != nil {
+		t.Fatalf("cannot load keystore: %v", err)
+	}
+	if len(chain) != 1 || !chain[0].Equal(pki.signerCert) {
+		t.Errorf("unexpected certificate chain %v", chain)
+	}
+	if !publicKeyEqual(key.Public(), pki.signerKey.Public()) {
+		t.Errorf("wrong key in keystore")
+	}
+	if _, _, err := loadSigningKey(p12File, "wrong"); err == nil {
+		t.Errorf("wrong password not detected")
+	}
+}
@@ -523,6 +523,30 @@ func (thumb *Thumbnail) GetMetadata(object object.Object) (map[string]any, error
 	return result, nil
 }
 
+// ImageRequest describes an image transformation with the decoder of the thumbnail extension.
+// The region is cropped, scaled to Width x Height, mirrored, rotated and encoded in this order
+type ImageRequest struct {
+	// region in pixels
+	X, Y, W, H int
+	// target size of the region
+	Width, Height int
+	Mirror        bool
+	// clockwise rotation: 0, 90, 180 or 270
+	Rotation int
+	Gray     bool
+	// jpg or png
+	Format string
+}
+
+// DecodedImage is a decoded image, which can be transformed several times.
+// Transform can be called concurrently. the image must not be used after Close
+type DecodedImage interface {
+	Width() int
+	Height() int
+	Transform(req *ImageRequest) ([]byte, error)
+	Close()
+}
+
 var (
 	_ extension.Extension          = &Thumbnail{}
 	_ object.ExtensionObjectChange = &Thumbnail{}
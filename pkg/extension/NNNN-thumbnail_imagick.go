@@ -7,6 +7,7 @@ import (
 	"io"
 	"slices"
 	"strings"
+	"sync"
 
 	"emperror.dev/errors"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
@@ -106,3 +107,111 @@ func (thumb *Thumbnail) StreamObject(object object.Object, reader io.Reader, sta
 	thumb.streamInfo[head][infoName] = ml
 	return nil
 }
+
+// ImageSize returns the dimensions of an image
+func ImageSize(reader io.Reader) (int, int, error) {
+	imgBytes, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "cannot read image")
+	}
+	mw := imagick.NewMagickWand()
+	defer mw.Destroy()
+	if err := mw.PingImageBlob(imgBytes); err != nil {
+		return 0, 0, errors.Wrap(err, "cannot decode image")
+	}
+	return int(mw.GetImageWidth()), int(mw.GetImageHeight()), nil
+}
+
+// TransformImage decodes an image and applies the request
+func TransformImage(reader io.Reader, req *ImageRequest) ([]byte, error) {
+	img, err := DecodeImage(reader)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer img.Close()
+	return img.Transform(req)
+}
+
+// imagickImage transforms a clone of the decoded image. the wand is not thread safe,
+// so cloning is guarded by the lock
+type imagickImage struct {
+	lock          sync.Mutex
+	mw            *imagick.MagickWand
+	width, height int
+}
+
+// DecodeImage decodes an image for several transformations
+func DecodeImage(reader io.Reader) (DecodedImage, error) {
+	imgBytes, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read image")
+	}
+	mw := imagick.NewMagickWand()
+	if err := mw.ReadImageBlob(imgBytes); err != nil {
+		mw.Destroy()
+		return nil, errors.Wrap(err, "cannot decode image")
+	}
+	return &imagickImage{mw: mw, width: int(mw.GetImageWidth()), height: int(mw.GetImageHeight())}, nil
+}
+
+func (ii *imagickImage) Width() int  { return ii.width }
+func (ii *imagickImage) Height() int { return ii.height }
+
+func (ii *imagickImage) Close() {
+	ii.lock.Lock()
+	defer ii.lock.Unlock()
+	if ii.mw != nil {
+		ii.mw.Destroy()
+		ii.mw = nil
+	}
+}
+
+// Transform applies the request to a clone of the image
+func (ii *imagickImage) Transform(req *ImageRequest) ([]byte, error) {
+	ii.lock.Lock()
+	if ii.mw == nil {
+		ii.lock.Unlock()
+		return nil, errors.New("image already closed")
+	}
+	mw := ii.mw.Clone()
+	ii.lock.Unlock()
+	defer mw.Destroy()
+
+	if err := mw.CropImage(uint(req.W), uint(req.H), req.X, req.Y); err != nil {
+		return nil, errors.Wrapf(err, "cannot crop region %d,%d,%d,%d", req.X, req.Y, req.W, req.H)
+	}
+	if err := mw.SetImagePage(0, 0, 0, 0); err != nil {
+		return nil, errors.Wrap(err, "cannot reset image page")
+	}
+	if req.Width != req.W || req.Height != req.H {
+		if err := mw.ResizeImage(uint(req.Width), uint(req.Height), imagick.FILTER_LANCZOS); err != nil {
+			return nil, errors.Wrapf(err, "cannot resize image to %d x %d", req.Width, req.Height)
+		}
+	}
+	if req.Mirror {
+		if err := mw.FlopImage(); err != nil {
+			return nil, errors.Wrap(err, "cannot mirror image")
+		}
+	}
+	if req.Rotation != 0 {
+		background := imagick.NewPixelWand()
+		defer background.Destroy()
+		if err := mw.RotateImage(background, float64(req.Rotation)); err != nil {
+			return nil, errors.Wrapf(err, "cannot rotate image by %d", req.Rotation)
+		}
+	}
+	if req.Gray {
+		if err := mw.TransformImageColorspace(imagick.COLORSPACE_GRAY); err != nil {
+			return nil, errors.Wrap(err, "cannot convert image to gray")
+		}
+	}
+	format := req.Format
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	if err := mw.SetImageFormat(format); err != nil {
+		return nil, errors.Wrapf(err, "cannot set image format '%s'", req.Format)
+	}
+	mw.ResetIterator()
+	return mw.GetImageBlob(), nil
+}
@@ -3,8 +3,10 @@
 package extension
 
 import (
+	"bytes"
 	"fmt"
 	"image"
+	"image/draw"
 	"image/jpeg"
 	"image/png"
 	"io"
@@ -220,3 +222,116 @@ func (thumb *Thumbnail) DeleteFileAfter(object object.Object, dest string, area
 }
 
 var _ object.ExtensionContentChange = &Thumbnail{}
+
+// ImageSize returns the dimensions of an image without decoding the pixels
+func ImageSize(reader io.Reader) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(reader)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "cannot decode image config")
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// TransformImage decodes an image and applies the request
+func TransformImage(reader io.Reader, req *ImageRequest) ([]byte, error) {
+	img, err := DecodeImage(reader)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer img.Close()
+	return img.Transform(req)
+}
+
+// nativeImage is never modified, so concurrent transformations need no lock
+type nativeImage struct {
+	img image.Image
+}
+
+// DecodeImage decodes an image for several transformations
+func DecodeImage(reader io.Reader) (DecodedImage, error) {
+	img, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode image")
+	}
+	return &nativeImage{img: img}, nil
+}
+
+func (ni *nativeImage) Width() int  { return ni.img.Bounds().Dx() }
+func (ni *nativeImage) Height() int { return ni.img.Bounds().Dy() }
+func (ni *nativeImage) Close()      {}
+
+// Transform applies the request to the image
+func (ni *nativeImage) Transform(req *ImageRequest) ([]byte, error) {
+	var err error
+	img := ni.img
+	bounds := img.Bounds()
+	rect := image.Rect(req.X, req.Y, req.X+req.W, req.Y+req.H).Add(bounds.Min).Intersect(bounds)
+	if rect.Empty() {
+		return nil, errors.Errorf("region %d,%d,%d,%d outside of image", req.X, req.Y, req.W, req.H)
+	}
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		img = sub.SubImage(rect)
+	} else {
+		dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+		draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+		img = dst
+	}
+	if req.Width != rect.Dx() || req.Height != rect.Dy() {
+		img = resize.Resize(uint(req.Width), uint(req.Height), img, resize.Lanczos3)
+	}
+	if req.Mirror || req.Rotation != 0 {
+		img = rotateImage(img, req.Mirror, req.Rotation)
+	}
+	if req.Gray {
+		gray := image.NewGray(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Src)
+		img = gray
+	}
+	var buf = &bytes.Buffer{}
+	switch req.Format {
+	case "png":
+		err = png.Encode(buf, img)
+	case "jpg", "jpeg":
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 90})
+	default:
+		err = errors.Errorf("unsupported image format '%s'", req.Format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot encode image as %s", req.Format)
+	}
+	return buf.Bytes(), nil
+}
+
+// rotateImage mirrors horizontally and rotates clockwise by multiples of 90 degrees
+func rotateImage(img image.Image, mirror bool, rotation int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	var dst *image.RGBA
+	if rotation == 90 || rotation == 270 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx := x
+			if mirror {
+				sx = w - 1 - x
+			}
+			c := img.At(bounds.Min.X+sx, bounds.Min.Y+y)
+			switch rotation {
+			case 90:
+				dst.Set(h-1-y, x, c)
+			case 180:
+				dst.Set(w-1-x, h-1-y, c)
+			case 270:
+				dst.Set(y, w-1-x, c)
+			default:
+				dst.Set(x, y, c)
+			}
+		}
+	}
+	return dst
+}
@@ -12,6 +12,7 @@ import (
 	"io"
 	"slices"
 	"strings"
+	"sync"
 )
 
 func (thumb *Thumbnail) StreamObject(object object.Object, reader io.Reader, stateFiles []string, dest string) error {
@@ -105,3 +106,116 @@ func (thumb *Thumbnail) StreamObject(object object.Object, reader io.Reader, sta
 	thumb.streamInfo[head][infoName] = ml
 	return nil
 }
+
+// ImageSize returns the dimensions of an image
+func ImageSize(reader io.Reader) (int, int, error) {
+	img, err := vips.NewImageFromReader(reader)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "cannot decode image")
+	}
+	defer img.Close()
+	return img.Width(), img.Height(), nil
+}
+
+// TransformImage decodes an image and applies the request
+func TransformImage(reader io.Reader, req *ImageRequest) ([]byte, error) {
+	img, err := DecodeImage(reader)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer img.Close()
+	return img.Transform(req)
+}
+
+// vipsImage transforms a copy of the decoded image. copying is guarded by the lock
+type vipsImage struct {
+	lock          sync.Mutex
+	img           *vips.ImageRef
+	width, height int
+}
+
+// DecodeImage decodes an image for several transformations
+func DecodeImage(reader io.Reader) (DecodedImage, error) {
+	img, err := vips.NewImageFromReader(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode image")
+	}
+	return &vipsImage{img: img, width: img.Width(), height: img.Height()}, nil
+}
+
+func (vi *vipsImage) Width() int  { return vi.width }
+func (vi *vipsImage) Height() int { return vi.height }
+
+func (vi *vipsImage) Close() {
+	vi.lock.Lock()
+	defer vi.lock.Unlock()
+	if vi.img != nil {
+		vi.img.Close()
+		vi.img = nil
+	}
+}
+
+// Transform applies the request to a copy of the image
+func (vi *vipsImage) Transform(req *ImageRequest) ([]byte, error) {
+	vi.lock.Lock()
+	if vi.img == nil {
+		vi.lock.Unlock()
+		return nil, errors.New("image already closed")
+	}
+	img, err := vi.img.Copy()
+	vi.lock.Unlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot copy image")
+	}
+	defer img.Close()
+
+	if req.X != 0 || req.Y != 0 || req.W != img.Width() || req.H != img.Height() {
+		if err := img.ExtractArea(req.X, req.Y, req.W, req.H); err != nil {
+			return nil, errors.Wrapf(err, "cannot extract region %d,%d,%d,%d", req.X, req.Y, req.W, req.H)
+		}
+	}
+	if req.Width != req.W || req.Height != req.H {
+		if err := img.ResizeWithVScale(float64(req.Width)/float64(req.W), float64(req.Height)/float64(req.H), vips.KernelLanczos3); err != nil {
+			return nil, errors.Wrapf(err, "cannot resize image to %d x %d", req.Width, req.Height)
+		}
+	}
+	if req.Mirror {
+		if err := img.Flip(vips.DirectionHorizontal); err != nil {
+			return nil, errors.Wrap(err, "cannot mirror image")
+		}
+	}
+	var angle vips.Angle
+	switch req.Rotation {
+	case 90:
+		angle = vips.Angle90
+	case 180:
+		angle = vips.Angle180
+	case 270:
+		angle = vips.Angle270
+	default:
+		angle = vips.Angle0
+	}
+	if angle != vips.Angle0 {
+		if err := img.Rotate(angle); err != nil {
+			return nil, errors.Wrapf(err, "cannot rotate image by %d", req.Rotation)
+		}
+	}
+	if req.Gray {
+		if err := img.ToColorSpace(vips.InterpretationBW); err != nil {
+			return nil, errors.Wrap(err, "cannot convert image to gray")
+		}
+	}
+	var imgBytes []byte
+	switch req.Format {
+	case "png":
+		imgBytes, _, err = img.ExportPng(vips.NewPngExportParams())
+	case "jpg", "jpeg":
+		imgBytes, _, err = img.ExportJpeg(vips.NewJpegExportParams())
+	default:
+		err = errors.Errorf("unsupported image format '%s'", req.Format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot encode image as %s", req.Format)
+	}
+	return imgBytes, nil
+}
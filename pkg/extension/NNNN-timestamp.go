@@ -3,11 +3,16 @@ package extension
 import (
 	"bytes"
 	"crypto"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"path"
+	"slices"
+	"time"
 
 	"emperror.dev/errors"
+	"github.com/digitorus/pkcs7"
 	"github.com/digitorus/timestamp"
 	"github.com/je4/filesystem/v3/pkg/writefs"
 	"github.com/je4/utils/v2/pkg/zLogger"
@@ -17,6 +22,7 @@ import (
 	"io"
 	"io/fs"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -24,7 +30,14 @@ const TimestampName = "NNNN-timestamp"
 const TimestampDescription = "signs ocfl versions"
 
 func GetTimestampParams() []*extension.ExtensionExternalParam {
-	return []*extension.ExtensionExternalParam{}
+	return []*extension.ExtensionExternalParam{
+		{
+			ExtensionName: TimestampName,
+			Functions:     []string{"add", "update", "create"},
+			Param:         "offline",
+			Description:   "store pending timestamp requests for timestamp-flush instead of calling the authority (true/false)",
+		},
+	}
 }
 
 func NewTimestampFS(fsys fs.FS, logger zLogger.ZLogger) (*Timestamp, error) {
@@ -48,6 +61,7 @@ func NewTimestamp(config *TimestampConfig, logger zLogger.ZLogger) (*Timestamp,
 	sl := &Timestamp{
 		TimestampConfig: config,
 		logger:          logger,
+		offline:         config.Offline,
 	}
 	if config.ExtensionName != sl.GetName() {
 		return nil, errors.New(fmt.Sprintf("invalid extension name'%s'for extension %s", config.ExtensionName, sl.GetName()))
@@ -59,11 +73,63 @@ type TimestampConfig struct {
 	*extension.ExtensionConfig
 	Authority map[string]string `json:"Authority"` // https://freetsa.org/tsr
 	CertChain bool              `json:"CertChain"`
+	Offline   bool              `json:"Offline"`
 }
 type Timestamp struct {
 	*TimestampConfig
-	fsys   fs.FS
-	logger zLogger.ZLogger
+	fsys    fs.FS
+	logger  zLogger.ZLogger
+	offline bool
+}
+
+// timestampHash maps the ocfl digest algorithm to the hash of the timestamp request
+func timestampHash(digestAlgorithm string) (crypto.Hash, error) {
+	switch strings.ToLower(digestAlgorithm) {
+	case "sha256":
+		return crypto.SHA256, nil
+	case "sha512":
+		return crypto.SHA512, nil
+	case "sha1":
+		return crypto.SHA1, nil
+	case "md5":
+		return crypto.MD5, nil
+	default:
+		return 0, errors.Errorf("unsupported hash algorithm '%s'", digestAlgorithm)
+	}
+}
+
+// requestTimestamp sends a timestamp query to the authority and returns the raw response
+func requestTimestamp(client *http.Client, url string, rqst []byte) ([]byte, error) {
+	tsaReq, err := http.NewRequest("POST", url, bytes.NewReader(rqst))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create request")
+	}
+	tsaReq.Header.Set("content-type", "application/timestamp-query")
+	tsaReq.Header.Set("content-length", fmt.Sprintf("%d", len(rqst)))
+	// tsaReq.Header.Set("user-agent", "curl/8.5.0")
+	// tsaReq.Header.Set("accept", "*/*")
+
+	resp, err := client.Do(tsaReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot send request")
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("invalid response from TSA %v: %s", tsaReq, resp.Status)
+	}
+	return body, nil
+}
+
+func newTimestampClient() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
 }
 
 func (sl *Timestamp) trustedTimestamp(object object.Object) error {
@@ -72,19 +138,20 @@ func (sl *Timestamp) trustedTimestamp(object object.Object) error {
 		return errors.Wrap(err, "cannot marshal inventory")
 	}
 
-	var ha crypto.Hash
-	switch strings.ToLower(string(object.GetInventory().GetDigestAlgorithm())) {
-	case "sha256":
-		ha = crypto.SHA256
-	case "sha512":
-		ha = crypto.SHA512
-	case "sha1":
-		ha = crypto.SHA1
-	case "md5":
-		ha = crypto.MD5
-	default:
-		return errors.Errorf("unsupported hash algorithm '%s'", object.GetInventory().GetDigestAlgorithm())
+	ha, err := timestampHash(string(object.GetInventory().GetDigestAlgorithm()))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	checksumBytes, err := hex.DecodeString(checksumString)
+	if err != nil {
+		return errors.Wrapf(err, "cannot decode checksum '%s'", checksumString)
 	}
+	return errors.WithStack(sl.storeTimestamps(object.GetInventory().GetHead(), ha, checksumBytes))
+}
+
+// storeTimestamps writes the timestamp request of the version for every authority.
+// online the response is stored too, offline the request stays pending for FlushTimestamps
+func (sl *Timestamp) storeTimestamps(version string, ha crypto.Hash, checksumBytes []byte) error {
 	/*
 		rqst, err := timestamp.CreateRequest(bytes.NewReader(inventory), &timestamp.RequestOptions{
 			Hash: ha,
@@ -93,10 +160,6 @@ func (sl *Timestamp) trustedTimestamp(object object.Object) error {
 			return errors.Wrap(err, "cannot create request")
 		}
 	*/
-	checksumBytes, err := hex.DecodeString(checksumString)
-	if err != nil {
-		return errors.Wrapf(err, "cannot decode checksum '%s'", checksumString)
-	}
 	req := &timestamp.Request{
 		HashAlgorithm: ha,
 		HashedMessage: checksumBytes,
@@ -114,32 +177,19 @@ func (sl *Timestamp) trustedTimestamp(object object.Object) error {
 			return errors.Wrap(err, "cannot parse request")
 		}
 	*/
-	client := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
+	client := newTimestampClient()
 	for name, url := range sl.Authority {
-		tsaReq, err := http.NewRequest("POST", url, bytes.NewReader(rqst))
-		if err != nil {
-			return errors.Wrap(err, "cannot create request")
-		}
-		tsaReq.Header.Set("content-type", "application/timestamp-query")
-		tsaReq.Header.Set("content-length", fmt.Sprintf("%d", len(rqst)))
-		// tsaReq.Header.Set("user-agent", "curl/8.5.0")
-		// tsaReq.Header.Set("accept", "*/*")
-
-		resp, err := client.Do(tsaReq)
-		if err != nil {
-			return errors.Wrap(err, "cannot send request")
+		queryfile := fmt.Sprintf("data/%s.%s.tsq", name, version)
+		if sl.offline {
+			if _, err := writefs.WriteFile(sl.fsys, queryfile, rqst); err != nil {
+				return errors.Wrapf(err, "cannot write query file '%s'", queryfile)
+			}
+			sl.logger.Info().Msgf("timestamp request '%s' pending", queryfile)
+			continue
 		}
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
+		body, err := requestTimestamp(client, url, rqst)
 		if err != nil {
-			return errors.Wrap(err, "cannot read response")
-		}
-		if resp.StatusCode != http.StatusOK {
-			return errors.Errorf("invalid response from TSA %v: %s", tsaReq, resp.Status)
+			return errors.Wrapf(err, "cannot get timestamp from '%s'", name)
 		}
 		ts, err := timestamp.ParseResponse(body)
 		if err != nil {
@@ -147,11 +197,10 @@ func (sl *Timestamp) trustedTimestamp(object object.Object) error {
 		}
 		sl.logger.Debug().Msgf("Timestamp response: %+v", ts)
 		//	_ = ts
-		sigfile := fmt.Sprintf("data/%s.%s.tsr", name, object.GetInventory().GetHead())
+		sigfile := fmt.Sprintf("data/%s.%s.tsr", name, version)
 		if _, err := writefs.WriteFile(sl.fsys, sigfile, body); err != nil {
 			return errors.Wrapf(err, "cannot write Timestamp file '%s'", sigfile)
 		}
-		queryfile := fmt.Sprintf("data/%s.%s.tsq", name, object.GetInventory().GetHead())
 		if _, err := writefs.WriteFile(sl.fsys, queryfile, rqst); err != nil {
 			return errors.Wrapf(err, "cannot write query file '%s'", queryfile)
 		}
@@ -159,6 +208,216 @@ func (sl *Timestamp) trustedTimestamp(object object.Object) error {
 	return nil
 }
 
+// timestampFiles returns the authorities of all files with suffix per version
+func (sl *Timestamp) timestampFiles(suffix string) (map[string][]string, error) {
+	var files = map[string][]string{}
+	entries, err := fs.ReadDir(sl.fsys, "data")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.Wrap(err, "cannot read timestamp folder")
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		// <authority>.<version><suffix>
+		base := strings.TrimSuffix(name, suffix)
+		pos := strings.LastIndex(base, ".")
+		if pos < 0 {
+			continue
+		}
+		files[base[pos+1:]] = append(files[base[pos+1:]], base[:pos])
+	}
+	for _, authorities := range files {
+		slices.Sort(authorities)
+	}
+	return files, nil
+}
+
+// PendingTimestamps returns the timestamp requests without response
+func (sl *Timestamp) PendingTimestamps() ([]*TimestampResult, error) {
+	if sl.fsys == nil {
+		return nil, errors.New("no filesystem set")
+	}
+	queries, err := sl.timestampFiles(".tsq")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	responses, err := sl.timestampFiles(".tsr")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var versions = []string{}
+	for version := range queries {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	var pending = []*TimestampResult{}
+	for _, version := range versions {
+		for _, authority := range queries[version] {
+			if slices.Contains(responses[version], authority) {
+				continue
+			}
+			pending = append(pending, &TimestampResult{Version: version, Authority: authority})
+		}
+	}
+	return pending, nil
+}
+
+// FlushTimestamps sends the pending timestamp requests to the authorities and stores the responses.
+// the genesis time of these timestamps is the time of the flush
+func (sl *Timestamp) FlushTimestamps(client *http.Client) ([]*TimestampResult, error) {
+	pending, err := sl.PendingTimestamps()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if client == nil {
+		client = newTimestampClient()
+	}
+	for _, result := range pending {
+		url, ok := sl.Authority[result.Authority]
+		if !ok {
+			result.Err = errors.Errorf("unknown timestamp authority '%s'", result.Authority)
+			continue
+		}
+		queryfile := fmt.Sprintf("data/%s.%s.tsq", result.Authority, result.Version)
+		rqst, err := fs.ReadFile(sl.fsys, queryfile)
+		if err != nil {
+			result.Err = errors.Wrapf(err, "cannot read '%s'", queryfile)
+			continue
+		}
+		req, err := timestamp.ParseRequest(rqst)
+		if err != nil {
+			result.Err = errors.Wrapf(err, "cannot parse '%s'", queryfile)
+			continue
+		}
+		body, err := requestTimestamp(client, url, rqst)
+		if err != nil {
+			result.Err = errors.Wrapf(err, "cannot get timestamp from '%s'", result.Authority)
+			continue
+		}
+		ts, err := timestamp.ParseResponse(body)
+		if err != nil {
+			result.Err = errors.Wrapf(err, "cannot parse response of '%s'", result.Authority)
+			continue
+		}
+		if !bytes.Equal(ts.HashedMessage, req.HashedMessage) {
+			result.Err = errors.Errorf("message imprint of response %x does not match request %x", ts.HashedMessage, req.HashedMessage)
+			continue
+		}
+		sigfile := fmt.Sprintf("data/%s.%s.tsr", result.Authority, result.Version)
+		if _, err := writefs.WriteFile(sl.fsys, sigfile, body); err != nil {
+			return nil, errors.Wrapf(err, "cannot write Timestamp file '%s'", sigfile)
+		}
+		result.Genesis = ts.Time
+	}
+	return pending, nil
+}
+
+// VerifyTimestampToken checks a timestamp response against the content of a version inventory.
+// the signer certificate must chain to roots at the time of the timestamp. certs are added
+// to the certificates of the token (tsa or intermediate certificates for tokens without chain)
+func VerifyTimestampToken(tsr []byte, inventory []byte, roots *x509.CertPool, certs []*x509.Certificate) (*timestamp.Timestamp, error) {
+	if roots == nil {
+		return nil, errors.New("no trust store")
+	}
+	ts, err := timestamp.ParseResponse(tsr)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse timestamp response")
+	}
+	if !ts.HashAlgorithm.Available() {
+		return nil, errors.Errorf("hash algorithm %v not available", ts.HashAlgorithm)
+	}
+	h := ts.HashAlgorithm.New()
+	h.Write(inventory)
+	if digest := h.Sum(nil); !bytes.Equal(digest, ts.HashedMessage) {
+		return nil, errors.Errorf("message imprint %x does not match inventory digest %x", ts.HashedMessage, digest)
+	}
+	p7, err := pkcs7.Parse(ts.RawToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse timestamp token")
+	}
+	p7.Certificates = append(p7.Certificates, certs...)
+	intermediates := x509.NewCertPool()
+	for _, cert := range p7.Certificates {
+		intermediates.AddCert(cert)
+	}
+	if err := p7.VerifyWithOpts(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   ts.Time,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}); err != nil {
+		return nil, errors.Wrap(err, "cannot verify timestamp signature")
+	}
+	return ts, nil
+}
+
+// TimestampResult is the verification result of a stored timestamp
+type TimestampResult struct {
+	Version   string
+	Authority string
+	Genesis   time.Time
+	Err       error
+}
+
+// VerifyTimestamps verifies the stored timestamps of all versions of the object.
+// versions without timestamp get a result with an error
+func (sl *Timestamp) VerifyTimestamps(object object.Object, roots *x509.CertPool, certs []*x509.Certificate) ([]*TimestampResult, error) {
+	if sl.fsys == nil {
+		return nil, errors.New("no filesystem set")
+	}
+	tokens, err := sl.timestampFiles(".tsr")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	queries, err := sl.timestampFiles(".tsq")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var results = []*TimestampResult{}
+	for _, version := range object.GetInventory().GetVersionStrings() {
+		authorities := tokens[version]
+		if len(authorities) == 0 {
+			result := &TimestampResult{
+				Version: version,
+				Err:     errors.Errorf("no timestamp for version %s", version),
+			}
+			if len(queries[version]) > 0 {
+				result.Err = errors.Errorf("timestamp for version %s pending", version)
+			}
+			results = append(results, result)
+			continue
+		}
+		inventoryName := path.Join(version, "inventory.json")
+		inventory, err := fs.ReadFile(object.GetFS(), inventoryName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read '%s'", inventoryName)
+		}
+		for _, authority := range authorities {
+			result := &TimestampResult{
+				Version:   version,
+				Authority: authority,
+			}
+			results = append(results, result)
+			tsrName := fmt.Sprintf("data/%s.%s.tsr", authority, version)
+			tsr, err := fs.ReadFile(sl.fsys, tsrName)
+			if err != nil {
+				result.Err = errors.Wrapf(err, "cannot read '%s'", tsrName)
+				continue
+			}
+			ts, err := VerifyTimestampToken(tsr, inventory, roots, certs)
+			if err != nil {
+				result.Err = errors.Wrapf(err, "invalid timestamp '%s'", tsrName)
+				continue
+			}
+			result.Genesis = ts.Time
+		}
+	}
+	return results, nil
+}
+
 func (sl *Timestamp) VersionDone(object object.Object) error {
 	if sl.fsys == nil {
 		return errors.New("no filesystem set")
@@ -191,6 +450,17 @@ func (sl *Timestamp) SetFS(fsys fs.FS, create bool) {
 }
 
 func (sl *Timestamp) SetParams(params map[string]string) error {
+	if params == nil {
+		return nil
+	}
+	name := fmt.Sprintf("ext-%s-%s", TimestampName, "offline")
+	if str := strings.TrimSpace(params[name]); str != "" {
+		offline, err := strconv.ParseBool(str)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value '%s' for parameter '%s'", str, name)
+		}
+		sl.offline = offline
+	}
 	return nil
 }
 
@@ -0,0 +1,306 @@
+package extension
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/fs"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/digitorus/timestamp"
+	"github.com/je4/filesystem/v3/pkg/osfsrw"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/rs/zerolog"
+)
+
+type testTSA struct {
+	caCert  *x509.Certificate
+	tsaCert *x509.Certificate
+	tsaKey  crypto.Signer
+	genesis time.Time
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate ca key: %v", err)
+	}
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gocfl test ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("cannot create ca certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("cannot parse ca certificate: %v", err)
+	}
+
+	tsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate tsa key: %v", err)
+	}
+	tsaTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "gocfl test tsa"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	tsaDER, err := x509.CreateCertificate(rand.Reader, tsaTemplate, caCert, tsaKey.Public(), caKey)
+	if err != nil {
+		t.Fatalf("cannot create tsa certificate: %v", err)
+	}
+	tsaCert, err := x509.ParseCertificate(tsaDER)
+	if err != nil {
+		t.Fatalf("cannot parse tsa certificate: %v", err)
+	}
+	return &testTSA{
+		caCert:  caCert,
+		tsaCert: tsaCert,
+		tsaKey:  tsaKey,
+		genesis: now.Truncate(time.Second).UTC(),
+	}
+}
+
+func (tsa *testTSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := timestamp.ParseRequest(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ts := &timestamp.Timestamp{
+		HashAlgorithm:     req.HashAlgorithm,
+		HashedMessage:     req.HashedMessage,
+		Time:              tsa.genesis,
+		Policy:            []int{1, 2, 3, 4, 1},
+		AddTSACertificate: req.Certificates,
+	}
+	resp, err := ts.CreateResponseWithOpts(tsa.tsaCert, tsa.tsaKey, crypto.SHA256)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/timestamp-reply")
+	_, _ = w.Write(resp)
+}
+
+func getTestTimestamp(t *testing.T, url string, inventory []byte, certChain bool) []byte {
+	ha, err := timestampHash("sha512")
+	if err != nil {
+		t.Fatalf("cannot get hash: %v", err)
+	}
+	h := ha.New()
+	h.Write(inventory)
+	req := &timestamp.Request{
+		HashAlgorithm: ha,
+		HashedMessage: h.Sum(nil),
+		Certificates:  certChain,
+	}
+	rqst, err := req.Marshal()
+	if err != nil {
+		t.Fatalf("cannot marshal request: %v", err)
+	}
+	tsr, err := requestTimestamp(http.DefaultClient, url, rqst)
+	if err != nil {
+		t.Fatalf("cannot get timestamp: %v", err)
+	}
+	return tsr
+}
+
+func TestVerifyTimestampToken(t *testing.T) {
+	tsa := newTestTSA(t)
+	srv := httptest.NewServer(tsa)
+	defer srv.Close()
+
+	inventory := []byte(`{"id": "test", "head": "v1"}`)
+	roots := x509.NewCertPool()
+	roots.AddCert(tsa.caCert)
+
+	tsr := getTestTimestamp(t, srv.URL, inventory, true)
+	ts, err := VerifyTimestampToken(tsr, inventory, roots, nil)
+	if err != nil {
+		t.Fatalf("cannot verify timestamp: %v", err)
+	}
+	if !ts.Time.Equal(tsa.genesis) {
+		t.Errorf("genesis time %v != %v", ts.Time, tsa.genesis)
+	}
+
+	if _, err := VerifyTimestampToken(tsr, []byte(`{"id": "test", "head": "v2"}`), roots, nil); err == nil {
+		t.Errorf("modified inventory verified")
+	}
+
+	other := newTestTSA(t)
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(other.caCert)
+	if _, err := VerifyTimestampToken(tsr, inventory, otherRoots, nil); err == nil {
+		t.Errorf("timestamp verified with foreign trust store")
+	}
+
+	// token without certificates needs the tsa certificate from the trust store
+	tsr = getTestTimestamp(t, srv.URL, inventory, false)
+	if _, err := VerifyTimestampToken(tsr, inventory, roots, nil); err == nil {
+		t.Errorf("timestamp without tsa certificate verified")
+	}
+	if _, err := VerifyTimestampToken(tsr, inventory, roots, []*x509.Certificate{tsa.tsaCert}); err != nil {
+		t.Errorf("cannot verify timestamp with tsa certificate: %v", err)
+	}
+}
+
+func TestFlushTimestamps(t *testing.T) {
+	tsa := newTestTSA(t)
+	srv := httptest.NewServer(tsa)
+	defer srv.Close()
+
+	logger := zerolog.Nop()
+	fsys, err := osfsrw.NewFS(t.TempDir(), true, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	sl, err := NewTimestamp(&TimestampConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: TimestampName},
+		Authority:       map[string]string{"local": srv.URL},
+		CertChain:       true,
+		Offline:         true,
+	}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create extension: %v", err)
+	}
+	sl.SetFS(fsys, true)
+
+	inventory := []byte(`{"id": "test", "head": "v1"}`)
+	h := crypto.SHA512.New()
+	h.Write(inventory)
+	if err := sl.storeTimestamps("v1", crypto.SHA512, h.Sum(nil)); err != nil {
+		t.Fatalf("cannot store pending timestamp: %v", err)
+	}
+	if _, err := fs.Stat(fsys, "data/local.v1.tsr"); err == nil {
+		t.Fatalf("offline mode stored a timestamp response")
+	}
+	pending, err := sl.PendingTimestamps()
+	if err != nil {
+		t.Fatalf("cannot get pending timestamps: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Version != "v1" || pending[0].Authority != "local" {
+		t.Fatalf("invalid pending timestamps %v", pending)
+	}
+
+	results, err := sl.FlushTimestamps(srv.Client())
+	if err != nil {
+		t.Fatalf("cannot flush timestamps: %v", err)
+	}
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("invalid flush result %v", results)
+	}
+	if !results[0].Genesis.Equal(tsa.genesis) {
+		t.Errorf("genesis time %v != %v", results[0].Genesis, tsa.genesis)
+	}
+	tsr, err := fs.ReadFile(fsys, "data/local.v1.tsr")
+	if err != nil {
+		t.Fatalf("cannot read timestamp response: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(tsa.caCert)
+	if _, err := VerifyTimestampToken(tsr, inventory, roots, nil); err != nil {
+		t.Errorf("cannot verify flushed timestamp: %v", err)
+	}
+	if pending, err = sl.PendingTimestamps(); err != nil || len(pending) != 0 {
+		t.Errorf("pending timestamps after flush: %v - %v", pending, err)
+	}
+}
+
+// newTimestampedObject creates an object with one version and returns the object and its folder
+func newTimestampedObject(t *testing.T, url string) (object.Object, *Timestamp, string) {
+	logger := zerolog.Nop()
+	sl, err := NewTimestamp(&TimestampConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: TimestampName},
+		Authority:       map[string]string{"local": url},
+		CertChain:       true,
+	}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create extension: %v", err)
+	}
+	obj, dir := newTestObject(t, "test:timestamp", sl)
+	if _, err := obj.StartUpdate(nil, "timestamp test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddData([]byte("content"), "file.txt", false, "content", false, false); err != nil {
+		t.Fatalf("cannot add data: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	return obj, sl, dir
+}
+
+func TestTimestampObject(t *testing.T) {
+	tsa := newTestTSA(t)
+	srv := httptest.NewServer(tsa)
+	defer srv.Close()
+
+	obj, sl, _ := newTimestampedObject(t, srv.URL)
+	roots := x509.NewCertPool()
+	roots.AddCert(tsa.caCert)
+	results, err := sl.VerifyTimestamps(obj, roots, nil)
+	if err != nil {
+		t.Fatalf("cannot verify timestamps: %v", err)
+	}
+	if len(results) != 1 || results[0].Version != "v1" || results[0].Authority != "local" {
+		t.Fatalf("unexpected results %v", results)
+	}
+	if results[0].Err != nil {
+		t.Fatalf("stored timestamp not valid: %v", results[0].Err)
+	}
+	if !results[0].Genesis.Equal(tsa.genesis) {
+		t.Errorf("genesis time %v != %v", results[0].Genesis, tsa.genesis)
+	}
+}
+
+func TestTimestampObjectUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	// the version is stored without timestamp
+	obj, sl, dir := newTimestampedObject(t, url)
+	for _, name := range []string{"inventory.json", "v1/inventory.json", "v1/content/file.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("'%s' missing: %v", name, err)
+		}
+	}
+	results, err := sl.VerifyTimestamps(obj, x509.NewCertPool(), nil)
+	if err != nil {
+		t.Fatalf("cannot verify timestamps: %v", err)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Errorf("missing timestamp not reported: %v", results)
+	}
+}
@@ -0,0 +1,557 @@
+package extension
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/andybalholm/brotli"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"golang.org/x/exp/slices"
+)
+
+const VirusScanName = "NNNN-virusscan"
+const VirusScanDescription = "virus scan of all ingested files with a clamd compatible daemon"
+
+const (
+	VirusScanPolicyReject     = "reject"
+	VirusScanPolicyQuarantine = "quarantine"
+	VirusScanPolicyAbort      = "abort"
+)
+
+const (
+	VirusScanStatusClean      = "clean"
+	VirusScanStatusInfected   = "infected"
+	VirusScanStatusNotScanned = "not scanned"
+)
+
+// size of the INSTREAM chunks
+const clamdChunkSize = 64 * 1024
+
+var virusScanPolicies = []string{VirusScanPolicyReject, VirusScanPolicyQuarantine, VirusScanPolicyAbort}
+
+func GetVirusScanParams() []*extension.ExtensionExternalParam {
+	return []*extension.ExtensionExternalParam{
+		{
+			ExtensionName: VirusScanName,
+			Functions:     []string{"add", "update", "create", "ingest"},
+			Param:         "addr",
+			Description:   "address of clamd (unix:///var/run/clamav/clamd.ctl or tcp://localhost:3310)",
+		},
+	}
+}
+
+func NewVirusScanFS(fsys fs.FS, logger zLogger.ZLogger) (*VirusScan, error) {
+	data, err := fs.ReadFile(fsys, "config.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read config.json")
+	}
+
+	var config = &VirusScanConfig{
+		Policy:   VirusScanPolicyReject,
+		Timeout:  60,
+		Compress: "none",
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal VirusScanConfig '%s'", string(data))
+	}
+	return NewVirusScan(config, logger)
+}
+
+func NewVirusScan(config *VirusScanConfig, logger zLogger.ZLogger) (*VirusScan, error) {
+	sl := &VirusScan{
+		VirusScanConfig: config,
+		logger:          logger,
+		buffer:          map[string]*bytes.Buffer{},
+		scanned:         map[string]*VirusScanResult{},
+	}
+	if config.ExtensionName != sl.GetName() {
+		return nil, errors.New(fmt.Sprintf("invalid extension name'%s'for extension %s", config.ExtensionName, sl.GetName()))
+	}
+	config.Policy = strings.ToLower(config.Policy)
+	if config.Policy == "" {
+		config.Policy = VirusScanPolicyReject
+	}
+	if !slices.Contains(virusScanPolicies, config.Policy) {
+		return nil, errors.Errorf("invalid policy '%s' in config file", config.Policy)
+	}
+	if config.Policy == VirusScanPolicyQuarantine && config.QuarantineArea == "" {
+		return nil, errors.New("no quarantineArea for policy 'quarantine' in config file")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 60
+	}
+	if config.Compress == "" {
+		config.Compress = "none"
+	}
+	if !slices.Contains(compress, config.Compress) {
+		return nil, errors.Errorf("invalid compression '%s' in config file", config.Compress)
+	}
+	return sl, nil
+}
+
+type VirusScanConfig struct {
+	*extension.ExtensionConfig
+	Address        string `json:"address"`
+	Timeout        int    `json:"timeout"` // seconds
+	Policy         string `json:"policy"`
+	QuarantineArea string `json:"quarantineArea,omitempty"`
+	StorageType    string `json:"storageType"`
+	StorageName    string `json:"storageName"`
+	Compress       string `json:"compress"`
+}
+
+// VirusScanResult is one line of the result jsonl
+type VirusScanResult struct {
+	Path      string    `json:"path"`
+	Area      string    `json:"area"`
+	Status    string    `json:"status"`
+	Signature string    `json:"signature,omitempty"`
+	Action    string    `json:"action"`
+	Engine    string    `json:"engine,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+type VirusScan struct {
+	*VirusScanConfig
+	fsys        fs.FS
+	logger      zLogger.ZLogger
+	engine      string
+	currentHead string
+	buffer      map[string]*bytes.Buffer
+	writer      *brotli.Writer
+	// scanned contains the results of the files to be added by manifest path
+	scanned map[string]*VirusScanResult
+}
+
+func (sl *VirusScan) Terminate() error {
+	return nil
+}
+
+func (sl *VirusScan) GetFS() fs.FS {
+	return sl.fsys
+}
+
+func (sl *VirusScan) GetConfig() any {
+	return sl.VirusScanConfig
+}
+
+func (sl *VirusScan) IsRegistered() bool {
+	return false
+}
+
+func (sl *VirusScan) SetFS(fsys fs.FS, create bool) {
+	sl.fsys = fsys
+}
+
+func (sl *VirusScan) SetParams(params map[string]string) error {
+	name := fmt.Sprintf("ext-%s-%s", VirusScanName, "addr")
+	if addr := strings.TrimSpace(params[name]); addr != "" {
+		sl.Address = addr
+	}
+	return nil
+}
+
+func (sl *VirusScan) GetName() string { return VirusScanName }
+
+func (sl *VirusScan) WriteConfig() error {
+	if sl.fsys == nil {
+		return errors.New("no filesystem set")
+	}
+	configWriter, err := writefs.Create(sl.fsys, "config.json")
+	if err != nil {
+		return errors.Wrap(err, "cannot open config.json")
+	}
+	defer configWriter.Close()
+	jenc := json.NewEncoder(configWriter)
+	jenc.SetIndent("", "   ")
+	if err := jenc.Encode(sl.VirusScanConfig); err != nil {
+		return errors.Wrapf(err, "cannot encode config to file")
+	}
+
+	return nil
+}
+
+// clamdDial connects to a clamd address (unix:///path, tcp://host:port or host:port)
+func clamdDial(address string, timeout time.Duration) (net.Conn, error) {
+	network, addr := "tcp", address
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot parse clamd address '%s'", address)
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "unix":
+			network, addr = "unix", u.Path
+		case "tcp":
+			addr = u.Host
+		default:
+			return nil, errors.Errorf("unsupported clamd address scheme '%s'", u.Scheme)
+		}
+	}
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot connect to clamd '%s'", address)
+	}
+	return conn, nil
+}
+
+// clamdCommand sends a null terminated command and returns the reply without terminator
+func clamdCommand(conn net.Conn, cmd string, body io.Reader, timeout time.Duration) (string, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if _, err := conn.Write([]byte("z" + cmd + "\x00")); err != nil {
+		return "", errors.Wrapf(err, "cannot send '%s'", cmd)
+	}
+	if body != nil {
+		buf := make([]byte, 4+clamdChunkSize)
+		for {
+			n, err := io.ReadFull(body, buf[4:])
+			if n > 0 {
+				binary.BigEndian.PutUint32(buf[:4], uint32(n))
+				if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+					return "", errors.WithStack(err)
+				}
+				if _, err := conn.Write(buf[:4+n]); err != nil {
+					return "", errors.Wrap(err, "cannot send chunk")
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return "", errors.Wrap(err, "cannot read data")
+			}
+		}
+		if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+			return "", errors.Wrap(err, "cannot send end of stream")
+		}
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return "", errors.Wrapf(err, "cannot read reply of '%s'", cmd)
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// ClamdScan streams the data to clamd and returns the name of the signature, if a virus is found
+func ClamdScan(address string, timeout time.Duration, r io.Reader) (infected bool, signature string, err error) {
+	conn, err := clamdDial(address, timeout)
+	if err != nil {
+		return false, "", err
+	}
+	defer conn.Close()
+	reply, err := clamdCommand(conn, "INSTREAM", r, timeout)
+	if err != nil {
+		return false, "", err
+	}
+	// stream: OK | stream: <signature> FOUND | <message> ERROR
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return false, "", nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return true, strings.TrimSuffix(reply, " FOUND"), nil
+	default:
+		return false, "", errors.Errorf("clamd error: %s", reply)
+	}
+}
+
+// ClamdVersion returns the engine and signature version of clamd
+func ClamdVersion(address string, timeout time.Duration) (string, error) {
+	conn, err := clamdDial(address, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return clamdCommand(conn, "VERSION", nil, timeout)
+}
+
+func (sl *VirusScan) timeout() time.Duration {
+	return time.Duration(sl.Timeout) * time.Second
+}
+
+// premis returns the PREMIS extension of the object or nil
+func (sl *VirusScan) premis(object object.Object) *Premis {
+	for _, ext := range object.GetExtensionManager().GetExtensions() {
+		if p, ok := ext.(*Premis); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// writeResult appends the result to the jsonl of the current version
+func (sl *VirusScan) writeResult(object object.Object, result *VirusScanResult) error {
+	head := object.GetInventory().GetHead()
+	if _, ok := sl.buffer[head]; !ok {
+		sl.buffer[head] = &bytes.Buffer{}
+	}
+	if sl.currentHead != head {
+		sl.writer = brotli.NewWriter(sl.buffer[head])
+		sl.currentHead = head
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errors.Errorf("cannot marshal result %v", result)
+	}
+	if _, err := sl.writer.Write(append(data, []byte("\n")...)); err != nil {
+		return errors.Errorf("cannot brotli %s", string(data))
+	}
+	return nil
+}
+
+// quarantine adds the file to the quarantine area without extension hooks
+func (sl *VirusScan) quarantine(object object.Object, sourceFS fs.FS, source string, result *VirusScanResult) error {
+	fp, err := sourceFS.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open '%v/%s'", sourceFS, source)
+	}
+	defer fp.Close()
+	names, err := object.BuildNames([]string{source}, sl.QuarantineArea)
+	if err != nil {
+		return errors.Wrapf(err, "cannot build names for '%s' in area '%s'", source, sl.QuarantineArea)
+	}
+	digest, err := object.AddReader(fp, []string{source}, sl.QuarantineArea, true, false)
+	if err != nil {
+		return errors.Wrapf(err, "cannot add '%s' to area '%s'", source, sl.QuarantineArea)
+	}
+	if len(names.ExternalPaths) > 0 {
+		result.Path = names.ExternalPaths[0]
+	}
+	result.Area = sl.QuarantineArea
+	if premis := sl.premis(object); premis != nil {
+		premis.AddRecord(&PremisRecord{
+			Event:   PremisEventVirusCheck,
+			Path:    names.ManifestPath,
+			Digest:  digest,
+			Time:    result.Time,
+			Outcome: "fail",
+			Detail:  result.Engine,
+			Note:    fmt.Sprintf("%s found, file quarantined", result.Signature),
+		})
+	}
+	return nil
+}
+
+func (sl *VirusScan) AddFileBefore(obj object.Object, sourceFS fs.FS, source string, dest string, area string, isDir bool) error {
+	if isDir || (sl.QuarantineArea != "" && area == sl.QuarantineArea) {
+		return nil
+	}
+	statePath, err := obj.GetExtensionManager().BuildObjectStatePath(obj, source, area)
+	if err != nil {
+		return errors.Wrapf(err, "cannot build state path for '%s'", source)
+	}
+	result := &VirusScanResult{
+		Path:   statePath,
+		Area:   area,
+		Status: VirusScanStatusClean,
+		Action: "added",
+		Time:   time.Now(),
+	}
+	if sourceFS == nil {
+		// the object buffers streamed content for content inspection. never store unscanned files
+		result.Status = VirusScanStatusNotScanned
+		result.Action = "aborted"
+		if err := sl.writeResult(obj, result); err != nil {
+			return err
+		}
+		return errors.Errorf("cannot scan '%s' for viruses: no source filesystem", source)
+	}
+	if sl.engine == "" {
+		if sl.engine, err = ClamdVersion(sl.Address, sl.timeout()); err != nil {
+			return errors.Wrap(err, "cannot get clamd version")
+		}
+	}
+	result.Engine = sl.engine
+
+	fp, err := sourceFS.Open(source)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open '%v/%s'", sourceFS, source)
+	}
+	infected, signature, err := ClamdScan(sl.Address, sl.timeout(), fp)
+	fp.Close()
+	if err != nil {
+		return errors.Wrapf(err, "cannot scan '%s'", source)
+	}
+	if !infected {
+		sl.scanned[obj.GetInventory().BuildManifestName(dest)] = result
+		return sl.writeResult(obj, result)
+	}
+
+	sl.logger.Error().Msgf("[%s] virus '%s' found in '%s'", obj.GetID(), signature, source)
+	result.Status = VirusScanStatusInfected
+	result.Signature = signature
+	switch sl.Policy {
+	case VirusScanPolicyAbort:
+		result.Action = "aborted"
+		if err := sl.writeResult(obj, result); err != nil {
+			return err
+		}
+		return errors.Errorf("virus '%s' found in '%s'", signature, source)
+	case VirusScanPolicyQuarantine:
+		result.Action = "quarantined"
+		if err := sl.quarantine(obj, sourceFS, source, result); err != nil {
+			return err
+		}
+	default:
+		result.Action = "rejected"
+	}
+	if err := sl.writeResult(obj, result); err != nil {
+		return err
+	}
+	return object.ErrSkipFile
+}
+
+// InspectContent is always true, every file has to be scanned before it's stored
+func (sl *VirusScan) InspectContent() bool {
+	return true
+}
+
+func (sl *VirusScan) UpdateFileBefore(object object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *VirusScan) DeleteFileBefore(object object.Object, dest string, area string) error {
+	return nil
+}
+
+func (sl *VirusScan) AddFileAfter(object object.Object, sourceFS fs.FS, source []string, internalPath, digest, area string, isDir bool) error {
+	result, ok := sl.scanned[internalPath]
+	if !ok {
+		return nil
+	}
+	delete(sl.scanned, internalPath)
+	premis := sl.premis(object)
+	if premis == nil {
+		return nil
+	}
+	var size int64
+	if sourceFS != nil && len(source) > 0 {
+		if fi, err := fs.Stat(sourceFS, source[0]); err == nil {
+			size = fi.Size()
+		}
+	}
+	premis.AddRecord(&PremisRecord{
+		Event:   PremisEventVirusCheck,
+		Path:    internalPath,
+		Digest:  digest,
+		Size:    size,
+		Time:    result.Time,
+		Outcome: "success",
+		Detail:  result.Engine,
+	})
+	return nil
+}
+
+func (sl *VirusScan) UpdateFileAfter(object object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+
+func (sl *VirusScan) DeleteFileAfter(object object.Object, dest string, area string) error {
+	return nil
+}
+
+func (sl *VirusScan) UpdateObjectBefore(object object.Object) error {
+	return nil
+}
+
+func (sl *VirusScan) UpdateObjectAfter(object object.Object) error {
+	if sl.writer == nil {
+		return nil
+	}
+	if err := sl.writer.Flush(); err != nil {
+		return errors.Wrap(err, "cannot flush brotli writer")
+	}
+	if err := sl.writer.Close(); err != nil {
+		return errors.Wrap(err, "cannot close brotli writer")
+	}
+	head := object.GetInventory().GetHead()
+	if head == "" {
+		return errors.Errorf("no head for object '%s'", object.GetID())
+	}
+	buffer, ok := sl.buffer[head]
+	if !ok {
+		return nil
+	}
+	if err := WriteJsonL(
+		object,
+		"virusscan",
+		buffer.Bytes(),
+		sl.Compress,
+		sl.StorageType,
+		sl.StorageName,
+		sl.fsys,
+	); err != nil {
+		return errors.Wrap(err, "cannot write jsonl")
+	}
+	return nil
+}
+
+func (sl *VirusScan) GetMetadata(object object.Object) (map[string]any, error) {
+	var err error
+	var result = map[string]any{}
+
+	inventory := object.GetInventory()
+	for v := range inventory.GetVersions() {
+		var data []byte
+		if buf, ok := sl.buffer[v]; ok && buf.Len() > 0 {
+			reader := brotli.NewReader(bytes.NewBuffer(buf.Bytes()))
+			data, err = io.ReadAll(reader)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot read buffer for '%s' '%s'", object.GetID(), v)
+			}
+		} else {
+			data, err = ReadJsonL(object, "virusscan", v, sl.Compress, sl.StorageType, sl.StorageName, sl.fsys)
+			if err != nil {
+				continue
+			}
+		}
+		var lines = map[string]*VirusScanResult{}
+		r := bufio.NewScanner(bytes.NewReader(data))
+		r.Buffer(make([]byte, 128*1024), 16*1024*1024)
+		for r.Scan() {
+			var line = &VirusScanResult{}
+			if err := json.Unmarshal(r.Bytes(), line); err != nil {
+				return nil, errors.Wrapf(err, "cannot unmarshal line from for '%s' %s - [%s]", object.GetID(), v, r.Text())
+			}
+			lines[line.Path] = line
+		}
+		if err := r.Err(); err != nil {
+			return nil, errors.Wrapf(err, "cannot scan lines for '%s' %s", object.GetID(), v)
+		}
+		if err := inventory.IterateStateFiles(v, func(internals, externals []string, digest string) error {
+			for _, external := range externals {
+				if line, ok := lines[external]; ok {
+					result[digest] = line
+				}
+			}
+			return nil
+		}); err != nil {
+			return nil, errors.Wrapf(err, "cannot iterate state files for '%s' version '%s'", object.GetID(), v)
+		}
+	}
+	return result, nil
+}
+
+// check interface satisfaction
+var (
+	_ extension.Extension               = &VirusScan{}
+	_ object.ExtensionContentChange     = &VirusScan{}
+	_ object.ExtensionContentInspection = &VirusScan{}
+	_ object.ExtensionObjectChange      = &VirusScan{}
+	_ object.ExtensionMetadata          = &VirusScan{}
+)
@@ -0,0 +1,290 @@
+package extension
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/rs/zerolog"
+)
+
+// fakeClamd answers INSTREAM with FOUND, if the stream contains the EICAR marker
+func fakeClamd(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString(0)
+				if err != nil {
+					return
+				}
+				switch cmd {
+				case "zVERSION\x00":
+					conn.Write([]byte("ClamAV 1.0.0/27000\x00"))
+				case "zINSTREAM\x00":
+					var data = &bytes.Buffer{}
+					for {
+						var size uint32
+						if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+							return
+						}
+						if size == 0 {
+							break
+						}
+						if _, err := io.CopyN(data, r, int64(size)); err != nil {
+							return
+						}
+					}
+					if strings.Contains(data.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+						conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+					} else {
+						conn.Write([]byte("stream: OK\x00"))
+					}
+				default:
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestClamdScan(t *testing.T) {
+	addr := fakeClamd(t)
+	timeout := 5 * time.Second
+
+	version, err := ClamdVersion("tcp://"+addr, timeout)
+	if err != nil {
+		t.Fatalf("cannot get version: %v", err)
+	}
+	if version != "ClamAV 1.0.0/27000" {
+		t.Errorf("unexpected version '%s'", version)
+	}
+
+	// more than one chunk
+	clean := strings.Repeat("clean content ", clamdChunkSize/5)
+	infected, signature, err := ClamdScan(addr, timeout, strings.NewReader(clean))
+	if err != nil {
+		t.Fatalf("cannot scan: %v", err)
+	}
+	if infected || signature != "" {
+		t.Errorf("clean data reported as infected with '%s'", signature)
+	}
+
+	infected, signature, err = ClamdScan(addr, timeout, strings.NewReader(clean+"EICAR-STANDARD-ANTIVIRUS-TEST-FILE"))
+	if err != nil {
+		t.Fatalf("cannot scan: %v", err)
+	}
+	if !infected || signature != "Eicar-Test-Signature" {
+		t.Errorf("virus not detected: %v '%s'", infected, signature)
+	}
+
+	if _, err := clamdDial("http://"+addr, timeout); err == nil {
+		t.Errorf("invalid scheme not detected")
+	}
+}
+
+const eicar = "X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*"
+
+func newTestVirusScan(t *testing.T, policy, quarantineArea string) *VirusScan {
+	logger := zerolog.Nop()
+	sl, err := NewVirusScan(&VirusScanConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: VirusScanName},
+		Address:         "tcp://" + fakeClamd(t),
+		Timeout:         5,
+		Policy:          policy,
+		QuarantineArea:  quarantineArea,
+		StorageType:     "extension",
+		StorageName:     "data",
+		Compress:        "none",
+	}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create virus scan extension: %v", err)
+	}
+	return sl
+}
+
+// readVirusScanResults reads the results of version v from the extension folder of the object
+func readVirusScanResults(t *testing.T, dir, v string) map[string]*VirusScanResult {
+	data, err := os.ReadFile(filepath.Join(dir, "extensions", VirusScanName, "data", "virusscan_"+v+".jsonl"))
+	if err != nil {
+		t.Fatalf("cannot read results: %v", err)
+	}
+	var results = map[string]*VirusScanResult{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var result = &VirusScanResult{}
+		if err := json.Unmarshal([]byte(line), result); err != nil {
+			t.Fatalf("cannot unmarshal '%s': %v", line, err)
+		}
+		results[result.Path] = result
+	}
+	return results
+}
+
+func TestVirusScanPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy   string
+		area     string
+		manifest []string
+		action   string
+	}{
+		{policy: VirusScanPolicyReject, manifest: []string{"v1/content/README.md", "v1/content/payload/clean.txt"}, action: "rejected"},
+		{policy: VirusScanPolicyQuarantine, area: "quarantine", manifest: []string{"v1/content/README.md", "v1/content/payload/clean.txt", "v1/content/quarantine/virus.txt"}, action: "quarantined"},
+		{policy: VirusScanPolicyAbort},
+	} {
+		t.Run(test.policy, func(t *testing.T) {
+			sl := newTestVirusScan(t, test.policy, test.area)
+			subpath, err := NewContentSubPath(&ContentSubPathConfig{
+				ExtensionConfig: &extension.ExtensionConfig{ExtensionName: ContentSubPathName},
+				Paths: map[string]ContentSubPathEntry{
+					"content":    {Path: "payload"},
+					"quarantine": {Path: "quarantine"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("cannot create content subpath extension: %v", err)
+			}
+			obj, dir := newTestObject(t, "test:"+test.policy, subpath, sl)
+			if _, err := obj.StartUpdate(nil, "virus scan test", "test", "mailto:test@example.org", false); err != nil {
+				t.Fatalf("cannot start update: %v", err)
+			}
+			source := writeTestFiles(t, map[string]string{"clean.txt": "clean", "virus.txt": eicar})
+			err = obj.AddFolder(source, nil, false, "content")
+			if test.policy == VirusScanPolicyAbort {
+				if err == nil {
+					t.Fatalf("infected file must abort the update")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cannot add folder: %v", err)
+			}
+			if err := obj.EndUpdate(); err != nil {
+				t.Fatalf("cannot end update: %v", err)
+			}
+			if err := obj.Close(); err != nil {
+				t.Fatalf("cannot close object: %v", err)
+			}
+			if paths := manifestPaths(obj); !slices.Equal(paths, test.manifest) {
+				t.Errorf("expected manifest %v, got %v", test.manifest, paths)
+			}
+			results := readVirusScanResults(t, dir, "v1")
+			var virus *VirusScanResult
+			for _, result := range results {
+				if result.Status == VirusScanStatusInfected {
+					virus = result
+				}
+			}
+			if virus == nil {
+				t.Fatalf("no infected file in results %v", results)
+			}
+			if virus.Action != test.action || virus.Signature != "Eicar-Test-Signature" {
+				t.Errorf("unexpected result %+v", virus)
+			}
+		})
+	}
+}
+
+func TestVirusScanStreamed(t *testing.T) {
+	sl := newTestVirusScan(t, VirusScanPolicyReject, "")
+	obj, dir := newTestObject(t, "test:streamed", sl)
+	if _, err := obj.StartUpdate(nil, "virus scan test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddData([]byte(eicar), "virus.txt", false, "content", false, false); err != nil {
+		t.Fatalf("cannot add data: %v", err)
+	}
+	if _, err := obj.AddReader(io.NopCloser(strings.NewReader("clean")), []string{"clean.txt"}, "content", false, false); err != nil {
+		t.Fatalf("cannot add reader: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	if paths := manifestPaths(obj); !slices.Equal(paths, []string{"v1/content/clean.txt"}) {
+		t.Errorf("expected manifest [v1/content/clean.txt], got %v", paths)
+	}
+	for path, result := range readVirusScanResults(t, dir, "v1") {
+		expected := VirusScanStatusClean
+		if strings.HasSuffix(path, "virus.txt") {
+			expected = VirusScanStatusInfected
+		}
+		if result.Status != expected {
+			t.Errorf("%s: expected status '%s', got '%s'", path, expected, result.Status)
+		}
+	}
+}
+
+func TestVirusScanNoSource(t *testing.T) {
+	sl := newTestVirusScan(t, VirusScanPolicyReject, "")
+	obj, _ := newTestObject(t, "test:nosource", sl)
+	if _, err := obj.StartUpdate(nil, "virus scan test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	err := sl.AddFileBefore(obj, nil, "file.txt", "file.txt", "content", false)
+	if err == nil || errors.Is(err, object.ErrSkipFile) {
+		t.Errorf("unscanned file must fail, got %v", err)
+	}
+}
+
+// testSourceChange overwrites the source file after the virus scan
+type testSourceChange struct {
+	*testContentChange
+	dir string
+}
+
+func (tc *testSourceChange) AddFileBefore(obj object.Object, sourceFS fs.FS, source string, dest string, area string, isDir bool) error {
+	if isDir {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(tc.dir, filepath.FromSlash(source)), []byte(eicar), 0644)
+}
+
+// the scanned content is stored, even if the source changes after the scan
+func TestVirusScanSourceChanged(t *testing.T) {
+	sl := newTestVirusScan(t, VirusScanPolicyAbort, "")
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "clean.txt"), []byte("clean"), 0644); err != nil {
+		t.Fatalf("cannot write source: %v", err)
+	}
+	change := &testSourceChange{testContentChange: &testContentChange{name: "test-source-change"}, dir: sourceDir}
+	obj, dir := newTestObject(t, "test:sourcechanged", sl, change)
+	if _, err := obj.StartUpdate(nil, "virus scan test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(os.DirFS(sourceDir), nil, false, "content"); err != nil {
+		t.Fatalf("cannot add folder: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "v1", "content", "clean.txt"))
+	if err != nil {
+		t.Fatalf("cannot read stored file: %v", err)
+	}
+	if string(data) != "clean" {
+		t.Errorf("stored content differs from scanned content: '%s'", string(data))
+	}
+}
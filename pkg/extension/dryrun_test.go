@@ -0,0 +1,105 @@
+package extension
+
+import (
+	"bytes"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+)
+
+// newTestDryRunObject returns an object with version v1 containing a.txt and b.txt
+func newTestDryRunObject(t *testing.T, id string) object.Object {
+	obj, _ := newTestObject(t, id, newTestPolicy(t))
+	if _, err := obj.StartUpdate(nil, "dry run test", "test", "mailto:test@example.org", false); err != nil {
+		t.Fatalf("cannot start update: %v", err)
+	}
+	if err := obj.AddFolder(writeTestFiles(t, map[string]string{"a.txt": "a", "b.txt": "b"}), nil, false, "content"); err != nil {
+		t.Fatalf("cannot add folder: %v", err)
+	}
+	if err := obj.EndUpdate(); err != nil {
+		t.Fatalf("cannot end update: %v", err)
+	}
+	if err := obj.Close(); err != nil {
+		t.Fatalf("cannot close object: %v", err)
+	}
+	return obj
+}
+
+// dryRunActions returns the actions and content paths of the report by state path
+func dryRunActions(report *object.DryRunReport) map[string]string {
+	var result = map[string]string{}
+	for _, f := range report.Files {
+		result[f.StatePath] = string(f.Action) + " " + f.ContentPath
+	}
+	return result
+}
+
+func TestDryRun(t *testing.T) {
+	source := writeTestFiles(t, map[string]string{
+		"a.txt":       "a",
+		"b.txt":       "B",
+		"c.txt":       "a",
+		"d.txt":       "d",
+		"sub/.hidden": "x",
+	})
+	for _, test := range []struct {
+		checkDuplicate bool
+		expected       map[string]string
+	}{
+		{false, map[string]string{
+			// content of unchanged files is stored again without deduplication
+			"a.txt":       "unchanged v2/content/a.txt",
+			"b.txt":       "update v2/content/b.txt",
+			"c.txt":       "add v2/content/c.txt",
+			"d.txt":       "add v2/content/d.txt",
+			"sub/.hidden": "skip ",
+		}},
+		{true, map[string]string{
+			"a.txt":       "unchanged ",
+			"b.txt":       "update v2/content/b.txt",
+			"c.txt":       "deduplicate v1/content/a.txt",
+			"d.txt":       "add v2/content/d.txt",
+			"sub/.hidden": "skip ",
+		}},
+	} {
+		obj := newTestDryRunObject(t, "id:dryrun")
+		manifest := manifestPaths(obj)
+		report, err := obj.DryRun(map[string]fs.FS{"content": source}, test.checkDuplicate, "dry run", "test", "mailto:test@example.org", false)
+		if err != nil {
+			t.Fatalf("deduplicate %v: dry run failed: %v", test.checkDuplicate, err)
+		}
+		if report.Version != "v2" || report.NewObject {
+			t.Errorf("deduplicate %v: unexpected version %s, new object %v", test.checkDuplicate, report.Version, report.NewObject)
+		}
+		actions := dryRunActions(report)
+		if len(actions) != len(test.expected) {
+			t.Errorf("deduplicate %v: expected %v, got %v", test.checkDuplicate, test.expected, actions)
+		}
+		for statePath, expected := range test.expected {
+			if actions[statePath] != expected {
+				t.Errorf("deduplicate %v: %s: expected '%s', got '%s'", test.checkDuplicate, statePath, expected, actions[statePath])
+			}
+		}
+		if after := manifestPaths(obj); !slices.Equal(manifest, after) {
+			t.Errorf("deduplicate %v: dry run changed the manifest %v", test.checkDuplicate, after)
+		}
+
+		var buf bytes.Buffer
+		if err := report.Write(&buf); err != nil {
+			t.Fatalf("cannot write report: %v", err)
+		}
+		// unchanged files are listed, if their content is stored again
+		if strings.Contains(buf.String(), "unchanged   a.txt -> v2/content/a.txt") != !test.checkDuplicate {
+			t.Errorf("deduplicate %v: unexpected report\n%s", test.checkDuplicate, buf.String())
+		}
+	}
+
+	// policy errors abort the dry run like the ingest
+	obj := newTestDryRunObject(t, "id:dryrun")
+	if _, err := obj.DryRun(map[string]fs.FS{"content": writeTestFiles(t, map[string]string{"setup.exe": "x"})}, false, "dry run", "test", "mailto:test@example.org", false); err == nil {
+		t.Errorf("policy violation not reported")
+	}
+}
@@ -0,0 +1,133 @@
+package extension
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/je4/filesystem/v3/pkg/osfsrw"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/version"
+	"github.com/rs/zerolog"
+)
+
+// newTestObject creates an empty object with the given extensions in a temporary folder.
+// it returns the object and its folder
+func newTestObject(t *testing.T, id string, exts ...extension.Extension) (object.Object, string) {
+	return newTestObjectContext(t, context.Background(), id, exts...)
+}
+
+// newTestObjectContext creates an empty object like newTestObject with options carried by ctx
+func newTestObjectContext(t *testing.T, ctx context.Context, id string, exts ...extension.Extension) (object.Object, string) {
+	logger := zerolog.Nop()
+	dir := t.TempDir()
+	fsys, err := osfsrw.NewFS(dir, true, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	factory, err := extension.NewExtensionFactory(map[string]string{}, &logger)
+	if err != nil {
+		t.Fatalf("cannot create extension factory: %v", err)
+	}
+	manager, err := NewGOCFLExtensionManager(&extension.ExtensionManagerConfig{
+		ExtensionConfig: &extension.ExtensionConfig{ExtensionName: GOCFLExtensionManagerName},
+		Sort:            map[string][]string{},
+		Exclusion:       map[string][][]string{},
+	})
+	if err != nil {
+		t.Fatalf("cannot create extension manager: %v", err)
+	}
+	initial, err := NewInitialFS(nil)
+	if err != nil {
+		t.Fatalf("cannot create initial extension: %v", err)
+	}
+	manager.SetInitial(initial)
+	for _, ext := range exts {
+		if err := manager.Add(ext); err != nil {
+			t.Fatalf("cannot add extension '%s': %v", ext.GetName(), err)
+		}
+	}
+	manager.Finalize()
+	ctx = validation.NewContextValidation(ctx)
+	obj, err := object.CreateObject(ctx, id, version.Version1_1, checksum.DigestSHA512, nil, factory, manager, fsys, &logger)
+	if err != nil {
+		t.Fatalf("cannot create object: %v", err)
+	}
+	return obj, dir
+}
+
+// writeTestFiles creates a source folder with the files
+func writeTestFiles(t *testing.T, files map[string]string) fs.FS {
+	dir := t.TempDir()
+	for name, content := range files {
+		fullpath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+			t.Fatalf("cannot create folder for '%s': %v", name, err)
+		}
+		if err := os.WriteFile(fullpath, []byte(content), 0644); err != nil {
+			t.Fatalf("cannot write '%s': %v", name, err)
+		}
+	}
+	return os.DirFS(dir)
+}
+
+// manifestPaths returns the sorted content paths of the object
+func manifestPaths(obj object.Object) []string {
+	var paths = []string{}
+	for _, p := range obj.GetInventory().GetManifest() {
+		paths = append(paths, p...)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// testContentChange is a content change extension which returns the error of the source file in AddFileBefore
+type testContentChange struct {
+	name   string
+	errs   map[string]error
+	before []string
+	after  []string
+}
+
+func (tc *testContentChange) GetName() string                          { return tc.name }
+func (tc *testContentChange) SetFS(fsys fs.FS, create bool)            {}
+func (tc *testContentChange) GetFS() fs.FS                             { return nil }
+func (tc *testContentChange) SetParams(params map[string]string) error { return nil }
+func (tc *testContentChange) WriteConfig() error                       { return nil }
+func (tc *testContentChange) GetConfig() any                           { return nil }
+func (tc *testContentChange) IsRegistered() bool                       { return false }
+func (tc *testContentChange) Terminate() error                         { return nil }
+
+func (tc *testContentChange) AddFileBefore(obj object.Object, sourceFS fs.FS, source string, dest string, area string, isDir bool) error {
+	if isDir {
+		return nil
+	}
+	tc.before = append(tc.before, source)
+	return tc.errs[source]
+}
+func (tc *testContentChange) UpdateFileBefore(obj object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+func (tc *testContentChange) DeleteFileBefore(obj object.Object, dest string, area string) error {
+	return nil
+}
+func (tc *testContentChange) AddFileAfter(obj object.Object, sourceFS fs.FS, source []string, internalPath, digest, area string, isDir bool) error {
+	if !isDir {
+		tc.after = append(tc.after, source...)
+	}
+	return nil
+}
+func (tc *testContentChange) UpdateFileAfter(obj object.Object, sourceFS fs.FS, source, dest, area string, isDir bool) error {
+	return nil
+}
+func (tc *testContentChange) DeleteFileAfter(obj object.Object, dest string, area string) error {
+	return nil
+}
+
+var _ object.ExtensionContentChange = &testContentChange{}
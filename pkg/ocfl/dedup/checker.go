@@ -0,0 +1,72 @@
+package dedup
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/je4/utils/v2/pkg/zLogger"
+)
+
+// Checker reports content, which is already stored in other objects, while an object is ingested.
+// The index is not changed, the ingested object is added after its version has been written
+type Checker struct {
+	idx    *Index
+	folder string
+	logger zLogger.ZLogger
+}
+
+// NewChecker returns a checker of the object in folder
+func NewChecker(idx *Index, folder string, logger zLogger.ZLogger) *Checker {
+	return &Checker{
+		idx:    idx,
+		folder: folder,
+		logger: logger,
+	}
+}
+
+// Index returns the index of the checker
+func (c *Checker) Index() *Index {
+	return c.idx
+}
+
+// Check warns, if one of the digests of the new content file is stored in other objects.
+// it returns the ids of these objects
+func (c *Checker) Check(digests map[checksum.DigestAlgorithm]string, manifestPath string) []string {
+	if c == nil {
+		return nil
+	}
+	var ids = map[string]bool{}
+	for alg, digest := range digests {
+		for _, e := range c.idx.Lookup(string(alg), strings.ToLower(digest), c.folder) {
+			ids[e.Object] = true
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var objects = []string{}
+	for id := range ids {
+		objects = append(objects, id)
+	}
+	sort.Strings(objects)
+	c.logger.Warn().Msgf("content of '%s' is also stored in %v", manifestPath, objects)
+	return objects
+}
+
+type contextKey struct{}
+
+// NewContext returns a context which carries the checker. objects check their new content with it
+func NewContext(ctx context.Context, checker *Checker) context.Context {
+	return context.WithValue(ctx, contextKey{}, checker)
+}
+
+// FromContext returns the checker of the context or nil
+func FromContext(ctx context.Context) *Checker {
+	if ctx == nil {
+		return nil
+	}
+	checker, _ := ctx.Value(contextKey{}).(*Checker)
+	return checker
+}
@@ -0,0 +1,439 @@
+package dedup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+)
+
+// ExtensionName is the storage root extension, which contains the content digest index
+const ExtensionName = "NNNN-dedup-index"
+
+// Folder is the storage root folder which contains the content digest index.
+// OCFL allows no other folders than objects and extensions in the storage root
+const Folder = "extensions/" + ExtensionName
+
+const IndexFile = Folder + "/index.json"
+
+const configFile = Folder + "/config.json"
+
+// Entry is one content file of an object
+type Entry struct {
+	Object string `json:"object"`
+	Folder string `json:"folder"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+}
+
+// Index maps "<digest algorithm>:<digest>" to all content files with this digest in the storage root.
+// Manifest and fixity digests are indexed, so objects with different digest algorithms are matched
+// if they share one algorithm in manifest or fixity.
+// OCFL does not allow references between objects, so the index is only used for reporting.
+type Index struct {
+	Digests map[string][]*Entry `json:"digests"`
+	// Objects maps the indexed object folders to the sidecar of their root inventory
+	Objects map[string]string `json:"objects"`
+	fsys    fs.FS
+	logger  zLogger.ZLogger
+}
+
+type inventory struct {
+	ID              string                         `json:"id"`
+	DigestAlgorithm string                         `json:"digestAlgorithm"`
+	Manifest        map[string][]string            `json:"manifest"`
+	Fixity          map[string]map[string][]string `json:"fixity"`
+}
+
+func key(alg, digest string) string {
+	return fmt.Sprintf("%s:%s", alg, digest)
+}
+
+// NewIndex returns an empty index of the storage root
+func NewIndex(fsys fs.FS, logger zLogger.ZLogger) *Index {
+	return &Index{
+		Digests: map[string][]*Entry{},
+		Objects: map[string]string{},
+		fsys:    fsys,
+		logger:  logger,
+	}
+}
+
+// LoadIndex reads the index of the storage root. if there is no index, an empty index is returned
+func LoadIndex(storageRootFS fs.FS, logger zLogger.ZLogger) (*Index, bool, error) {
+	idx := NewIndex(storageRootFS, logger)
+	data, err := fs.ReadFile(storageRootFS, IndexFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return idx, false, nil
+		}
+		return nil, false, errors.Wrapf(err, "cannot read '%s'", IndexFile)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, false, errors.Wrapf(err, "cannot unmarshal '%s'", IndexFile)
+	}
+	if idx.Digests == nil {
+		idx.Digests = map[string][]*Entry{}
+	}
+	if idx.Objects == nil {
+		idx.Objects = map[string]string{}
+	}
+	return idx, true, nil
+}
+
+// BuildIndex reads the inventories of all given object folders
+func BuildIndex(storageRootFS fs.FS, objectFolders []string, logger zLogger.ZLogger) (*Index, error) {
+	idx := NewIndex(storageRootFS, logger)
+	for _, folder := range objectFolders {
+		if err := idx.AddObject(folder); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return idx, nil
+}
+
+// Update adds the objects, which are not indexed or have changed since, and removes the objects,
+// which do not exist anymore. Objects written while the index was not used are added this way.
+// it returns true if the index has changed
+func (idx *Index) Update(objectFolders []string) (bool, error) {
+	var changed bool
+	var exists = map[string]bool{}
+	for _, folder := range objectFolders {
+		exists[folder] = true
+		sidecar, err := idx.readSidecar(folder)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if current, ok := idx.Objects[folder]; ok && current == sidecar && sidecar != "" {
+			continue
+		}
+		if err := idx.AddObject(folder); err != nil {
+			return false, errors.WithStack(err)
+		}
+		changed = true
+	}
+	for folder := range idx.Objects {
+		if !exists[folder] {
+			idx.removeFolder(folder)
+			delete(idx.Objects, folder)
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// readSidecar returns the content of the sidecar of the root inventory or an empty string if there is none
+func (idx *Index) readSidecar(folder string) (string, error) {
+	names, err := fs.Glob(idx.fsys, path.Join(folder, "inventory.json.*"))
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot search sidecar in '%s'", folder)
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	sort.Strings(names)
+	data, err := fs.ReadFile(idx.fsys, names[0])
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot read '%s'", names[0])
+	}
+	return string(data), nil
+}
+
+// Store writes the index to the storage root
+func (idx *Index) Store() error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal dedup index")
+	}
+	if _, err := writefs.WriteFile(idx.fsys, IndexFile, data); err != nil {
+		return errors.Wrapf(err, "cannot write '%s'", IndexFile)
+	}
+	// the config makes the folder a valid extension
+	if _, err := fs.Stat(idx.fsys, configFile); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return errors.Wrapf(err, "cannot stat '%s'", configFile)
+	}
+	config, err := json.MarshalIndent(map[string]string{"extensionName": ExtensionName}, "", "   ")
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal '%s'", configFile)
+	}
+	if _, err := writefs.WriteFile(idx.fsys, configFile, config); err != nil {
+		return errors.Wrapf(err, "cannot write '%s'", configFile)
+	}
+	return nil
+}
+
+// Lookup returns the content files with the digest, which are not in the object folder exclude
+func (idx *Index) Lookup(alg, digest, exclude string) []*Entry {
+	var result = []*Entry{}
+	for _, e := range idx.Digests[key(alg, digest)] {
+		if e.Folder != exclude {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (idx *Index) removeFolder(folder string) {
+	for k, entries := range idx.Digests {
+		var result = []*Entry{}
+		for _, e := range entries {
+			if e.Folder != folder {
+				result = append(result, e)
+			}
+		}
+		if len(result) == 0 {
+			delete(idx.Digests, k)
+		} else {
+			idx.Digests[k] = result
+		}
+	}
+}
+
+func (idx *Index) readInventory(folder string) (*inventory, error) {
+	name := path.Join(folder, "inventory.json")
+	data, err := fs.ReadFile(idx.fsys, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read '%s'", name)
+	}
+	var inv = &inventory{}
+	if err := json.Unmarshal(data, inv); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal '%s'", name)
+	}
+	return inv, nil
+}
+
+// AddObject (re-)reads the inventory of the object in folder and replaces its entries
+func (idx *Index) AddObject(folder string) error {
+	sidecar, err := idx.readSidecar(folder)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	inv, err := idx.readInventory(folder)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	idx.removeFolder(folder)
+	var entries = map[string]*Entry{}
+	entry := func(p string) *Entry {
+		if e, ok := entries[p]; ok {
+			return e
+		}
+		var size int64 = -1
+		if fi, err := fs.Stat(idx.fsys, path.Join(folder, p)); err == nil {
+			size = fi.Size()
+		} else {
+			idx.logger.Warn().Err(err).Msgf("cannot stat '%s/%s'", folder, p)
+		}
+		e := &Entry{
+			Object: inv.ID,
+			Folder: folder,
+			Path:   p,
+			Size:   size,
+		}
+		entries[p] = e
+		return e
+	}
+	for digest, paths := range inv.Manifest {
+		k := key(inv.DigestAlgorithm, digest)
+		for _, p := range paths {
+			idx.Digests[k] = append(idx.Digests[k], entry(p))
+		}
+	}
+	for alg, digests := range inv.Fixity {
+		if alg == inv.DigestAlgorithm {
+			continue
+		}
+		for digest, paths := range digests {
+			k := key(alg, digest)
+			for _, p := range paths {
+				// fixity may contain paths which are not in the manifest
+				if _, ok := entries[p]; ok {
+					idx.Digests[k] = append(idx.Digests[k], entry(p))
+				}
+			}
+		}
+	}
+	idx.Objects[folder] = sidecar
+	return nil
+}
+
+// Duplicate is a content, which is stored in more than one object. Digest is the first
+// of the digests of the content in the index
+type Duplicate struct {
+	Digest       string   `json:"digest"`
+	Digests      []string `json:"digests"`
+	Size         int64    `json:"size"`
+	Objects      []string `json:"objects"`
+	Files        []*Entry `json:"files"`
+	SavableBytes int64    `json:"savableBytes"`
+}
+
+type Report struct {
+	Objects      int          `json:"objects"`
+	Digests      int          `json:"digests"`
+	TotalBytes   int64        `json:"totalBytes"`
+	SavableBytes int64        `json:"savableBytes"`
+	Duplicates   []*Duplicate `json:"duplicates"`
+}
+
+// content is a group of digests of different algorithms, which share at least one file
+type content struct {
+	digests []string
+	files   []*Entry
+}
+
+// contents groups the digests of the index by content. digests of different algorithms
+// belong to the same content, if they are digests of the same file
+func (idx *Index) contents() []*content {
+	var parent = map[string]string{}
+	var find func(k string) string
+	find = func(k string) string {
+		if parent[k] == k {
+			return k
+		}
+		parent[k] = find(parent[k])
+		return parent[k]
+	}
+	var fileDigest = map[string]string{}
+	for k, entries := range idx.Digests {
+		if _, ok := parent[k]; !ok {
+			parent[k] = k
+		}
+		for _, e := range entries {
+			f := e.Folder + "/" + e.Path
+			if other, ok := fileDigest[f]; ok {
+				parent[find(k)] = find(other)
+			} else {
+				fileDigest[f] = k
+			}
+		}
+	}
+	var groups = map[string]*content{}
+	var files = map[string]map[string]bool{}
+	for k, entries := range idx.Digests {
+		root := find(k)
+		c, ok := groups[root]
+		if !ok {
+			c = &content{}
+			groups[root] = c
+			files[root] = map[string]bool{}
+		}
+		c.digests = append(c.digests, k)
+		for _, e := range entries {
+			f := e.Folder + "/" + e.Path
+			if !files[root][f] {
+				files[root][f] = true
+				c.files = append(c.files, e)
+			}
+		}
+	}
+	var result = []*content{}
+	for _, c := range groups {
+		sort.Strings(c.digests)
+		result = append(result, c)
+	}
+	return result
+}
+
+func (idx *Index) duplicate(c *content) *Duplicate {
+	var objects = map[string]bool{}
+	var size int64 = -1
+	for _, e := range c.files {
+		objects[e.Folder] = true
+		if e.Size >= 0 {
+			size = e.Size
+		}
+	}
+	if len(objects) < 2 {
+		return nil
+	}
+	dup := &Duplicate{
+		Digest:  c.digests[0],
+		Digests: c.digests,
+		Size:    size,
+		Objects: []string{},
+		Files:   c.files,
+	}
+	var ids = map[string]bool{}
+	for _, e := range c.files {
+		if !ids[e.Object] {
+			ids[e.Object] = true
+			dup.Objects = append(dup.Objects, e.Object)
+		}
+	}
+	sort.Strings(dup.Objects)
+	if size > 0 {
+		dup.SavableBytes = size * int64(len(objects)-1)
+	}
+	return dup
+}
+
+// Report lists all contents which are stored in more than one object
+func (idx *Index) Report() *Report {
+	contents := idx.contents()
+	report := &Report{
+		Duplicates: []*Duplicate{},
+		Digests:    len(contents),
+	}
+	var objects = map[string]bool{}
+	for _, c := range contents {
+		for _, e := range c.files {
+			objects[e.Folder] = true
+			if e.Size > 0 {
+				report.TotalBytes += e.Size
+			}
+		}
+		if dup := idx.duplicate(c); dup != nil {
+			report.Duplicates = append(report.Duplicates, dup)
+			report.SavableBytes += dup.SavableBytes
+		}
+	}
+	report.Objects = len(objects)
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		if report.Duplicates[i].SavableBytes != report.Duplicates[j].SavableBytes {
+			return report.Duplicates[i].SavableBytes > report.Duplicates[j].SavableBytes
+		}
+		return report.Duplicates[i].Digest < report.Duplicates[j].Digest
+	})
+	return report
+}
+
+// ObjectReport lists all contents of the object in folder which are also stored in other objects
+func (idx *Index) ObjectReport(folder string) *Report {
+	report := &Report{
+		Duplicates: []*Duplicate{},
+		Objects:    1,
+	}
+	for _, c := range idx.contents() {
+		var found bool
+		for _, e := range c.files {
+			if e.Folder == folder {
+				found = true
+				if e.Size > 0 {
+					report.TotalBytes += e.Size
+				}
+			}
+		}
+		if !found {
+			continue
+		}
+		report.Digests++
+		if dup := idx.duplicate(c); dup != nil {
+			report.Duplicates = append(report.Duplicates, dup)
+			if dup.Size > 0 {
+				report.SavableBytes += dup.Size
+			}
+		}
+	}
+	sort.Slice(report.Duplicates, func(i, j int) bool {
+		return report.Duplicates[i].Digest < report.Duplicates[j].Digest
+	})
+	return report
+}
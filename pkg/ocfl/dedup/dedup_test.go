@@ -0,0 +1,162 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/rs/zerolog"
+)
+
+// addTestObject writes inventory, sidecar and content files of an object in folder
+func addTestObject(t *testing.T, fsys fstest.MapFS, folder, id, alg string, files map[string]string, manifest map[string][]string, fixity map[string]map[string][]string) {
+	data, err := json.Marshal(&inventory{ID: id, DigestAlgorithm: alg, Manifest: manifest, Fixity: fixity})
+	if err != nil {
+		t.Fatalf("cannot marshal inventory: %v", err)
+	}
+	fsys[folder+"/inventory.json"] = &fstest.MapFile{Data: data}
+	fsys[folder+"/inventory.json."+alg] = &fstest.MapFile{Data: []byte(fmt.Sprintf("%x inventory.json", sha512.Sum512(data)))}
+	for name, content := range files {
+		fsys[folder+"/"+name] = &fstest.MapFile{Data: []byte(content)}
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+	logger := zerolog.Nop()
+	fsys := fstest.MapFS{}
+	addTestObject(t, fsys, "a", "id:a", "sha512", map[string]string{"v1/content/x": "x"},
+		map[string][]string{"x512": {"v1/content/x"}}, nil)
+	idx := NewIndex(fsys, &logger)
+	if changed, err := idx.Update([]string{"a"}); err != nil || !changed {
+		t.Fatalf("first update: changed %v, %v", changed, err)
+	}
+	// object b was written while the index was not used
+	addTestObject(t, fsys, "b", "id:b", "sha512", map[string]string{"v1/content/y": "x"},
+		map[string][]string{"x512": {"v1/content/y"}}, nil)
+	if changed, err := idx.Update([]string{"a", "b"}); err != nil || !changed {
+		t.Fatalf("update with new object: changed %v, %v", changed, err)
+	}
+	if changed, err := idx.Update([]string{"a", "b"}); err != nil || changed {
+		t.Errorf("update without changes: changed %v, %v", changed, err)
+	}
+	if report := idx.Report(); len(report.Duplicates) != 1 || len(report.Duplicates[0].Objects) != 2 {
+		t.Fatalf("expected one duplicate in two objects, got %+v", report.Duplicates)
+	}
+
+	// new version of b without the duplicate
+	addTestObject(t, fsys, "b", "id:b", "sha512", map[string]string{"v2/content/z": "z"},
+		map[string][]string{"z512": {"v2/content/z"}, "extra": {"v2/content/extra"}}, nil)
+	if changed, err := idx.Update([]string{"a", "b"}); err != nil || !changed {
+		t.Fatalf("update with changed object: changed %v, %v", changed, err)
+	}
+	if report := idx.Report(); len(report.Duplicates) != 0 {
+		t.Errorf("duplicate of old version reported: %+v", report.Duplicates)
+	}
+
+	// a removed
+	if changed, err := idx.Update([]string{"b"}); err != nil || !changed {
+		t.Fatalf("update with removed object: changed %v, %v", changed, err)
+	}
+	if _, ok := idx.Objects["a"]; ok {
+		t.Errorf("removed object still indexed")
+	}
+	if _, ok := idx.Digests["sha512:x512"]; ok {
+		t.Errorf("content of removed object still indexed")
+	}
+}
+
+func TestIndexDigestAlgorithms(t *testing.T) {
+	logger := zerolog.Nop()
+	fsys := fstest.MapFS{}
+	// sha512 object with sha256 fixity
+	addTestObject(t, fsys, "a", "id:a", "sha512", map[string]string{"v1/content/x": "xxxx", "v1/content/y": "yy"},
+		map[string][]string{"x512": {"v1/content/x"}, "y512": {"v1/content/y"}},
+		map[string]map[string][]string{
+			"sha256": {"x256": {"v1/content/x"}, "y256": {"v1/content/y"}},
+			"md5":    {"gone": {"v0/content/gone"}},
+		})
+	// sha256 object
+	addTestObject(t, fsys, "b", "id:b", "sha256", map[string]string{"v1/content/x": "xxxx"},
+		map[string][]string{"x256": {"v1/content/x"}}, nil)
+	// sha512 object without fixity
+	addTestObject(t, fsys, "c", "id:c", "sha512", map[string]string{"v1/content/x": "xxxx", "v1/content/y": "yy"},
+		map[string][]string{"x512": {"v1/content/x"}, "y512": {"v1/content/y"}}, nil)
+
+	idx, err := BuildIndex(fsys, []string{"a", "b", "c"}, &logger)
+	if err != nil {
+		t.Fatalf("cannot build index: %v", err)
+	}
+	if _, ok := idx.Digests["md5:gone"]; ok {
+		t.Errorf("fixity of file without manifest entry indexed")
+	}
+	report := idx.Report()
+	if report.Objects != 3 || report.Digests != 2 {
+		t.Errorf("expected 3 objects with 2 contents, got %d objects, %d contents", report.Objects, report.Digests)
+	}
+	if len(report.Duplicates) != 2 {
+		t.Fatalf("expected 2 duplicates, got %+v", report.Duplicates)
+	}
+	x := report.Duplicates[0]
+	if x.Digest != "sha256:x256" || len(x.Digests) != 2 || x.Digests[1] != "sha512:x512" {
+		t.Errorf("unexpected digests of x: %v", x.Digests)
+	}
+	if len(x.Objects) != 3 || len(x.Files) != 3 {
+		t.Errorf("x: expected 3 objects and files, got %v, %d files", x.Objects, len(x.Files))
+	}
+	if x.SavableBytes != 8 {
+		t.Errorf("x: expected 8 savable bytes, got %d", x.SavableBytes)
+	}
+	y := report.Duplicates[1]
+	if len(y.Objects) != 2 || y.Objects[0] != "id:a" || y.Objects[1] != "id:c" {
+		t.Errorf("y: unexpected objects %v", y.Objects)
+	}
+	if report.TotalBytes != 4*3+2*2 {
+		t.Errorf("unexpected total bytes %d", report.TotalBytes)
+	}
+
+	objReport := idx.ObjectReport("b")
+	if objReport.Digests != 1 || len(objReport.Duplicates) != 1 || objReport.SavableBytes != 4 {
+		t.Errorf("unexpected object report %+v", objReport)
+	}
+}
+
+func TestChecker(t *testing.T) {
+	logger := zerolog.Nop()
+	fsys := fstest.MapFS{}
+	addTestObject(t, fsys, "a", "id:a", "sha512", map[string]string{"v1/content/x": "x"},
+		map[string][]string{"x512": {"v1/content/x"}},
+		map[string]map[string][]string{"sha256": {"x256": {"v1/content/x"}}})
+	idx, err := BuildIndex(fsys, []string{"a"}, &logger)
+	if err != nil {
+		t.Fatalf("cannot build index: %v", err)
+	}
+	checker := NewChecker(idx, "b", &logger)
+	for _, test := range []struct {
+		digests map[checksum.DigestAlgorithm]string
+		objects []string
+	}{
+		{map[checksum.DigestAlgorithm]string{checksum.DigestSHA512: "X512"}, []string{"id:a"}},
+		{map[checksum.DigestAlgorithm]string{checksum.DigestSHA512: "y512", checksum.DigestSHA256: "x256"}, []string{"id:a"}},
+		{map[checksum.DigestAlgorithm]string{checksum.DigestSHA512: "y512"}, nil},
+	} {
+		if objects := checker.Check(test.digests, "v1/content/y"); !slices.Equal(objects, test.objects) {
+			t.Errorf("%v: expected %v, got %v", test.digests, test.objects, objects)
+		}
+	}
+	// content of the object itself is no cross object duplicate
+	if objects := NewChecker(idx, "a", &logger).Check(map[checksum.DigestAlgorithm]string{checksum.DigestSHA512: "x512"}, "v2/content/x"); objects != nil {
+		t.Errorf("content of own object reported: %v", objects)
+	}
+	var none *Checker
+	if objects := none.Check(map[checksum.DigestAlgorithm]string{checksum.DigestSHA512: "x512"}, "v1/content/y"); objects != nil {
+		t.Errorf("nil checker reported %v", objects)
+	}
+	if FromContext(context.Background()) != nil || FromContext(NewContext(context.Background(), checker)) != checker {
+		t.Errorf("checker not carried by context")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package link
+
+import (
+	"os"
+	"syscall"
+)
+
+func sameDevice(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	sa, ok := fa.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	sb, ok := fb.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return sa.Dev == sb.Dev
+}
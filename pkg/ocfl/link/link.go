@@ -0,0 +1,135 @@
+package link
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"emperror.dev/errors"
+)
+
+// Mode defines how content files are put into the version folder
+type Mode string
+
+const (
+	// ModeCopy copies every byte (default)
+	ModeCopy Mode = "copy"
+	// ModeHardlink creates a hard link to the source file. source and object share the same data,
+	// changes to the source file after ingest will modify the object
+	ModeHardlink Mode = "hardlink"
+	// ModeReflink clones the source file (copy on write) on filesystems which support it (XFS, Btrfs)
+	ModeReflink Mode = "reflink"
+)
+
+// ErrNotSupported is returned if the link cannot be created and the content has to be copied
+var ErrNotSupported = errors.New("link not supported")
+
+func ParseMode(str string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(str))); m {
+	case "", ModeCopy:
+		return ModeCopy, nil
+	case ModeHardlink, ModeReflink:
+		return m, nil
+	default:
+		return "", errors.Errorf("invalid link mode '%s' - must be one of copy, hardlink or reflink", str)
+	}
+}
+
+// fallbackErrors are errors where a copy is possible instead of the link
+var fallbackErrors = []error{
+	syscall.EXDEV,
+	syscall.EPERM,
+	syscall.EINVAL,
+	syscall.ENOTSUP,
+	syscall.EOPNOTSUPP,
+	syscall.ENOTTY,
+	syscall.ENOSYS,
+	syscall.EMLINK,
+}
+
+func isFallback(err error) bool {
+	for _, e := range fallbackErrors {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Link creates dst from src without copying data. The parent folders of dst are created.
+// If the filesystem cannot link the files, an error wrapping ErrNotSupported is returned
+// and dst does not exist.
+func Link(mode Mode, src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return errors.Wrapf(err, "cannot create folder for '%s'", dst)
+	}
+	var err error
+	switch mode {
+	case ModeHardlink:
+		err = os.Link(src, dst)
+	case ModeReflink:
+		err = reflink(src, dst)
+	default:
+		return errors.Wrapf(ErrNotSupported, "link mode '%s'", mode)
+	}
+	if err == nil {
+		return nil
+	}
+	if isFallback(err) || errors.Is(err, ErrNotSupported) {
+		return errors.Wrapf(ErrNotSupported, "cannot %s '%s' -> '%s': %v", mode, src, dst, err)
+	}
+	return errors.Wrapf(err, "cannot %s '%s' -> '%s'", mode, src, dst)
+}
+
+// Linker holds the local folders of an ingest. Linking is only possible if
+// source and object are folders in the local filesystem
+type Linker struct {
+	Mode Mode
+	// Sources maps the area to the local source folder
+	Sources map[string]string
+	// Root is the local folder of the storage root
+	Root string
+	// Dest is the local folder of the object
+	Dest string
+}
+
+// ForObject returns a copy of the linker with the destination set to the object folder below Root
+func (l *Linker) ForObject(folder string) *Linker {
+	return &Linker{
+		Mode:    l.Mode,
+		Sources: l.Sources,
+		Root:    l.Root,
+		Dest:    filepath.Join(l.Root, filepath.FromSlash(folder)),
+	}
+}
+
+// Paths returns the local filenames of a source file and its manifest path in the object.
+// ok is false if the area has no local source folder
+func (l *Linker) Paths(area, path, manifestPath string) (src, dst string, ok bool) {
+	if l == nil || l.Mode == ModeCopy || l.Mode == "" || l.Dest == "" {
+		return "", "", false
+	}
+	srcFolder, ok := l.Sources[area]
+	if !ok || srcFolder == "" {
+		return "", "", false
+	}
+	return filepath.Join(srcFolder, filepath.FromSlash(path)), filepath.Join(l.Dest, filepath.FromSlash(manifestPath)), true
+}
+
+type contextKey struct{}
+
+// NewContext returns a context which carries the linker
+func NewContext(ctx context.Context, linker *Linker) context.Context {
+	return context.WithValue(ctx, contextKey{}, linker)
+}
+
+// FromContext returns the linker of the context or nil
+func FromContext(ctx context.Context) *Linker {
+	if ctx == nil {
+		return nil
+	}
+	linker, _ := ctx.Value(contextKey{}).(*Linker)
+	return linker
+}
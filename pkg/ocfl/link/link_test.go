@@ -0,0 +1,173 @@
+package link
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"emperror.dev/errors"
+)
+
+var testContent = []byte("Lorem ipsum dolor sit amet, consetetur sadipscing elitr")
+
+func writeSource(t *testing.T, dir string) string {
+	src := filepath.Join(dir, "source", "file.txt")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatalf("cannot create source folder: %v", err)
+	}
+	if err := os.WriteFile(src, testContent, 0644); err != nil {
+		t.Fatalf("cannot write source file: %v", err)
+	}
+	return src
+}
+
+func checkContent(t *testing.T, dst string) {
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("cannot read '%s': %v", dst, err)
+	}
+	if !bytes.Equal(data, testContent) {
+		t.Errorf("content of '%s' does not match source", dst)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for str, mode := range map[string]Mode{"": ModeCopy, "copy": ModeCopy, "HardLink": ModeHardlink, " reflink ": ModeReflink} {
+		m, err := ParseMode(str)
+		if err != nil {
+			t.Errorf("ParseMode(%q): %v", str, err)
+			continue
+		}
+		if m != mode {
+			t.Errorf("ParseMode(%q) = %s, expected %s", str, m, mode)
+		}
+	}
+	if _, err := ParseMode("symlink"); err == nil {
+		t.Errorf("ParseMode(\"symlink\") should fail")
+	}
+}
+
+func TestHardlink(t *testing.T) {
+	dir := t.TempDir()
+	src := writeSource(t, dir)
+	dst := filepath.Join(dir, "object", "v1", "content", "file.txt")
+	if err := Link(ModeHardlink, src, dst); err != nil {
+		t.Fatalf("cannot hardlink: %v", err)
+	}
+	checkContent(t, dst)
+	fs, _ := os.Stat(src)
+	fd, _ := os.Stat(dst)
+	if !os.SameFile(fs, fd) {
+		t.Errorf("'%s' is not a hardlink of '%s'", dst, src)
+	}
+}
+
+// TestHardlinkCrossDevice links from tmpfs to the test folder, which fails with EXDEV
+func TestHardlinkCrossDevice(t *testing.T) {
+	shm := "/dev/shm"
+	if fi, err := os.Stat(shm); err != nil || !fi.IsDir() {
+		t.Skipf("no tmpfs at %s", shm)
+	}
+	srcDir, err := os.MkdirTemp(shm, "gocfl-link-")
+	if err != nil {
+		t.Skipf("cannot create folder in %s: %v", shm, err)
+	}
+	defer os.RemoveAll(srcDir)
+	dir := t.TempDir()
+	if sameDevice(srcDir, dir) {
+		t.Skipf("%s and %s are on the same device", srcDir, dir)
+	}
+	src := writeSource(t, srcDir)
+	dst := filepath.Join(dir, "object", "v1", "content", "file.txt")
+
+	err = Link(ModeHardlink, src, dst)
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("cross device hardlink should return ErrNotSupported: %v", err)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		t.Errorf("'%s' should not exist after failed link", dst)
+	}
+}
+
+// TestReflink uses the folder in GOCFL_REFLINK_TESTDIR (i.e. a loopback XFS or Btrfs mount) if set.
+// On other filesystems ErrNotSupported is expected
+func TestReflink(t *testing.T) {
+	dir := os.Getenv("GOCFL_REFLINK_TESTDIR")
+	if dir == "" {
+		dir = t.TempDir()
+	} else {
+		var err error
+		dir, err = os.MkdirTemp(dir, "gocfl-link-")
+		if err != nil {
+			t.Fatalf("cannot create folder in GOCFL_REFLINK_TESTDIR: %v", err)
+		}
+		defer os.RemoveAll(dir)
+	}
+	src := writeSource(t, dir)
+	dst := filepath.Join(dir, "object", "v1", "content", "file.txt")
+
+	if err := Link(ModeReflink, src, dst); err != nil {
+		if !errors.Is(err, ErrNotSupported) {
+			t.Fatalf("cannot reflink: %v", err)
+		}
+		if os.Getenv("GOCFL_REFLINK_TESTDIR") != "" {
+			t.Fatalf("expected reflink in GOCFL_REFLINK_TESTDIR: %v", err)
+		}
+		if _, err := os.Stat(dst); err == nil {
+			t.Errorf("'%s' should not exist after failed reflink", dst)
+		}
+		t.Skipf("no reflink support in '%s': %v", dir, err)
+	}
+	checkContent(t, dst)
+	t.Logf("reflink in '%s'", dir)
+
+	// a clone is a separate file, changes must not affect the source
+	if err := os.WriteFile(dst, []byte("changed"), 0644); err != nil {
+		t.Fatalf("cannot write '%s': %v", dst, err)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("cannot read '%s': %v", src, err)
+	}
+	if !bytes.Equal(data, testContent) {
+		t.Errorf("source changed after writing to reflink")
+	}
+}
+
+func TestCopyMode(t *testing.T) {
+	dir := t.TempDir()
+	src := writeSource(t, dir)
+	dst := filepath.Join(dir, "object", "v1", "content", "file.txt")
+	if err := Link(ModeCopy, src, dst); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Link(ModeCopy) should return ErrNotSupported: %v", err)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		t.Errorf("'%s' should not exist with copy mode", dst)
+	}
+}
+
+func TestLinkerPaths(t *testing.T) {
+	l := (&Linker{
+		Mode:    ModeHardlink,
+		Sources: map[string]string{"content": "/data/staging"},
+		Root:    "/data/ocfl",
+	}).ForObject("object")
+	src, dst, ok := l.Paths("content", "dir/file.txt", "v1/content/dir/file.txt")
+	if !ok {
+		t.Fatalf("no paths for area content")
+	}
+	if src != filepath.FromSlash("/data/staging/dir/file.txt") {
+		t.Errorf("wrong source path %s", src)
+	}
+	if dst != filepath.FromSlash("/data/ocfl/object/v1/content/dir/file.txt") {
+		t.Errorf("wrong destination path %s", dst)
+	}
+	if _, _, ok := l.Paths("metadata", "file.txt", "v1/content/metadata/file.txt"); ok {
+		t.Errorf("area without local source should not be linked")
+	}
+	var nilLinker *Linker
+	if _, _, ok := nilLinker.Paths("content", "file.txt", "v1/content/file.txt"); ok {
+		t.Errorf("nil linker should not link")
+	}
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package link
+
+import (
+	"os"
+
+	"emperror.dev/errors"
+	"golang.org/x/sys/unix"
+)
+
+// reflink clones src to dst with the FICLONE ioctl
+func reflink(src, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open '%s'", src)
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "cannot create '%s'", dst)
+	}
+	if err := unix.IoctlFileClone(int(w.Fd()), int(r.Fd())); err != nil {
+		w.Close()
+		os.Remove(dst)
+		return errors.WithStack(err)
+	}
+	return errors.Wrapf(w.Close(), "cannot close '%s'", dst)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package link
+
+import (
+	"emperror.dev/errors"
+)
+
+func reflink(src, dst string) error {
+	return errors.WithStack(ErrNotSupported)
+}
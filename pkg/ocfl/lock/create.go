@@ -0,0 +1,52 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/minio/minio-go/v7"
+)
+
+// NewOSCreate returns an atomic create of lock files in the local storage root folder dir
+func NewOSCreate(dir string) CreateFunc {
+	return func(name string, data []byte) error {
+		fullpath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+			return errors.Wrapf(err, "cannot create folder for '%s'", fullpath)
+		}
+		fp, err := os.OpenFile(fullpath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return errors.Wrapf(err, "cannot create '%s'", fullpath)
+		}
+		if _, err := fp.Write(data); err != nil {
+			fp.Close()
+			_ = os.Remove(fullpath)
+			return errors.Wrapf(err, "cannot write '%s'", fullpath)
+		}
+		return errors.Wrapf(fp.Close(), "cannot close '%s'", fullpath)
+	}
+}
+
+// NewS3Create returns an atomic create of lock files for a storage root in bucket below prefix.
+// It uses a conditional put (If-None-Match: *)
+func NewS3Create(client *minio.Client, bucket, prefix string) CreateFunc {
+	return func(name string, data []byte) error {
+		key := strings.TrimLeft(path.Join(prefix, name), "/")
+		opts := minio.PutObjectOptions{ContentType: "application/json"}
+		opts.SetMatchETagExcept("*")
+		if _, err := client.PutObject(context.Background(), bucket, key, bytes.NewReader(data), int64(len(data)), opts); err != nil {
+			if resp := minio.ToErrorResponse(err); resp.StatusCode == http.StatusPreconditionFailed || resp.Code == "PreconditionFailed" {
+				return errors.Wrapf(fs.ErrExist, "'%s/%s'", bucket, key)
+			}
+			return errors.Wrapf(err, "cannot put '%s/%s'", bucket, key)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,372 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+)
+
+// ExtensionName is the storage root extension, which contains the object lock files
+const ExtensionName = "NNNN-object-lock"
+
+// Folder is the storage root folder which contains the object lock files.
+// OCFL allows no other folders than objects and extensions in the storage root
+const Folder = "extensions/" + ExtensionName
+
+const configFile = Folder + "/config.json"
+
+const DefaultExpiry = time.Hour
+
+var ErrLocked = errors.New("object locked")
+
+// ErrLost is returned by Renew and Unlock, if the lock has been taken over or removed
+var ErrLost = errors.New("object lock lost")
+
+// CreateFunc writes the file only, if it does not exist. If it exists, the returned
+// error matches fs.ErrExist
+type CreateFunc func(name string, data []byte) error
+
+type Lock struct {
+	ID      string    `json:"id"`
+	Owner   string    `json:"owner"`
+	Host    string    `json:"host"`
+	PID     int       `json:"pid"`
+	Token   string    `json:"token"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+func (l *Lock) IsStale() bool {
+	return time.Now().After(l.Expires)
+}
+
+func (l *Lock) String() string {
+	return fmt.Sprintf("'%s' locked by %s@%s (pid %d) since %s, expires %s", l.ID, l.Owner, l.Host, l.PID, l.Created.Format(time.RFC3339), l.Expires.Format(time.RFC3339))
+}
+
+// Locker manages lock files for objects within one storage root.
+// Lock files are created with an atomic create (see SetCreate). Without it, writefs has
+// no conditional put, so after writing a lock file it is read back and the token is
+// compared. This detects the loser of a race on local disk and on s3 with read-after-write
+// consistency, but leaves a small window, where both writers see their own token.
+type Locker struct {
+	fsys   fs.FS
+	create CreateFunc
+	expiry time.Duration
+	owner  string
+	logger zLogger.ZLogger
+}
+
+func NewLocker(storageRootFS fs.FS, owner string, expiry time.Duration, logger zLogger.ZLogger) *Locker {
+	if expiry <= 0 {
+		expiry = DefaultExpiry
+	}
+	if owner == "" {
+		owner = "unknown"
+	}
+	return &Locker{
+		fsys:   storageRootFS,
+		expiry: expiry,
+		owner:  owner,
+		logger: logger,
+	}
+}
+
+// SetCreate sets the atomic create of the lock files (see NewOSCreate, NewS3Create)
+func (l *Locker) SetCreate(create CreateFunc) {
+	l.create = create
+}
+
+// Expiry returns the lifetime of a lock without renewal
+func (l *Locker) Expiry() time.Duration {
+	return l.expiry
+}
+
+func lockName(id string) string {
+	return Folder + "/" + url.PathEscape(id) + ".lock"
+}
+
+// writeConfig writes the config.json of the lock extension, so the folder is a valid extension
+func (l *Locker) writeConfig() error {
+	if _, err := fs.Stat(l.fsys, configFile); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return errors.Wrapf(err, "cannot stat '%s'", configFile)
+	}
+	data, err := json.MarshalIndent(map[string]string{"extensionName": ExtensionName}, "", "   ")
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal '%s'", configFile)
+	}
+	if _, err := writefs.WriteFile(l.fsys, configFile, data); err != nil {
+		return errors.Wrapf(err, "cannot write '%s'", configFile)
+	}
+	return nil
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "cannot create random token")
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Get returns the current lock of the object or nil if the object is not locked
+func (l *Locker) Get(id string) (*Lock, error) {
+	name := lockName(id)
+	data, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "cannot read lock file '%s'", name)
+	}
+	var lck = &Lock{}
+	if err := json.Unmarshal(data, lck); err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal lock file '%s'", name)
+	}
+	return lck, nil
+}
+
+// Lock acquires the lock for the object. stale locks are taken over.
+func (l *Locker) Lock(id string) (*Lock, error) {
+	current, err := l.Get(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if current != nil {
+		if !current.IsStale() {
+			return nil, errors.Wrapf(ErrLocked, "%s", current.String())
+		}
+		l.logger.Warn().Msgf("taking over stale lock %s", current.String())
+		if l.create != nil {
+			// the atomic create needs a free name. another writer may have been faster
+			if err := l.removeToken(id, current.Token); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+
+	if err := l.writeConfig(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	now := time.Now()
+	lck := &Lock{
+		ID:      id,
+		Owner:   l.owner,
+		Host:    hostname,
+		PID:     os.Getpid(),
+		Token:   token,
+		Created: now,
+		Expires: now.Add(l.expiry),
+	}
+	data, err := json.MarshalIndent(lck, "", "   ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot marshal lock for '%s'", id)
+	}
+	name := lockName(id)
+	if l.create != nil {
+		if err := l.create(name, data); err != nil {
+			if !errors.Is(err, fs.ErrExist) {
+				return nil, errors.Wrapf(err, "cannot create lock file '%s'", name)
+			}
+			if winner, _ := l.Get(id); winner != nil {
+				return nil, errors.Wrapf(ErrLocked, "%s", winner.String())
+			}
+			return nil, errors.Wrapf(ErrLocked, "lock file '%s' exists", name)
+		}
+		l.logger.Debug().Msgf("lock acquired: %s", lck.String())
+		return lck, nil
+	}
+	if _, err := writefs.WriteFile(l.fsys, name, data); err != nil {
+		return nil, errors.Wrapf(err, "cannot write lock file '%s'", name)
+	}
+
+	// read back to check, whether we won a concurrent race
+	check, err := l.Get(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if check == nil || check.Token != lck.Token {
+		if check != nil {
+			return nil, errors.Wrapf(ErrLocked, "%s", check.String())
+		}
+		return nil, errors.Wrapf(ErrLocked, "lock file '%s' vanished", name)
+	}
+	l.logger.Debug().Msgf("lock acquired: %s", lck.String())
+	return lck, nil
+}
+
+// Renew extends the expiry of a lock acquired by Lock
+func (l *Locker) Renew(lck *Lock) error {
+	current, err := l.Get(lck.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if current == nil {
+		return errors.Wrapf(ErrLost, "lock for '%s' removed", lck.ID)
+	}
+	if current.Token != lck.Token {
+		return errors.Wrapf(ErrLost, "lock for '%s' has been taken over: %s", lck.ID, current.String())
+	}
+	lck.Expires = time.Now().Add(l.expiry)
+	data, err := json.MarshalIndent(lck, "", "   ")
+	if err != nil {
+		return errors.Wrapf(err, "cannot marshal lock for '%s'", lck.ID)
+	}
+	name := lockName(lck.ID)
+	if _, err := writefs.WriteFile(l.fsys, name, data); err != nil {
+		return errors.Wrapf(err, "cannot write lock file '%s'", name)
+	}
+	l.logger.Debug().Msgf("lock renewed: %s", lck.String())
+	return nil
+}
+
+// KeepAlive renews the lock every third of the expiry until stop is called.
+// long running updates keep their lock this way
+func (l *Locker) KeepAlive(lck *Lock) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(l.expiry / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := l.Renew(lck); err != nil {
+					l.logger.Error().Err(err).Msgf("cannot renew lock of '%s'", lck.ID)
+					if errors.Is(err, ErrLost) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// Unlock releases a lock acquired by Lock
+func (l *Locker) Unlock(lck *Lock) error {
+	if lck == nil {
+		return nil
+	}
+	current, err := l.Get(lck.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if current == nil {
+		l.logger.Warn().Msgf("lock for '%s' already removed", lck.ID)
+		return nil
+	}
+	if current.Token != lck.Token {
+		return errors.Wrapf(ErrLost, "lock for '%s' has been taken over: %s", lck.ID, current.String())
+	}
+	return errors.WithStack(l.remove(lck.ID))
+}
+
+// removeToken removes the lock file, if it still contains the lock with token
+func (l *Locker) removeToken(id, token string) error {
+	current, err := l.Get(id)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if current == nil || current.Token != token {
+		return nil
+	}
+	return errors.WithStack(l.remove(id))
+}
+
+// ForceUnlock removes the lock of the object regardless of its owner
+func (l *Locker) ForceUnlock(id string) (*Lock, error) {
+	current, err := l.Get(id)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if current == nil {
+		return nil, nil
+	}
+	if err := l.remove(id); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return current, nil
+}
+
+func (l *Locker) remove(id string) error {
+	name := lockName(id)
+	if err := writefs.Remove(l.fsys, name); err != nil {
+		return errors.Wrapf(err, "cannot remove lock file '%s'", name)
+	}
+	return nil
+}
+
+// List returns all locks of the storage root
+func (l *Locker) List() ([]*Lock, error) {
+	entries, err := fs.ReadDir(l.fsys, Folder)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []*Lock{}, nil
+		}
+		return nil, errors.Wrapf(err, "cannot read lock folder '%s'", Folder)
+	}
+	var result = []*Lock{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".lock") {
+			continue
+		}
+		id, err := url.PathUnescape(strings.TrimSuffix(entry.Name(), ".lock"))
+		if err != nil {
+			l.logger.Warn().Err(err).Msgf("invalid lock file name '%s'", entry.Name())
+			continue
+		}
+		lck, err := l.Get(id)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if lck != nil {
+			result = append(result, lck)
+		}
+	}
+	return result, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a context which carries the locker. objects lock themselves with it
+// from Init or StartUpdate until Close
+func NewContext(ctx context.Context, locker *Locker) context.Context {
+	return context.WithValue(ctx, contextKey{}, locker)
+}
+
+// FromContext returns the locker of the context or nil
+func FromContext(ctx context.Context) *Locker {
+	if ctx == nil {
+		return nil
+	}
+	locker, _ := ctx.Value(contextKey{}).(*Locker)
+	return locker
+}
@@ -0,0 +1,182 @@
+package lock
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/osfsrw"
+	"github.com/rs/zerolog"
+)
+
+// newTestLocker returns a locker for a temporary storage root. with atomic, lock files are created exclusively
+func newTestLocker(t *testing.T, dir string, owner string, expiry time.Duration, atomic bool) *Locker {
+	logger := zerolog.Nop()
+	fsys, err := osfsrw.NewFS(dir, true, &logger)
+	if err != nil {
+		t.Fatalf("cannot create filesystem: %v", err)
+	}
+	locker := NewLocker(fsys, owner, expiry, &logger)
+	if atomic {
+		locker.SetCreate(NewOSCreate(dir))
+	}
+	return locker
+}
+
+func TestLock(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		dir := t.TempDir()
+		first := newTestLocker(t, dir, "first", time.Hour, atomic)
+		second := newTestLocker(t, dir, "second", time.Hour, atomic)
+
+		lck, err := first.Lock("id:1")
+		if err != nil {
+			t.Fatalf("atomic %v: cannot lock: %v", atomic, err)
+		}
+		if _, err := second.Lock("id:1"); !errors.Is(err, ErrLocked) {
+			t.Errorf("atomic %v: expected ErrLocked, got %v", atomic, err)
+		}
+		if _, err := second.Lock("id:2"); err != nil {
+			t.Errorf("atomic %v: cannot lock other object: %v", atomic, err)
+		}
+		locks, err := first.List()
+		if err != nil {
+			t.Fatalf("atomic %v: cannot list locks: %v", atomic, err)
+		}
+		if len(locks) != 2 {
+			t.Errorf("atomic %v: expected 2 locks, got %d", atomic, len(locks))
+		}
+		if err := first.Unlock(lck); err != nil {
+			t.Errorf("atomic %v: cannot unlock: %v", atomic, err)
+		}
+		if _, err := second.Lock("id:1"); err != nil {
+			t.Errorf("atomic %v: cannot lock released object: %v", atomic, err)
+		}
+	}
+}
+
+func TestOSCreate(t *testing.T) {
+	dir := t.TempDir()
+	create := NewOSCreate(dir)
+	if err := create(lockName("id:1"), []byte("first")); err != nil {
+		t.Fatalf("cannot create: %v", err)
+	}
+	if err := create(lockName("id:1"), []byte("second")); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected fs.ErrExist, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(lockName("id:1"))))
+	if err != nil {
+		t.Fatalf("cannot read lock file: %v", err)
+	}
+	if string(data) != "first" {
+		t.Errorf("lock file overwritten: '%s'", string(data))
+	}
+}
+
+func TestLockStale(t *testing.T) {
+	for _, atomic := range []bool{true, false} {
+		dir := t.TempDir()
+		first := newTestLocker(t, dir, "first", time.Millisecond, atomic)
+		second := newTestLocker(t, dir, "second", time.Hour, atomic)
+
+		lck, err := first.Lock("id:1")
+		if err != nil {
+			t.Fatalf("atomic %v: cannot lock: %v", atomic, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		taken, err := second.Lock("id:1")
+		if err != nil {
+			t.Fatalf("atomic %v: cannot take over stale lock: %v", atomic, err)
+		}
+		if taken.Owner != "second" {
+			t.Errorf("atomic %v: unexpected owner %s", atomic, taken.Owner)
+		}
+		if err := first.Renew(lck); !errors.Is(err, ErrLost) {
+			t.Errorf("atomic %v: renew of lost lock: expected ErrLost, got %v", atomic, err)
+		}
+		if err := first.Unlock(lck); !errors.Is(err, ErrLost) {
+			t.Errorf("atomic %v: unlock of lost lock: expected ErrLost, got %v", atomic, err)
+		}
+		if current, err := second.Get("id:1"); err != nil || current == nil || current.Token != taken.Token {
+			t.Errorf("atomic %v: lock of new owner removed: %v, %v", atomic, current, err)
+		}
+	}
+}
+
+func TestLockRenew(t *testing.T) {
+	dir := t.TempDir()
+	locker := newTestLocker(t, dir, "first", 30*time.Millisecond, true)
+	other := newTestLocker(t, dir, "other", time.Hour, true)
+
+	lck, err := locker.Lock("id:1")
+	if err != nil {
+		t.Fatalf("cannot lock: %v", err)
+	}
+	expires := lck.Expires
+	time.Sleep(5 * time.Millisecond)
+	if err := locker.Renew(lck); err != nil {
+		t.Fatalf("cannot renew: %v", err)
+	}
+	current, err := locker.Get("id:1")
+	if err != nil || current == nil {
+		t.Fatalf("cannot get lock: %v", err)
+	}
+	if !current.Expires.After(expires) {
+		t.Errorf("expiry not extended: %s <= %s", current.Expires, expires)
+	}
+
+	// the lock must survive several expiry periods while it is kept alive
+	stop := locker.KeepAlive(lck)
+	time.Sleep(100 * time.Millisecond)
+	if _, err := other.Lock("id:1"); err == nil {
+		t.Errorf("lock with keep alive taken over")
+	}
+	stop()
+	if current, err := locker.Get("id:1"); err != nil || current == nil || current.Token != lck.Token || current.IsStale() {
+		t.Errorf("lock not kept alive: %v, %v", current, err)
+	}
+	if err := locker.Unlock(lck); err != nil {
+		t.Errorf("cannot unlock: %v", err)
+	}
+	if err := locker.Renew(lck); !errors.Is(err, ErrLost) {
+		t.Errorf("renew of removed lock: expected ErrLost, got %v", err)
+	}
+}
+
+func TestLockExtensionConfig(t *testing.T) {
+	dir := t.TempDir()
+	locker := newTestLocker(t, dir, "first", time.Hour, true)
+	if _, err := locker.Lock("id:1"); err != nil {
+		t.Fatalf("cannot lock: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "extensions", ExtensionName, "config.json"))
+	if err != nil {
+		t.Fatalf("cannot read extension config: %v", err)
+	}
+	if !strings.Contains(string(data), `"extensionName": "`+ExtensionName+`"`) {
+		t.Errorf("invalid extension config: %s", string(data))
+	}
+	// config.json is not a lock
+	locks, err := locker.List()
+	if err != nil {
+		t.Fatalf("cannot list locks: %v", err)
+	}
+	if len(locks) != 1 {
+		t.Errorf("expected 1 lock, got %d", len(locks))
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Errorf("locker in empty context")
+	}
+	locker := newTestLocker(t, t.TempDir(), "first", time.Hour, false)
+	if FromContext(NewContext(context.Background(), locker)) != locker {
+		t.Errorf("locker not found in context")
+	}
+}
@@ -0,0 +1,210 @@
+package logical
+
+import (
+	"container/list"
+	"context"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/je4/filesystem/v3/pkg/writefs"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+)
+
+const objectCacheSize = 32
+
+// CachedObject is a loaded object with its metadata. Object and Metadata are not modified after loading
+// and can be used by concurrent requests
+type CachedObject struct {
+	Object   object.Object
+	Metadata *object.ObjectMetadata
+	folder   string
+	sidecar  string
+	// checked is guarded by the lock of the cache
+	checked time.Time
+	lock    sync.Mutex
+	trees   map[string]*Tree
+	values  map[string]any
+}
+
+// Tree returns the cached logical view of a version
+func (o *CachedObject) Tree(version, area string) (*Tree, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	key := area + "/" + version
+	if tree, ok := o.trees[key]; ok {
+		return tree, nil
+	}
+	tree, err := BuildTree(o.Object, version, area)
+	if err != nil {
+		return nil, err
+	}
+	o.trees[key] = tree
+	return tree, nil
+}
+
+// Value returns the value of key, which is created once for the loaded object.
+// it keeps data of the users of the cache (i.e. filesystems), which is derived from the object
+func (o *CachedObject) Value(key string, create func() (any, error)) (any, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if value, ok := o.values[key]; ok {
+		return value, nil
+	}
+	value, err := create()
+	if err != nil {
+		return nil, err
+	}
+	o.values[key] = value
+	return value, nil
+}
+
+// ObjectCache keeps the recently used objects of a storage root.
+// A cached object is reloaded if the sidecar of its root inventory has changed
+type ObjectCache struct {
+	storageRoot      storageroot.StorageRoot
+	extensionFactory *extension.ExtensionFactory
+	obfuscate        bool
+	size             int
+	refresh          time.Duration
+	logger           zLogger.ZLogger
+	lock             sync.Mutex
+	lru              *list.List
+	entries          map[string]*list.Element
+}
+
+func NewObjectCache(storageRoot storageroot.StorageRoot, extensionFactory *extension.ExtensionFactory, size int, refresh time.Duration, obfuscate bool, logger zLogger.ZLogger) *ObjectCache {
+	if size <= 0 {
+		size = objectCacheSize
+	}
+	return &ObjectCache{
+		storageRoot:      storageRoot,
+		extensionFactory: extensionFactory,
+		obfuscate:        obfuscate,
+		size:             size,
+		refresh:          refresh,
+		logger:           logger,
+		lru:              list.New(),
+		entries:          map[string]*list.Element{},
+	}
+}
+
+// Get returns the current state of the object. errors wrap fs.ErrNotExist if there is no object with this id
+func (oc *ObjectCache) Get(id string) (*CachedObject, error) {
+	if o := oc.lookup(id); o != nil {
+		return o, nil
+	}
+	o, err := oc.load(id)
+	if err != nil {
+		return nil, err
+	}
+	oc.add(id, o)
+	return o, nil
+}
+
+// add puts the object in front of the lru list and removes the least recently used objects
+func (oc *ObjectCache) add(id string, o *CachedObject) {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	if elem, ok := oc.entries[id]; ok {
+		oc.lru.Remove(elem)
+	}
+	oc.entries[id] = oc.lru.PushFront(o)
+	for oc.lru.Len() > oc.size {
+		oldest := oc.lru.Back()
+		oc.lru.Remove(oldest)
+		delete(oc.entries, oldest.Value.(*CachedObject).Object.GetID())
+	}
+}
+
+// lookup returns the cached object if its root inventory has not changed
+func (oc *ObjectCache) lookup(id string) *CachedObject {
+	oc.lock.Lock()
+	elem, ok := oc.entries[id]
+	if !ok {
+		oc.lock.Unlock()
+		return nil
+	}
+	oc.lru.MoveToFront(elem)
+	o := elem.Value.(*CachedObject)
+	fresh := time.Since(o.checked) < oc.refresh
+	oc.lock.Unlock()
+	if fresh {
+		return o
+	}
+	sidecar, err := oc.readSidecar(o.folder, o.Object)
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	if err != nil || sidecar != o.sidecar {
+		if err != nil {
+			oc.logger.Debug().Err(err).Msgf("reloading object %s", id)
+		}
+		if current, ok := oc.entries[id]; ok && current == elem {
+			oc.lru.Remove(elem)
+			delete(oc.entries, id)
+		}
+		return nil
+	}
+	o.checked = time.Now()
+	return o
+}
+
+// readSidecar reads the digest of the root inventory
+func (oc *ObjectCache) readSidecar(folder string, obj object.Object) (string, error) {
+	name := path.Join(folder, "inventory.json."+string(obj.GetInventory().GetDigestAlgorithm()))
+	data, err := fs.ReadFile(oc.storageRoot.GetFS(), name)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot read '%s'", name)
+	}
+	return string(data), nil
+}
+
+func (oc *ObjectCache) load(id string) (*CachedObject, error) {
+	exists, err := oc.storageRoot.ObjectExists(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot check for object %s", id)
+	}
+	if !exists {
+		return nil, errors.Wrapf(fs.ErrNotExist, "object %s not found", id)
+	}
+	folder, err := oc.storageRoot.IdToFolder(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get folder for object %s", id)
+	}
+	fsys, err := writefs.Sub(oc.storageRoot.GetFS(), folder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot create subfs for %v / %s", oc.storageRoot.GetFS(), folder)
+	}
+	obj, err := object.LoadObject(context.Background(), fsys, oc.extensionFactory, oc.logger)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot load object %s", id)
+	}
+	// read the sidecar after loading. a version written in between leads to a reload on the next request
+	sidecar, err := oc.readSidecar(folder, obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	metadata, err := obj.GetMetadata()
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get metadata for object %s", id)
+	}
+	if oc.obfuscate {
+		if err := metadata.Obfuscate(); err != nil {
+			return nil, errors.Wrapf(err, "cannot obfuscate metadata of object %s", id)
+		}
+	}
+	return &CachedObject{
+		Object:   obj,
+		Metadata: metadata,
+		folder:   folder,
+		sidecar:  sidecar,
+		checked:  time.Now(),
+		trees:    map[string]*Tree{},
+		values:   map[string]any{},
+	}, nil
+}
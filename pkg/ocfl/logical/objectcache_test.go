@@ -0,0 +1,129 @@
+package logical
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/inventory"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/storageroot"
+	"github.com/rs/zerolog"
+)
+
+type testStorageRoot struct {
+	storageroot.StorageRoot
+	fsys fstest.MapFS
+}
+
+func newTestStorageRoot() *testStorageRoot {
+	return &testStorageRoot{fsys: fstest.MapFS{}}
+}
+
+func (sr *testStorageRoot) GetFS() fs.FS { return sr.fsys }
+
+type testInventory struct {
+	inventory.Inventory
+}
+
+func (i *testInventory) GetDigestAlgorithm() checksum.DigestAlgorithm { return checksum.DigestSHA512 }
+
+type testObject struct {
+	object.Object
+	id string
+}
+
+func (o *testObject) GetID() string                     { return o.id }
+func (o *testObject) GetInventory() inventory.Inventory { return &testInventory{} }
+
+// cacheObject puts an object with the current sidecar of the storage root into the cache
+func cacheObject(t *testing.T, oc *ObjectCache, sr *testStorageRoot, id string) *CachedObject {
+	sr.fsys[id+"/inventory.json.sha512"] = &fstest.MapFile{Data: []byte("1111 inventory.json")}
+	o := &CachedObject{
+		Object:  &testObject{id: id},
+		folder:  id,
+		sidecar: "1111 inventory.json",
+		checked: time.Now(),
+		trees:   map[string]*Tree{},
+		values:  map[string]any{},
+	}
+	oc.add(id, o)
+	return o
+}
+
+func TestObjectCacheLRU(t *testing.T) {
+	logger := zerolog.Nop()
+	sr := newTestStorageRoot()
+	oc := NewObjectCache(sr, nil, 2, time.Hour, false, &logger)
+
+	o1 := cacheObject(t, oc, sr, "id:1")
+	cacheObject(t, oc, sr, "id:2")
+	// id:1 is now the most recently used object
+	if o := oc.lookup("id:1"); o != o1 {
+		t.Fatalf("id:1 not cached")
+	}
+	cacheObject(t, oc, sr, "id:3")
+	if o := oc.lookup("id:2"); o != nil {
+		t.Errorf("least recently used object id:2 not evicted")
+	}
+	for _, id := range []string{"id:1", "id:3"} {
+		if o := oc.lookup(id); o == nil {
+			t.Errorf("%s not cached", id)
+		}
+	}
+	if oc.lru.Len() != len(oc.entries) || len(oc.entries) != 2 {
+		t.Errorf("cache size %d/%d, expected 2", oc.lru.Len(), len(oc.entries))
+	}
+}
+
+func TestObjectCacheRefresh(t *testing.T) {
+	logger := zerolog.Nop()
+	sr := newTestStorageRoot()
+	oc := NewObjectCache(sr, nil, 2, 0, false, &logger)
+
+	o1 := cacheObject(t, oc, sr, "id:1")
+	if o := oc.lookup("id:1"); o != o1 {
+		t.Fatalf("unchanged object not returned from cache")
+	}
+
+	// a new version changes the root inventory and its sidecar
+	sr.fsys["id:1/inventory.json.sha512"] = &fstest.MapFile{Data: []byte("2222 inventory.json")}
+	if o := oc.lookup("id:1"); o != nil {
+		t.Errorf("changed object returned from cache")
+	}
+	if _, ok := oc.entries["id:1"]; ok {
+		t.Errorf("changed object not removed from cache")
+	}
+
+	// a deleted object is removed from the cache
+	cacheObject(t, oc, sr, "id:2")
+	delete(sr.fsys, "id:2/inventory.json.sha512")
+	if o := oc.lookup("id:2"); o != nil {
+		t.Errorf("deleted object returned from cache")
+	}
+}
+
+func TestCachedObjectValue(t *testing.T) {
+	logger := zerolog.Nop()
+	oc := NewObjectCache(newTestStorageRoot(), nil, 2, time.Hour, false, &logger)
+	o := cacheObject(t, oc, oc.storageRoot.(*testStorageRoot), "id:1")
+	var created int
+	create := func() (any, error) {
+		created++
+		return created, nil
+	}
+	for i := 0; i < 2; i++ {
+		value, err := o.Value("key", create)
+		if err != nil {
+			t.Fatalf("cannot get value: %v", err)
+		}
+		if value.(int) != 1 {
+			t.Errorf("value %v, expected 1", value)
+		}
+	}
+	if created != 1 {
+		t.Errorf("value created %d times", created)
+	}
+}
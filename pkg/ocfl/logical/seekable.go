@@ -0,0 +1,112 @@
+package logical
+
+import (
+	"io"
+	"io/fs"
+
+	"emperror.dev/errors"
+)
+
+// seekableFile makes files in zip or encrypted containers seekable.
+// Seek only sets the offset. The next Read skips forward or reopens the file
+// and skips to the offset, if the offset is before the current position
+type seekableFile struct {
+	fsys   fs.FS
+	name   string
+	info   fs.FileInfo
+	file   fs.File
+	pos    int64
+	offset int64
+}
+
+// OpenSeekable opens a file of the object filesystem as io.ReadSeeker which is needed for http range requests.
+// Files which are already seekable are returned unchanged
+func OpenSeekable(fsys fs.FS, name string) (fs.File, error) {
+	fp, err := fsys.Open(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, ok := fp.(io.ReadSeeker); ok {
+		return fp, nil
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return nil, errors.Wrapf(err, "cannot stat '%s'", name)
+	}
+	if info.IsDir() {
+		return fp, nil
+	}
+	return &seekableFile{
+		fsys: fsys,
+		name: name,
+		info: info,
+		file: fp,
+	}, nil
+}
+
+func (sf *seekableFile) Stat() (fs.FileInfo, error) {
+	return sf.info, nil
+}
+
+func (sf *seekableFile) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = sf.offset + offset
+	case io.SeekEnd:
+		abs = sf.info.Size() + offset
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if abs < 0 {
+		return 0, errors.Errorf("negative position %d", abs)
+	}
+	sf.offset = abs
+	return abs, nil
+}
+
+func (sf *seekableFile) Read(p []byte) (int, error) {
+	if sf.file == nil {
+		return 0, fs.ErrClosed
+	}
+	if sf.offset < sf.pos {
+		if err := sf.file.Close(); err != nil {
+			return 0, errors.Wrapf(err, "cannot close '%s'", sf.name)
+		}
+		fp, err := sf.fsys.Open(sf.name)
+		if err != nil {
+			sf.file = nil
+			return 0, errors.Wrapf(err, "cannot reopen '%s'", sf.name)
+		}
+		sf.file = fp
+		sf.pos = 0
+	}
+	if sf.offset > sf.pos {
+		n, err := io.CopyN(io.Discard, sf.file, sf.offset-sf.pos)
+		sf.pos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := sf.file.Read(p)
+	sf.pos += int64(n)
+	sf.offset = sf.pos
+	return n, err
+}
+
+func (sf *seekableFile) Close() error {
+	if sf.file == nil {
+		return nil
+	}
+	err := sf.file.Close()
+	sf.file = nil
+	return errors.WithStack(err)
+}
+
+var (
+	_ io.ReadSeekCloser = &seekableFile{}
+	_ fs.File           = &seekableFile{}
+)
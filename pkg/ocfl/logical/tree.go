@@ -0,0 +1,95 @@
+// Package logical provides the logical view of object versions after extract path mapping
+// and a cache of loaded objects, which is shared by display, webdav and mount
+package logical
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"emperror.dev/errors"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
+)
+
+// File is a file of the logical view of an object version
+type File struct {
+	Internal string
+	Digest   string
+}
+
+// Tree is the logical view of an object version after extract path mapping
+type Tree struct {
+	Files   map[string]*File
+	Dirs    map[string]map[string]bool
+	ModTime time.Time
+}
+
+// BuildTree maps the state of a version with the extract path mapping of the object extensions
+// (like extract). files which do not belong to area are skipped
+func BuildTree(obj object.Object, version string, area string) (*Tree, error) {
+	inv := obj.GetInventory()
+	v, ok := inv.GetVersions()[version]
+	if !ok {
+		return nil, errors.Wrapf(fs.ErrNotExist, "invalid version '%s'", version)
+	}
+	tree := &Tree{
+		Files: map[string]*File{},
+		Dirs:  map[string]map[string]bool{"": {}},
+	}
+	if v.Created != nil {
+		tree.ModTime = v.Created.Time
+	}
+	extensionManager := obj.GetExtensionManager()
+	if err := inv.IterateStateFiles(version, func(internals, externals []string, digest string) error {
+		for _, external := range externals {
+			logical, err := extensionManager.BuildObjectExtractPath(obj, external, area)
+			if err != nil {
+				if errors.Is(errors.Cause(err), object.ExtensionObjectExtractPathWrongAreaError) {
+					continue
+				}
+				return errors.Wrapf(err, "cannot map path '%s'", external)
+			}
+			logical = strings.Trim(path.Clean("/"+logical), "/")
+			if logical == "" {
+				continue
+			}
+			tree.Files[logical] = &File{Internal: internals[0], Digest: digest}
+			for name := logical; name != ""; {
+				parent := path.Dir(name)
+				if parent == "." {
+					parent = ""
+				}
+				children, ok := tree.Dirs[parent]
+				if !ok {
+					children = map[string]bool{}
+					tree.Dirs[parent] = children
+				}
+				children[path.Base(name)] = true
+				if ok {
+					break
+				}
+				name = parent
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "cannot iterate state files of version %s", version)
+	}
+	return tree, nil
+}
+
+// Children returns the sorted names of the entries of a folder
+func (t *Tree) Children(dir string) ([]string, bool) {
+	children, ok := t.Dirs[dir]
+	if !ok {
+		return nil, false
+	}
+	var names = []string{}
+	for child := range children {
+		names = append(names, child)
+	}
+	sort.Strings(names)
+	return names, true
+}
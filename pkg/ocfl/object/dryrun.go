@@ -0,0 +1,247 @@
+package object
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"emperror.dev/errors"
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/version"
+)
+
+type DryRunAction string
+
+const (
+	DryRunAdd         DryRunAction = "add"
+	DryRunUpdate      DryRunAction = "update"
+	DryRunDelete      DryRunAction = "delete"
+	DryRunDeduplicate DryRunAction = "deduplicate"
+	DryRunUnchanged   DryRunAction = "unchanged"
+	DryRunSkip        DryRunAction = "skip"
+)
+
+type DryRunFile struct {
+	Action      DryRunAction `json:"action"`
+	Area        string       `json:"area,omitempty"`
+	Source      string       `json:"source,omitempty"`
+	StatePath   string       `json:"statePath"`
+	ContentPath string       `json:"contentPath,omitempty"`
+	Digest      string       `json:"digest,omitempty"`
+}
+
+type DryRunReport struct {
+	ObjectID   string        `json:"objectID"`
+	ObjectPath string        `json:"objectPath"`
+	NewObject  bool          `json:"newObject"`
+	Version    string        `json:"version"`
+	Files      []*DryRunFile `json:"files"`
+}
+
+func (r *DryRunReport) Count(action DryRunAction) int {
+	var result int
+	for _, f := range r.Files {
+		if f.Action == action {
+			result++
+		}
+	}
+	return result
+}
+
+func (r *DryRunReport) Write(w io.Writer) error {
+	var newObject string
+	if r.NewObject {
+		newObject = " (new object)"
+	}
+	if _, err := fmt.Fprintf(w, "object '%s'%s\npath: %s\nnew version: %s\n", r.ObjectID, newObject, r.ObjectPath, r.Version); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, f := range r.Files {
+		// unchanged files without content path are not stored again
+		if f.Action == DryRunUnchanged && f.ContentPath == "" {
+			continue
+		}
+		var err error
+		switch f.Action {
+		case DryRunDelete, DryRunSkip:
+			_, err = fmt.Fprintf(w, "  %-11s %s\n", f.Action, f.StatePath)
+		default:
+			_, err = fmt.Fprintf(w, "  %-11s %s -> %s\n", f.Action, f.StatePath, f.ContentPath)
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	_, err := fmt.Fprintf(w, "added: %d, updated: %d, deleted: %d, deduplicated: %d, unchanged: %d, skipped: %d\n",
+		r.Count(DryRunAdd), r.Count(DryRunUpdate), r.Count(DryRunDelete), r.Count(DryRunDeduplicate), r.Count(DryRunUnchanged), r.Count(DryRunSkip))
+	return errors.WithStack(err)
+}
+
+type dryRunInitializer interface {
+	initDryRun(id string, digest checksum.DigestAlgorithm, fixity []checksum.DigestAlgorithm) error
+}
+
+// NewDryRunObject creates an object with an empty in-memory inventory. in contrast to CreateObject
+// nothing is written to fsys
+func NewDryRunObject(ctx context.Context, id string, ver version.OCFLVersion, digest checksum.DigestAlgorithm, fixity []checksum.DigestAlgorithm, extensionFactory *extension.ExtensionFactory, manager extension.ExtensionManager, fsys fs.FS, logger zLogger.ZLogger) (Object, error) {
+	object, err := NewObject(ctx, fsys, ver, extensionFactory, manager, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot instantiate object")
+	}
+	dr, ok := object.(dryRunInitializer)
+	if !ok {
+		return nil, errors.Errorf("object version %s does not support dry run", ver)
+	}
+	if err := dr.initDryRun(id, digest, fixity); err != nil {
+		return nil, errors.Wrap(err, "cannot initialize object")
+	}
+	return object, nil
+}
+
+func (object *ObjectBase) initDryRun(id string, digest checksum.DigestAlgorithm, fixity []checksum.DigestAlgorithm) error {
+	var err error
+	object.i, err = object.CreateInventory(id, digest, fixity)
+	if err != nil {
+		return errors.Wrapf(err, "cannot create inventory for '%s'", id)
+	}
+	return nil
+}
+
+// DryRun simulates StartUpdate, AddFolder and EndUpdate on the in-memory inventory.
+// Only the path building extension hooks and the DryRunFile hooks are called, content and
+// object change hooks are skipped because they may write to the object.
+// The object must not be stored afterwards.
+func (object *ObjectBase) DryRun(sources map[string]fs.FS, checkDuplicate bool, msg, userName, userAddress string, echo bool) (*DryRunReport, error) {
+	report := &DryRunReport{
+		ObjectID:  object.GetID(),
+		NewObject: object.i.GetHead() == "",
+		Files:     []*DryRunFile{},
+	}
+	var headState = map[string]string{}
+	if !report.NewObject {
+		if err := object.i.IterateStateFiles(object.i.GetHead(), func(internals, externals []string, digest string) error {
+			for _, external := range externals {
+				headState[external] = digest
+			}
+			return nil
+		}); err != nil {
+			return nil, errors.Wrapf(err, "cannot iterate state files of '%s'", object.GetID())
+		}
+	}
+	if err := object.i.NewVersion(msg, userName, userAddress); err != nil {
+		return nil, errors.Wrap(err, "cannot create new object version")
+	}
+	report.Version = object.i.GetHead()
+
+	var areas = []string{}
+	for area := range sources {
+		areas = append(areas, area)
+	}
+	sort.Strings(areas)
+
+	var planned = map[string]string{}
+	var seen = []string{}
+	for _, area := range areas {
+		fsys := sources[area]
+		if err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if d.IsDir() {
+				return nil
+			}
+			path = filepath.ToSlash(path)
+			names, err := object.BuildNames([]string{path}, area)
+			if err != nil {
+				return errors.Wrapf(err, "cannot create names for '%s'", path)
+			}
+			fp, err := fsys.Open(path)
+			if err != nil {
+				return errors.Wrapf(err, "cannot open file '%v/%s'", fsys, path)
+			}
+			digest, err := checksum.Checksum(fp, object.i.GetDigestAlgorithm())
+			fp.Close()
+			if err != nil {
+				return errors.Wrapf(err, "cannot create digest of '%s'", path)
+			}
+			statePath := names.ExternalPaths[0]
+			seen = append(seen, statePath)
+			file := &DryRunFile{
+				Area:        area,
+				Source:      path,
+				StatePath:   statePath,
+				ContentPath: names.ManifestPath,
+				Digest:      digest,
+			}
+			prevDigest, exists := headState[statePath]
+			switch {
+			case checkDuplicate && exists && prevDigest == digest:
+				file.Action = DryRunUnchanged
+				file.ContentPath = ""
+			case checkDuplicate && len(object.i.GetDuplicates(digest)) > 0:
+				file.Action = DryRunDeduplicate
+				file.ContentPath = object.i.GetDuplicates(digest)[0]
+			case checkDuplicate && planned[digest] != "":
+				file.Action = DryRunDeduplicate
+				file.ContentPath = planned[digest]
+			default:
+				if err := object.extensionManager.DryRunFile(object, fsys, path, area); err != nil {
+					if !errors.Is(err, ErrSkipFile) {
+						return errors.Wrapf(err, "cannot add file '%s'", path)
+					}
+					file.Action = DryRunSkip
+					file.ContentPath = ""
+					break
+				}
+				planned[digest] = names.ManifestPath
+				switch {
+				case exists && prevDigest == digest:
+					// without deduplication the content is stored again, the state does not change
+					file.Action = DryRunUnchanged
+				case exists:
+					file.Action = DryRunUpdate
+				default:
+					file.Action = DryRunAdd
+				}
+			}
+			report.Files = append(report.Files, file)
+			return nil
+		}); err != nil {
+			return nil, errors.Wrapf(err, "cannot walk area '%s'", area)
+		}
+	}
+
+	if echo && !report.NewObject {
+		basePath, err := object.extensionManager.BuildObjectStatePath(object, ".", "")
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot build external path for '.'")
+		}
+		if basePath == "." {
+			basePath = ""
+		}
+		sort.Strings(seen)
+		for statePath := range headState {
+			if !strings.HasPrefix(statePath, basePath) {
+				continue
+			}
+			if idx := sort.SearchStrings(seen, statePath); idx < len(seen) && seen[idx] == statePath {
+				continue
+			}
+			report.Files = append(report.Files, &DryRunFile{
+				Action:    DryRunDelete,
+				StatePath: statePath,
+				Digest:    headState[statePath],
+			})
+		}
+	}
+	sort.SliceStable(report.Files, func(i, j int) bool {
+		return report.Files[i].StatePath < report.Files[j].StatePath
+	})
+	return report, nil
+}
@@ -0,0 +1,304 @@
+package object
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis/ead3"
+	"github.com/ocfl-archive/gocfl/v2/version"
+	"github.com/ocfl-archive/indexer/v3/pkg/indexer"
+	"golang.org/x/exp/maps"
+)
+
+const ead3Namespace = "http://ead3.archivists.org/schema/"
+
+// ead3Folder is a logical folder of the head version of an object
+type ead3Folder struct {
+	folders map[string]*ead3Folder
+	files   map[string]string // name -> digest
+}
+
+func newEAD3Folder() *ead3Folder {
+	return &ead3Folder{
+		folders: map[string]*ead3Folder{},
+		files:   map[string]string{},
+	}
+}
+
+func (f *ead3Folder) add(parts []string, digest string) {
+	if len(parts) == 1 {
+		f.files[parts[0]] = digest
+		return
+	}
+	sub, ok := f.folders[parts[0]]
+	if !ok {
+		sub = newEAD3Folder()
+		f.folders[parts[0]] = sub
+	}
+	sub.add(parts[1:], digest)
+}
+
+// ead3RootFolder is a folder of the storage root above the object folders
+type ead3RootFolder struct {
+	folders map[string]*ead3RootFolder
+	objects []string
+}
+
+func newEAD3RootFolder() *ead3RootFolder {
+	return &ead3RootFolder{
+		folders: map[string]*ead3RootFolder{},
+	}
+}
+
+func (f *ead3RootFolder) add(parts []string, id string) {
+	if len(parts) == 0 {
+		f.objects = append(f.objects, id)
+		return
+	}
+	sub, ok := f.folders[parts[0]]
+	if !ok {
+		sub = newEAD3RootFolder()
+		f.folders[parts[0]] = sub
+	}
+	sub.add(parts[1:], id)
+}
+
+// ead3Components returns the storage root folders and the objects of folder
+func (srm *StorageRootMetadata) ead3Components(folder *ead3RootFolder) []*ead3.C {
+	var result []*ead3.C
+	names := maps.Keys(folder.folders)
+	slices.Sort(names)
+	for _, name := range names {
+		result = append(result, &ead3.C{
+			LevelAttr:      "otherlevel",
+			OtherlevelAttr: "folder",
+			MCBaseDescgrp: &ead3.MCBaseDescgrp{
+				Did: &ead3.Did{
+					Unittitle: []*ead3.Unittitle{{Value: name}},
+				},
+			},
+			C: srm.ead3Components(folder.folders[name]),
+		})
+	}
+	slices.Sort(folder.objects)
+	for _, id := range folder.objects {
+		result = append(result, srm.Objects[id].EAD3Component())
+	}
+	return result
+}
+
+func newEAD3Part(str string) []*ead3.Part {
+	return []*ead3.Part{{Value: str}}
+}
+
+func newEAD3Physdesc(localType, str string) *ead3.Physdesc {
+	return &ead3.Physdesc{LocaltypeAttr: localType, Value: str}
+}
+
+func ead3String(meta map[string]any, key string) string {
+	str, _ := meta[key].(string)
+	return str
+}
+
+// EAD3 builds an EAD3 finding aid of the objects.
+// the storage root is described as collection, the folders of the storage root above the objects as
+// folder components and every object as file with the logical folders and files of the head version
+// as sub components
+func (srm *StorageRootMetadata) EAD3(title string) *ead3.Ead {
+	now := time.Now()
+	result := &ead3.Ead{
+		XMLNS: ead3Namespace,
+		Control: &ead3.Control{
+			Recordid: &ead3.Recordid{Value: title},
+			Filedesc: &ead3.Filedesc{
+				Titlestmt: &ead3.Titlestmt{
+					Titleproper: []*ead3.Titleproper{{Value: title}},
+				},
+			},
+			Maintenancestatus: &ead3.Maintenancestatus{ValueAttr: "new"},
+			Maintenanceagency: &ead3.Maintenanceagency{
+				Agencyname: []*ead3.Agencyname{{Value: "gocfl"}},
+			},
+			Maintenancehistory: &ead3.Maintenancehistory{
+				Maintenanceevent: []*ead3.Maintenanceevent{{
+					Eventtype:     &ead3.Eventtype{ValueAttr: "created"},
+					Eventdatetime: &ead3.Eventdatetime{StandarddatetimeAttr: now.Format(time.RFC3339), Value: now.Format(time.RFC3339)},
+					Agenttype:     &ead3.Agenttype{ValueAttr: "machine"},
+					Agent:         &ead3.Agent{Value: "gocfl " + version.Version},
+				}},
+			},
+		},
+		Archdesc: &ead3.Archdesc{
+			LevelAttr: "collection",
+			Did: &ead3.Did{
+				Unittitle: []*ead3.Unittitle{{Value: title}},
+			},
+		},
+	}
+	root := newEAD3RootFolder()
+	for id := range srm.Objects {
+		var parts []string
+		if folder := strings.Trim(srm.Folders[id], "/"); folder != "" {
+			// the last part is the object folder itself
+			parts = strings.Split(folder, "/")
+			parts = parts[:len(parts)-1]
+		}
+		root.add(parts, id)
+	}
+	dsc := &ead3.Dsc{
+		C: srm.ead3Components(root),
+	}
+	if len(dsc.C) > 0 {
+		result.Archdesc.Dsc = []*ead3.Dsc{dsc}
+	}
+	return result
+}
+
+// EAD3Component builds the EAD3 component of the object.
+// descriptive metadata is taken from NNNN-metafile, technical metadata from NNNN-indexer
+func (om *ObjectMetadata) EAD3Component() *ead3.C {
+	var metaFile map[string]any
+	if extMap, ok := om.Extension.(map[string]any); ok {
+		metaFile, _ = extMap["NNNN-metafile"].(map[string]any)
+	}
+	title := ead3String(metaFile, "title")
+	if title == "" {
+		title = om.ID
+	}
+	did := &ead3.Did{
+		Unittitle: []*ead3.Unittitle{{Value: title}},
+		Unitid:    []*ead3.Unitid{{Value: om.ID}},
+	}
+	if signature := ead3String(metaFile, "signature"); signature != "" {
+		did.Unitid = append(did.Unitid, &ead3.Unitid{LocaltypeAttr: "signature", Value: signature})
+	}
+	if unitdate := om.ead3Unitdate(); unitdate != nil {
+		did.Unitdate = []*ead3.Unitdate{unitdate}
+	}
+	origination := &ead3.Origination{}
+	if user := ead3String(metaFile, "user"); user != "" {
+		origination.Persname = append(origination.Persname, &ead3.Persname{Part: newEAD3Part(user)})
+	} else if head, ok := om.Versions[om.Head]; ok && head.Name != "" {
+		origination.Persname = append(origination.Persname, &ead3.Persname{Part: newEAD3Part(head.Name)})
+	}
+	if organisation := ead3String(metaFile, "organisation"); organisation != "" {
+		origination.Corpname = append(origination.Corpname, &ead3.Corpname{Part: newEAD3Part(organisation)})
+	}
+	if len(origination.Persname)+len(origination.Corpname) > 0 {
+		did.Origination = []*ead3.Origination{origination}
+	}
+
+	root := newEAD3Folder()
+	var numFiles int
+	for digest, fm := range om.Files {
+		for _, name := range fm.VersionName[om.Head] {
+			root.add(strings.Split(name, "/"), digest)
+			numFiles++
+		}
+	}
+	did.Physdesc = []*ead3.Physdesc{newEAD3Physdesc("extent", fmt.Sprintf("%d files", numFiles))}
+
+	result := &ead3.C{
+		LevelAttr: "file",
+		MCBaseDescgrp: &ead3.MCBaseDescgrp{
+			Did: did,
+		},
+	}
+	if description := ead3String(metaFile, "description"); description != "" {
+		result.Scopecontent = []*ead3.Scopecontent{{P: []*ead3.P{{Value: description}}}}
+	}
+	if keywords, ok := metaFile["keywords"].([]any); ok {
+		controlaccess := &ead3.Controlaccess{}
+		for _, keyword := range keywords {
+			if str, ok := keyword.(string); ok && str != "" {
+				controlaccess.Subject = append(controlaccess.Subject, &ead3.Subject{Part: newEAD3Part(str)})
+			}
+		}
+		if len(controlaccess.Subject) > 0 {
+			result.Controlaccess = []*ead3.Controlaccess{controlaccess}
+		}
+	}
+	result.C = om.ead3Components(root, "")
+	return result
+}
+
+// ead3Unitdate returns the date range from the first to the head version
+func (om *ObjectMetadata) ead3Unitdate() *ead3.Unitdate {
+	var first, last time.Time
+	for _, v := range om.Versions {
+		if first.IsZero() || v.Created.Before(first) {
+			first = v.Created
+		}
+		if last.IsZero() || v.Created.After(last) {
+			last = v.Created
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	from, to := first.Format(time.DateOnly), last.Format(time.DateOnly)
+	if from == to {
+		return &ead3.Unitdate{NormalAttr: from, Value: from}
+	}
+	return &ead3.Unitdate{
+		NormalAttr: from + "/" + to,
+		Value:      from + " - " + to,
+	}
+}
+
+func (om *ObjectMetadata) ead3Components(folder *ead3Folder, folderPath string) []*ead3.C {
+	var result []*ead3.C
+	names := maps.Keys(folder.folders)
+	slices.Sort(names)
+	for _, name := range names {
+		result = append(result, &ead3.C{
+			LevelAttr:      "otherlevel",
+			OtherlevelAttr: "folder",
+			MCBaseDescgrp: &ead3.MCBaseDescgrp{
+				Did: &ead3.Did{
+					Unittitle: []*ead3.Unittitle{{Value: name}},
+				},
+			},
+			C: om.ead3Components(folder.folders[name], path.Join(folderPath, name)),
+		})
+	}
+	names = maps.Keys(folder.files)
+	slices.Sort(names)
+	for _, name := range names {
+		digest := folder.files[name]
+		did := &ead3.Did{
+			Unittitle: []*ead3.Unittitle{{Value: name}},
+			Unitid:    []*ead3.Unitid{{LocaltypeAttr: string(om.DigestAlgorithm), Value: digest}},
+			Dao:       []*ead3.Dao{{DaotypeAttr: "unknown", HrefAttr: path.Join(folderPath, name)}},
+		}
+		if fm, ok := om.Files[digest]; ok {
+			if idx, ok := fm.Extension["NNNN-indexer"].(*indexer.ResultV2); ok && idx != nil {
+				if idx.Mimetype != "" {
+					did.Physdesc = append(did.Physdesc, newEAD3Physdesc("mimetype", idx.Mimetype))
+				}
+				if idx.Pronom != "" {
+					did.Physdesc = append(did.Physdesc, newEAD3Physdesc("pronom", idx.Pronom))
+				}
+				if idx.Size > 0 {
+					did.Physdesc = append(did.Physdesc, newEAD3Physdesc("size", fmt.Sprintf("%v bytes", idx.Size)))
+				}
+				if idx.Width > 0 {
+					did.Physdesc = append(did.Physdesc, newEAD3Physdesc("dimensions", fmt.Sprintf("%vx%v", idx.Width, idx.Height)))
+				}
+				if idx.Duration > 0 {
+					did.Physdesc = append(did.Physdesc, newEAD3Physdesc("duration", fmt.Sprintf("%vs", idx.Duration)))
+				}
+			}
+		}
+		result = append(result, &ead3.C{
+			LevelAttr: "item",
+			MCBaseDescgrp: &ead3.MCBaseDescgrp{
+				Did: did,
+			},
+		})
+	}
+	return result
+}
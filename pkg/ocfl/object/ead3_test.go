@@ -0,0 +1,160 @@
+package object
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis"
+	"github.com/ocfl-archive/gocfl/v2/pkg/dilcis/ead3"
+	"github.com/ocfl-archive/indexer/v3/pkg/indexer"
+)
+
+func TestStorageRootMetadataEAD3(t *testing.T) {
+	now := time.Now()
+	srm := &StorageRootMetadata{
+		Objects: map[string]*ObjectMetadata{
+			"id:abc": {
+				ID:              "id:abc",
+				DigestAlgorithm: checksum.DigestSHA512,
+				Head:            "v2",
+				Versions: map[string]*VersionMetadata{
+					"v1": {Created: now.Add(-48 * time.Hour), Name: "Jane Doe"},
+					"v2": {Created: now, Name: "Jane Doe"},
+				},
+				Files: FilesMetadata{
+					"aaaa": {
+						VersionName: map[string][]string{"v1": {"data/images/a.tif"}, "v2": {"data/images/a.tif"}},
+						Extension: map[string]any{
+							"NNNN-indexer": &indexer.ResultV2{Mimetype: "image/tiff", Pronom: "fmt/353", Size: 1234, Width: 10, Height: 20},
+						},
+					},
+					"bbbb": {
+						VersionName: map[string][]string{"v2": {"data/readme.txt"}},
+						Extension:   map[string]any{},
+					},
+					"cccc": {
+						VersionName: map[string][]string{"v1": {"data/deleted.txt"}},
+						Extension:   map[string]any{},
+					},
+				},
+				Extension: map[string]any{
+					"NNNN-metafile": map[string]any{
+						"title":        "Images & Text",
+						"description":  "test object",
+						"keywords":     []any{"images", "text"},
+						"organisation": "Example Archive",
+						"user":         "Jane Doe",
+					},
+				},
+			},
+			"id:def": {
+				ID:       "id:def",
+				Head:     "v1",
+				Versions: map[string]*VersionMetadata{"v1": {Created: now}},
+				Files:    FilesMetadata{},
+			},
+		},
+	}
+	ead := srm.EAD3("test storage root")
+
+	if len(ead.Archdesc.Dsc) != 1 || len(ead.Archdesc.Dsc[0].C) != 2 {
+		t.Fatalf("expected one component per object")
+	}
+	obj := ead.Archdesc.Dsc[0].C[0]
+	if obj.Did.Unittitle[0].Value != "Images & Text" || obj.Did.Unitid[0].Value != "id:abc" {
+		t.Errorf("metafile title or object id not mapped")
+	}
+	if ead.Archdesc.Dsc[0].C[1].Did.Unittitle[0].Value != "id:def" {
+		t.Errorf("object id not used as fallback title")
+	}
+	// data
+	if len(obj.C) != 1 || obj.C[0].Did.Unittitle[0].Value != "data" {
+		t.Fatalf("expected folder 'data'")
+	}
+	// images, readme.txt - deleted.txt is not part of head
+	data := obj.C[0]
+	if len(data.C) != 2 {
+		t.Fatalf("expected 2 components in 'data', got %d", len(data.C))
+	}
+	tif := data.C[0].C[0]
+	if tif.LevelAttr != "item" || tif.Did.Dao[0].HrefAttr != "data/images/a.tif" {
+		t.Errorf("unexpected item component for 'a.tif'")
+	}
+	if len(tif.Did.Physdesc) != 4 {
+		t.Errorf("expected 4 physdesc entries from indexer, got %d", len(tif.Did.Physdesc))
+	}
+
+	validateEAD3(t, ead)
+}
+
+func TestStorageRootMetadataEAD3Hierarchy(t *testing.T) {
+	now := time.Now()
+	srm := &StorageRootMetadata{
+		Objects: map[string]*ObjectMetadata{},
+		Folders: map[string]string{
+			"id:a": "3a/c1/id=3Aa",
+			"id:b": "3a/f7/id=3Ab",
+			"id:c": "4b/id=3Ac",
+			"id:d": "id=3Ad",
+		},
+	}
+	for _, id := range []string{"id:a", "id:b", "id:c", "id:d", "id:e"} {
+		srm.Objects[id] = &ObjectMetadata{
+			ID:       id,
+			Head:     "v1",
+			Versions: map[string]*VersionMetadata{"v1": {Created: now}},
+			Files:    FilesMetadata{},
+		}
+	}
+	ead := srm.EAD3("test storage root")
+
+	titles := func(cs []*ead3.C) string {
+		var result []string
+		for _, c := range cs {
+			result = append(result, c.LevelAttr+":"+c.Did.Unittitle[0].Value)
+		}
+		return strings.Join(result, ",")
+	}
+	tests := []struct {
+		name string
+		cs   []*ead3.C
+		want string
+	}{
+		// objects without known folder are placed below the root
+		{"root", ead.Archdesc.Dsc[0].C, "otherlevel:3a,otherlevel:4b,file:id:d,file:id:e"},
+		{"3a", ead.Archdesc.Dsc[0].C[0].C, "otherlevel:c1,otherlevel:f7"},
+		{"3a/c1", ead.Archdesc.Dsc[0].C[0].C[0].C, "file:id:a"},
+		{"3a/f7", ead.Archdesc.Dsc[0].C[0].C[1].C, "file:id:b"},
+		{"4b", ead.Archdesc.Dsc[0].C[1].C, "file:id:c"},
+	}
+	for _, tt := range tests {
+		if got := titles(tt.cs); got != tt.want {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.want, got)
+		}
+	}
+	if ead.Archdesc.Dsc[0].C[0].OtherlevelAttr != "folder" {
+		t.Errorf("storage root folder not described as folder")
+	}
+
+	validateEAD3(t, ead)
+}
+
+// validateEAD3 validates the ead against ead3.xsd, if xmllint is available
+func validateEAD3(t *testing.T, ead *ead3.Ead) {
+	t.Helper()
+	xmlBytes, err := xml.MarshalIndent(ead, "", "  ")
+	if err != nil {
+		t.Fatalf("cannot marshal ead: %v", err)
+	}
+
+	validator, err := dilcis.NewXMLLint("xmllint")
+	if err != nil {
+		t.Skip("xmllint not found - skipping schema validation")
+	}
+	if err := validator.Validate(dilcis.SchemaEAD3, append([]byte(xml.Header), xmlBytes...)); err != nil {
+		t.Errorf("ead.xml not valid: %v", err)
+	}
+}
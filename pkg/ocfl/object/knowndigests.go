@@ -0,0 +1,30 @@
+package object
+
+import (
+	"context"
+
+	"github.com/je4/utils/v2/pkg/checksum"
+)
+
+// KnownDigests returns verified digests of a source file, e.g. from the manifests of a
+// BagIt bag. AddFile stores them as fixity without computing them again
+type KnownDigests func(area, path string) map[checksum.DigestAlgorithm]string
+
+type knownDigestsKey struct{}
+
+// NewKnownDigestsContext returns a context which carries the known digests of the source files
+func NewKnownDigestsContext(ctx context.Context, known KnownDigests) context.Context {
+	return context.WithValue(ctx, knownDigestsKey{}, known)
+}
+
+// knownDigests returns the known digests of the source file or nil
+func knownDigests(ctx context.Context, area, path string) map[checksum.DigestAlgorithm]string {
+	if ctx == nil {
+		return nil
+	}
+	known, _ := ctx.Value(knownDigestsKey{}).(KnownDigests)
+	if known == nil {
+		return nil
+	}
+	return known(area, path)
+}
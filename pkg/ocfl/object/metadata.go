@@ -112,6 +112,8 @@ func (om *ObjectMetadata) Obfuscate() error {
 
 type StorageRootMetadata struct {
 	Objects map[string]*ObjectMetadata
+	// Folders maps object ids to their folders in the storage root
+	Folders map[string]string `json:"-"`
 }
 
 func (srm *StorageRootMetadata) Obfuscate() error {
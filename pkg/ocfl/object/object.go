@@ -49,6 +49,7 @@ type Object interface {
 	GetVersion() version.OCFLVersion
 	Check() error
 	Close() error
+	Unlock() error
 	GetFS() fs.FS
 	IsModified() bool
 	Stat(w io.Writer, statInfo []stat.StatInfo) error
@@ -57,6 +58,7 @@ type Object interface {
 	GetAreaPath(area string) (string, error)
 	GetExtensionManager() ExtensionManager
 	BuildNames(files []string, area string) (*NamesStruct, error)
+	DryRun(sources map[string]fs.FS, checkDuplicate bool, msg, userName, userAddress string, echo bool) (*DryRunReport, error)
 }
 
 func GetObjectVersion(ctx context.Context, ofs fs.FS) (ver version.OCFLVersion, err error) {
@@ -131,6 +133,7 @@ func CreateObject(ctx context.Context, id string, ver version.OCFLVersion, diges
 
 	// create initial filesystem structure for new object
 	if err = object.Init(id, digest, fixity, manager); err != nil {
+		_ = object.Unlock()
 		return nil, errors.Wrap(err, "cannot initialize object")
 	}
 
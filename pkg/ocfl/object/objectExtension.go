@@ -21,8 +21,11 @@ const (
 	ExtensionAreaName               = "Area"
 	ExtensionStreamName             = "Stream"
 	ExtensionNewVersionName         = "NewVersion"
+	ExtensionVersionCheckName       = "VersionCheck"
 	ExtensionVersionDoneName        = "VersionDone"
 	ExtensionInitialName            = "Initial"
+	ExtensionContentInspectionName  = "ContentInspection"
+	ExtensionDryRunName             = "DryRun"
 )
 
 type ExtensionObjectContentPath interface {
@@ -32,6 +35,9 @@ type ExtensionObjectContentPath interface {
 
 var ExtensionObjectExtractPathWrongAreaError = fmt.Errorf("invalid area")
 
+// ErrSkipFile is returned by AddFileBefore, if the file must not be added to the object
+var ErrSkipFile = fmt.Errorf("skip file")
+
 type ExtensionObjectExtractPath interface {
 	extension.Extension
 	BuildObjectExtractPath(object Object, originalPath string, area string) (string, error)
@@ -62,6 +68,21 @@ type ExtensionContentChange interface {
 	DeleteFileAfter(object Object, dest string, area string) error
 }
 
+// ExtensionContentInspection is implemented by content change extensions which read the source file
+// in AddFileBefore. If InspectContent returns true, the content is buffered before AddFileBefore and
+// stored from the buffer, so that sourceFS is never nil and the inspected content is the stored content
+type ExtensionContentInspection interface {
+	extension.Extension
+	InspectContent() bool
+}
+
+// ExtensionDryRun is implemented by extensions which take part in a dry run.
+// DryRunFile only computes and must not write anything. It returns ErrSkipFile, if the file would not be added
+type ExtensionDryRun interface {
+	extension.Extension
+	DryRunFile(object Object, sourceFS fs.FS, source string, area string) error
+}
+
 type ExtensionObjectChange interface {
 	extension.Extension
 	UpdateObjectBefore(object Object) error
@@ -78,6 +99,15 @@ type ExtensionMetadata interface {
 	GetMetadata(object Object) (map[string]any, error)
 }
 
+// ExtensionVersionCheck is called before the version inventory is stored.
+// errors fail the version
+type ExtensionVersionCheck interface {
+	extension.Extension
+	CheckVersion(object Object) error
+}
+
+// ExtensionVersionDone is called after the version inventory is stored.
+// errors are logged and reported as validation warning, the version stays
 type ExtensionVersionDone interface {
 	extension.Extension
 	VersionDone(object Object) error
@@ -7,6 +7,8 @@ type ExtensionManager interface {
 	ExtensionObjectContentPath
 	ExtensionObjectStatePath
 	ExtensionContentChange
+	ExtensionContentInspection
+	ExtensionDryRun
 	ExtensionObjectChange
 	ExtensionFixityDigest
 	ExtensionObjectExtractPath
@@ -14,4 +16,6 @@ type ExtensionManager interface {
 	ExtensionArea
 	ExtensionStream
 	ExtensionNewVersion
+	ExtensionVersionCheck
+	ExtensionVersionDone
 }
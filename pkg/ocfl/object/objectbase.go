@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -19,8 +20,12 @@ import (
 	"github.com/je4/filesystem/v3/pkg/writefs"
 	"github.com/je4/utils/v2/pkg/checksum"
 	"github.com/je4/utils/v2/pkg/zLogger"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/dedup"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/inventory"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/link"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/lock"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/progress"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/stat"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
@@ -48,6 +53,8 @@ type ObjectBase struct {
 	echo               bool
 	updateFiles        []string
 	area               string
+	objectLock         *lock.Lock
+	stopLock           func()
 }
 
 // newObjectBase creates an empty ObjectBase structure
@@ -458,6 +465,11 @@ func (object *ObjectBase) Init(id string, digest checksum.DigestAlgorithm, fixit
 
 	object.extensionManager = extensionManager.(ExtensionManager)
 
+	// lock before the check, a concurrent writer may create the same object
+	if _, err := object.lockObject(id); err != nil {
+		return errors.WithStack(err)
+	}
+
 	// first check whether object is not empty
 	fp, err := object.fsys.Open(objectConformanceDeclarationFile)
 	if err == nil {
@@ -549,8 +561,62 @@ func (object *ObjectBase) echoDelete() error {
 	return nil
 }
 
+// lockObject locks the object with the locker of the context (see lock.NewContext) and keeps the
+// lock alive until Unlock. it returns true if the lock has been acquired by this call
+func (object *ObjectBase) lockObject(id string) (bool, error) {
+	locker := lock.FromContext(object.ctx)
+	if locker == nil || object.objectLock != nil || id == "" {
+		return false, nil
+	}
+	lck, err := locker.Lock(id)
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot lock object '%s'", id)
+	}
+	object.objectLock = lck
+	object.stopLock = locker.KeepAlive(lck)
+	return true, nil
+}
+
+// Unlock releases the lock of the object. Close calls it, callers must call it if an update fails
+func (object *ObjectBase) Unlock() error {
+	if object.objectLock == nil {
+		return nil
+	}
+	lck := object.objectLock
+	object.stopLock()
+	object.objectLock = nil
+	object.stopLock = nil
+	return errors.Wrapf(lock.FromContext(object.ctx).Unlock(lck), "cannot unlock object '%s'", lck.ID)
+}
+
+// checkHead fails if another process has stored a version since the object has been loaded
+func (object *ObjectBase) checkHead() error {
+	data, err := fs.ReadFile(object.fsys, "inventory.json")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return errors.Wrapf(err, "cannot read '%v/inventory.json'", object.fsys)
+	}
+	var current = struct {
+		Head string `json:"head"`
+	}{}
+	if err := json.Unmarshal(data, &current); err != nil {
+		return errors.Wrapf(err, "cannot unmarshal '%v/inventory.json'", object.fsys)
+	}
+	if current.Head != object.i.GetHead() {
+		return errors.Errorf("object '%s' has been updated to %s since it has been loaded with %s", object.GetID(), current.Head, object.i.GetHead())
+	}
+	return nil
+}
+
 func (object *ObjectBase) Close() error {
 	object.logger.Info().Msgf(fmt.Sprintf("Closing object '%s'", object.GetID()))
+	defer func() {
+		if err := object.Unlock(); err != nil {
+			object.logger.Error().Err(err).Msgf("cannot unlock object '%s'", object.GetID())
+		}
+	}()
 	if !(object.i.IsWriteable()) {
 		return nil
 	}
@@ -575,6 +641,16 @@ func (object *ObjectBase) StartUpdate(sourceFS fs.FS, msg string, UserName strin
 	object.logger.Debug().Msgf("'%s' / '%s' / '%s'", msg, UserName, UserAddress)
 	object.echo = echo
 
+	locked, err := object.lockObject(object.GetID())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if locked {
+		if err := object.checkHead(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
 	subfs, err := writefs.SubFSCreate(object.fsys, "extensions")
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot create subfs of %v for folder '%s'", object.fsys, "extensions")
@@ -614,9 +690,17 @@ func (object *ObjectBase) EndUpdate() error {
 	if err := object.i.Clean(); err != nil {
 		return errors.Wrap(err, "cannot clean inventory")
 	}
+	if err := object.extensionManager.CheckVersion(object); err != nil {
+		return errors.Wrapf(err, "cannot execute ext.CheckVersion()")
+	}
 	if err := object.StoreInventory(true, false); err != nil {
 		return errors.Wrap(err, "cannot store inventory")
 	}
+	if err := object.extensionManager.VersionDone(object); err != nil {
+		// the version is stored already. it stays valid without the results of VersionDone (i.e. timestamps)
+		object.logger.Error().Err(err).Msgf("cannot execute ext.VersionDone() for '%s' version %s", object.GetID(), object.i.GetHead())
+		object.AddValidationWarning(validation.W000, "cannot execute ext.VersionDone() for version %s: %v", object.i.GetHead(), err)
+	}
 
 	if needVersion, err := object.extensionManager.NeedNewVersion(object); err != nil {
 		return errors.Wrapf(err, "cannot execute ext.NeedNewVersion()")
@@ -657,11 +741,29 @@ func (object *ObjectBase) EndArea() error {
 
 func (object *ObjectBase) AddFolder(fsys fs.FS, versionFS fs.FS, checkDuplicate bool, area string) error {
 	object.logger.Debug().Msgf("walking '%v'", fsys)
+	reporter := progress.FromContext(object.ctx)
+	if reporter != progress.Nil {
+		// the totals are counted from the directory listing before the content is read
+		files, bytes, err := progress.Count(fsys, ".")
+		if err != nil {
+			object.logger.Debug().Err(err).Msgf("cannot count files of '%v'", fsys)
+			files, bytes = -1, -1
+		}
+		reporter.Start("add "+area, object.GetID(), files, bytes)
+		defer reporter.Finish()
+	}
 	if err := fs.WalkDir(fsys, ".", func(path string, info fs.DirEntry, err error) error {
 		path = filepath.ToSlash(path)
 		if err := object.AddFile(fsys, versionFS, path, checkDuplicate, area, false, info.IsDir()); err != nil {
 			return errors.Wrapf(err, "cannot add file '%s'", path)
 		}
+		if !info.IsDir() && reporter != progress.Nil {
+			var size int64
+			if fi, err := info.Info(); err == nil {
+				size = fi.Size()
+			}
+			reporter.Advance(path, 1, size)
+		}
 		return nil
 	}); err != nil {
 		return errors.Wrap(err, "cannot walk filesystem")
@@ -670,12 +772,10 @@ func (object *ObjectBase) AddFolder(fsys fs.FS, versionFS fs.FS, checkDuplicate
 	return nil
 }
 
-func (object *ObjectBase) addReader(r io.ReadCloser, versionFS fs.FS, names *NamesStruct, noExtensionHook bool) (string, error) {
+func (object *ObjectBase) addReader(r io.ReadCloser, versionFS fs.FS, names *NamesStruct, known map[checksum.DigestAlgorithm]string, noExtensionHook bool) (string, error) {
 
 	digestAlgorithms := object.i.GetFixityDigestAlgorithm()
 
-	var digest string
-
 	object.updateFiles = append(object.updateFiles, names.ExternalPaths...)
 
 	if !slices.Contains(digestAlgorithms, object.i.GetDigestAlgorithm()) {
@@ -688,7 +788,15 @@ func (object *ObjectBase) addReader(r io.ReadCloser, versionFS fs.FS, names *Nam
 	}
 	defer writer.Close()
 
+	return object.storeReader(r, writer, digestAlgorithms, names, known, noExtensionHook)
+}
+
+// storeReader writes r to writer, calls the StreamObject extension hook and adds the file to the inventory.
+// known digests of the source are added to the computed ones
+func (object *ObjectBase) storeReader(r io.Reader, writer io.Writer, digestAlgorithms []checksum.DigestAlgorithm, names *NamesStruct, known map[checksum.DigestAlgorithm]string, noExtensionHook bool) (string, error) {
+	var digest string
 	var checksums map[checksum.DigestAlgorithm]string
+	var err error
 	if noExtensionHook {
 		checksums, err = checksum.Copy(digestAlgorithms, r, writer)
 		if err != nil {
@@ -732,6 +840,18 @@ func (object *ObjectBase) addReader(r io.ReadCloser, versionFS fs.FS, names *Nam
 	} else {
 		checksums[object.i.GetDigestAlgorithm()] = digest
 	}
+	for alg, knownDigest := range known {
+		knownDigest = strings.ToLower(knownDigest)
+		if computed, ok := checksums[alg]; ok {
+			if computed != knownDigest {
+				return "", errors.Errorf("%s digest of '%v' has changed: %s != %s", alg, names.ExternalPaths, computed, knownDigest)
+			}
+			continue
+		}
+		checksums[alg] = knownDigest
+	}
+	// content of other objects is reported only, OCFL allows no references between objects
+	dedup.FromContext(object.ctx).Check(checksums, names.ManifestPath)
 	if err := object.i.AddFile(names.ExternalPaths, names.ManifestPath, checksums); err != nil {
 		return "", errors.Wrapf(err, "cannot append '%v'/'%s' to inventory", names.ExternalPaths, names.InternalPath)
 	}
@@ -739,6 +859,76 @@ func (object *ObjectBase) addReader(r io.ReadCloser, versionFS fs.FS, names *Nam
 	return digest, nil
 }
 
+// addLink puts the content into the version folder with the link mode of the context.
+// The digests are computed by reading file. If linking is not possible, an empty digest is returned
+// and the content has to be copied.
+func (object *ObjectBase) addLink(file io.Reader, area, path string, names *NamesStruct, known map[checksum.DigestAlgorithm]string, noExtensionHook bool) (string, error) {
+	src, dst, ok := link.FromContext(object.ctx).Paths(area, path, names.ManifestPath)
+	if !ok {
+		return "", nil
+	}
+	mode := link.FromContext(object.ctx).Mode
+	if err := link.Link(mode, src, dst); err != nil {
+		if errors.Is(err, link.ErrNotSupported) {
+			object.logger.Debug().Err(err).Msgf("[%s] %s not possible, copying '%s'", object.GetID(), mode, path)
+			return "", nil
+		}
+		return "", errors.WithStack(err)
+	}
+	object.logger.Debug().Msgf("[%s] %s '%s' -> '%s'", object.GetID(), mode, src, dst)
+
+	digestAlgorithms := object.i.GetFixityDigestAlgorithm()
+	if !slices.Contains(digestAlgorithms, object.i.GetDigestAlgorithm()) {
+		digestAlgorithms = append(digestAlgorithms, object.i.GetDigestAlgorithm())
+	}
+	digest, err := object.storeReader(file, io.Discard, digestAlgorithms, names, known, noExtensionHook)
+	if err != nil {
+		// the link is not part of the manifest
+		if err2 := os.Remove(dst); err2 != nil {
+			object.logger.Error().Err(err2).Msgf("[%s] cannot remove '%s'", object.GetID(), dst)
+		}
+		return "", errors.WithStack(err)
+	}
+	object.updateFiles = append(object.updateFiles, names.ExternalPaths...)
+	return digest, nil
+}
+
+// spoolContent writes r to a temporary folder, so that extensions which inspect the content
+// (ExtensionContentInspection) can read streamed files before they are stored.
+// cleanup removes the folder
+func spoolContent(r io.Reader, path string) (fsys fs.FS, cleanup func(), err error) {
+	if !fs.ValidPath(path) {
+		return nil, nil, errors.Errorf("invalid path '%s'", path)
+	}
+	dir, err := os.MkdirTemp("", "gocfl-spool-")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot create temporary folder")
+	}
+	cleanup = func() {
+		os.RemoveAll(dir)
+	}
+	name := filepath.Join(dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		cleanup()
+		return nil, nil, errors.Wrapf(err, "cannot create folder for '%s'", name)
+	}
+	fp, err := os.Create(name)
+	if err != nil {
+		cleanup()
+		return nil, nil, errors.Wrapf(err, "cannot create '%s'", name)
+	}
+	if _, err := io.Copy(fp, r); err != nil {
+		fp.Close()
+		cleanup()
+		return nil, nil, errors.Wrapf(err, "cannot write '%s'", name)
+	}
+	if err := fp.Close(); err != nil {
+		cleanup()
+		return nil, nil, errors.Wrapf(err, "cannot close '%s'", name)
+	}
+	return os.DirFS(dir), cleanup, nil
+}
+
 func (object *ObjectBase) BuildNames(files []string, area string) (*NamesStruct, error) {
 	var err error
 	result := &NamesStruct{
@@ -771,18 +961,41 @@ func (object *ObjectBase) AddReader(r io.ReadCloser, files []string, area string
 	}
 	path := files[0]
 	names, err := object.BuildNames(files, area)
+	if err != nil {
+		return "", errors.Wrapf(err, "cannot build names for '%s'", path)
+	}
 
 	object.logger.Info().Msgf("adding file %s:%v", area, files)
 
+	var sourceFS fs.FS
+	if !noExtensionHook && !isDir && object.extensionManager.InspectContent() {
+		var cleanup func()
+		sourceFS, cleanup, err = spoolContent(r, path)
+		if err != nil {
+			return "", errors.Wrapf(err, "cannot buffer '%s' for content inspection", path)
+		}
+		defer cleanup()
+		fp, err := sourceFS.Open(path)
+		if err != nil {
+			return "", errors.Wrapf(err, "cannot open buffered '%s'", path)
+		}
+		defer fp.Close()
+		r = fp
+	}
+
 	if !noExtensionHook {
-		if err := object.extensionManager.AddFileBefore(object, nil, path, names.InternalPath, area, false); err != nil {
+		if err := object.extensionManager.AddFileBefore(object, sourceFS, path, names.InternalPath, area, false); err != nil {
+			if errors.Is(err, ErrSkipFile) {
+				object.logger.Info().Msgf("[%s] skipping file %s:%s", object.GetID(), area, path)
+				return "", nil
+			}
 			return "", errors.Wrapf(err, "error on AddFileBefore() extension hook")
 		}
 	}
 
 	var digest string
 	if !isDir {
-		digest, err = object.addReader(r, nil, names, noExtensionHook)
+		digest, err = object.addReader(r, nil, names, nil, noExtensionHook)
 		if err != nil {
 			return "", errors.Wrapf(err, "cannot add file '%s' to object", path)
 		}
@@ -791,7 +1004,7 @@ func (object *ObjectBase) AddReader(r io.ReadCloser, files []string, area string
 	}
 
 	if !noExtensionHook {
-		if err := object.extensionManager.AddFileAfter(object, nil, names.ExternalPaths, names.ManifestPath, digest, area, isDir); err != nil {
+		if err := object.extensionManager.AddFileAfter(object, sourceFS, names.ExternalPaths, names.ManifestPath, digest, area, isDir); err != nil {
 			return "", errors.Wrapf(err, "error on AddFileAfter() extension hook")
 		}
 	}
@@ -822,6 +1035,27 @@ func (object *ObjectBase) AddData(data []byte, path string, checkDuplicate bool,
 
 	object.updateFiles = append(object.updateFiles, newPath)
 
+	var sourceFS fs.FS
+	if !noExtensionHook && !isDir && object.extensionManager.InspectContent() {
+		var cleanup func()
+		sourceFS, cleanup, err = spoolContent(bytes.NewReader(data), path)
+		if err != nil {
+			return errors.Wrapf(err, "cannot buffer '%s' for content inspection", path)
+		}
+		defer cleanup()
+	}
+
+	// the hooks check the path rules before duplicates are stored as virtual copies
+	if !noExtensionHook {
+		if err := object.extensionManager.AddFileBefore(object, sourceFS, path, names.InternalPath, area, false); err != nil {
+			if errors.Is(err, ErrSkipFile) {
+				object.logger.Info().Msgf("[%s] skipping file %s:%s", object.GetID(), area, path)
+				return nil
+			}
+			return errors.Wrapf(err, "error on AddFileBefore() extension hook")
+		}
+	}
+
 	var dataReader = bytes.NewReader(data)
 	if checkDuplicate {
 		// do the checksum
@@ -856,22 +1090,16 @@ func (object *ObjectBase) AddData(data []byte, path string, checkDuplicate bool,
 		}
 	}
 
-	if !noExtensionHook {
-		if err := object.extensionManager.AddFileBefore(object, nil, path, names.InternalPath, area, false); err != nil {
-			return errors.Wrapf(err, "error on AddFileBefore() extension hook")
-		}
-	}
-
 	var r = io.NopCloser(dataReader)
 	if !isDir {
-		digest, err = object.addReader(r, nil, names, noExtensionHook)
+		digest, err = object.addReader(r, nil, names, nil, noExtensionHook)
 		if err != nil {
 			return errors.Wrapf(err, "cannot add file '%s' to object", path)
 		}
 	}
 
 	if !noExtensionHook {
-		if err := object.extensionManager.AddFileAfter(object, nil, names.ExternalPaths, names.ManifestPath, digest, area, isDir); err != nil {
+		if err := object.extensionManager.AddFileAfter(object, sourceFS, names.ExternalPaths, names.ManifestPath, digest, area, isDir); err != nil {
 			return errors.Wrapf(err, "error on AddFileAfter() extension hook")
 		}
 	}
@@ -912,6 +1140,35 @@ func (object *ObjectBase) AddFile(fsys fs.FS, versionFS fs.FS, path string, chec
 
 		object.updateFiles = append(object.updateFiles, newPath)
 
+		// inspected content is read once from the source, so the stored content is the inspected one
+		var sourceFS = fsys
+		var spooled bool
+		if !noExtensionHook && object.extensionManager.InspectContent() {
+			spoolFS, cleanup, err := spoolContent(file, path)
+			file.Close()
+			if err != nil {
+				return errors.Wrapf(err, "cannot buffer '%s' for content inspection", path)
+			}
+			defer cleanup()
+			if file, err = spoolFS.Open(path); err != nil {
+				return errors.Wrapf(err, "cannot open buffered '%s'", path)
+			}
+			sourceFS = spoolFS
+			spooled = true
+		}
+
+		// the hooks check the path rules before duplicates are stored as virtual copies
+		if !noExtensionHook {
+			if err := object.extensionManager.AddFileBefore(object, sourceFS, path, names.InternalPath, area, isDir); err != nil {
+				file.Close()
+				if errors.Is(err, ErrSkipFile) {
+					object.logger.Info().Msgf("[%s] skipping file %s:%s", object.GetID(), area, path)
+					return nil
+				}
+				return errors.Wrapf(err, "error on AddFileBefore() extension hook")
+			}
+		}
+
 		if checkDuplicate {
 			// do the checksum
 			digest, err = checksum.Checksum(file, object.i.GetDigestAlgorithm())
@@ -926,7 +1183,7 @@ func (object *ObjectBase) AddFile(fsys fs.FS, versionFS fs.FS, path string, chec
 				}
 			} else {
 				// otherwise reopen it
-				file, err = fsys.Open(path)
+				file, err = sourceFS.Open(path)
 				if err != nil {
 					return errors.Wrapf(err, "cannot open file '%v/%s'", fsys, path)
 				}
@@ -937,11 +1194,13 @@ func (object *ObjectBase) AddFile(fsys fs.FS, versionFS fs.FS, path string, chec
 				return errors.Wrapf(err, "cannot check duplicate for '%s' [%s]", names.InternalPath, digest)
 			}
 			if dup {
+				file.Close()
 				object.logger.Info().Msgf("[%s] '%s' already exists. ignoring", object.GetID(), newPath)
 				return nil
 			}
 			// file already ingested, but new virtual name
 			if dups := object.i.GetDuplicates(digest); len(dups) > 0 {
+				file.Close()
 				object.logger.Info().Msgf("[%s] file with same content as '%s' already exists. creating virtual copy", object.GetID(), newPath)
 				if err := object.i.CopyFile(newPath, digest); err != nil {
 					return errors.Wrapf(err, "cannot append '%s' to inventory as '%s'", path, names.InternalPath)
@@ -953,16 +1212,24 @@ func (object *ObjectBase) AddFile(fsys fs.FS, versionFS fs.FS, path string, chec
 				digestAlgorithms = append(digestAlgorithms, object.i.GetDigestAlgorithm())
 			}
 		}
-		if !noExtensionHook {
-			if err := object.extensionManager.AddFileBefore(object, nil, path, names.InternalPath, area, isDir); err != nil {
-				return errors.Wrapf(err, "error on AddFileBefore() extension hook")
+		known := knownDigests(object.ctx, area, path)
+		var linked string
+		if !spooled {
+			// a link would store the source, which may have changed since the inspection
+			linked, err = object.addLink(file, area, path, names, known, noExtensionHook)
+			if err != nil {
+				file.Close()
+				return errors.Wrapf(err, "cannot link file '%s' to object", path)
 			}
 		}
-
-		digest, err = object.addReader(file, versionFS, names, noExtensionHook)
-		if err != nil {
-			file.Close()
-			return errors.Wrapf(err, "cannot add file '%s' to object", path)
+		if linked != "" {
+			digest = linked
+		} else {
+			digest, err = object.addReader(file, versionFS, names, known, noExtensionHook)
+			if err != nil {
+				file.Close()
+				return errors.Wrapf(err, "cannot add file '%s' to object", path)
+			}
 		}
 		if err := file.Close(); err != nil {
 			return errors.Wrapf(err, "cannot close file '%s'", path)
@@ -1359,6 +1626,29 @@ func (object *ObjectBase) createContentManifest() (map[checksum.DigestAlgorithm]
 
 	result := map[checksum.DigestAlgorithm]map[string][]string{}
 	versions := object.i.GetVersionStrings()
+	// the manifest lists the content files. other files of the versions are not counted
+	reporter := progress.FromContext(object.ctx)
+	var manifestFiles = map[string]bool{}
+	if reporter != progress.Nil {
+		for _, paths := range object.i.GetManifest() {
+			for _, p := range paths {
+				manifestFiles[p] = true
+			}
+		}
+		// all files of the version folders are read
+		var bytes int64
+		for _, version := range versions {
+			_, size, err := progress.Count(object.fsys, version)
+			if err != nil {
+				object.logger.Debug().Err(err).Msgf("cannot count files of '%v/%s'", object.fsys, version)
+				bytes = -1
+				break
+			}
+			bytes += size
+		}
+		reporter.Start("validate", object.GetID(), int64(len(manifestFiles)), bytes)
+		defer reporter.Finish()
+	}
 	for _, version := range versions {
 		if err := fs.WalkDir(
 			object.fsys,
@@ -1379,6 +1669,17 @@ func (object *ObjectBase) createContentManifest() (map[checksum.DigestAlgorithm]
 				if err != nil {
 					return errors.Wrapf(err, "cannot read and create checksums for file '%s'", fname)
 				}
+				if reporter != progress.Nil {
+					var size int64
+					if fi, err := d.Info(); err == nil {
+						size = fi.Size()
+					}
+					var files int64
+					if manifestFiles[fname] {
+						files = 1
+					}
+					reporter.Advance(fname, files, size)
+				}
 				for d, cs := range css {
 					if _, ok := result[d]; !ok {
 						result[d] = map[string][]string{}
@@ -1456,6 +1757,18 @@ func (object *ObjectBase) Extract(fsys fs.FS, version string, withManifest bool,
 	var manifest strings.Builder
 	var err error
 	var digestAlg = object.i.GetDigestAlgorithm()
+	reporter := progress.FromContext(object.ctx)
+	if reporter != progress.Nil {
+		var files int64
+		if err := object.i.IterateStateFiles(version, func(internals, externals []string, digest string) error {
+			files += int64(len(externals))
+			return nil
+		}); err != nil {
+			return errors.Wrap(err, "cannot iterate external files")
+		}
+		reporter.Start("extract", object.GetID(), files, -1)
+		defer reporter.Finish()
+	}
 	if err := object.i.IterateStateFiles(version, func(internals, externals []string, digest string) error {
 		for _, external := range externals {
 			external, err = object.extensionManager.BuildObjectExtractPath(object, external, area)
@@ -1493,6 +1806,13 @@ func (object *ObjectBase) Extract(fsys fs.FS, version string, withManifest bool,
 				if copyDigest != digest {
 					return errors.Errorf("invalid digest for '%s' - [%s] != [%s]", internal, copyDigests, digest)
 				}
+				if reporter != progress.Nil {
+					var size int64
+					if fi, err := src.Stat(); err == nil {
+						size = fi.Size()
+					}
+					reporter.Advance(external, 1, size)
+				}
 				return nil
 			}(); err != nil {
 				return err
@@ -0,0 +1,166 @@
+package progress
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// Event is a snapshot of the progress of a running phase.
+// FilesTotal and BytesTotal are -1 if unknown
+type Event struct {
+	Phase      string        `json:"phase"`
+	Object     string        `json:"object,omitempty"`
+	Path       string        `json:"path,omitempty"`
+	Files      int64         `json:"files"`
+	FilesTotal int64         `json:"filesTotal"`
+	Bytes      int64         `json:"bytes"`
+	BytesTotal int64         `json:"bytesTotal"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Throughput float64       `json:"throughput"`
+	ETA        time.Duration `json:"eta"`
+	Done       bool          `json:"done"`
+}
+
+// Reporter receives progress information from object and storage root code
+type Reporter interface {
+	// Start begins a new phase (i.e. "add", "extract" or "validate") for one object
+	Start(phase, object string, filesTotal, bytesTotal int64)
+	// Advance reports processed files and bytes. path is the last processed file
+	Advance(path string, files, bytes int64)
+	// Finish ends the current phase
+	Finish()
+}
+
+// Sink is the output of a progress tracker
+type Sink interface {
+	Emit(event *Event)
+}
+
+type nilReporter struct{}
+
+func (nilReporter) Start(string, string, int64, int64) {}
+func (nilReporter) Advance(string, int64, int64)       {}
+func (nilReporter) Finish()                            {}
+
+// Nil is a reporter which does nothing
+var Nil Reporter = nilReporter{}
+
+// Count returns number and size of the files below root. it reads the directory listing only,
+// which is cheap for local folders, zip files and s3
+func Count(fsys fs.FS, root string) (files, bytes int64, err error) {
+	err = fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files++
+		bytes += fi.Size()
+		return nil
+	})
+	return files, bytes, err
+}
+
+type contextKey struct{}
+
+// NewContext returns a context which carries the reporter
+func NewContext(ctx context.Context, reporter Reporter) context.Context {
+	return context.WithValue(ctx, contextKey{}, reporter)
+}
+
+// FromContext returns the reporter of the context or Nil
+func FromContext(ctx context.Context) Reporter {
+	if ctx == nil {
+		return Nil
+	}
+	if reporter, ok := ctx.Value(contextKey{}).(Reporter); ok && reporter != nil {
+		return reporter
+	}
+	return Nil
+}
+
+// Tracker computes throughput and eta and emits events to a sink at most once per interval
+type Tracker struct {
+	sync.Mutex
+	sink     Sink
+	interval time.Duration
+	event    Event
+	start    time.Time
+	last     time.Time
+}
+
+func NewTracker(sink Sink, interval time.Duration) *Tracker {
+	return &Tracker{
+		sink:     sink,
+		interval: interval,
+	}
+}
+
+func (t *Tracker) Start(phase, object string, filesTotal, bytesTotal int64) {
+	t.Lock()
+	defer t.Unlock()
+	t.start = time.Now()
+	t.last = time.Time{}
+	t.event = Event{
+		Phase:      phase,
+		Object:     object,
+		FilesTotal: filesTotal,
+		BytesTotal: bytesTotal,
+	}
+	t.emit(true)
+}
+
+func (t *Tracker) Advance(path string, files, bytes int64) {
+	t.Lock()
+	defer t.Unlock()
+	t.event.Path = path
+	t.event.Files += files
+	t.event.Bytes += bytes
+	t.emit(false)
+}
+
+func (t *Tracker) Finish() {
+	t.Lock()
+	defer t.Unlock()
+	t.event.Done = true
+	t.emit(true)
+}
+
+func (t *Tracker) emit(force bool) {
+	now := time.Now()
+	if !force && now.Sub(t.last) < t.interval {
+		return
+	}
+	t.last = now
+	e := t.event
+	e.Elapsed = now.Sub(t.start)
+	if secs := e.Elapsed.Seconds(); secs > 0 {
+		e.Throughput = float64(e.Bytes) / secs
+	}
+	switch {
+	case e.Done:
+		e.ETA = 0
+	case e.BytesTotal > 0 && e.Bytes > 0:
+		e.ETA = time.Duration(float64(e.Elapsed) * float64(e.BytesTotal-e.Bytes) / float64(e.Bytes))
+	case e.FilesTotal > 0 && e.Files > 0:
+		e.ETA = time.Duration(float64(e.Elapsed) * float64(e.FilesTotal-e.Files) / float64(e.Files))
+	default:
+		e.ETA = -1
+	}
+	if e.ETA < -1 {
+		e.ETA = 0
+	}
+	t.sink.Emit(&e)
+}
+
+var (
+	_ Reporter = &Tracker{}
+	_ Reporter = nilReporter{}
+)
@@ -0,0 +1,126 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type testSink struct {
+	events []Event
+}
+
+func (s *testSink) Emit(e *Event) {
+	s.events = append(s.events, *e)
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != Nil {
+		t.Errorf("reporter of empty context is not Nil")
+	}
+	tracker := NewTracker(&testSink{}, time.Second)
+	if FromContext(NewContext(context.Background(), tracker)) != tracker {
+		t.Errorf("reporter not carried by context")
+	}
+	if FromContext(NewContext(context.Background(), nil)) != Nil {
+		t.Errorf("nil reporter is not Nil")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	sink := &testSink{}
+	tracker := NewTracker(sink, time.Hour)
+	tracker.Start("add", "id:1", 4, 400)
+	tracker.Advance("a", 1, 100)
+	tracker.Advance("b", 1, 100)
+	// events within the interval are dropped
+	if len(sink.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(sink.events))
+	}
+	start := sink.events[0]
+	if start.Phase != "add" || start.Object != "id:1" || start.FilesTotal != 4 || start.BytesTotal != 400 || start.Done {
+		t.Errorf("unexpected start event %+v", start)
+	}
+	if start.ETA != -1 {
+		t.Errorf("eta without progress: expected -1, got %v", start.ETA)
+	}
+	tracker.Finish()
+	if len(sink.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(sink.events))
+	}
+	done := sink.events[1]
+	if !done.Done || done.Files != 2 || done.Bytes != 200 || done.Path != "b" || done.ETA != 0 {
+		t.Errorf("unexpected finish event %+v", done)
+	}
+
+	// a new phase starts with empty counters
+	tracker = NewTracker(sink, 0)
+	tracker.Start("validate", "", 10, -1)
+	time.Sleep(10 * time.Millisecond)
+	tracker.Advance("c", 5, 0)
+	e := sink.events[len(sink.events)-1]
+	if e.Files != 5 || e.Object != "" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	// half of the files took the elapsed time
+	if e.ETA <= 0 || e.ETA > 2*e.Elapsed {
+		t.Errorf("eta %v not estimated from files, elapsed %v", e.ETA, e.Elapsed)
+	}
+	if e.Throughput != 0 {
+		t.Errorf("throughput without bytes: %v", e.Throughput)
+	}
+}
+
+func TestTTYSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewTTYSink(&buf)
+	sink.Emit(&Event{Phase: "add", Object: "id:1", Files: 3, FilesTotal: -1, Bytes: 2048, BytesTotal: -1, ETA: -1})
+	line := buf.String()
+	for _, str := range []string{"add id:1", " 3 files ", "2.0 kB ", "ETA --:--:--"} {
+		if !strings.Contains(line, str) {
+			t.Errorf("'%s' not in '%s'", str, line)
+		}
+	}
+	if strings.HasSuffix(line, "\n") {
+		t.Errorf("running phase ends line")
+	}
+
+	buf.Reset()
+	sink.Emit(&Event{Phase: "extract", Files: 5, FilesTotal: 10, Bytes: 50, BytesTotal: 100, ETA: 90 * time.Minute})
+	line = buf.String()
+	for _, str := range []string{"[" + strings.Repeat("=", barWidth/2) + strings.Repeat(" ", barWidth/2) + "]", " 50% ", "5/10 files", "ETA 01:30:00"} {
+		if !strings.Contains(line, str) {
+			t.Errorf("'%s' not in '%s'", str, line)
+		}
+	}
+
+	buf.Reset()
+	sink.Emit(&Event{Phase: "extract", Files: 12, FilesTotal: 10, Done: true})
+	line = buf.String()
+	if !strings.Contains(line, "100%") || !strings.HasSuffix(line, "\n") {
+		t.Errorf("unexpected finished line '%s'", line)
+	}
+}
+
+func TestCount(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":       {Data: []byte("aaa")},
+		"sub/b.txt":   {Data: []byte("bb")},
+		"sub/c/d.txt": {Data: []byte("")},
+		"other/e.txt": {Data: []byte("eeeee")},
+	}
+	files, bytes, err := Count(fsys, ".")
+	if err != nil || files != 4 || bytes != 10 {
+		t.Errorf("expected 4 files with 10 bytes, got %d, %d, %v", files, bytes, err)
+	}
+	files, bytes, err = Count(fsys, "sub")
+	if err != nil || files != 2 || bytes != 2 {
+		t.Errorf("expected 2 files with 2 bytes in sub, got %d, %d, %v", files, bytes, err)
+	}
+	if _, _, err := Count(fsys, "missing"); err == nil {
+		t.Errorf("missing root not detected")
+	}
+}
@@ -0,0 +1,100 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/je4/utils/v2/pkg/zLogger"
+)
+
+// IsTerminal checks whether f is a character device
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+const barWidth = 30
+
+// TTYSink renders a progress bar in one line
+type TTYSink struct {
+	w io.Writer
+}
+
+func NewTTYSink(w io.Writer) *TTYSink {
+	return &TTYSink{w: w}
+}
+
+func formatETA(eta time.Duration) string {
+	if eta < 0 {
+		return "--:--:--"
+	}
+	eta = eta.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(eta.Hours()), int(eta.Minutes())%60, int(eta.Seconds())%60)
+}
+
+func (s *TTYSink) Emit(e *Event) {
+	var ratio float64
+	switch {
+	case e.BytesTotal > 0:
+		ratio = float64(e.Bytes) / float64(e.BytesTotal)
+	case e.FilesTotal > 0:
+		ratio = float64(e.Files) / float64(e.FilesTotal)
+	}
+	if e.Done {
+		ratio = 1
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
+	files := fmt.Sprintf("%d", e.Files)
+	if e.FilesTotal >= 0 {
+		files += fmt.Sprintf("/%d", e.FilesTotal)
+	}
+	bytes := humanize.Bytes(uint64(e.Bytes))
+	if e.BytesTotal >= 0 {
+		bytes += "/" + humanize.Bytes(uint64(e.BytesTotal))
+	}
+	fmt.Fprintf(s.w, "\r%s %s [%s] %3.0f%% %s files %s %s/s ETA %s\033[K",
+		e.Phase, e.Object, bar, ratio*100, files, bytes, humanize.Bytes(uint64(e.Throughput)), formatETA(e.ETA))
+	if e.Done {
+		fmt.Fprintln(s.w)
+	}
+}
+
+// LogSink writes structured progress events for non-interactive runs
+type LogSink struct {
+	logger zLogger.ZLogger
+}
+
+func NewLogSink(logger zLogger.ZLogger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Emit(e *Event) {
+	s.logger.Info().
+		Str("phase", e.Phase).
+		Str("object", e.Object).
+		Int64("files", e.Files).
+		Int64("filesTotal", e.FilesTotal).
+		Int64("bytes", e.Bytes).
+		Int64("bytesTotal", e.BytesTotal).
+		Float64("throughput", e.Throughput).
+		Dur("elapsed", e.Elapsed).
+		Dur("eta", e.ETA).
+		Bool("done", e.Done).
+		Msg("progress")
+}
+
+var (
+	_ Sink = &TTYSink{}
+	_ Sink = &LogSink{}
+)
@@ -101,6 +101,17 @@ func CreateStorageRoot(ctx context.Context, fsys fs.FS, ver version.OCFLVersion,
 	return storageRoot, nil
 }
 
+// NewDryRunStorageRoot creates a storage root for layout resolution only. in contrast to CreateStorageRoot
+// nothing is written to fsys
+func NewDryRunStorageRoot(ctx context.Context, fsys fs.FS, ver version.OCFLVersion, extensionFactory *extension.ExtensionFactory, extensionManager ExtensionManager, digest checksum.DigestAlgorithm, logger zLogger.ZLogger) (StorageRoot, error) {
+	storageRoot, err := newStorageRoot(ctx, fsys, ver, extensionFactory, extensionManager, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot instantiate storage root")
+	}
+	storageRoot.SetDigest(digest)
+	return storageRoot, nil
+}
+
 func LoadStorageRoot(ctx context.Context, fsys fs.FS, extensionFactory *extension.ExtensionFactory, logger zLogger.ZLogger) (StorageRoot, error) {
 	ver, err := util.GetVersion(ctx, fsys, ".", "ocfl_")
 	if err != nil && !errors.Is(err, ocflerrors.ErrVersionNone) {
@@ -18,6 +18,7 @@ import (
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/extension"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/object"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/ocflerrors"
+	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/progress"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/stat"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/util"
 	"github.com/ocfl-archive/gocfl/v2/pkg/ocfl/validation"
@@ -286,6 +287,10 @@ func (osr *StorageRootBase) ObjectExists(id string) (bool, error) {
 
 // all folder trees, which end in a folder containing a file
 func (osr *StorageRootBase) GetObjectFolders() ([]string, error) {
+	// the number of objects is not known before the scan
+	reporter := progress.FromContext(osr.ctx)
+	reporter.Start("scan objects", "", -1, -1)
+	defer reporter.Finish()
 	var recurse func(base string) ([]string, error)
 	recurse = func(base string) ([]string, error) {
 		des, err := fs.ReadDir(osr.fsys, base)
@@ -307,6 +312,7 @@ func (osr *StorageRootBase) GetObjectFolders() ([]string, error) {
 					continue
 				}
 				result = append(result, base)
+				reporter.Advance(base, 1, 0)
 				break
 			}
 
@@ -350,6 +356,7 @@ func (osr *StorageRootBase) CreateObject(id string, ver version.OCFLVersion, dig
 
 	// create initial filesystem structure for new object
 	if err = object.Init(id, digest, fixity, manager); err != nil {
+		_ = object.Unlock()
 		return nil, errors.Wrap(err, "cannot initialize object")
 	}
 
@@ -387,8 +394,12 @@ func (osr *StorageRootBase) CheckDirectory() (err error) {
 	if err != nil {
 		return errors.Wrap(err, "cannot get files")
 	}
+	reporter := progress.FromContext(osr.ctx)
+	reporter.Start("validate storage root", "", int64(len(files)), -1)
+	defer reporter.Finish()
 	var ver version.OCFLVersion
 	for _, file := range files {
+		reporter.Advance(file.Name(), 1, 0)
 		if file.IsDir() {
 			continue
 		} else {